@@ -6,6 +6,7 @@ require (
 	github.com/googollee/go-socket.io v1.7.0
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.30.0
+	github.com/yuin/gopher-lua v1.1.1
 )
 
 require (
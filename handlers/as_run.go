@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// AsRunLogger zapisuje do dziennika emisji wszystko, co trafiło na antenę
+// Źródłem są eventy OBS (zmiany widoczności, sceny, start mediów), więc wpisy
+// powstają niezależnie od tego, czy zmianę zrobił kontroler, rundown czy ktoś w OBS
+type AsRunLogger struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+
+	itemNames *sceneItemNames
+
+	// OBS bez CreateRecordChapter - nie próbuj ponownie
+	recordChaptersUnsupported atomic.Bool
+}
+
+func NewAsRunLogger(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler) *AsRunLogger {
+	return &AsRunLogger{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		itemNames:     newSceneItemNames(obsClient),
+	}
+}
+
+// Start rozpoczyna nasłuchiwanie na eventy programu z OBS
+func (al *AsRunLogger) Start() {
+	log.Println("Starting As-Run Logger...")
+
+	al.OBSClient.OnEvent("CurrentProgramSceneChanged", func(event map[string]interface{}) {
+		sceneName, _ := event["sceneName"].(string)
+		al.Log(&models.AsRunEntry{
+			EventType: models.AsRunProgramScene,
+			SceneName: sceneName,
+			Title:     sceneName,
+		})
+	})
+
+	al.OBSClient.OnEvent("SceneItemEnableStateChanged", func(event map[string]interface{}) {
+		sceneName, _ := event["sceneName"].(string)
+		itemID, _ := event["sceneItemId"].(float64)
+		enabled, _ := event["sceneItemEnabled"].(bool)
+		al.handleItemEnabled(sceneName, int(itemID), enabled)
+	})
+
+	al.OBSClient.OnEvent("MediaInputPlaybackStarted", func(event map[string]interface{}) {
+		inputName, _ := event["inputName"].(string)
+		al.handleMediaStarted(inputName)
+	})
+
+	log.Println("As-Run Logger started successfully")
+}
+
+// Log zapisuje wpis dla aktualnego odcinka (czas i offset od startu programu)
+func (al *AsRunLogger) Log(entry *models.AsRunEntry) {
+	episode, err := models.GetCurrentEpisode(al.DB)
+	if err != nil {
+		return
+	}
+
+	entry.EpisodeID = episode.ID
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	if timing, err := models.GetShowTiming(al.DB, episode.ID); err == nil && timing.StartedAt != nil {
+		if !entry.Timestamp.Before(*timing.StartedAt) {
+			offset := entry.Timestamp.Sub(*timing.StartedAt).Milliseconds()
+			entry.OffsetMs = &offset
+		}
+	}
+
+	if err := al.DB.Create(entry).Error; err != nil {
+		log.Printf("As-run: błąd zapisu wpisu %s: %v", entry.EventType, err)
+		return
+	}
+
+	al.SocketHandler.Broadcast("as_run_entry", entry)
+
+	if entry.OffsetMs != nil {
+		al.markRecordChapter(entry)
+	}
+}
+
+// MediaEnded zapisuje koniec odtwarzania z faktycznym czasem (wywoływane przez MediaMonitor)
+func (al *AsRunLogger) MediaEnded(inputName string, reason string) {
+	entry := &models.AsRunEntry{
+		EventType:  models.AsRunMediaEnd,
+		SourceName: inputName,
+		Title:      inputName,
+	}
+
+	episode, err := models.GetCurrentEpisode(al.DB)
+	if err != nil {
+		return
+	}
+
+	// Dopasuj ostatni start w tym źródle
+	var start models.AsRunEntry
+	err = al.DB.Where("episode_id = ? AND source_name = ? AND event_type = ?", episode.ID, inputName, models.AsRunMediaStart).
+		Order("timestamp DESC").
+		First(&start).Error
+	if err == nil {
+		entry.Title = start.Title
+		entry.MediaID = start.MediaID
+		entry.DurationMs = time.Since(start.Timestamp).Milliseconds()
+	}
+
+	details, _ := json.Marshal(map[string]string{"reason": reason})
+	entry.Details = string(details)
+
+	al.Log(entry)
+}
+
+// Overlay zapisuje komunikat wysłany do overlay
+func (al *AsRunLogger) Overlay(data map[string]interface{}) {
+	title, _ := data["action"].(string)
+	details, _ := json.Marshal(data)
+
+	al.Log(&models.AsRunEntry{
+		EventType: models.AsRunOverlay,
+		Title:     title,
+		Details:   string(details),
+	})
+}
+
+// SegmentStarted zapisuje start segmentu rundownu
+func (al *AsRunLogger) SegmentStarted(segment models.RundownSegment, at time.Time) {
+	segmentID := segment.ID
+	al.Log(&models.AsRunEntry{
+		Timestamp: at,
+		EventType: models.AsRunSegment,
+		SceneName: segment.SceneName,
+		Title:     segment.Title,
+		SegmentID: &segmentID,
+	})
+}
+
+// handleItemEnabled zapisuje pokazanie/zdjęcie źródła lub otwarcie/zamknięcie mikrofonu
+func (al *AsRunLogger) handleItemEnabled(sceneName string, itemID int, enabled bool) {
+	sourceName := al.itemNames.Get(sceneName, itemID)
+	if sourceName == "" {
+		sourceName = fmt.Sprintf("#%d", itemID)
+	}
+
+	entry := &models.AsRunEntry{
+		SceneName:  sceneName,
+		SourceName: sourceName,
+		Title:      sourceName,
+	}
+
+	if sceneName == rundownMicScene {
+		entry.EventType = models.AsRunMicClose
+		if enabled {
+			entry.EventType = models.AsRunMicOpen
+		}
+		al.fillMicrophonePerson(entry)
+	} else {
+		entry.EventType = models.AsRunSourceOff
+		if enabled {
+			entry.EventType = models.AsRunSourceOn
+		}
+	}
+
+	al.Log(entry)
+}
+
+// handleMediaStarted zapisuje start odtwarzania (tytuł z media wczytanego do źródła)
+func (al *AsRunLogger) handleMediaStarted(inputName string) {
+	entry := &models.AsRunEntry{
+		EventType:  models.AsRunMediaStart,
+		SourceName: inputName,
+		Title:      inputName,
+	}
+
+	if al.SocketHandler.MediaMonitor != nil {
+		if media, ok := al.SocketHandler.MediaMonitor.GetLoaded(inputName); ok {
+			mediaID := media.ID
+			entry.MediaID = &mediaID
+			entry.Title = media.Title
+		}
+	}
+
+	al.Log(entry)
+}
+
+// fillMicrophonePerson uzupełnia wpis mikrofonu o osobę przypisaną w odcinku
+func (al *AsRunLogger) fillMicrophonePerson(entry *models.AsRunEntry) {
+	episode, err := models.GetCurrentEpisode(al.DB)
+	if err != nil {
+		return
+	}
+
+	assignment, err := models.GetEpisodeSourceAssignment(al.DB, episode.ID, entry.SourceName)
+	if err != nil || assignment == nil {
+		return
+	}
+
+	if assignment.StaffID != nil {
+		var staff models.Staff
+		if err := al.DB.First(&staff, *assignment.StaffID).Error; err == nil {
+			entry.StaffID = assignment.StaffID
+			entry.Title = staff.FirstName + " " + staff.LastName
+		}
+	} else if assignment.GuestID != nil {
+		var guest models.Guest
+		if err := al.DB.First(&guest, *assignment.GuestID).Error; err == nil {
+			entry.GuestID = assignment.GuestID
+			entry.Title = guest.FirstName + " " + guest.LastName
+		}
+	}
+}
+
+type AsRunHandler struct {
+	DB *gorm.DB
+}
+
+func NewAsRunHandler(db *gorm.DB) *AsRunHandler {
+	return &AsRunHandler{DB: db}
+}
+
+// Nazwy typów wpisów w raporcie
+var asRunLabels = map[string]string{
+	models.AsRunProgramScene: "SCENA",
+	models.AsRunSourceOn:     "NA ANTENIE",
+	models.AsRunSourceOff:    "ZDJĘTE",
+	models.AsRunMicOpen:      "MIKROFON ON",
+	models.AsRunMicClose:     "MIKROFON OFF",
+	models.AsRunMediaStart:   "MEDIA START",
+	models.AsRunMediaEnd:     "MEDIA KONIEC",
+	models.AsRunOverlay:      "OVERLAY",
+	models.AsRunSegment:      "SEGMENT",
+}
+
+// loadEntries pobiera wpisy odcinka (opcjonalnie filtrowane ?type=a,b)
+func (h *AsRunHandler) loadEntries(w http.ResponseWriter, r *http.Request) (*models.Episode, []models.AsRunEntry, bool) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	var episode models.Episode
+	if err := h.DB.Preload("Season").First(&episode, episodeID).Error; err != nil {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	query := h.DB.Where("episode_id = ?", episodeID)
+	if types := r.URL.Query().Get("type"); types != "" {
+		query = query.Where("event_type IN ?", strings.Split(types, ","))
+	}
+
+	var entries []models.AsRunEntry
+	if err := query.Order("timestamp ASC, id ASC").Find(&entries).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return &episode, entries, true
+}
+
+// GetAsRun - GET /api/episodes/{episode_id}/as-run[?type=mic_open,mic_close]
+func (h *AsRunHandler) GetAsRun(w http.ResponseWriter, r *http.Request) {
+	_, entries, ok := h.loadEntries(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetAsRunCSV - GET /api/episodes/{episode_id}/as-run/csv
+func (h *AsRunHandler) GetAsRunCSV(w http.ResponseWriter, r *http.Request) {
+	episode, entries, ok := h.loadEntries(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"as-run_S%02dE%02d.csv\"", episode.Season.Number, episode.SeasonEpisode))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"timestamp", "offset", "offset_ms", "event_type", "scene", "source", "title", "media_id", "segment_id", "staff_id", "guest_id", "duration_ms", "details"})
+
+	for _, entry := range entries {
+		offset, offsetMs := "", ""
+		if entry.OffsetMs != nil {
+			offset = formatOffset(*entry.OffsetMs)
+			offsetMs = strconv.FormatInt(*entry.OffsetMs, 10)
+		}
+
+		writer.Write([]string{
+			entry.Timestamp.Format(time.RFC3339Nano),
+			offset,
+			offsetMs,
+			entry.EventType,
+			entry.SceneName,
+			entry.SourceName,
+			entry.Title,
+			formatOptionalID(entry.MediaID),
+			formatOptionalID(entry.SegmentID),
+			formatOptionalID(entry.StaffID),
+			formatOptionalID(entry.GuestID),
+			strconv.FormatInt(entry.DurationMs, 10),
+			entry.Details,
+		})
+	}
+
+	writer.Flush()
+}
+
+// GetAsRunReport - GET /api/episodes/{episode_id}/as-run/report
+// Czytelny raport tekstowy (dla archiwum i postprodukcji)
+func (h *AsRunHandler) GetAsRunReport(w http.ResponseWriter, r *http.Request) {
+	episode, entries, ok := h.loadEntries(w, r)
+	if !ok {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "DZIENNIK EMISJI (AS-RUN)\n")
+	fmt.Fprintf(&b, "Odcinek: S%02dE%02d (#%d) %s\n", episode.Season.Number, episode.SeasonEpisode, episode.EpisodeNumber, episode.Title)
+	fmt.Fprintf(&b, "Data odcinka: %s\n", episode.EpisodeDate.Format("2006-01-02"))
+
+	if timing, err := models.GetShowTiming(h.DB, episode.ID); err == nil && timing.StartedAt != nil {
+		fmt.Fprintf(&b, "Start programu: %s (%s)\n", timing.StartedAt.Format("2006-01-02 15:04:05"), timing.StartedBy)
+		if timing.EndedAt != nil {
+			fmt.Fprintf(&b, "Koniec programu: %s (czas: %s)\n", timing.EndedAt.Format("2006-01-02 15:04:05"), formatOffset(timing.EndedAt.Sub(*timing.StartedAt).Milliseconds()))
+		}
+	}
+	fmt.Fprintf(&b, "Wpisów: %d\n\n", len(entries))
+
+	for _, entry := range entries {
+		offset := "   --   "
+		if entry.OffsetMs != nil {
+			offset = formatOffset(*entry.OffsetMs)
+		}
+
+		label := asRunLabels[entry.EventType]
+		if label == "" {
+			label = entry.EventType
+		}
+
+		line := fmt.Sprintf("[%s] %s  %-13s", offset, entry.Timestamp.Format("15:04:05"), label)
+		if entry.SceneName != "" && entry.EventType != models.AsRunProgramScene {
+			line += " " + entry.SceneName + " /"
+		}
+		if entry.SourceName != "" && entry.SourceName != entry.Title {
+			line += " " + entry.SourceName + " -"
+		}
+		line += " " + entry.Title
+		if entry.EventType == models.AsRunMediaEnd && entry.DurationMs > 0 {
+			line += fmt.Sprintf(" (odtwarzano %s)", formatOffset(entry.DurationMs))
+		}
+
+		b.WriteString(line + "\n")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// ClearAsRun - DELETE /api/episodes/{episode_id}/as-run (np. po próbie)
+func (h *AsRunHandler) ClearAsRun(w http.ResponseWriter, r *http.Request) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Where("episode_id = ?", episodeID).Delete(&models.AsRunEntry{}).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// formatOffset formatuje czas w milisekundach jako HH:MM:SS
+func formatOffset(ms int64) string {
+	seconds := ms / 1000
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, (seconds%3600)/60, seconds%60)
+}
+
+// formatOptionalID formatuje opcjonalne ID (pusty tekst dla NULL)
+func formatOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
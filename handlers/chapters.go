@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Minimalna długość rozdziału (YouTube ignoruje listy z krótszymi rozdziałami)
+const minChapterLengthMs = 10 * 1000
+
+// Tytuł rozdziału dodawanego na początku, gdy pierwsze zdarzenie nie jest w 00:00
+const openingChapterTitle = "Początek"
+
+// Chapter reprezentuje rozdział nagrania wyliczony z dziennika emisji
+type Chapter struct {
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+	Title   string `json:"title"`
+	Source  string `json:"source"` // segment, media, guest, opening
+}
+
+type ChaptersHandler struct {
+	DB *gorm.DB
+}
+
+func NewChaptersHandler(db *gorm.DB) *ChaptersHandler {
+	return &ChaptersHandler{DB: db}
+}
+
+// guestChapterTitle zwraca tytuł rozdziału dla gościa: "Imię Nazwisko – temat"
+func guestChapterTitle(db *gorm.DB, episodeID uint, guestID uint) string {
+	var guest models.Guest
+	if err := db.First(&guest, guestID).Error; err != nil {
+		return ""
+	}
+
+	title := guest.FirstName + " " + guest.LastName
+
+	var episodeGuest models.EpisodeGuest
+	if err := db.Where("episode_id = ? AND guest_id = ?", episodeID, guestID).First(&episodeGuest).Error; err == nil && episodeGuest.Topic != "" {
+		title += " – " + episodeGuest.Topic
+	}
+
+	return title
+}
+
+// segmentChapterTitle zwraca tytuł rozdziału dla segmentu
+// Rozmowa z gośćmi → goście i temat, pozostałe → tytuł segmentu
+func segmentChapterTitle(db *gorm.DB, episodeID uint, segmentID uint) string {
+	var segment models.RundownSegment
+	if err := db.Preload("Guests").First(&segment, segmentID).Error; err != nil {
+		return ""
+	}
+
+	if segment.Type == models.SegmentTypeInterview && len(segment.Guests) > 0 {
+		titles := make([]string, 0, len(segment.Guests))
+		for _, guest := range segment.Guests {
+			if title := guestChapterTitle(db, episodeID, guest.GuestID); title != "" {
+				titles = append(titles, title)
+			}
+		}
+		if len(titles) > 0 {
+			return strings.Join(titles, ", ")
+		}
+	}
+
+	return segment.Title
+}
+
+// BuildChapters wylicza rozdziały z przejść segmentów, mediów i gości zapisanych w as-run
+// Zwraca rozdziały posortowane od 00:00, każdy z czasem końca
+// Gdy odcinek był nagrywany, czasy liczone są od startu nagrywania (pasują do pliku),
+// w przeciwnym razie od startu programu
+func BuildChapters(db *gorm.DB, episodeID uint) ([]Chapter, error) {
+	var entries []models.AsRunEntry
+	err := db.Where("episode_id = ? AND offset_ms IS NOT NULL AND event_type IN ?", episodeID,
+		[]string{models.AsRunSegment, models.AsRunMediaStart, models.AsRunMicOpen}).
+		Order("timestamp ASC, id ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var recordStart *time.Time
+	if timing, err := models.GetShowTiming(db, episodeID); err == nil {
+		recordStart = timing.RecordStartedAt
+	}
+
+	// Gdy odcinek szedł według rundownu, segmenty wyznaczają rozmowy
+	// i wejścia mikrofonów gości nie są potrzebne
+	hasSegments := false
+	for _, entry := range entries {
+		if entry.EventType == models.AsRunSegment {
+			hasSegments = true
+			break
+		}
+	}
+
+	chapters := make([]Chapter, 0)
+	seenGuests := make(map[uint]bool)
+
+	for _, entry := range entries {
+		chapter := Chapter{StartMs: *entry.OffsetMs}
+		if recordStart != nil {
+			chapter.StartMs = entry.Timestamp.Sub(*recordStart).Milliseconds()
+			if chapter.StartMs < 0 {
+				chapter.StartMs = 0 // Zdarzenie sprzed startu nagrywania trwa na początku pliku
+			}
+		}
+
+		switch entry.EventType {
+		case models.AsRunSegment:
+			if entry.SegmentID == nil {
+				continue
+			}
+			chapter.Title = segmentChapterTitle(db, episodeID, *entry.SegmentID)
+			chapter.Source = "segment"
+		case models.AsRunMediaStart:
+			chapter.Title = entry.Title
+			if entry.MediaID != nil {
+				var media models.EpisodeMedia
+				if err := db.First(&media, *entry.MediaID).Error; err == nil {
+					chapter.Title = media.Title
+				}
+			}
+			chapter.Source = "media"
+		case models.AsRunMicOpen:
+			// Tylko pierwsze wejście gościa
+			if hasSegments || entry.GuestID == nil || seenGuests[*entry.GuestID] {
+				continue
+			}
+			seenGuests[*entry.GuestID] = true
+			chapter.Title = guestChapterTitle(db, episodeID, *entry.GuestID)
+			chapter.Source = "guest"
+		}
+
+		if chapter.Title == "" {
+			continue
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].StartMs < chapters[j].StartMs })
+
+	// Scal: zbyt krótki rozdział zastępowany następnym, powtórzone tytuły łączone
+	merged := make([]Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.Title == chapter.Title {
+				continue
+			}
+			if chapter.StartMs-last.StartMs < minChapterLengthMs {
+				start := last.StartMs
+				*last = chapter
+				last.StartMs = start
+				continue
+			}
+		}
+		merged = append(merged, chapter)
+	}
+
+	// Pierwszy rozdział musi zaczynać się w 00:00
+	if len(merged) > 0 && merged[0].StartMs > 0 {
+		if merged[0].StartMs < minChapterLengthMs {
+			merged[0].StartMs = 0
+		} else {
+			merged = append([]Chapter{{StartMs: 0, Title: openingChapterTitle, Source: "opening"}}, merged...)
+		}
+	}
+
+	// Czas końca: początek następnego rozdziału, a dla ostatniego - koniec programu
+	endMs := showEndOffset(db, episodeID, recordStart)
+	for i := range merged {
+		if i+1 < len(merged) {
+			merged[i].EndMs = merged[i+1].StartMs
+		} else {
+			merged[i].EndMs = endMs
+			if merged[i].EndMs < merged[i].StartMs {
+				merged[i].EndMs = merged[i].StartMs
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// showEndOffset zwraca koniec programu względem startu nagrywania lub programu
+// (gdy program trwa - ostatni wpis as-run)
+func showEndOffset(db *gorm.DB, episodeID uint, recordStart *time.Time) int64 {
+	if timing, err := models.GetShowTiming(db, episodeID); err == nil && timing.StartedAt != nil && timing.EndedAt != nil {
+		if recordStart != nil {
+			return timing.EndedAt.Sub(*recordStart).Milliseconds()
+		}
+		return timing.EndedAt.Sub(*timing.StartedAt).Milliseconds()
+	}
+
+	var last models.AsRunEntry
+	if err := db.Where("episode_id = ? AND offset_ms IS NOT NULL", episodeID).Order("timestamp DESC").First(&last).Error; err == nil {
+		if recordStart != nil {
+			return last.Timestamp.Sub(*recordStart).Milliseconds()
+		}
+		return *last.OffsetMs
+	}
+	return 0
+}
+
+// formatChapterTime formatuje czas w formacie YouTube (MM:SS lub H:MM:SS)
+func formatChapterTime(ms int64, withHours bool) string {
+	seconds := ms / 1000
+	if withHours {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, (seconds%3600)/60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// escapeFFMetadata zabezpiecza znaki specjalne formatu FFMETADATA1
+func escapeFFMetadata(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "=", "\\=", ";", "\\;", "#", "\\#", "\n", "\\\n")
+	return replacer.Replace(value)
+}
+
+// loadChapters pobiera rozdziały odcinka z parametru URL
+func (h *ChaptersHandler) loadChapters(w http.ResponseWriter, r *http.Request) (*models.Episode, []Chapter, bool) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	var episode models.Episode
+	if err := h.DB.Preload("Season").First(&episode, episodeID).Error; err != nil {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	chapters, err := BuildChapters(h.DB, episode.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return &episode, chapters, true
+}
+
+// GetChapters - GET /api/episodes/{episode_id}/chapters
+func (h *ChaptersHandler) GetChapters(w http.ResponseWriter, r *http.Request) {
+	_, chapters, ok := h.loadChapters(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chapters)
+}
+
+// GetYouTubeChapters - GET /api/episodes/{episode_id}/chapters/youtube
+// Lista "00:00 Tytuł" do wklejenia w opis filmu
+func (h *ChaptersHandler) GetYouTubeChapters(w http.ResponseWriter, r *http.Request) {
+	_, chapters, ok := h.loadChapters(w, r)
+	if !ok {
+		return
+	}
+
+	withHours := len(chapters) > 0 && chapters[len(chapters)-1].EndMs >= 3600*1000
+
+	var b strings.Builder
+	for _, chapter := range chapters {
+		fmt.Fprintf(&b, "%s %s\n", formatChapterTime(chapter.StartMs, withHours), chapter.Title)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// GetFFMetadataChapters - GET /api/episodes/{episode_id}/chapters/ffmetadata
+// Plik do wstawienia rozdziałów: ffmpeg -i in.mp4 -i chapters.txt -map_metadata 1 -codec copy out.mp4
+func (h *ChaptersHandler) GetFFMetadataChapters(w http.ResponseWriter, r *http.Request) {
+	episode, chapters, ok := h.loadChapters(w, r)
+	if !ok {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	fmt.Fprintf(&b, "title=%s\n", escapeFFMetadata(episode.Title))
+
+	for _, chapter := range chapters {
+		b.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+		fmt.Fprintf(&b, "START=%d\nEND=%d\n", chapter.StartMs, chapter.EndMs)
+		fmt.Fprintf(&b, "title=%s\n", escapeFFMetadata(chapter.Title))
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"chapters_S%02dE%02d.txt\"", episode.Season.Number, episode.SeasonEpisode))
+	w.Write([]byte(b.String()))
+}
+
+// GetPodcastChapters - GET /api/episodes/{episode_id}/chapters/podcast
+// Format JSON Chapters z Podcasting 2.0 (application/json+chapters)
+func (h *ChaptersHandler) GetPodcastChapters(w http.ResponseWriter, r *http.Request) {
+	_, chapters, ok := h.loadChapters(w, r)
+	if !ok {
+		return
+	}
+
+	type podcastChapter struct {
+		StartTime float64 `json:"startTime"`
+		EndTime   float64 `json:"endTime,omitempty"`
+		Title     string  `json:"title"`
+	}
+
+	items := make([]podcastChapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		items = append(items, podcastChapter{
+			StartTime: float64(chapter.StartMs) / 1000,
+			EndTime:   float64(chapter.EndMs) / 1000,
+			Title:     chapter.Title,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json+chapters")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":  "1.2.0",
+		"chapters": items,
+	})
+}
+
+// markRecordChapter dodaje rozdział do trwającego nagrania w OBS (segmenty i media)
+// Przy starszym OBS (brak CreateRecordChapter) funkcja wyłącza się po pierwszej próbie
+func (al *AsRunLogger) markRecordChapter(entry *models.AsRunEntry) {
+	if al.recordChaptersUnsupported.Load() {
+		return
+	}
+
+	var title string
+	switch entry.EventType {
+	case models.AsRunSegment:
+		if entry.SegmentID != nil {
+			title = segmentChapterTitle(al.DB, entry.EpisodeID, *entry.SegmentID)
+		}
+	case models.AsRunMediaStart:
+		title = entry.Title
+	}
+	if title == "" {
+		return
+	}
+
+	// Wywołanie OBS poza wątkiem wywołującym - Log jest wołany m.in. pod blokadą rundownu
+	go func() {
+		err := al.OBSClient.CreateRecordChapter(title)
+		if errors.Is(err, obsws.ErrUnsupportedRequest) {
+			al.recordChaptersUnsupported.Store(true)
+			log.Println("As-run: OBS nie obsługuje CreateRecordChapter - rozdziały tylko w eksporcie")
+			return
+		}
+		if err == nil {
+			log.Printf("As-run: rozdział nagrania \"%s\"", title)
+		}
+		// Pozostałe błędy (np. brak nagrywania lub format bez rozdziałów) są ignorowane
+	}()
+}
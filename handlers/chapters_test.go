@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"obs-controller/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// seedChapterEntries zapisuje starty mediów w podanych sekundach od startu programu
+func seedChapterEntries(t *testing.T, db *gorm.DB, episodeID uint, showStart time.Time, seconds map[string]int) {
+	t.Helper()
+	for title, sec := range seconds {
+		offset := int64(sec) * 1000
+		entry := models.AsRunEntry{
+			EpisodeID: episodeID,
+			Timestamp: showStart.Add(time.Duration(sec) * time.Second),
+			EventType: models.AsRunMediaStart,
+			Title:     title,
+			OffsetMs:  &offset,
+		}
+		if err := db.Create(&entry).Error; err != nil {
+			t.Fatalf("create as-run entry: %v", err)
+		}
+	}
+}
+
+func chapterStarts(chapters []Chapter) map[string]int64 {
+	starts := make(map[string]int64)
+	for _, chapter := range chapters {
+		starts[chapter.Title] = chapter.StartMs
+	}
+	return starts
+}
+
+func TestBuildChaptersUsesRecordStart(t *testing.T) {
+	db := openTestDB(t)
+	episode := createCurrentEpisode(t, db, nil)
+
+	showStart := time.Now().Add(-time.Hour)
+	recordStart := showStart.Add(time.Minute)
+	ended := showStart.Add(10 * time.Minute)
+	timing, _ := models.GetShowTiming(db, episode.ID)
+	db.Model(timing).Updates(map[string]interface{}{
+		"started_at":        &showStart,
+		"ended_at":          &ended,
+		"record_started_at": &recordStart,
+	})
+
+	seedChapterEntries(t, db, episode.ID, showStart, map[string]int{"Intro": 30, "Reportaż": 120, "Rozmowa": 300})
+
+	chapters, err := BuildChapters(db, episode.ID)
+	if err != nil {
+		t.Fatalf("build chapters: %v", err)
+	}
+
+	want := map[string]int64{"Intro": 0, "Reportaż": 60_000, "Rozmowa": 240_000}
+	got := chapterStarts(chapters)
+	for title, start := range want {
+		if got[title] != start {
+			t.Errorf("%s starts at %d ms, want %d ms (chapters %+v)", title, got[title], start, chapters)
+		}
+	}
+	if last := chapters[len(chapters)-1]; last.EndMs != 540_000 {
+		t.Errorf("last chapter ends at %d ms, want 540000 (show end from record start)", last.EndMs)
+	}
+}
+
+func TestBuildChaptersWithoutRecordingUsesShowStart(t *testing.T) {
+	db := openTestDB(t)
+	episode := createCurrentEpisode(t, db, nil)
+
+	showStart := time.Now().Add(-time.Hour)
+	timing, _ := models.GetShowTiming(db, episode.ID)
+	db.Model(timing).Update("started_at", &showStart)
+
+	seedChapterEntries(t, db, episode.ID, showStart, map[string]int{"Intro": 0, "Reportaż": 120})
+
+	chapters, err := BuildChapters(db, episode.ID)
+	if err != nil {
+		t.Fatalf("build chapters: %v", err)
+	}
+
+	got := chapterStarts(chapters)
+	if got["Intro"] != 0 || got["Reportaż"] != 120_000 {
+		t.Errorf("chapters = %+v, want offsets from show start", chapters)
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Domyślna prędkość przewijania napisów końcowych (px/s)
+const creditsDefaultSpeed = 60
+
+// CreditsName - osoba w napisach końcowych
+type CreditsName struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"` // Goście: typ gościa
+}
+
+// CreditsGroup - grupa napisów (typ ekipy lub goście)
+type CreditsGroup struct {
+	Title       string        `json:"title"`
+	StaffTypeID *uint         `json:"staff_type_id,omitempty"` // nil = goście
+	Names       []CreditsName `json:"names"`
+}
+
+// Credits - napisy końcowe odcinka
+type Credits struct {
+	EpisodeID uint           `json:"episode_id"`
+	Title     string         `json:"title"`
+	Groups    []CreditsGroup `json:"groups"`
+}
+
+// BuildCredits układa napisy końcowe odcinka: ekipa pogrupowana po typach
+// (typeOrder lub StaffType.CreditsOrder), na końcu goście w kolejności wystąpienia
+// Osoba z kilkoma typami w odcinku występuje w każdej ze swoich grup
+func BuildCredits(db *gorm.DB, episodeID uint, typeOrder []uint) (*Credits, error) {
+	var episode models.Episode
+	if err := db.First(&episode, episodeID).Error; err != nil {
+		return nil, err
+	}
+
+	var staff []models.EpisodeStaff
+	if err := db.Preload("Staff").Preload("StaffTypes.StaffType").
+		Where("episode_id = ?", episodeID).Find(&staff).Error; err != nil {
+		return nil, err
+	}
+
+	groups := make(map[uint]*CreditsGroup)
+	types := make(map[uint]models.StaffType)
+	for _, member := range staff {
+		for _, staffType := range member.StaffTypes {
+			group, ok := groups[staffType.StaffTypeID]
+			if !ok {
+				typeID := staffType.StaffTypeID
+				group = &CreditsGroup{Title: staffType.StaffType.Name, StaffTypeID: &typeID}
+				groups[typeID] = group
+				types[typeID] = staffType.StaffType
+			}
+			group.Names = append(group.Names, CreditsName{
+				Name: member.Staff.FirstName + " " + member.Staff.LastName,
+			})
+		}
+	}
+
+	// Kolejność typów: najpierw wskazane w typeOrder, pozostałe wg CreditsOrder i nazwy
+	position := make(map[uint]int, len(typeOrder))
+	for i, typeID := range typeOrder {
+		position[typeID] = i
+	}
+	typeIDs := make([]uint, 0, len(groups))
+	for typeID := range groups {
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Slice(typeIDs, func(i, j int) bool {
+		a, b := typeIDs[i], typeIDs[j]
+		posA, okA := position[a]
+		posB, okB := position[b]
+		if okA != okB {
+			return okA
+		}
+		if okA {
+			return posA < posB
+		}
+		if types[a].CreditsOrder != types[b].CreditsOrder {
+			return types[a].CreditsOrder < types[b].CreditsOrder
+		}
+		return types[a].Name < types[b].Name
+	})
+
+	credits := &Credits{
+		EpisodeID: episode.ID,
+		Title:     episode.Title,
+		Groups:    make([]CreditsGroup, 0, len(typeIDs)+1),
+	}
+	for _, typeID := range typeIDs {
+		group := groups[typeID]
+		sort.SliceStable(group.Names, func(i, j int) bool {
+			return creditsSortKey(group.Names[i].Name) < creditsSortKey(group.Names[j].Name)
+		})
+		credits.Groups = append(credits.Groups, *group)
+	}
+
+	var guests []models.EpisodeGuest
+	if err := db.Preload("Guest.GuestType").Where("episode_id = ?", episodeID).
+		Order("segment_order ASC").Order("id ASC").Find(&guests).Error; err != nil {
+		return nil, err
+	}
+	if len(guests) > 0 {
+		group := CreditsGroup{Title: "Goście", Names: make([]CreditsName, 0, len(guests))}
+		for _, guest := range guests {
+			group.Names = append(group.Names, CreditsName{
+				Name:   guest.Guest.FirstName + " " + guest.Guest.LastName,
+				Detail: guest.Guest.GuestType.Name,
+			})
+		}
+		credits.Groups = append(credits.Groups, group)
+	}
+
+	return credits, nil
+}
+
+// creditsSortKey - sortowanie po nazwisku ("Imię Nazwisko" → "nazwisko imię")
+func creditsSortKey(name string) string {
+	parts := strings.Fields(strings.ToLower(name))
+	if len(parts) < 2 {
+		return strings.Join(parts, " ")
+	}
+	return parts[len(parts)-1] + " " + strings.Join(parts[:len(parts)-1], " ")
+}
+
+// parseCreditsTypeOrder odczytuje kolejność typów ekipy z "3,1,2"
+func parseCreditsTypeOrder(value string) ([]uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var order []uint
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type_order")
+		}
+		order = append(order, uint(id))
+	}
+	return order, nil
+}
+
+// CreditsRollRequest - polecenie wyświetlenia napisów końcowych (socket, REST, makra)
+type CreditsRollRequest struct {
+	EpisodeID uint   `json:"episode_id"` // 0 = aktualny odcinek
+	Speed     int    `json:"speed"`      // px/s (0 = domyślnie)
+	Channel   string `json:"channel"`
+	TypeOrder []uint `json:"type_order"` // Kolejność typów ekipy (pusta = StaffType.CreditsOrder)
+}
+
+// rollCredits buduje napisy końcowe i wyświetla je szablonem credits
+// Overlay zdejmuje szablon sam po przewinięciu wszystkich napisów
+func rollCredits(sh *SocketHandler, req CreditsRollRequest) (*OverlayItem, error) {
+	if sh.Overlay == nil {
+		return nil, fmt.Errorf("Overlay niedostępny")
+	}
+	if req.Speed < 0 {
+		return nil, fmt.Errorf("speed nie może być ujemne")
+	}
+	if req.Speed == 0 {
+		req.Speed = creditsDefaultSpeed
+	}
+
+	if req.EpisodeID == 0 {
+		episode, err := models.GetCurrentEpisode(sh.DB)
+		if err != nil {
+			return nil, fmt.Errorf("Brak aktualnego odcinka")
+		}
+		req.EpisodeID = episode.ID
+	}
+
+	credits, err := BuildCredits(sh.DB, req.EpisodeID, req.TypeOrder)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("Episode not found")
+		}
+		return nil, err
+	}
+	if len(credits.Groups) == 0 {
+		return nil, fmt.Errorf("Odcinek nie ma ekipy ani gości")
+	}
+
+	item, err := sh.Overlay.Show(req.Channel, OverlayTemplateCredits, map[string]interface{}{
+		"title":      credits.Title,
+		"groups":     credits.Groups,
+		"speed":      req.Speed,
+		"episode_id": credits.EpisodeID,
+	}, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Napisy końcowe: odcinek %d", credits.EpisodeID)
+	return item, nil
+}
+
+type CreditsHandler struct {
+	DB            *gorm.DB
+	SocketHandler *SocketHandler
+}
+
+func NewCreditsHandler(db *gorm.DB, socketHandler *SocketHandler) *CreditsHandler {
+	return &CreditsHandler{DB: db, SocketHandler: socketHandler}
+}
+
+// GetCredits - GET /api/episodes/{episode_id}/credits?type_order=3,1,2
+func (h *CreditsHandler) GetCredits(w http.ResponseWriter, r *http.Request) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	typeOrder, err := parseCreditsTypeOrder(r.URL.Query().Get("type_order"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	credits, err := BuildCredits(h.DB, uint(episodeID), typeOrder)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Episode not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credits)
+}
+
+// RollCredits - POST /api/credits/roll
+// Body (opcjonalne): {"episode_id": 0, "speed": 60, "channel": "main", "type_order": [3, 1]}
+func (h *CreditsHandler) RollCredits(w http.ResponseWriter, r *http.Request) {
+	var req CreditsRollRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	item, err := rollCredits(h.SocketHandler, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
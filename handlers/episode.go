@@ -11,7 +11,8 @@ import (
 )
 
 type EpisodeHandler struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	SocketHandler *SocketHandler // Kontrolery przechodzą do pokoju nowego aktualnego odcinka (nil = bez powiadomień)
 }
 
 func NewEpisodeHandler(db *gorm.DB) *EpisodeHandler {
@@ -82,6 +83,7 @@ func (h *EpisodeHandler) CreateEpisode(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.currentEpisodeChanged(episode.ID)
 	} else {
 		if err := h.DB.Create(&episode).Error; err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -126,12 +128,15 @@ func (h *EpisodeHandler) UpdateEpisode(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.DB.First(&episode, id)
+		h.currentEpisodeChanged(uint(id))
 	} else {
 		episode.SeasonID = updateData.SeasonID
 		episode.EpisodeNumber = updateData.EpisodeNumber
 		episode.SeasonEpisode = updateData.SeasonEpisode
 		episode.Title = updateData.Title
 		episode.EpisodeDate = updateData.EpisodeDate
+		episode.AirTime = updateData.AirTime
+		episode.TargetDuration = updateData.TargetDuration
 		episode.IsCurrent = updateData.IsCurrent
 
 		if err := h.DB.Save(&episode).Error; err != nil {
@@ -194,6 +199,8 @@ func (h *EpisodeHandler) SetCurrentEpisode(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.currentEpisodeChanged(uint(id))
+
 	var episode models.Episode
 	h.DB.Preload("Season").First(&episode, id)
 
@@ -201,6 +208,13 @@ func (h *EpisodeHandler) SetCurrentEpisode(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(episode)
 }
 
+// currentEpisodeChanged - kontrolery dołączają do pokoju nowego aktualnego odcinka
+func (h *EpisodeHandler) currentEpisodeChanged(episodeID uint) {
+	if h.SocketHandler != nil {
+		h.SocketHandler.Broadcast("current_episode_changed", map[string]interface{}{"episode_id": episodeID})
+	}
+}
+
 // GetNextEpisodeNumbers - GET /api/episodes/next-numbers
 func (h *EpisodeHandler) GetNextEpisodeNumbers(w http.ResponseWriter, r *http.Request) {
 	// Pobierz aktualny sezon
@@ -17,9 +17,10 @@ import (
 )
 
 type EpisodeMediaHandler struct {
-	DB        *gorm.DB
-	MediaPath string        // Ścieżka bazowa do mediów
-	OBSClient *obsws.Client // Klient OBS-WebSocket
+	DB           *gorm.DB
+	MediaPath    string        // Ścieżka bazowa do mediów
+	OBSClient    *obsws.Client // Klient OBS-WebSocket
+	MediaMonitor *MediaMonitor // Monitor punktów in/out (opcjonalny)
 }
 
 func NewEpisodeMediaHandler(db *gorm.DB, mediaPath string, obsClient *obsws.Client) *EpisodeMediaHandler {
@@ -74,6 +75,11 @@ func (h *EpisodeMediaHandler) CreateEpisodeMedia(w http.ResponseWriter, r *http.
 
 	media.EpisodeID = uint(episodeID)
 
+	if err := validateMediaTrim(media.InPoint, media.OutPoint); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Sprawdź czy ten sam plik nie jest już przypisany do tego odcinka
 	if media.FilePath != nil && *media.FilePath != "" {
 		var existingMedia models.EpisodeMedia
@@ -92,6 +98,11 @@ func (h *EpisodeMediaHandler) CreateEpisodeMedia(w http.ResponseWriter, r *http.
 		if duration, err := utils.GetMediaDuration(fullPath); err == nil {
 			media.Duration = duration
 		}
+	} else if isURLMedia(media) && media.Duration == 0 {
+		// ffprobe obsługuje też adresy sieciowe
+		if duration, err := probeURLDuration(r.Context(), *media.URL); err == nil {
+			media.Duration = duration
+		}
 	}
 
 	if err := h.DB.Create(&media).Error; err != nil {
@@ -145,6 +156,13 @@ func (h *EpisodeMediaHandler) UpdateEpisodeMedia(w http.ResponseWriter, r *http.
 	media.Description = updateData.Description
 	media.FilePath = updateData.FilePath
 	media.URL = updateData.URL
+	media.InPoint = updateData.InPoint
+	media.OutPoint = updateData.OutPoint
+
+	if err := validateMediaTrim(media.InPoint, media.OutPoint); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Jeśli zmieniono FilePath, odczytaj nowy duration
 	if media.FilePath != nil && *media.FilePath != "" {
@@ -153,6 +171,10 @@ func (h *EpisodeMediaHandler) UpdateEpisodeMedia(w http.ResponseWriter, r *http.
 		if duration, err := utils.GetMediaDuration(fullPath); err == nil {
 			media.Duration = duration
 		}
+	} else if isURLMedia(media) {
+		if duration, err := probeURLDuration(r.Context(), *media.URL); err == nil {
+			media.Duration = duration
+		}
 	}
 
 	if err := h.DB.Save(&media).Error; err != nil {
@@ -392,25 +414,8 @@ func (h *EpisodeMediaHandler) GetCurrentMediaForScene(w http.ResponseWriter, r *
 		return
 	}
 
-	// 1. Najpierw szukaj przypisania które jest aktywne (current_in_scene = scene.ID lub 0)
-	var assignment models.EpisodeMediaGroup
-	result = h.DB.Preload("EpisodeMedia").
-		Preload("MediaGroup").
-		Where("media_group_id = ? AND (current_in_scene = ? OR current_in_scene = 0)",
-			mediaGroup.ID, scene.ID).
-		Order("\"order\" ASC").
-		First(&assignment)
-
-	// 2. Jeśli nie znaleziono aktywnego, weź plik z najniższym order
-	if result.Error == gorm.ErrRecordNotFound {
-		result = h.DB.Preload("EpisodeMedia").
-			Preload("MediaGroup").
-			Where("media_group_id = ?", mediaGroup.ID).
-			Order("\"order\" ASC").
-			First(&assignment)
-	}
-
-	if result.Error == gorm.ErrRecordNotFound {
+	assignment, err := currentGroupMedia(h.DB, mediaGroup.ID, scene.ID)
+	if err == gorm.ErrRecordNotFound {
 		// Brak mediów w tej grupie
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -420,24 +425,15 @@ func (h *EpisodeMediaHandler) GetCurrentMediaForScene(w http.ResponseWriter, r *
 		return
 	}
 
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	currentMedia := assignment.EpisodeMedia
 
-	// Jeśli mamy plik i OBS jest połączony, ustaw go w źródle
-	if currentMedia.FilePath != nil && *currentMedia.FilePath != "" && h.OBSClient != nil && h.OBSClient.IsConnected() {
-		// Pobierz bezwzględną ścieżkę do katalogu aplikacji
-		absMediaPath, err := filepath.Abs(h.MediaPath)
-		if err != nil {
-			absMediaPath = h.MediaPath
-		}
-
-		// Zbuduj pełną ścieżkę: C:/Users/.../media/season_1/file.mp4
-		fullPath := filepath.Join(absMediaPath, filepath.FromSlash(*currentMedia.FilePath))
-
+	// Jeśli mamy plik (lub URL) i OBS jest połączony, ustaw go w źródle
+	if hasMediaSource(currentMedia) && h.OBSClient != nil && h.OBSClient.IsConnected() {
 		// Określ nazwę źródła w OBS na podstawie nazwy sceny
 		var inputName string
 		if sceneName == "MEDIA" {
@@ -447,16 +443,17 @@ func (h *EpisodeMediaHandler) GetCurrentMediaForScene(w http.ResponseWriter, r *
 		}
 
 		if inputName != "" {
-			// Ustaw ustawienia źródła w OBS
-			err = h.OBSClient.SetInputSettings(inputName, map[string]interface{}{
-				"local_file":          fullPath,
-				"clear_on_media_end":  false,
-				"close_when_inactive": true,
-			})
+			// Ustaw ustawienia źródła w OBS (plik lokalny lub wejście sieciowe dla URL)
+			inputSettings, err := buildMediaInputSettings(h.MediaPath, currentMedia)
+			if err == nil {
+				err = h.OBSClient.SetInputSettings(inputName, inputSettings)
+			}
 
 			if err != nil {
 				// Loguj błąd, ale nie przerywaj - zwróć dane mimo błędu OBS
 				fmt.Printf("Błąd ustawiania pliku w OBS dla źródła %s: %v\n", inputName, err)
+			} else if h.MediaMonitor != nil {
+				h.MediaMonitor.Track(inputName, currentMedia)
 			}
 		}
 	}
@@ -468,7 +465,65 @@ func (h *EpisodeMediaHandler) GetCurrentMediaForScene(w http.ResponseWriter, r *
 		"title":     currentMedia.Title,
 		"file_path": currentMedia.FilePath,
 		"url":       currentMedia.URL,
-		"duration":  currentMedia.Duration,
+		"duration":  currentMedia.PlayDuration(),
+		"in_point":  currentMedia.InPoint,
+		"out_point": currentMedia.OutPoint,
 		"group":     assignment.MediaGroup.Name,
 	})
 }
+
+// CheckMediaURL - GET /api/episodes/{episode_id}/media/{id}/url-status
+// Sprawdza czy URL media odpowiada (dla mediów bez lokalnego pliku)
+func (h *EpisodeMediaHandler) CheckMediaURL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	episodeID, err := strconv.ParseUint(vars["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var media models.EpisodeMedia
+	if err := h.DB.Where("id = ? AND episode_id = ?", id, episodeID).First(&media).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Media not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if media.URL == nil || *media.URL == "" {
+		http.Error(w, "Media has no URL", http.StatusBadRequest)
+		return
+	}
+
+	status := map[string]interface{}{
+		"media_id":  media.ID,
+		"url":       *media.URL,
+		"reachable": true,
+	}
+	if err := utils.CheckURLReachable(*media.URL, mediaURLCheckTimeout); err != nil {
+		status["reachable"] = false
+		status["error"] = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// validateMediaTrim sprawdza poprawność punktów wejścia/wyjścia (w milisekundach)
+func validateMediaTrim(inPoint, outPoint int) error {
+	if inPoint < 0 || outPoint < 0 {
+		return fmt.Errorf("in_point and out_point must not be negative")
+	}
+	if outPoint > 0 && outPoint <= inPoint {
+		return fmt.Errorf("out_point must be greater than in_point")
+	}
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"obs-controller/models"
 	"obs-controller/obsws"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gorilla/mux"
@@ -29,6 +28,14 @@ func NewEpisodeSourceHandler(db *gorm.DB, obsClient *obsws.Client, mediaPath str
 	}
 }
 
+// journal zwraca dziennik operacji (nil gdy niedostępny)
+func (h *EpisodeSourceHandler) journal() *ActionJournal {
+	if h.SocketHandler == nil {
+		return nil
+	}
+	return h.SocketHandler.Journal
+}
+
 // AssignMediaToSource - POST /api/episodes/{episode_id}/sources/{source_name}/assign-media
 // Przypisuje konkretny plik media do źródła Media Source (Media1 lub Reportaze1)
 func (h *EpisodeSourceHandler) AssignMediaToSource(w http.ResponseWriter, r *http.Request) {
@@ -60,34 +67,36 @@ func (h *EpisodeSourceHandler) AssignMediaToSource(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Sprawdź czy plik istnieje
-	if media.FilePath == nil || *media.FilePath == "" {
-		http.Error(w, "Media has no file path", http.StatusBadRequest)
+	// Sprawdź czy jest plik lub URL
+	if !hasMediaSource(media) {
+		http.Error(w, "Media has no file path or URL", http.StatusBadRequest)
 		return
 	}
 
+	// Stan sprzed zmiany do dziennika operacji (undo)
+	snapshot := h.journal().SnapshotSource(uint(episodeID), sourceName, journalFieldMedia)
+
 	// Wczytaj plik do OBS (jeśli połączony)
 	if h.OBSClient != nil && h.OBSClient.IsConnected() {
-		// Pobierz bezwzględną ścieżkę
-		absMediaPath, err := filepath.Abs(h.MediaPath)
+		// Przygotuj ustawienia (plik lokalny lub wejście sieciowe dla URL)
+		inputSettings, err := buildMediaInputSettings(h.MediaPath, media)
 		if err != nil {
-			absMediaPath = h.MediaPath
+			h.broadcastURLUnreachable(uint(episodeID), sourceName, media, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
 		}
 
-		// Zbuduj pełną ścieżkę
-		fullPath := filepath.Join(absMediaPath, filepath.FromSlash(*media.FilePath))
-
 		// Ustaw plik w źródle Media Source
-		err = h.OBSClient.SetInputSettings(sourceName, map[string]interface{}{
-			"local_file":          fullPath,
-			"clear_on_media_end":  false,
-			"close_when_inactive": true,
-		})
+		err = h.OBSClient.SetInputSettings(sourceName, inputSettings)
 
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to set media in OBS: %v", err), http.StatusInternalServerError)
 			return
 		}
+
+		if h.SocketHandler != nil && h.SocketHandler.MediaMonitor != nil {
+			h.SocketHandler.MediaMonitor.Track(sourceName, media)
+		}
 	}
 
 	// Zapisz przypisanie w bazie danych
@@ -96,10 +105,12 @@ func (h *EpisodeSourceHandler) AssignMediaToSource(w http.ResponseWriter, r *htt
 		http.Error(w, fmt.Sprintf("Failed to save assignment: %v", err), http.StatusInternalServerError)
 		return
 	}
+	h.journal().RecordSourceAssignment(operatorFromRequest(r), snapshot,
+		fmt.Sprintf("%s: media \"%s\"", sourceName, media.Title))
 
 	// Wyślij broadcast do wszystkich klientów
 	if h.SocketHandler != nil && h.SocketHandler.Server != nil {
-		h.SocketHandler.Server.BroadcastToNamespace("/", "source_media_assigned", map[string]interface{}{
+		h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_media_assigned", map[string]interface{}{
 			"episode_id":  episodeID,
 			"source_name": sourceName,
 			"media_id":    data.MediaID,
@@ -178,12 +189,15 @@ func (h *EpisodeSourceHandler) GetMediaForSourceModal(w http.ResponseWriter, r *
 	for _, group := range groups {
 		// Przygotuj listę mediów w grupie
 		mediaList := make([]map[string]interface{}, 0)
+		totalDuration := 0
 		for _, item := range group.MediaItems {
 			mediaList = append(mediaList, map[string]interface{}{
-				"id":    item.EpisodeMedia.ID,
-				"title": item.EpisodeMedia.Title,
-				"order": item.Order,
+				"id":       item.EpisodeMedia.ID,
+				"title":    item.EpisodeMedia.Title,
+				"order":    item.Order,
+				"duration": item.EpisodeMedia.PlayDuration(),
 			})
+			totalDuration += item.EpisodeMedia.PlayDuration()
 
 			// Sprawdź czy to jest grupa z aktualnym plikiem
 			if currentMediaID != nil && item.EpisodeMedia.ID == *currentMediaID {
@@ -192,11 +206,12 @@ func (h *EpisodeSourceHandler) GetMediaForSourceModal(w http.ResponseWriter, r *
 		}
 
 		result = append(result, map[string]interface{}{
-			"id":          group.ID,
-			"name":        group.Name,
-			"is_system":   group.IsSystem,
-			"media_items": mediaList,
-			"is_current":  currentGroupID != nil && *currentGroupID == group.ID,
+			"id":             group.ID,
+			"name":           group.Name,
+			"is_system":      group.IsSystem,
+			"media_items":    mediaList,
+			"total_duration": totalDuration,
+			"is_current":     currentGroupID != nil && *currentGroupID == group.ID,
 		})
 	}
 
@@ -283,30 +298,30 @@ func (h *EpisodeSourceHandler) autoAssignForSource(episodeID uint, sourceName st
 
 	media := assignment.EpisodeMedia
 
-	// Sprawdź czy plik istnieje
-	if media.FilePath == nil || *media.FilePath == "" {
+	// Sprawdź czy jest plik lub URL
+	if !hasMediaSource(media) {
 		return false, 0, ""
 	}
 
 	// Wczytaj plik do OBS (jeśli połączony)
 	if h.OBSClient != nil && h.OBSClient.IsConnected() {
-		absMediaPath, err := filepath.Abs(h.MediaPath)
+		inputSettings, err := buildMediaInputSettings(h.MediaPath, media)
 		if err != nil {
-			absMediaPath = h.MediaPath
+			fmt.Printf("Auto-assign: pomijam %s dla %s: %v\n", media.Title, sourceName, err)
+			h.broadcastURLUnreachable(episodeID, sourceName, media, err)
+			return false, 0, ""
 		}
 
-		fullPath := filepath.Join(absMediaPath, filepath.FromSlash(*media.FilePath))
-
-		err = h.OBSClient.SetInputSettings(sourceName, map[string]interface{}{
-			"local_file":          fullPath,
-			"clear_on_media_end":  false,
-			"close_when_inactive": true,
-		})
+		err = h.OBSClient.SetInputSettings(sourceName, inputSettings)
 
 		if err != nil {
 			fmt.Printf("Błąd ustawiania automatycznego pliku w OBS dla %s: %v\n", sourceName, err)
 			return false, 0, ""
 		}
+
+		if h.SocketHandler != nil && h.SocketHandler.MediaMonitor != nil {
+			h.SocketHandler.MediaMonitor.Track(sourceName, media)
+		}
 	}
 
 	// Zapisz przypisanie
@@ -318,7 +333,7 @@ func (h *EpisodeSourceHandler) autoAssignForSource(episodeID uint, sourceName st
 
 	// Wyślij broadcast
 	if h.SocketHandler != nil && h.SocketHandler.Server != nil {
-		h.SocketHandler.Server.BroadcastToNamespace("/", "source_media_assigned", map[string]interface{}{
+		h.SocketHandler.BroadcastToEpisode(episodeID, "source_media_assigned", map[string]interface{}{
 			"episode_id":  episodeID,
 			"source_name": sourceName,
 			"media_id":    media.ID,
@@ -423,25 +438,9 @@ func (h *EpisodeSourceHandler) autoAssignVLCForSource(episodeID uint, sourceName
 		return false, 0, ""
 	}
 
-	// Przygotuj playlistę dla VLC Video Source
-	playlist := make([]map[string]interface{}, 0)
-
-	absMediaPath, err := filepath.Abs(h.MediaPath)
-	if err != nil {
-		absMediaPath = h.MediaPath
-	}
-
-	for _, item := range selectedGroup.MediaItems {
-		// EpisodeMedia jest już załadowane przez Preload("MediaItems.EpisodeMedia")
-		media := item.EpisodeMedia
-
-		if media.FilePath != nil && *media.FilePath != "" {
-			fullPath := filepath.Join(absMediaPath, filepath.FromSlash(*media.FilePath))
-			playlist = append(playlist, map[string]interface{}{
-				"value": fullPath,
-			})
-		}
-	}
+	// Przygotuj playlistę dla VLC Video Source (pliki lokalne i URL)
+	// EpisodeMedia jest już załadowane przez Preload("MediaItems.EpisodeMedia")
+	playlist := buildPlaylist(h.MediaPath, selectedGroup.MediaItems)
 
 	if len(playlist) == 0 {
 		fmt.Printf("Auto-assign VLC: brak prawidłowych plików w grupie %s\n", selectedGroup.Name)
@@ -473,7 +472,7 @@ func (h *EpisodeSourceHandler) autoAssignVLCForSource(episodeID uint, sourceName
 
 	// Wyślij broadcast
 	if h.SocketHandler != nil && h.SocketHandler.Server != nil {
-		h.SocketHandler.Server.BroadcastToNamespace("/", "source_group_assigned", map[string]interface{}{
+		h.SocketHandler.BroadcastToEpisode(episodeID, "source_group_assigned", map[string]interface{}{
 			"episode_id":  episodeID,
 			"source_name": sourceName,
 			"group_id":    selectedGroup.ID,
@@ -523,31 +522,18 @@ func (h *EpisodeSourceHandler) AssignGroupToSource(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Przygotuj playlistę dla VLC Video Source
-	playlist := make([]map[string]interface{}, 0)
-
-	absMediaPath, err := filepath.Abs(h.MediaPath)
-	if err != nil {
-		absMediaPath = h.MediaPath
-	}
-
-	for _, item := range group.MediaItems {
-		// EpisodeMedia jest już załadowane przez Preload("MediaItems.EpisodeMedia")
-		media := item.EpisodeMedia
-
-		if media.FilePath != nil && *media.FilePath != "" {
-			fullPath := filepath.Join(absMediaPath, filepath.FromSlash(*media.FilePath))
-			playlist = append(playlist, map[string]interface{}{
-				"value": fullPath,
-			})
-		}
-	}
+	// Przygotuj playlistę dla VLC Video Source (pliki lokalne i URL)
+	// EpisodeMedia jest już załadowane przez Preload("MediaItems.EpisodeMedia")
+	playlist := buildPlaylist(h.MediaPath, group.MediaItems)
 
 	if len(playlist) == 0 {
 		http.Error(w, "No valid files in group", http.StatusBadRequest)
 		return
 	}
 
+	// Stan sprzed zmiany do dziennika operacji (undo)
+	snapshot := h.journal().SnapshotSource(uint(episodeID), sourceName, journalFieldGroup)
+
 	// Wczytaj playlistę do OBS (jeśli połączony)
 	if h.OBSClient != nil && h.OBSClient.IsConnected() {
 		err = h.OBSClient.SetInputSettings(sourceName, map[string]interface{}{
@@ -568,10 +554,12 @@ func (h *EpisodeSourceHandler) AssignGroupToSource(w http.ResponseWriter, r *htt
 		http.Error(w, fmt.Sprintf("Failed to save assignment: %v", err), http.StatusInternalServerError)
 		return
 	}
+	h.journal().RecordSourceAssignment(operatorFromRequest(r), snapshot,
+		fmt.Sprintf("%s: grupa \"%s\"", sourceName, group.Name))
 
 	// Wyślij broadcast
 	if h.SocketHandler != nil && h.SocketHandler.Server != nil {
-		h.SocketHandler.Server.BroadcastToNamespace("/", "source_group_assigned", map[string]interface{}{
+		h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_group_assigned", map[string]interface{}{
 			"episode_id":  uint(episodeID),
 			"source_name": sourceName,
 			"group_id":    group.ID,
@@ -620,6 +608,12 @@ func (h *EpisodeSourceHandler) GetGroupsForSourceModal(w http.ResponseWriter, r
 		return
 	}
 
+	groupIDs := make([]uint, len(groups))
+	for i, group := range groups {
+		groupIDs[i] = group.ID
+	}
+	totals := models.GetMediaGroupTotalDurations(h.DB, groupIDs)
+
 	// Przygotuj odpowiedź - tylko grupy z ≥2 plikami
 	result := make([]map[string]interface{}, 0)
 
@@ -632,11 +626,12 @@ func (h *EpisodeSourceHandler) GetGroupsForSourceModal(w http.ResponseWriter, r
 		isCurrent := currentGroupID != nil && group.ID == *currentGroupID
 
 		result = append(result, map[string]interface{}{
-			"id":         group.ID,
-			"name":       group.Name,
-			"is_system":  group.IsSystem,
-			"file_count": len(group.MediaItems),
-			"is_current": isCurrent,
+			"id":             group.ID,
+			"name":           group.Name,
+			"is_system":      group.IsSystem,
+			"file_count":     len(group.MediaItems),
+			"total_duration": totals[group.ID],
+			"is_current":     isCurrent,
 		})
 	}
 
@@ -710,7 +705,7 @@ func (h *EpisodeSourceHandler) AutoAssignCameraTypes(w http.ResponseWriter, r *h
 
 		// Broadcast WebSocket
 		if h.SocketHandler != nil {
-			h.SocketHandler.Server.BroadcastToNamespace("/", "source_camera_assigned", map[string]interface{}{
+			h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_camera_assigned", map[string]interface{}{
 				"episode_id":       uint(episodeID),
 				"source_name":      sourceName,
 				"camera_type_id":   cameraType.ID,
@@ -746,15 +741,18 @@ func (h *EpisodeSourceHandler) AssignCameraTypeToSource(w http.ResponseWriter, r
 
 	// Jeśli camera_type_id = null → wyłącz kamerę
 	if data.CameraTypeID == nil {
+		snapshot := h.journal().SnapshotSource(uint(episodeID), sourceName, journalFieldCamera)
 		err := models.DisableEpisodeSourceCamera(h.DB, uint(episodeID), sourceName)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.journal().RecordSourceAssignment(operatorFromRequest(r), snapshot,
+			fmt.Sprintf("%s: kamera wyłączona", sourceName))
 
 		// Broadcast WebSocket
 		if h.SocketHandler != nil {
-			h.SocketHandler.Server.BroadcastToNamespace("/", "source_camera_assigned", map[string]interface{}{
+			h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_camera_assigned", map[string]interface{}{
 				"episode_id":       uint(episodeID),
 				"source_name":      sourceName,
 				"camera_type_id":   nil,
@@ -791,15 +789,18 @@ func (h *EpisodeSourceHandler) AssignCameraTypeToSource(w http.ResponseWriter, r
 	}
 
 	// Przypisz typ kamery
+	snapshot := h.journal().SnapshotSource(uint(episodeID), sourceName, journalFieldCamera)
 	err = models.SetEpisodeSourceCameraType(h.DB, uint(episodeID), sourceName, *data.CameraTypeID, "manual")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.journal().RecordSourceAssignment(operatorFromRequest(r), snapshot,
+		fmt.Sprintf("%s: kamera \"%s\"", sourceName, cameraType.Name))
 
 	// Broadcast WebSocket
 	if h.SocketHandler != nil {
-		h.SocketHandler.Server.BroadcastToNamespace("/", "source_camera_assigned", map[string]interface{}{
+		h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_camera_assigned", map[string]interface{}{
 			"episode_id":       uint(episodeID),
 			"source_name":      sourceName,
 			"camera_type_id":   *data.CameraTypeID,
@@ -1016,14 +1017,17 @@ func (h *EpisodeSourceHandler) AssignMicrophonePerson(w http.ResponseWriter, r *
 
 	// Jeśli PersonID == null, usuń przypisanie
 	if data.PersonID == nil {
+		snapshot := h.journal().SnapshotSource(uint(episodeID), sourceName, journalFieldMicrophone)
 		if err := models.UnassignEpisodeSourceMicrophone(h.DB, uint(episodeID), sourceName); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.journal().RecordSourceAssignment(operatorFromRequest(r), snapshot,
+			fmt.Sprintf("%s: usunięto przypisanie osoby", sourceName))
 
 		// Broadcast WebSocket
 		if h.SocketHandler != nil {
-			h.SocketHandler.Server.BroadcastToNamespace("/", "source_microphone_assigned", map[string]interface{}{
+			h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_microphone_assigned", map[string]interface{}{
 				"episode_id":  episodeID,
 				"source_name": sourceName,
 				"person_id":   nil,
@@ -1061,14 +1065,17 @@ func (h *EpisodeSourceHandler) AssignMicrophonePerson(w http.ResponseWriter, r *
 	}
 
 	// Przypisz osobę do mikrofonu
+	snapshot := h.journal().SnapshotSource(uint(episodeID), sourceName, journalFieldMicrophone)
 	if err := models.SetEpisodeSourceMicrophone(h.DB, uint(episodeID), sourceName, *data.PersonID, data.PersonType, "manual"); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.journal().RecordSourceAssignment(operatorFromRequest(r), snapshot,
+		fmt.Sprintf("%s: %s", sourceName, personName))
 
 	// Broadcast WebSocket
 	if h.SocketHandler != nil {
-		h.SocketHandler.Server.BroadcastToNamespace("/", "source_microphone_assigned", map[string]interface{}{
+		h.SocketHandler.BroadcastToEpisode(uint(episodeID), "source_microphone_assigned", map[string]interface{}{
 			"episode_id":  episodeID,
 			"source_name": sourceName,
 			"person_id":   *data.PersonID,
@@ -1083,3 +1090,19 @@ func (h *EpisodeSourceHandler) AssignMicrophonePerson(w http.ResponseWriter, r *
 		"message": fmt.Sprintf("Assigned %s to %s", personName, sourceName),
 	})
 }
+
+// broadcastURLUnreachable informuje klientów że URL media nie odpowiada
+func (h *EpisodeSourceHandler) broadcastURLUnreachable(episodeID uint, sourceName string, media models.EpisodeMedia, err error) {
+	if !isURLMedia(media) || h.SocketHandler == nil || h.SocketHandler.Server == nil {
+		return
+	}
+
+	h.SocketHandler.BroadcastToEpisode(episodeID, "media_url_unreachable", map[string]interface{}{
+		"episode_id":  episodeID,
+		"source_name": sourceName,
+		"media_id":    media.ID,
+		"title":       media.Title,
+		"url":         *media.URL,
+		"error":       err.Error(),
+	})
+}
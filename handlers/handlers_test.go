@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"obs-controller/models"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB tworzy bazę w pamięci z pełną migracją i seedami
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := models.InitDB(db); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	return db
+}
+
+// newTestSocketHandler tworzy SocketHandler bez połączenia z OBS
+func newTestSocketHandler(t *testing.T, db *gorm.DB) *SocketHandler {
+	t.Helper()
+	h, err := NewSocketHandler(db, nil)
+	if err != nil {
+		t.Fatalf("socket handler: %v", err)
+	}
+	return h
+}
+
+// createCurrentEpisode tworzy aktualny odcinek z podaną godziną emisji
+func createCurrentEpisode(t *testing.T, db *gorm.DB, airTime *time.Time) *models.Episode {
+	t.Helper()
+	season := models.Season{Number: 1}
+	if err := db.Create(&season).Error; err != nil {
+		t.Fatalf("create season: %v", err)
+	}
+	episode := models.Episode{SeasonID: season.ID, EpisodeNumber: 1, SeasonEpisode: 1, Title: "Test", AirTime: airTime}
+	if err := models.CreateEpisodeAsCurrent(db, &episode); err != nil {
+		t.Fatalf("create episode: %v", err)
+	}
+	return &episode
+}
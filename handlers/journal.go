@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	socketio "github.com/googollee/go-socket.io"
+	"gorm.io/gorm"
+)
+
+const (
+	journalKeepPerOperator = 50              // Ile wpisów dziennika trzymać na operatora
+	journalHistoryLimit    = 10              // Domyślna długość historii
+	journalVolumeMerge     = 5 * time.Second // Ruchy suwaka głośności w tym oknie to jedna operacja
+)
+
+// Pola przypisania źródła (do wyboru zdarzenia broadcast przy cofaniu)
+const (
+	journalFieldMedia      = "media"
+	journalFieldGroup      = "group"
+	journalFieldCamera     = "camera"
+	journalFieldMicrophone = "microphone"
+)
+
+// journalVisibility - poprzednia widoczność źródła
+type journalVisibility struct {
+	SceneName  string `json:"scene_name"`
+	SourceName string `json:"source_name"`
+	Visible    bool   `json:"visible"`
+}
+
+// journalVolume - poprzednia głośność wejścia
+type journalVolume struct {
+	InputName string  `json:"input_name"`
+	VolumeDb  float64 `json:"volume_db"`
+}
+
+// journalSourceAssignment - poprzedni stan wpisu EpisodeSource
+type journalSourceAssignment struct {
+	EpisodeID    uint   `json:"episode_id"`
+	SourceName   string `json:"source_name"`
+	Field        string `json:"field"`
+	Existed      bool   `json:"existed"` // false = wpis nie istniał (przypisanie automatyczne)
+	MediaID      *uint  `json:"media_id"`
+	GroupID      *uint  `json:"group_id"`
+	CameraTypeID *uint  `json:"camera_type_id"`
+	StaffID      *uint  `json:"staff_id"`
+	GuestID      *uint  `json:"guest_id"`
+	AssignedBy   string `json:"assigned_by"`
+}
+
+// journalGroupItem - poprzednia wartość current_in_scene jednego media w grupie
+type journalGroupItem struct {
+	ID             uint  `json:"id"`
+	CurrentInScene *uint `json:"current_in_scene"`
+}
+
+// journalGroupCurrent - poprzednie aktywne media w grupie
+type journalGroupCurrent struct {
+	GroupID uint               `json:"group_id"`
+	Items   []journalGroupItem `json:"items"`
+}
+
+// ActionJournal zapisuje odwracalne operacje operatorów i pozwala cofnąć ostatnią z nich
+// Metody Snapshot*/Record* są bezpieczne dla nil (dziennik jest opcjonalny)
+type ActionJournal struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+	MediaPath     string
+
+	mu sync.Mutex // Jedno zapisywanie/cofanie naraz
+}
+
+func NewActionJournal(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler, mediaPath string) *ActionJournal {
+	return &ActionJournal{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		MediaPath:     mediaPath,
+	}
+}
+
+// RecordVisibility zapisuje zmianę widoczności źródła (previous = stan przed zmianą)
+func (j *ActionJournal) RecordVisibility(operator, sceneName, sourceName string, previous bool) {
+	if j == nil {
+		return
+	}
+
+	state := "wyłączone"
+	if !previous {
+		state = "włączone"
+	}
+	j.record(operator, models.JournalKindSourceVisibility,
+		fmt.Sprintf("%s / %s: %s", sceneName, sourceName, state),
+		journalVisibility{SceneName: sceneName, SourceName: sourceName, Visible: previous})
+}
+
+// RecordVolume zapisuje zmianę głośności wejścia
+// Kolejne zmiany tego samego wejścia w krótkim odstępie (suwak) łączy w jeden wpis
+func (j *ActionJournal) RecordVolume(operator, inputName string, previous, current float64) {
+	if j == nil {
+		return
+	}
+
+	description := fmt.Sprintf("%s: %.1f dB → %.1f dB", inputName, previous, current)
+
+	j.mu.Lock()
+	var last models.JournalEntry
+	err := j.DB.Where("operator = ?", operator).Order("id DESC").First(&last).Error
+	if err == nil && last.Kind == models.JournalKindInputVolume && last.UndoneAt == nil &&
+		time.Since(last.CreatedAt) < journalVolumeMerge {
+		var prev journalVolume
+		if json.Unmarshal(last.Previous, &prev) == nil && prev.InputName == inputName {
+			// Zachowaj głośność sprzed pierwszego ruchu, przesuń okno łączenia
+			last.Description = fmt.Sprintf("%s: %.1f dB → %.1f dB", inputName, prev.VolumeDb, current)
+			last.CreatedAt = time.Now()
+			j.DB.Save(&last)
+			j.mu.Unlock()
+			j.broadcastUpdated(operator)
+			return
+		}
+	}
+	j.mu.Unlock()
+
+	j.record(operator, models.JournalKindInputVolume, description,
+		journalVolume{InputName: inputName, VolumeDb: previous})
+}
+
+// SnapshotSource zapamiętuje przypisanie źródła przed zmianą (wywołać przed zapisem)
+func (j *ActionJournal) SnapshotSource(episodeID uint, sourceName, field string) *journalSourceAssignment {
+	if j == nil {
+		return nil
+	}
+
+	snapshot := &journalSourceAssignment{EpisodeID: episodeID, SourceName: sourceName, Field: field}
+
+	var source models.EpisodeSource
+	if err := j.DB.Where("episode_id = ? AND source_name = ?", episodeID, sourceName).First(&source).Error; err == nil {
+		snapshot.Existed = true
+		snapshot.MediaID = source.MediaID
+		snapshot.GroupID = source.GroupID
+		snapshot.CameraTypeID = source.CameraTypeID
+		snapshot.StaffID = source.StaffID
+		snapshot.GuestID = source.GuestID
+		snapshot.AssignedBy = source.AssignedBy
+	}
+	return snapshot
+}
+
+// RecordSourceAssignment zapisuje zmianę przypisania źródła (snapshot z SnapshotSource)
+func (j *ActionJournal) RecordSourceAssignment(operator string, snapshot *journalSourceAssignment, description string) {
+	if j == nil || snapshot == nil {
+		return
+	}
+	j.record(operator, models.JournalKindSourceAssignment, description, snapshot)
+}
+
+// SnapshotGroupCurrent zapamiętuje aktywne media w grupie przed zmianą
+func (j *ActionJournal) SnapshotGroupCurrent(groupID uint) *journalGroupCurrent {
+	if j == nil {
+		return nil
+	}
+
+	var items []models.EpisodeMediaGroup
+	if err := j.DB.Where("media_group_id = ?", groupID).Find(&items).Error; err != nil {
+		return nil
+	}
+
+	snapshot := &journalGroupCurrent{GroupID: groupID}
+	for _, item := range items {
+		snapshot.Items = append(snapshot.Items, journalGroupItem{ID: item.ID, CurrentInScene: item.CurrentInScene})
+	}
+	return snapshot
+}
+
+// RecordGroupCurrent zapisuje zmianę aktywnego media w grupie
+func (j *ActionJournal) RecordGroupCurrent(operator string, snapshot *journalGroupCurrent, description string) {
+	if j == nil || snapshot == nil {
+		return
+	}
+	j.record(operator, models.JournalKindGroupCurrent, description, snapshot)
+}
+
+// record zapisuje wpis dziennika i usuwa najstarsze wpisy operatora ponad limit
+func (j *ActionJournal) record(operator, kind, description string, previous interface{}) {
+	data, err := json.Marshal(previous)
+	if err != nil {
+		log.Printf("Błąd zapisu dziennika operacji: %v", err)
+		return
+	}
+
+	j.mu.Lock()
+	entry := models.JournalEntry{
+		Operator:    operator,
+		Kind:        kind,
+		Description: description,
+		Previous:    data,
+	}
+	if err := j.DB.Create(&entry).Error; err != nil {
+		j.mu.Unlock()
+		log.Printf("Błąd zapisu dziennika operacji: %v", err)
+		return
+	}
+
+	keep := j.DB.Model(&models.JournalEntry{}).Select("id").
+		Where("operator = ?", operator).Order("id DESC").Limit(journalKeepPerOperator)
+	j.DB.Where("operator = ? AND id NOT IN (?)", operator, keep).Delete(&models.JournalEntry{})
+	j.mu.Unlock()
+
+	j.broadcastUpdated(operator)
+}
+
+// History zwraca ostatnie operacje operatora (najnowsze pierwsze)
+func (j *ActionJournal) History(operator string, limit int) ([]models.JournalEntry, error) {
+	if limit <= 0 {
+		limit = journalHistoryLimit
+	}
+
+	var entries []models.JournalEntry
+	err := j.DB.Where("operator = ?", operator).Order("id DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// UndoLast cofa ostatnią nie cofniętą operację operatora (stan OBS i bazy)
+func (j *ActionJournal) UndoLast(operator string) (*models.JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entry models.JournalEntry
+	err := j.DB.Where("operator = ? AND undone_at IS NULL", operator).Order("id DESC").First(&entry).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("Brak operacji do cofnięcia")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := j.undo(entry); err != nil {
+		return nil, fmt.Errorf("Nie udało się cofnąć \"%s\": %v", entry.Description, err)
+	}
+
+	now := time.Now()
+	entry.UndoneAt = &now
+	if err := j.DB.Save(&entry).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("Cofnięto operację [%s] %s: %s", operator, entry.Kind, entry.Description)
+
+	if j.SocketHandler != nil && j.SocketHandler.Server != nil {
+		j.SocketHandler.Broadcast("action_undone", entry)
+	}
+	j.broadcastUpdated(operator)
+
+	return &entry, nil
+}
+
+// undo przywraca stan zapisany we wpisie
+func (j *ActionJournal) undo(entry models.JournalEntry) error {
+	switch entry.Kind {
+	case models.JournalKindSourceVisibility:
+		var prev journalVisibility
+		if err := json.Unmarshal(entry.Previous, &prev); err != nil {
+			return err
+		}
+		if !j.obsConnected() {
+			return fmt.Errorf("OBS nie jest połączony")
+		}
+		return j.SocketHandler.setSourceVisible(prev.SceneName, prev.SourceName, prev.Visible)
+
+	case models.JournalKindInputVolume:
+		var prev journalVolume
+		if err := json.Unmarshal(entry.Previous, &prev); err != nil {
+			return err
+		}
+		if !j.obsConnected() {
+			return fmt.Errorf("OBS nie jest połączony")
+		}
+		if err := j.SocketHandler.setInputVolume(prev.InputName, prev.VolumeDb); err != nil {
+			return err
+		}
+		// Zmiana z naszej aplikacji nie jest broadcastowana przez VolumeMonitor
+		j.SocketHandler.Broadcast("volume_changed", map[string]interface{}{
+			"source_name": prev.InputName,
+			"volume_db":   prev.VolumeDb,
+		})
+		return nil
+
+	case models.JournalKindSourceAssignment:
+		var prev journalSourceAssignment
+		if err := json.Unmarshal(entry.Previous, &prev); err != nil {
+			return err
+		}
+		return j.undoSourceAssignment(prev)
+
+	case models.JournalKindGroupCurrent:
+		var prev journalGroupCurrent
+		if err := json.Unmarshal(entry.Previous, &prev); err != nil {
+			return err
+		}
+		err := j.DB.Transaction(func(tx *gorm.DB) error {
+			for _, item := range prev.Items {
+				if err := tx.Model(&models.EpisodeMediaGroup{}).Where("id = ?", item.ID).
+					Update("current_in_scene", item.CurrentInScene).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return applyGroupCurrentMedia(j.SocketHandler, j.MediaPath, prev.GroupID)
+	}
+
+	return fmt.Errorf("nieznany rodzaj operacji: %s", entry.Kind)
+}
+
+// undoSourceAssignment przywraca wpis EpisodeSource i zawartość źródła media w OBS
+func (j *ActionJournal) undoSourceAssignment(prev journalSourceAssignment) error {
+	var source models.EpisodeSource
+	err := j.DB.Where("episode_id = ? AND source_name = ?", prev.EpisodeID, prev.SourceName).First(&source).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if !prev.Existed {
+		// Wcześniej nie było wpisu - wróć do przypisania automatycznego
+		if err == nil {
+			if err := j.DB.Delete(&source).Error; err != nil {
+				return err
+			}
+		}
+	} else {
+		if err == gorm.ErrRecordNotFound {
+			source = models.EpisodeSource{EpisodeID: prev.EpisodeID, SourceName: prev.SourceName}
+		}
+		source.MediaID = prev.MediaID
+		source.GroupID = prev.GroupID
+		source.CameraTypeID = prev.CameraTypeID
+		source.StaffID = prev.StaffID
+		source.GuestID = prev.GuestID
+		source.AssignedBy = prev.AssignedBy
+		if err := j.DB.Save(&source).Error; err != nil {
+			return err
+		}
+	}
+
+	switch prev.Field {
+	case journalFieldMedia, journalFieldGroup:
+		return j.restoreMediaSource(prev)
+	case journalFieldCamera:
+		j.broadcastCamera(prev)
+	case journalFieldMicrophone:
+		j.broadcastMicrophone(prev)
+	}
+	return nil
+}
+
+// restoreMediaSource wczytuje do OBS poprzedni plik lub playlistę źródła
+// Gdy wcześniej nic nie było przypisane, zawartość źródła w OBS zostaje bez zmian
+func (j *ActionJournal) restoreMediaSource(prev journalSourceAssignment) error {
+	event := map[string]interface{}{
+		"episode_id":  prev.EpisodeID,
+		"source_name": prev.SourceName,
+	}
+
+	switch {
+	case prev.GroupID != nil:
+		if j.obsConnected() {
+			// Ta sama ścieżka co przypisanie grupy: playlista wg kolejności, przypisanie VLC, broadcast
+			_, err := loadGroupIntoSource(j.SocketHandler, j.MediaPath, prev.EpisodeID, prev.SourceName, *prev.GroupID, prev.AssignedBy)
+			return err
+		}
+		var group models.MediaGroup
+		if err := j.DB.First(&group, *prev.GroupID).Error; err != nil {
+			return err
+		}
+		j.SocketHandler.SaveVLCAssignment(prev.EpisodeID, prev.SourceName, group.Name, group.ID)
+		event["group_id"] = group.ID
+		event["name"] = group.Name
+		j.SocketHandler.BroadcastToEpisode(prev.EpisodeID, "source_group_assigned", event)
+
+	case prev.MediaID != nil:
+		var media models.EpisodeMedia
+		if err := j.DB.First(&media, *prev.MediaID).Error; err != nil {
+			return err
+		}
+		if j.obsConnected() {
+			inputSettings, err := buildMediaInputSettings(j.MediaPath, media)
+			if err != nil {
+				return err
+			}
+			if err := j.OBSClient.SetInputSettings(prev.SourceName, inputSettings); err != nil {
+				return err
+			}
+			if j.SocketHandler.MediaMonitor != nil {
+				j.SocketHandler.MediaMonitor.Track(prev.SourceName, media)
+			}
+		}
+		event["media_id"] = media.ID
+		event["title"] = media.Title
+		j.SocketHandler.BroadcastToEpisode(prev.EpisodeID, "source_media_assigned", event)
+
+	default:
+		event["media_id"] = nil
+		event["title"] = prev.SourceName
+		j.SocketHandler.BroadcastToEpisode(prev.EpisodeID, "source_media_assigned", event)
+	}
+
+	return nil
+}
+
+// broadcastCamera informuje klientów o przywróconym typie kamery
+func (j *ActionJournal) broadcastCamera(prev journalSourceAssignment) {
+	event := map[string]interface{}{
+		"episode_id":       prev.EpisodeID,
+		"source_name":      prev.SourceName,
+		"camera_type_id":   nil,
+		"camera_type_name": nil,
+		"is_disabled":      prev.Existed && prev.CameraTypeID == nil && prev.AssignedBy == "manual",
+	}
+
+	if prev.CameraTypeID != nil {
+		var cameraType models.CameraType
+		if err := j.DB.First(&cameraType, *prev.CameraTypeID).Error; err == nil {
+			event["camera_type_id"] = cameraType.ID
+			event["camera_type_name"] = cameraType.Name
+		}
+	}
+
+	j.SocketHandler.BroadcastToEpisode(prev.EpisodeID, "source_camera_assigned", event)
+}
+
+// broadcastMicrophone informuje klientów o przywróconej osobie przy mikrofonie
+func (j *ActionJournal) broadcastMicrophone(prev journalSourceAssignment) {
+	event := map[string]interface{}{
+		"episode_id":  prev.EpisodeID,
+		"source_name": prev.SourceName,
+		"person_id":   nil,
+		"person_type": "",
+		"person_name": prev.SourceName,
+	}
+
+	if prev.StaffID != nil {
+		var staff models.Staff
+		if err := j.DB.First(&staff, *prev.StaffID).Error; err == nil {
+			event["person_id"] = staff.ID
+			event["person_type"] = "staff"
+			event["person_name"] = staff.LastName + " " + staff.FirstName
+		}
+	} else if prev.GuestID != nil {
+		var guest models.Guest
+		if err := j.DB.First(&guest, *prev.GuestID).Error; err == nil {
+			event["person_id"] = guest.ID
+			event["person_type"] = "guest"
+			event["person_name"] = guest.LastName + " " + guest.FirstName
+		}
+	}
+
+	j.SocketHandler.BroadcastToEpisode(prev.EpisodeID, "source_microphone_assigned", event)
+}
+
+func (j *ActionJournal) obsConnected() bool {
+	return j.OBSClient != nil && j.OBSClient.IsConnected()
+}
+
+// broadcastUpdated informuje klientów że historia operatora się zmieniła
+func (j *ActionJournal) broadcastUpdated(operator string) {
+	if j.SocketHandler != nil && j.SocketHandler.Server != nil {
+		j.SocketHandler.Broadcast("journal_updated", map[string]interface{}{
+			"operator": operator,
+		})
+	}
+}
+
+// operatorFromRequest rozpoznaje operatora żądania REST: nagłówek X-Operator lub adres IP klienta
+func operatorFromRequest(r *http.Request) string {
+	if operator := strings.TrimSpace(r.Header.Get("X-Operator")); operator != "" {
+		return operator
+	}
+	return operatorFromAddr(r.RemoteAddr)
+}
+
+// operatorFromConn rozpoznaje operatora połączenia Socket.IO (adres IP klienta)
+func operatorFromConn(s socketio.Conn) string {
+	if operator := strings.TrimSpace(s.RemoteHeader().Get("X-Operator")); operator != "" {
+		return operator
+	}
+	if s.RemoteAddr() == nil {
+		return "unknown"
+	}
+	return operatorFromAddr(s.RemoteAddr().String())
+}
+
+// operatorFromAddr zamienia adres klienta na identyfikator operatora (bez portu)
+func operatorFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "localhost"
+	}
+	return host
+}
+
+// JournalHandler udostępnia dziennik operacji przez REST
+type JournalHandler struct {
+	DB      *gorm.DB
+	Journal *ActionJournal
+}
+
+func NewJournalHandler(db *gorm.DB, journal *ActionJournal) *JournalHandler {
+	return &JournalHandler{DB: db, Journal: journal}
+}
+
+// GetHistory - GET /api/journal?operator=&limit=
+// Domyślnie historia operatora wykonującego żądanie
+func (h *JournalHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	operator := r.URL.Query().Get("operator")
+	if operator == "" {
+		operator = operatorFromRequest(r)
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	entries, err := h.Journal.History(operator, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"operator": operator,
+		"entries":  entries,
+	})
+}
+
+// UndoLast - POST /api/journal/undo
+// Cofa ostatnią operację operatora wykonującego żądanie
+func (h *JournalHandler) UndoLast(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.Journal.UndoLast(operatorFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
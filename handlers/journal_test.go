@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestJournal(t *testing.T, db *gorm.DB) *ActionJournal {
+	t.Helper()
+	return NewActionJournal(db, &obsws.Client{}, newTestSocketHandler(t, db), t.TempDir())
+}
+
+func journalEntries(t *testing.T, db *gorm.DB, operator string) []models.JournalEntry {
+	t.Helper()
+	var entries []models.JournalEntry
+	if err := db.Where("operator = ?", operator).Order("id ASC").Find(&entries).Error; err != nil {
+		t.Fatalf("load journal: %v", err)
+	}
+	return entries
+}
+
+func TestRecordVolumeMergesWithinWindow(t *testing.T) {
+	db := openTestDB(t)
+	j := newTestJournal(t, db)
+
+	j.RecordVolume("op", "Mic1", -10, -8)
+	j.RecordVolume("op", "Mic1", -8, -5)
+
+	entries := journalEntries(t, db, "op")
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1 merged entry", len(entries))
+	}
+	if want := "Mic1: -10.0 dB → -5.0 dB"; entries[0].Description != want {
+		t.Errorf("description = %q, want %q", entries[0].Description, want)
+	}
+
+	// Inne wejście nie jest łączone
+	j.RecordVolume("op", "Mic2", 0, -3)
+	if entries = journalEntries(t, db, "op"); len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2 after another input", len(entries))
+	}
+}
+
+func TestRecordVolumeSplitsAfterWindow(t *testing.T) {
+	db := openTestDB(t)
+	j := newTestJournal(t, db)
+
+	j.RecordVolume("op", "Mic1", -10, -8)
+	past := time.Now().Add(-journalVolumeMerge - time.Second)
+	if err := db.Model(&models.JournalEntry{}).Where("operator = ?", "op").Update("created_at", past).Error; err != nil {
+		t.Fatalf("age entry: %v", err)
+	}
+	j.RecordVolume("op", "Mic1", -8, -5)
+
+	if entries := journalEntries(t, db, "op"); len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2 outside merge window", len(entries))
+	}
+}
+
+func TestUndoGroupCurrentRestoresActiveMedia(t *testing.T) {
+	db := openTestDB(t)
+	j := newTestJournal(t, db)
+	episode := createCurrentEpisode(t, db, nil)
+	if err := db.Create(&models.Scene{Name: "MEDIA"}).Error; err != nil {
+		t.Fatalf("create scene: %v", err)
+	}
+
+	var group models.MediaGroup
+	if err := db.Where("episode_id = ? AND name = ?", episode.ID, "MEDIA").First(&group).Error; err != nil {
+		t.Fatalf("load MEDIA group: %v", err)
+	}
+	var items []models.EpisodeMediaGroup
+	for i, title := range []string{"A", "B"} {
+		media := models.EpisodeMedia{EpisodeID: episode.ID, Title: title}
+		if err := db.Create(&media).Error; err != nil {
+			t.Fatalf("create media: %v", err)
+		}
+		item := models.EpisodeMediaGroup{EpisodeMediaID: media.ID, MediaGroupID: group.ID, Order: i}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("create group item: %v", err)
+		}
+		items = append(items, item)
+	}
+	if err := models.SetCurrentMediaInGroup(db, group.ID, items[0].EpisodeMediaID, 0); err != nil {
+		t.Fatalf("set current: %v", err)
+	}
+
+	snapshot := j.SnapshotGroupCurrent(group.ID)
+	if err := models.SetCurrentMediaInGroup(db, group.ID, items[1].EpisodeMediaID, 0); err != nil {
+		t.Fatalf("set current: %v", err)
+	}
+	j.RecordGroupCurrent("op", snapshot, "zmiana")
+
+	if _, err := j.UndoLast("op"); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+
+	var restored []models.EpisodeMediaGroup
+	db.Where("media_group_id = ?", group.ID).Order("\"order\" ASC").Find(&restored)
+	if restored[0].CurrentInScene == nil || *restored[0].CurrentInScene != 0 {
+		t.Errorf("first item current_in_scene = %v, want 0", restored[0].CurrentInScene)
+	}
+	if restored[1].CurrentInScene != nil {
+		t.Errorf("second item current_in_scene = %v, want nil", *restored[1].CurrentInScene)
+	}
+}
+
+func TestUndoGroupAssignmentRestoresVLCAssignment(t *testing.T) {
+	db := openTestDB(t)
+	j := newTestJournal(t, db)
+	episode := createCurrentEpisode(t, db, nil)
+
+	var groups []models.MediaGroup
+	db.Where("episode_id = ?", episode.ID).Order("\"order\" ASC").Find(&groups)
+	if len(groups) < 2 {
+		t.Fatalf("system groups = %d, want 2", len(groups))
+	}
+	if err := models.SetEpisodeSourceGroup(db, episode.ID, "Media2", groups[0].ID, "manual"); err != nil {
+		t.Fatalf("assign group: %v", err)
+	}
+
+	snapshot := j.SnapshotSource(episode.ID, "Media2", journalFieldGroup)
+	if err := models.SetEpisodeSourceGroup(db, episode.ID, "Media2", groups[1].ID, "manual"); err != nil {
+		t.Fatalf("assign group: %v", err)
+	}
+	j.RecordSourceAssignment("op", snapshot, "zmiana")
+
+	if _, err := j.UndoLast("op"); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+
+	var source models.EpisodeSource
+	db.Where("episode_id = ? AND source_name = ?", episode.ID, "Media2").First(&source)
+	if source.GroupID == nil || *source.GroupID != groups[0].ID {
+		t.Errorf("group_id = %v, want %d", source.GroupID, groups[0].ID)
+	}
+	assignment, _ := j.SocketHandler.GetVLCAssignments(episode.ID)["Media2"].(map[string]interface{})
+	if assignment["group_id"] != groups[0].ID {
+		t.Errorf("vlc assignment = %v, want group %d", assignment, groups[0].ID)
+	}
+}
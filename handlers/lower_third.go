@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"obs-controller/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Domyślny czas wyświetlania paska z nazwiskiem
+const lowerThirdDefaultDuration = 8000
+
+// LowerThird reprezentuje pasek z nazwiskiem (dane strukturalne dla overlayu)
+type LowerThird struct {
+	SourceName string     `json:"source_name"` // Mikrofon, z którego wzięto osobę (pusty = osoba wskazana wprost)
+	PersonType string     `json:"person_type"` // "staff" lub "guest"
+	PersonID   uint       `json:"person_id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`  // Typy ekipy w odcinku lub typ gościa
+	Topic      string     `json:"topic"` // Temat rozmowy (goście)
+	DurationMs int        `json:"duration_ms"`
+	Channel    string     `json:"channel,omitempty"`
+	ShownAt    *time.Time `json:"shown_at,omitempty"`
+}
+
+// LowerThirdRequest - żądanie pokazania paska
+// Osoba z mikrofonu (source_name) lub wprost (person_type + person_id); name/role/topic nadpisują dane z bazy
+type LowerThirdRequest struct {
+	SourceName string `json:"source_name"`
+	PersonType string `json:"person_type"`
+	PersonID   uint   `json:"person_id"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	Topic      string `json:"topic"`
+	DurationMs int    `json:"duration_ms"` // 0 = domyślnie, <0 = do ręcznego zdjęcia
+	Channel    string `json:"channel"`     // Kanał overlayu (pusty = domyślny)
+}
+
+// LowerThirds buduje paski z nazwiskami osób przy mikrofonach
+// i wyświetla je szablonem lower_third overlayu
+type LowerThirds struct {
+	DB            *gorm.DB
+	SocketHandler *SocketHandler
+}
+
+func NewLowerThirds(db *gorm.DB, socketHandler *SocketHandler) *LowerThirds {
+	return &LowerThirds{
+		DB:            db,
+		SocketHandler: socketHandler,
+	}
+}
+
+// Show pokazuje pasek (czas wyświetlania pilnuje OverlayManager)
+func (lt *LowerThirds) Show(req LowerThirdRequest) (*LowerThird, error) {
+	if lt.SocketHandler.Overlay == nil {
+		return nil, fmt.Errorf("Overlay niedostępny")
+	}
+
+	episode, err := models.GetCurrentEpisode(lt.DB)
+	if err != nil {
+		return nil, fmt.Errorf("Brak aktualnego odcinka")
+	}
+
+	var strap *LowerThird
+	if req.SourceName != "" {
+		strap, err = lt.resolveMicrophone(episode.ID, req.SourceName)
+	} else if req.PersonType != "" {
+		strap, err = lt.resolvePerson(episode.ID, req.PersonType, req.PersonID)
+	} else if req.Name != "" {
+		strap = &LowerThird{}
+	} else {
+		err = fmt.Errorf("Podaj source_name, osobę lub name")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		strap.Name = req.Name
+	}
+	if req.Role != "" {
+		strap.Role = req.Role
+	}
+	if req.Topic != "" {
+		strap.Topic = req.Topic
+	}
+
+	item, err := lt.SocketHandler.Overlay.Show(req.Channel, OverlayTemplateLowerThird, strap.params(), req.DurationMs)
+	if err != nil {
+		return nil, err
+	}
+	strap.DurationMs = item.DurationMs
+	strap.Channel = item.Channel
+	strap.ShownAt = &item.ShownAt
+
+	log.Printf("Pasek: %s (%s)", strap.Name, strap.Role)
+	return strap, nil
+}
+
+// Hide zdejmuje aktualny pasek z kanału
+func (lt *LowerThirds) Hide(channel string) {
+	if lt.SocketHandler.Overlay != nil {
+		lt.SocketHandler.Overlay.Hide(channel, OverlayTemplateLowerThird)
+	}
+}
+
+// Current zwraca pasek wyświetlany na kanale (nil = brak)
+func (lt *LowerThirds) Current(channel string) *OverlayItem {
+	if lt.SocketHandler.Overlay == nil {
+		return nil
+	}
+	return lt.SocketHandler.Overlay.Get(channel, OverlayTemplateLowerThird)
+}
+
+// params zamienia pasek na parametry szablonu lower_third
+func (strap *LowerThird) params() map[string]interface{} {
+	params := map[string]interface{}{
+		"name":  strap.Name,
+		"role":  strap.Role,
+		"topic": strap.Topic,
+	}
+	if strap.SourceName != "" {
+		params["source_name"] = strap.SourceName
+	}
+	if strap.PersonType != "" {
+		params["person_type"] = strap.PersonType
+		params["person_id"] = strap.PersonID
+	}
+	return params
+}
+
+// Microphones zwraca paski dla wszystkich mikrofonów z przypisaną osobą w aktualnym odcinku
+func (lt *LowerThirds) Microphones() ([]LowerThird, error) {
+	episode, err := models.GetCurrentEpisode(lt.DB)
+	if err != nil {
+		return nil, fmt.Errorf("Brak aktualnego odcinka")
+	}
+
+	var sources []models.EpisodeSource
+	if err := lt.DB.Where("episode_id = ? AND (staff_id IS NOT NULL OR guest_id IS NOT NULL)", episode.ID).
+		Order("source_name ASC").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+
+	straps := make([]LowerThird, 0, len(sources))
+	for _, source := range sources {
+		strap, err := lt.fromAssignment(episode.ID, source)
+		if err != nil {
+			continue
+		}
+		straps = append(straps, *strap)
+	}
+	return straps, nil
+}
+
+// resolveMicrophone buduje pasek dla osoby przypisanej do mikrofonu
+func (lt *LowerThirds) resolveMicrophone(episodeID uint, sourceName string) (*LowerThird, error) {
+	assignment, err := models.GetEpisodeSourceAssignment(lt.DB, episodeID, sourceName)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil || (assignment.StaffID == nil && assignment.GuestID == nil) {
+		return nil, fmt.Errorf("Brak osoby przypisanej do %s", sourceName)
+	}
+	return lt.fromAssignment(episodeID, *assignment)
+}
+
+func (lt *LowerThirds) fromAssignment(episodeID uint, assignment models.EpisodeSource) (*LowerThird, error) {
+	var strap *LowerThird
+	var err error
+	if assignment.StaffID != nil {
+		strap, err = lt.resolvePerson(episodeID, "staff", *assignment.StaffID)
+	} else if assignment.GuestID != nil {
+		strap, err = lt.resolvePerson(episodeID, "guest", *assignment.GuestID)
+	} else {
+		return nil, fmt.Errorf("Brak osoby przypisanej do %s", assignment.SourceName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	strap.SourceName = assignment.SourceName
+	return strap, nil
+}
+
+// resolvePerson buduje pasek dla prowadzącego (typy w odcinku) lub gościa (typ + temat)
+func (lt *LowerThirds) resolvePerson(episodeID uint, personType string, personID uint) (*LowerThird, error) {
+	strap := &LowerThird{PersonType: personType, PersonID: personID}
+
+	switch personType {
+	case "staff":
+		var staff models.Staff
+		if err := lt.DB.First(&staff, personID).Error; err != nil {
+			return nil, fmt.Errorf("Staff not found")
+		}
+		strap.Name = staff.FirstName + " " + staff.LastName
+
+		var episodeStaff models.EpisodeStaff
+		if err := lt.DB.Preload("StaffTypes.StaffType").
+			Where("episode_id = ? AND staff_id = ?", episodeID, personID).
+			First(&episodeStaff).Error; err == nil {
+			roles := make([]string, 0, len(episodeStaff.StaffTypes))
+			for _, staffType := range episodeStaff.StaffTypes {
+				roles = append(roles, staffType.StaffType.Name)
+			}
+			strap.Role = strings.Join(roles, ", ")
+		}
+
+	case "guest":
+		var guest models.Guest
+		if err := lt.DB.Preload("GuestType").First(&guest, personID).Error; err != nil {
+			return nil, fmt.Errorf("Guest not found")
+		}
+		strap.Name = guest.FirstName + " " + guest.LastName
+		strap.Role = guest.GuestType.Name
+
+		var episodeGuest models.EpisodeGuest
+		if err := lt.DB.Where("episode_id = ? AND guest_id = ?", episodeID, personID).
+			First(&episodeGuest).Error; err == nil {
+			strap.Topic = episodeGuest.Topic
+		}
+
+	default:
+		return nil, fmt.Errorf("Invalid person_type")
+	}
+
+	return strap, nil
+}
@@ -0,0 +1,738 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Maksymalne zagnieżdżenie makr (run_macro) - chroni przed zapętleniem
+const macroMaxDepth = 5
+
+// Liczba zakończonych uruchomień pamiętanych do podglądu
+const macroRecentRuns = 20
+
+// Statusy uruchomienia makra
+const (
+	MacroRunRunning   = "running"
+	MacroRunDone      = "done"
+	MacroRunFailed    = "failed"
+	MacroRunCancelled = "cancelled"
+)
+
+// MacroRun reprezentuje pojedyncze uruchomienie makra
+type MacroRun struct {
+	ID          string     `json:"run_id"`
+	MacroID     uint       `json:"macro_id"`
+	MacroName   string     `json:"macro_name"`
+	TriggeredBy string     `json:"triggered_by"`
+	StepCount   int        `json:"step_count"`
+	CurrentStep int        `json:"current_step"` // Indeks kroku (od 0)
+	Status      string     `json:"status"`
+	Errors      []string   `json:"errors"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+
+	cancel context.CancelFunc
+}
+
+// MacroRunner wykonuje makra w tle, z postępem przez Socket.IO i możliwością anulowania
+type MacroRunner struct {
+	DB            *gorm.DB
+	SocketHandler *SocketHandler
+	MediaPath     string
+
+	runs      map[string]*MacroRun
+	recent    []*MacroRun
+	nextRunID uint64
+	mu        sync.Mutex
+}
+
+func NewMacroRunner(db *gorm.DB, socketHandler *SocketHandler, mediaPath string) *MacroRunner {
+	return &MacroRunner{
+		DB:            db,
+		SocketHandler: socketHandler,
+		MediaPath:     mediaPath,
+		runs:          make(map[string]*MacroRun),
+	}
+}
+
+// Run uruchamia makro w tle i zwraca stan uruchomienia
+func (mr *MacroRunner) Run(macroID uint, triggeredBy string) (*MacroRun, error) {
+	macro, err := mr.loadMacro(macroID)
+	if err != nil {
+		return nil, err
+	}
+	return mr.start(macro, triggeredBy)
+}
+
+// RunByName uruchamia makro po nazwie (wyzwalacze zewnętrzne, np. Stream Deck)
+func (mr *MacroRunner) RunByName(name string, triggeredBy string) (*MacroRun, error) {
+	var macro models.Macro
+	if err := mr.DB.Where("name = ?", name).First(&macro).Error; err != nil {
+		return nil, fmt.Errorf("makro %s nie istnieje", name)
+	}
+	return mr.Run(macro.ID, triggeredBy)
+}
+
+// Cancel anuluje uruchomienie makra (pusty runID = wszystkie trwające)
+func (mr *MacroRunner) Cancel(runID string) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if runID == "" {
+		for _, run := range mr.runs {
+			run.cancel()
+		}
+		return len(mr.runs), nil
+	}
+
+	run, ok := mr.runs[runID]
+	if !ok {
+		return 0, fmt.Errorf("uruchomienie %s nie trwa", runID)
+	}
+	run.cancel()
+	return 1, nil
+}
+
+// Runs zwraca trwające i ostatnio zakończone uruchomienia
+func (mr *MacroRunner) Runs() []MacroRun {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	result := make([]MacroRun, 0, len(mr.runs)+len(mr.recent))
+	for _, run := range mr.runs {
+		result = append(result, mr.snapshot(run))
+	}
+	for i := len(mr.recent) - 1; i >= 0; i-- {
+		result = append(result, mr.snapshot(mr.recent[i]))
+	}
+	return result
+}
+
+// loadMacro pobiera makro z krokami w kolejności
+func (mr *MacroRunner) loadMacro(macroID uint) (*models.Macro, error) {
+	var macro models.Macro
+	err := mr.DB.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).First(&macro, macroID).Error
+	if err != nil {
+		return nil, fmt.Errorf("makro %d nie istnieje", macroID)
+	}
+	return &macro, nil
+}
+
+func (mr *MacroRunner) start(macro *models.Macro, triggeredBy string) (*MacroRun, error) {
+	if len(macro.Steps) == 0 {
+		return nil, fmt.Errorf("makro %s nie ma kroków", macro.Name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mr.mu.Lock()
+	mr.nextRunID++
+	run := &MacroRun{
+		ID:          strconv.FormatUint(mr.nextRunID, 10),
+		MacroID:     macro.ID,
+		MacroName:   macro.Name,
+		TriggeredBy: triggeredBy,
+		StepCount:   len(macro.Steps),
+		Status:      MacroRunRunning,
+		Errors:      []string{},
+		StartedAt:   time.Now(),
+		cancel:      cancel,
+	}
+	mr.runs[run.ID] = run
+	snapshot := mr.snapshot(run)
+	mr.mu.Unlock()
+
+	log.Printf("Makro %s: start (uruchomienie %s, wyzwalacz: %s)", macro.Name, run.ID, triggeredBy)
+	mr.SocketHandler.Broadcast("macro_started", snapshot)
+
+	go mr.execute(ctx, run, macro)
+
+	return &snapshot, nil
+}
+
+// execute wykonuje kroki makra zgodnie z polityką błędów
+func (mr *MacroRunner) execute(ctx context.Context, run *MacroRun, macro *models.Macro) {
+	status := MacroRunDone
+
+	for i, step := range macro.Steps {
+		if ctx.Err() != nil {
+			status = MacroRunCancelled
+			break
+		}
+
+		mr.mu.Lock()
+		run.CurrentStep = i
+		mr.mu.Unlock()
+		mr.broadcastProgress(run, i, step, "running", "")
+
+		err := mr.executeStep(ctx, step.Action, step.Params, 0)
+		if ctx.Err() != nil {
+			status = MacroRunCancelled
+			mr.broadcastProgress(run, i, step, MacroRunCancelled, "")
+			break
+		}
+		if err != nil {
+			log.Printf("Makro %s: błąd kroku %d (%s): %v", macro.Name, i+1, step.Action, err)
+			mr.mu.Lock()
+			run.Errors = append(run.Errors, fmt.Sprintf("krok %d (%s): %v", i+1, step.Action, err))
+			mr.mu.Unlock()
+			mr.broadcastProgress(run, i, step, "error", err.Error())
+
+			if macro.OnError != models.MacroOnErrorContinue {
+				status = MacroRunFailed
+				break
+			}
+			continue
+		}
+
+		mr.broadcastProgress(run, i, step, "done", "")
+	}
+
+	if status == MacroRunDone && len(run.Errors) > 0 {
+		status = MacroRunFailed
+	}
+
+	mr.finish(run, status)
+}
+
+// finish kończy uruchomienie i przenosi je do listy ostatnich
+func (mr *MacroRunner) finish(run *MacroRun, status string) {
+	now := time.Now()
+
+	mr.mu.Lock()
+	run.cancel()
+	run.Status = status
+	run.FinishedAt = &now
+	delete(mr.runs, run.ID)
+	mr.recent = append(mr.recent, run)
+	if len(mr.recent) > macroRecentRuns {
+		mr.recent = mr.recent[len(mr.recent)-macroRecentRuns:]
+	}
+	snapshot := mr.snapshot(run)
+	mr.mu.Unlock()
+
+	log.Printf("Makro %s: koniec (%s)", run.MacroName, status)
+	mr.SocketHandler.Broadcast("macro_finished", snapshot)
+}
+
+// executeStep wykonuje pojedynczą akcję makra
+func (mr *MacroRunner) executeStep(ctx context.Context, action string, rawParams json.RawMessage, depth int) error {
+	sh := mr.SocketHandler
+	switch action {
+	case models.MacroActionWait, models.MacroActionOverlayMessage, models.MacroActionOverlayShow, models.MacroActionOverlayHide,
+		models.MacroActionRollCredits, models.MacroActionSponsorsStart, models.MacroActionSponsorsStop:
+	default:
+		if sh.OBSClient == nil {
+			return fmt.Errorf("OBS nie jest połączony")
+		}
+	}
+
+	var params struct {
+		SceneName   string                 `json:"scene_name"`
+		SourceName  string                 `json:"source_name"`
+		InputName   string                 `json:"input_name"`
+		Visible     bool                   `json:"visible"`
+		ToTop       bool                   `json:"to_top"`
+		VolumeDb    float64                `json:"volume_db"`
+		Ms          int                    `json:"ms"`
+		Data        map[string]interface{} `json:"data"`
+		MediaID     uint                   `json:"media_id"`
+		GroupID     uint                   `json:"group_id"`
+		MacroID     uint                   `json:"macro_id"`
+		MacroName   string                 `json:"macro_name"`
+		RequestType string                 `json:"request_type"`
+		RequestData map[string]interface{} `json:"request_data"`
+		Template    string                 `json:"template"`
+		Params      map[string]interface{} `json:"params"`
+		DurationMs  int                    `json:"duration_ms"`
+		Channel     string                 `json:"channel"`
+		EpisodeID   uint                   `json:"episode_id"`
+		Speed       int                    `json:"speed"`
+		Output      string                 `json:"output"`
+	}
+	if len(rawParams) > 0 {
+		if err := json.Unmarshal(rawParams, &params); err != nil {
+			return fmt.Errorf("błędne parametry: %v", err)
+		}
+	}
+
+	switch action {
+	case models.MacroActionSetScene:
+		return sh.OBSClient.SetCurrentProgramScene(params.SceneName)
+
+	case models.MacroActionToggleSource:
+		return sh.setSourceVisible(params.SceneName, params.SourceName, params.Visible)
+
+	case models.MacroActionTakeSource:
+		if sh.Rundown == nil {
+			return fmt.Errorf("realizacja rundownu nie jest dostępna")
+		}
+		return sh.Rundown.takeSource(params.SceneName, params.SourceName)
+
+	case models.MacroActionSetSourceIndex:
+		return sh.OBSClient.SetSceneItemIndex(params.SceneName, params.SourceName, params.ToTop)
+
+	case models.MacroActionSetVolume:
+		return sh.setInputVolume(params.InputName, params.VolumeDb)
+
+	case models.MacroActionWait:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(params.Ms) * time.Millisecond):
+			return nil
+		}
+
+	case models.MacroActionOverlayMessage:
+		sh.sendToOverlay(params.Data)
+		return nil
+
+	case models.MacroActionOverlayShow:
+		if sh.Overlay == nil {
+			return fmt.Errorf("overlay niedostępny")
+		}
+		_, err := sh.Overlay.Show(params.Channel, params.Template, params.Params, params.DurationMs)
+		return err
+
+	case models.MacroActionOverlayHide:
+		if sh.Overlay == nil {
+			return fmt.Errorf("overlay niedostępny")
+		}
+		if params.Template == "" {
+			sh.Overlay.HideAll(params.Channel)
+		} else {
+			sh.Overlay.Hide(params.Channel, params.Template)
+		}
+		return nil
+
+	case models.MacroActionRollCredits:
+		_, err := rollCredits(sh, CreditsRollRequest{
+			EpisodeID: params.EpisodeID,
+			Speed:     params.Speed,
+			Channel:   params.Channel,
+		})
+		return err
+
+	case models.MacroActionLoadMedia:
+		episode, err := models.GetCurrentEpisode(mr.DB)
+		if err != nil {
+			return fmt.Errorf("brak aktualnego odcinka")
+		}
+		var media models.EpisodeMedia
+		if err := mr.DB.Where("id = ? AND episode_id = ?", params.MediaID, episode.ID).First(&media).Error; err != nil {
+			return fmt.Errorf("medium %d nie należy do aktualnego odcinka", params.MediaID)
+		}
+		return loadMediaIntoSource(sh, mr.MediaPath, episode.ID, params.SourceName, media, "macro")
+
+	case models.MacroActionLoadGroup:
+		episode, err := models.GetCurrentEpisode(mr.DB)
+		if err != nil {
+			return fmt.Errorf("brak aktualnego odcinka")
+		}
+		_, err = loadGroupIntoSource(sh, mr.MediaPath, episode.ID, params.SourceName, params.GroupID, "macro")
+		return err
+
+	case models.MacroActionMuteMicrophones:
+		_, err := sh.muteAllMicrophones()
+		return err
+
+	case models.MacroActionRestoreMicrophones:
+		_, err := sh.restoreMicrophones()
+		return err
+
+	case models.MacroActionPanic:
+		if sh.Panic == nil {
+			return fmt.Errorf("przycisk awaryjny niedostępny")
+		}
+		_, err := sh.Panic.Activate("macro")
+		return err
+
+	case models.MacroActionPanicResume:
+		if sh.Panic == nil {
+			return fmt.Errorf("przycisk awaryjny niedostępny")
+		}
+		_, err := sh.Panic.Resume("macro")
+		return err
+
+	case models.MacroActionSponsorsStart:
+		if sh.Sponsors == nil {
+			return fmt.Errorf("rotacja sponsorów niedostępna")
+		}
+		_, err := sh.Sponsors.Start(SponsorRotationRequest{
+			Output:     params.Output,
+			Channel:    params.Channel,
+			SceneName:  params.SceneName,
+			SourceName: params.SourceName,
+		})
+		return err
+
+	case models.MacroActionSponsorsStop:
+		if sh.Sponsors == nil {
+			return fmt.Errorf("rotacja sponsorów niedostępna")
+		}
+		sh.Sponsors.Stop()
+		return nil
+
+	case models.MacroActionStartRecording:
+		return sh.OBSClient.StartRecord()
+
+	case models.MacroActionStopRecording:
+		return sh.OBSClient.StopRecord()
+
+	case models.MacroActionStartStreaming:
+		return sh.OBSClient.StartStream()
+
+	case models.MacroActionStopStreaming:
+		return sh.OBSClient.StopStream()
+
+	case models.MacroActionRundownNext:
+		if sh.Rundown == nil {
+			return fmt.Errorf("realizacja rundownu nie jest dostępna")
+		}
+		_, err := sh.Rundown.Next()
+		return err
+
+	case models.MacroActionRunMacro:
+		if params.MacroID == 0 && params.MacroName != "" {
+			var macro models.Macro
+			if err := mr.DB.Where("name = ?", params.MacroName).First(&macro).Error; err != nil {
+				return fmt.Errorf("makro %s nie istnieje", params.MacroName)
+			}
+			params.MacroID = macro.ID
+		}
+		return mr.runNested(ctx, params.MacroID, depth+1)
+
+	case models.MacroActionOBSRequest:
+		_, err := sh.OBSClient.Request(params.RequestType, params.RequestData)
+		return err
+	}
+
+	return fmt.Errorf("nieznana akcja %s", action)
+}
+
+// runNested wykonuje makro jako krok innego makra (synchronicznie, ta sama anulacja)
+func (mr *MacroRunner) runNested(ctx context.Context, macroID uint, depth int) error {
+	if depth > macroMaxDepth {
+		return fmt.Errorf("przekroczono maksymalne zagnieżdżenie makr (%d)", macroMaxDepth)
+	}
+
+	macro, err := mr.loadMacro(macroID)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for i, step := range macro.Steps {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := mr.executeStep(ctx, step.Action, step.Params, depth); err != nil {
+			if macro.OnError != models.MacroOnErrorContinue {
+				return fmt.Errorf("%s, krok %d: %v", macro.Name, i+1, err)
+			}
+			errs = append(errs, fmt.Sprintf("krok %d: %v", i+1, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %s", macro.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (mr *MacroRunner) broadcastProgress(run *MacroRun, index int, step models.MacroStep, status string, errMsg string) {
+	mr.SocketHandler.Broadcast("macro_progress", map[string]interface{}{
+		"run_id":     run.ID,
+		"macro_id":   run.MacroID,
+		"macro_name": run.MacroName,
+		"step":       index,
+		"step_count": run.StepCount,
+		"action":     step.Action,
+		"status":     status,
+		"error":      errMsg,
+	})
+}
+
+// snapshot kopiuje stan uruchomienia (wywoływać pod mr.mu)
+func (mr *MacroRunner) snapshot(run *MacroRun) MacroRun {
+	copied := *run
+	copied.Errors = append([]string{}, run.Errors...)
+	return copied
+}
+
+// validateMacroSteps sprawdza akcje i parametry kroków
+func validateMacroSteps(steps []models.MacroStep) error {
+	for i, step := range steps {
+		if err := validateMacroAction(step.Action, step.Params); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateMacroAction sprawdza akcję (krok makra lub akcję reguły) i jej parametry
+func validateMacroAction(action string, params json.RawMessage) error {
+	known := false
+	for _, candidate := range models.MacroActions {
+		if action == candidate {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	if len(params) > 0 && string(params) != "null" {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(params, &decoded); err != nil {
+			return fmt.Errorf("params must be a JSON object")
+		}
+	}
+	return nil
+}
+
+// validateMacro sprawdza poprawność makra
+func validateMacro(macro *models.Macro) error {
+	if strings.TrimSpace(macro.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if macro.OnError == "" {
+		macro.OnError = models.MacroOnErrorStop
+	}
+	if macro.OnError != models.MacroOnErrorStop && macro.OnError != models.MacroOnErrorContinue {
+		return fmt.Errorf("on_error must be %q or %q", models.MacroOnErrorStop, models.MacroOnErrorContinue)
+	}
+	return validateMacroSteps(macro.Steps)
+}
+
+type MacroHandler struct {
+	DB     *gorm.DB
+	Runner *MacroRunner
+}
+
+func NewMacroHandler(db *gorm.DB, runner *MacroRunner) *MacroHandler {
+	return &MacroHandler{DB: db, Runner: runner}
+}
+
+// GetMacros - GET /api/macros
+func (h *MacroHandler) GetMacros(w http.ResponseWriter, r *http.Request) {
+	var macros []models.Macro
+	err := h.DB.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Order("name ASC").Find(&macros).Error
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(macros)
+}
+
+// GetMacro - GET /api/macros/{id}
+func (h *MacroHandler) GetMacro(w http.ResponseWriter, r *http.Request) {
+	macro, ok := h.loadMacro(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(macro)
+}
+
+// CreateMacro - POST /api/macros
+func (h *MacroHandler) CreateMacro(w http.ResponseWriter, r *http.Request) {
+	var macro models.Macro
+	if err := json.NewDecoder(r.Body).Decode(&macro); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	macro.ID = 0
+	if err := validateMacro(&macro); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range macro.Steps {
+		macro.Steps[i].ID = 0
+		macro.Steps[i].Order = i + 1
+	}
+
+	if err := h.DB.Create(&macro).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(macro)
+}
+
+// UpdateMacro - PUT /api/macros/{id}
+// Kroki są zastępowane w całości, w kolejności z żądania
+func (h *MacroHandler) UpdateMacro(w http.ResponseWriter, r *http.Request) {
+	macro, ok := h.loadMacro(w, r)
+	if !ok {
+		return
+	}
+
+	var updateData models.Macro
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	macro.Name = updateData.Name
+	macro.Description = updateData.Description
+	macro.OnError = updateData.OnError
+	macro.Color = updateData.Color
+	macro.Steps = updateData.Steps
+
+	if err := validateMacro(&macro); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	steps := macro.Steps
+	for i := range steps {
+		steps[i].ID = 0
+		steps[i].MacroID = macro.ID
+		steps[i].Order = i + 1
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("macro_id = ?", macro.ID).Delete(&models.MacroStep{}).Error; err != nil {
+			return err
+		}
+		macro.Steps = nil
+		if err := tx.Save(&macro).Error; err != nil {
+			return err
+		}
+		if len(steps) > 0 {
+			if err := tx.Create(&steps).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	macro.Steps = steps
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(macro)
+}
+
+// DeleteMacro - DELETE /api/macros/{id}
+func (h *MacroHandler) DeleteMacro(w http.ResponseWriter, r *http.Request) {
+	macro, ok := h.loadMacro(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("macro_id = ?", macro.ID).Delete(&models.MacroStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&macro).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunMacro - POST /api/macros/{id}/run
+func (h *MacroHandler) RunMacro(w http.ResponseWriter, r *http.Request) {
+	macro, ok := h.loadMacro(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := h.Runner.Run(macro.ID, "rest")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// RunMacroByName - POST /api/macros/by-name/{name}/run
+// Dla wyzwalaczy zewnętrznych (Stream Deck, Companion, skrypty)
+func (h *MacroHandler) RunMacroByName(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	run, err := h.Runner.RunByName(name, "external")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// GetRuns - GET /api/macros/runs
+func (h *MacroHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Runner.Runs())
+}
+
+// CancelRun - POST /api/macros/runs/{run_id}/cancel
+func (h *MacroHandler) CancelRun(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["run_id"]
+
+	if _, err := h.Runner.Cancel(runID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MacroHandler) loadMacro(w http.ResponseWriter, r *http.Request) (models.Macro, bool) {
+	var macro models.Macro
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return macro, false
+	}
+
+	err = h.DB.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).First(&macro, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Macro not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return macro, false
+	}
+
+	return macro, true
+}
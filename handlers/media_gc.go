@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Folder kosza wewnątrz katalogu mediów (pomijany przy skanowaniu)
+const mediaTrashFolder = ".trash"
+
+// Domyślny czas przechowywania plików w koszu
+const defaultTrashRetentionDays = 30
+
+type MediaGCHandler struct {
+	DB            *gorm.DB
+	MediaPath     string
+	RetentionDays int // Po ilu dniach pliki z kosza są usuwane na stałe
+}
+
+func NewMediaGCHandler(db *gorm.DB, mediaPath string) *MediaGCHandler {
+	return &MediaGCHandler{
+		DB:            db,
+		MediaPath:     mediaPath,
+		RetentionDays: defaultTrashRetentionDays,
+	}
+}
+
+// OrphanFile reprezentuje plik w katalogu mediów bez rekordu EpisodeMedia
+type OrphanFile struct {
+	Path    string    `json:"path"` // Względna ścieżka (zawsze /), np. season_1/plik.mp4
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// MissingFile reprezentuje rekord EpisodeMedia wskazujący na nieistniejący plik
+type MissingFile struct {
+	MediaID   uint   `json:"media_id"`
+	EpisodeID uint   `json:"episode_id"`
+	Title     string `json:"title"`
+	Path      string `json:"path"`
+}
+
+// SeasonDiskUsage reprezentuje zajętość dysku przez folder sezonu
+type SeasonDiskUsage struct {
+	Folder      string `json:"folder"` // np. season_1
+	FileCount   int    `json:"file_count"`
+	TotalSize   int64  `json:"total_size"`
+	OrphanCount int    `json:"orphan_count"`
+	OrphanSize  int64  `json:"orphan_size"`
+}
+
+// EpisodeDiskUsage reprezentuje zajętość dysku przez pliki przypisane do odcinka
+type EpisodeDiskUsage struct {
+	EpisodeID uint   `json:"episode_id"`
+	Title     string `json:"title"`
+	FileCount int    `json:"file_count"`
+	TotalSize int64  `json:"total_size"`
+}
+
+// MediaGCReport reprezentuje raport porządkowania mediów
+type MediaGCReport struct {
+	Orphans    []OrphanFile       `json:"orphans"`
+	Missing    []MissingFile      `json:"missing"`
+	Seasons    []SeasonDiskUsage  `json:"seasons"`
+	Episodes   []EpisodeDiskUsage `json:"episodes"`
+	TrashSize  int64              `json:"trash_size"`
+	TotalSize  int64              `json:"total_size"`
+	OrphanSize int64              `json:"orphan_size"`
+}
+
+// buildMediaGCReport skanuje katalog mediów i porównuje go z rekordami EpisodeMedia
+func buildMediaGCReport(db *gorm.DB, mediaPath string) (*MediaGCReport, error) {
+	report := &MediaGCReport{
+		Orphans:  make([]OrphanFile, 0),
+		Missing:  make([]MissingFile, 0),
+		Seasons:  make([]SeasonDiskUsage, 0),
+		Episodes: make([]EpisodeDiskUsage, 0),
+	}
+
+	var media []models.EpisodeMedia
+	if err := db.Where("file_path IS NOT NULL AND file_path != ''").Find(&media).Error; err != nil {
+		return nil, err
+	}
+
+	// Mapa: względna ścieżka → rekordy media (ten sam plik może być w kilku odcinkach)
+	referenced := make(map[string][]models.EpisodeMedia)
+	for _, m := range media {
+		referenced[*m.FilePath] = append(referenced[*m.FilePath], m)
+	}
+
+	// Rozmiary plików na dysku: względna ścieżka → rozmiar
+	sizes := make(map[string]int64)
+
+	entries, err := os.ReadDir(mediaPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		// Pomiń foldery pomocnicze (.trash, .proxies)
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		usage := SeasonDiskUsage{Folder: entry.Name()}
+
+		files, err := os.ReadDir(filepath.Join(mediaPath, entry.Name()))
+		if err != nil {
+			log.Printf("Błąd odczytu folderu %s: %v", entry.Name(), err)
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+
+			relativePath := entry.Name() + "/" + file.Name()
+			sizes[relativePath] = info.Size()
+
+			usage.FileCount++
+			usage.TotalSize += info.Size()
+
+			if _, ok := referenced[relativePath]; !ok {
+				usage.OrphanCount++
+				usage.OrphanSize += info.Size()
+				report.Orphans = append(report.Orphans, OrphanFile{
+					Path:    relativePath,
+					Size:    info.Size(),
+					ModTime: info.ModTime(),
+				})
+			}
+		}
+
+		report.TotalSize += usage.TotalSize
+		report.OrphanSize += usage.OrphanSize
+		report.Seasons = append(report.Seasons, usage)
+	}
+
+	// Rekordy bez plików + zajętość per odcinek
+	episodeUsage := make(map[uint]*EpisodeDiskUsage)
+	for path, records := range referenced {
+		size, exists := sizes[path]
+		for _, m := range records {
+			if !exists {
+				report.Missing = append(report.Missing, MissingFile{
+					MediaID:   m.ID,
+					EpisodeID: m.EpisodeID,
+					Title:     m.Title,
+					Path:      path,
+				})
+				continue
+			}
+
+			usage, ok := episodeUsage[m.EpisodeID]
+			if !ok {
+				usage = &EpisodeDiskUsage{EpisodeID: m.EpisodeID}
+				episodeUsage[m.EpisodeID] = usage
+			}
+			usage.FileCount++
+			usage.TotalSize += size
+		}
+	}
+
+	for _, usage := range episodeUsage {
+		var episode models.Episode
+		if err := db.First(&episode, usage.EpisodeID).Error; err == nil {
+			usage.Title = episode.Title
+		}
+		report.Episodes = append(report.Episodes, *usage)
+	}
+
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i].Path < report.Orphans[j].Path })
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].MediaID < report.Missing[j].MediaID })
+	sort.Slice(report.Episodes, func(i, j int) bool { return report.Episodes[i].EpisodeID < report.Episodes[j].EpisodeID })
+
+	report.TrashSize = dirSize(filepath.Join(mediaPath, mediaTrashFolder))
+
+	return report, nil
+}
+
+// dirSize zwraca łączny rozmiar plików w katalogu (rekurencyjnie)
+func dirSize(path string) int64 {
+	var total int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// GetReport - GET /api/media/gc-report
+func (h *MediaGCHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := buildMediaGCReport(h.DB, h.MediaPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// TrashOrphans - POST /api/media/gc/trash
+// Przenosi pliki bez rekordów do kosza (media/.trash/{data}/season_N/...)
+// Body: {"paths": ["season_1/plik.mp4"]} - tylko wybrane sieroty, {"all": true} - wszystkie
+func (h *MediaGCHandler) TrashOrphans(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Paths []string `json:"paths"`
+		All   bool     `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, "Request body must contain paths or all:true", http.StatusBadRequest)
+		return
+	}
+	if len(data.Paths) == 0 && !data.All {
+		http.Error(w, "Request body must contain paths or all:true", http.StatusBadRequest)
+		return
+	}
+
+	report, err := buildMediaGCReport(h.DB, h.MediaPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Jeśli podano ścieżki - przenieś tylko te, które rzeczywiście są sierotami
+	selected := make(map[string]bool)
+	for _, p := range data.Paths {
+		selected[p] = true
+	}
+
+	batchDir := filepath.Join(h.MediaPath, mediaTrashFolder, time.Now().Format("2006-01-02_150405"))
+
+	moved := make([]string, 0)
+	var movedSize int64
+	failed := make(map[string]string)
+
+	for _, orphan := range report.Orphans {
+		if !data.All && !selected[orphan.Path] {
+			continue
+		}
+
+		src := filepath.Join(h.MediaPath, filepath.FromSlash(orphan.Path))
+		dst := filepath.Join(batchDir, filepath.FromSlash(orphan.Path))
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			failed[orphan.Path] = err.Error()
+			continue
+		}
+
+		if err := os.Rename(src, dst); err != nil {
+			failed[orphan.Path] = err.Error()
+			continue
+		}
+
+		moved = append(moved, orphan.Path)
+		movedSize += orphan.Size
+	}
+
+	log.Printf("Przeniesiono do kosza %d plików (%d B)", len(moved), movedSize)
+
+	// Przy okazji usuń stare partie z kosza
+	purged := h.PurgeTrash()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"moved":          moved,
+		"moved_size":     movedSize,
+		"failed":         failed,
+		"purged":         purged,
+		"retention_days": h.RetentionDays,
+	})
+}
+
+// PurgeTrashHandler - POST /api/media/gc/purge
+// Usuwa z kosza partie starsze niż okres przechowywania (?retention_days=N tylko dla tego wywołania)
+func (h *MediaGCHandler) PurgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	retentionDays := h.RetentionDays
+	if days := r.URL.Query().Get("retention_days"); days != "" {
+		value, err := strconv.Atoi(days)
+		if err != nil || value < 0 {
+			http.Error(w, "Invalid retention_days", http.StatusBadRequest)
+			return
+		}
+		retentionDays = value
+	}
+
+	purged := h.purgeTrashOlderThan(retentionDays)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"purged":         purged,
+		"retention_days": retentionDays,
+	})
+}
+
+// PurgeTrash usuwa z kosza partie starsze niż RetentionDays
+// Zwraca nazwy usuniętych partii
+func (h *MediaGCHandler) PurgeTrash() []string {
+	return h.purgeTrashOlderThan(h.RetentionDays)
+}
+
+// purgeTrashOlderThan usuwa z kosza partie starsze niż podana liczba dni
+func (h *MediaGCHandler) purgeTrashOlderThan(retentionDays int) []string {
+	purged := make([]string, 0)
+	trashPath := filepath.Join(h.MediaPath, mediaTrashFolder)
+
+	entries, err := os.ReadDir(trashPath)
+	if err != nil {
+		return purged
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		batchTime, err := time.ParseInLocation("2006-01-02_150405", entry.Name(), time.Local)
+		if err != nil {
+			// Nieznany format nazwy - nie ruszaj
+			continue
+		}
+
+		if batchTime.Before(cutoff) {
+			if err := os.RemoveAll(filepath.Join(trashPath, entry.Name())); err != nil {
+				log.Printf("Błąd usuwania partii kosza %s: %v", entry.Name(), err)
+				continue
+			}
+			purged = append(purged, entry.Name())
+		}
+	}
+
+	if len(purged) > 0 {
+		log.Printf("Usunięto z kosza %d partii starszych niż %d dni: %s", len(purged), retentionDays, strings.Join(purged, ", "))
+	}
+
+	return purged
+}
+
+// formatBytes formatuje rozmiar w czytelnej postaci (np. 1.5 GB)
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestMediaGC tworzy katalog mediów z dwoma plikami bez rekordów
+func newTestMediaGC(t *testing.T) *MediaGCHandler {
+	t.Helper()
+	mediaPath := t.TempDir()
+	os.MkdirAll(filepath.Join(mediaPath, "season_1"), 0755)
+	os.WriteFile(filepath.Join(mediaPath, "season_1", "a.mp4"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(mediaPath, "season_1", "b.mp4"), []byte("b"), 0644)
+	return NewMediaGCHandler(openTestDB(t), mediaPath)
+}
+
+func trashOrphans(h *MediaGCHandler, body string) (*httptest.ResponseRecorder, []string) {
+	rec := httptest.NewRecorder()
+	h.TrashOrphans(rec, httptest.NewRequest("POST", "/api/media/gc/trash", strings.NewReader(body)))
+
+	var result struct {
+		Moved []string `json:"moved"`
+	}
+	json.NewDecoder(rec.Body).Decode(&result)
+	return rec, result.Moved
+}
+
+func TestTrashOrphansRequiresExplicitSelection(t *testing.T) {
+	h := newTestMediaGC(t)
+
+	for _, body := range []string{"", "{}", `{"paths":[]}`, `{"all":false}`} {
+		rec, _ := trashOrphans(h, body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: status = %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	report, err := buildMediaGCReport(h.DB, h.MediaPath)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(report.Orphans) != 2 {
+		t.Errorf("expected orphans untouched, got %d", len(report.Orphans))
+	}
+}
+
+func TestTrashOrphansSelectedPaths(t *testing.T) {
+	h := newTestMediaGC(t)
+
+	rec, moved := trashOrphans(h, `{"paths":["season_1/a.mp4"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if len(moved) != 1 || moved[0] != "season_1/a.mp4" {
+		t.Errorf("moved = %v, want [season_1/a.mp4]", moved)
+	}
+	if _, err := os.Stat(filepath.Join(h.MediaPath, "season_1", "b.mp4")); err != nil {
+		t.Errorf("unselected orphan was moved: %v", err)
+	}
+}
+
+func TestTrashOrphansAll(t *testing.T) {
+	h := newTestMediaGC(t)
+
+	rec, moved := trashOrphans(h, `{"all":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if len(moved) != 2 {
+		t.Errorf("moved = %v, want both orphans", moved)
+	}
+}
+
+func TestPurgeTrashOverrideDoesNotChangeRetention(t *testing.T) {
+	h := newTestMediaGC(t)
+
+	oldBatch := time.Now().AddDate(0, 0, -10).Format("2006-01-02_150405")
+	os.MkdirAll(filepath.Join(h.MediaPath, mediaTrashFolder, oldBatch), 0755)
+
+	rec := httptest.NewRecorder()
+	h.PurgeTrashHandler(rec, httptest.NewRequest("POST", "/api/media/gc/purge?retention_days=0", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if h.RetentionDays != defaultTrashRetentionDays {
+		t.Errorf("retention days changed to %d", h.RetentionDays)
+	}
+	if _, err := os.Stat(filepath.Join(h.MediaPath, mediaTrashFolder, oldBatch)); !os.IsNotExist(err) {
+		t.Error("old batch not purged with retention_days=0")
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"obs-controller/models"
 	"strconv"
@@ -11,7 +12,8 @@ import (
 )
 
 type MediaGroupHandler struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Journal *ActionJournal // Dziennik operacji (undo), opcjonalny
 }
 
 func NewMediaGroupHandler(db *gorm.DB) *MediaGroupHandler {
@@ -35,6 +37,15 @@ func (h *MediaGroupHandler) GetMediaGroups(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	groupIDs := make([]uint, len(groups))
+	for i, group := range groups {
+		groupIDs[i] = group.ID
+	}
+	totals := models.GetMediaGroupTotalDurations(h.DB, groupIDs)
+	for i := range groups {
+		groups[i].TotalDuration = totals[groups[i].ID]
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(groups)
 }
@@ -60,6 +71,8 @@ func (h *MediaGroupHandler) GetMediaGroup(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	group.TotalDuration = models.GetMediaGroupTotalDuration(h.DB, group.ID)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(group)
 }
@@ -318,10 +331,13 @@ func (h *MediaGroupHandler) SetCurrentMediaInGroup(w http.ResponseWriter, r *htt
 		return
 	}
 
+	snapshot := h.Journal.SnapshotGroupCurrent(uint(groupID))
 	if err := models.SetCurrentMediaInGroup(h.DB, uint(groupID), uint(mediaID), data.SceneID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Journal.RecordGroupCurrent(operatorFromRequest(r), snapshot,
+		fmt.Sprintf("Grupa %d: aktywne media %d", groupID, mediaID))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
@@ -345,10 +361,13 @@ func (h *MediaGroupHandler) ClearCurrentMediaInGroup(w http.ResponseWriter, r *h
 		return
 	}
 
+	snapshot := h.Journal.SnapshotGroupCurrent(uint(groupID))
 	if err := models.ClearCurrentMediaInGroup(h.DB, uint(groupID), data.SceneID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Journal.RecordGroupCurrent(operatorFromRequest(r), snapshot,
+		fmt.Sprintf("Grupa %d: wyłączono aktywne media", groupID))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"obs-controller/models"
+	"obs-controller/utils"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	mediaURLCheckTimeout = 5 * time.Second  // Limit czasu sprawdzania dostępności mediów z URL
+	mediaURLProbeTimeout = 10 * time.Second // Limit czasu odczytu długości media z URL (ffprobe)
+)
+
+// hasMediaSource sprawdza czy media ma plik lub URL, który można wczytać do OBS
+func hasMediaSource(media models.EpisodeMedia) bool {
+	return (media.FilePath != nil && *media.FilePath != "") || (media.URL != nil && *media.URL != "")
+}
+
+// isURLMedia sprawdza czy media będzie odtwarzane z URL (brak lokalnego pliku)
+func isURLMedia(media models.EpisodeMedia) bool {
+	return (media.FilePath == nil || *media.FilePath == "") && media.URL != nil && *media.URL != ""
+}
+
+// mediaLocation zwraca pełną ścieżkę do pliku lub URL media
+// Lokalny plik ma pierwszeństwo przed URL
+func mediaLocation(mediaPath string, media models.EpisodeMedia) string {
+	if media.FilePath != nil && *media.FilePath != "" {
+		absMediaPath, err := filepath.Abs(mediaPath)
+		if err != nil {
+			absMediaPath = mediaPath
+		}
+		return filepath.Join(absMediaPath, filepath.FromSlash(*media.FilePath))
+	}
+	if media.URL != nil {
+		return *media.URL
+	}
+	return ""
+}
+
+// buildMediaInputSettings przygotowuje ustawienia dla źródła Media Source (ffmpeg_source)
+// Pliki lokalne → local_file, media z URL → wejście sieciowe (is_local_file: false)
+// Dla URL sprawdza najpierw czy adres odpowiada
+func buildMediaInputSettings(mediaPath string, media models.EpisodeMedia) (map[string]interface{}, error) {
+	if !hasMediaSource(media) {
+		return nil, fmt.Errorf("media has no file path or URL")
+	}
+
+	if isURLMedia(media) {
+		if err := utils.CheckURLReachable(*media.URL, mediaURLCheckTimeout); err != nil {
+			return nil, fmt.Errorf("media URL unreachable: %w", err)
+		}
+
+		return map[string]interface{}{
+			"is_local_file":       false,
+			"input":               *media.URL,
+			"clear_on_media_end":  false,
+			"close_when_inactive": true,
+		}, nil
+	}
+
+	return map[string]interface{}{
+		"is_local_file":       true,
+		"local_file":          mediaLocation(mediaPath, media),
+		"clear_on_media_end":  false,
+		"close_when_inactive": true,
+	}, nil
+}
+
+// probeURLDuration odczytuje długość media z URL, nie dłużej niż mediaURLProbeTimeout
+// ffprobe dostaje tylko adresy http/https z hostem w sieci publicznej (bez file:, adresów wewnętrznych)
+func probeURLDuration(ctx context.Context, rawURL string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, mediaURLProbeTimeout)
+	defer cancel()
+	if err := utils.CheckPublicURL(ctx, rawURL); err != nil {
+		return 0, err
+	}
+	return utils.GetMediaDurationContext(ctx, rawURL)
+}
+
+// buildPlaylist przygotowuje playlistę dla VLC Video Source
+// VLC przyjmuje zarówno ścieżki lokalne jak i URL - nieosiągalne URL są pomijane
+// Adresy sprawdzane są równolegle, więc playlista czeka najwyżej jeden mediaURLCheckTimeout
+// Pozycje grają w całości - VLC Video Source nie zna punktów wejścia/wyjścia
+func buildPlaylist(mediaPath string, items []models.EpisodeMediaGroup) []map[string]interface{} {
+	reachable := make([]bool, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		media := item.EpisodeMedia
+		if !hasMediaSource(media) {
+			continue
+		}
+		if !isURLMedia(media) {
+			reachable[i] = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, media models.EpisodeMedia) {
+			defer wg.Done()
+			if err := utils.CheckURLReachable(*media.URL, mediaURLCheckTimeout); err != nil {
+				fmt.Printf("Pomijam nieosiągalny URL w playliście (%s): %v\n", media.Title, err)
+				return
+			}
+			reachable[i] = true
+		}(i, media)
+	}
+	wg.Wait()
+
+	playlist := make([]map[string]interface{}, 0)
+	for i, item := range items {
+		if !reachable[i] {
+			continue
+		}
+		playlist = append(playlist, map[string]interface{}{
+			"value": mediaLocation(mediaPath, item.EpisodeMedia),
+		})
+	}
+
+	return playlist
+}
+
+// loadMediaIntoSource wczytuje plik do źródła Media Source, zapisuje przypisanie
+// i informuje klientów (jak ręczne przypisanie w kontrolerze)
+func loadMediaIntoSource(sh *SocketHandler, mediaPath string, episodeID uint, sourceName string, media models.EpisodeMedia, assignedBy string) error {
+	inputSettings, err := buildMediaInputSettings(mediaPath, media)
+	if err != nil {
+		return err
+	}
+	if err := sh.OBSClient.SetInputSettings(sourceName, inputSettings); err != nil {
+		return fmt.Errorf("błąd wczytywania %s do %s: %v", media.Title, sourceName, err)
+	}
+	if sh.MediaMonitor != nil {
+		sh.MediaMonitor.Track(sourceName, media)
+	}
+
+	models.SetEpisodeSourceMedia(sh.DB, episodeID, sourceName, media.ID, assignedBy)
+	sh.BroadcastToEpisode(episodeID, "source_media_assigned", map[string]interface{}{
+		"episode_id":  episodeID,
+		"source_name": sourceName,
+		"media_id":    media.ID,
+		"title":       media.Title,
+	})
+	return nil
+}
+
+// currentGroupMedia zwraca aktywne media grupy w scenie (current_in_scene = scena lub 0),
+// a gdy żadne nie jest aktywne - media z najniższym order
+func currentGroupMedia(db *gorm.DB, groupID, sceneID uint) (*models.EpisodeMediaGroup, error) {
+	var assignment models.EpisodeMediaGroup
+	err := db.Preload("EpisodeMedia").
+		Preload("MediaGroup").
+		Where("media_group_id = ? AND (current_in_scene = ? OR current_in_scene = 0)", groupID, sceneID).
+		Order("\"order\" ASC").
+		First(&assignment).Error
+	if err == gorm.ErrRecordNotFound {
+		err = db.Preload("EpisodeMedia").
+			Preload("MediaGroup").
+			Where("media_group_id = ?", groupID).
+			Order("\"order\" ASC").
+			First(&assignment).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// applyGroupCurrentMedia wczytuje aktywne media grupy MEDIA/REPORTAZE do źródła
+// Media1/Reportaze1 i informuje klientów - tylko przy cofnięciu operacji; samo ustawienie
+// aktywnego media przez REST nie zmienia tego, co jest w OBS
+// Pozostałe grupy nie mają własnego źródła - wtedy nic nie robi (podobnie bez sceny w bazie)
+func applyGroupCurrentMedia(sh *SocketHandler, mediaPath string, groupID uint) error {
+	var group models.MediaGroup
+	if err := sh.DB.First(&group, groupID).Error; err != nil {
+		return fmt.Errorf("grupa mediów %d nie istnieje", groupID)
+	}
+
+	var sourceName string
+	switch group.Name {
+	case "MEDIA":
+		sourceName = "Media1"
+	case "REPORTAZE":
+		sourceName = "Reportaze1"
+	default:
+		return nil
+	}
+
+	scene, err := models.GetMediaSceneByName(sh.DB, group.Name)
+	if err == gorm.ErrRecordNotFound {
+		return nil // Sceny nie zsynchronizowano jeszcze z OBS
+	}
+	if err != nil {
+		return err
+	}
+
+	assignment, err := currentGroupMedia(sh.DB, group.ID, scene.ID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	media := assignment.EpisodeMedia
+
+	if hasMediaSource(media) && sh.OBSClient != nil && sh.OBSClient.IsConnected() {
+		inputSettings, err := buildMediaInputSettings(mediaPath, media)
+		if err != nil {
+			return err
+		}
+		if err := sh.OBSClient.SetInputSettings(sourceName, inputSettings); err != nil {
+			return fmt.Errorf("błąd wczytywania %s do %s: %v", media.Title, sourceName, err)
+		}
+		if sh.MediaMonitor != nil {
+			sh.MediaMonitor.Track(sourceName, media)
+		}
+	}
+
+	if sh.Server != nil {
+		sh.BroadcastToEpisode(group.EpisodeID, "source_media_assigned", map[string]interface{}{
+			"episode_id":  group.EpisodeID,
+			"source_name": sourceName,
+			"media_id":    media.ID,
+			"title":       media.Title,
+		})
+	}
+	return nil
+}
+
+// loadGroupIntoSource wczytuje grupę jako playlistę do źródła VLC, zapisuje przypisanie
+// i informuje klientów
+func loadGroupIntoSource(sh *SocketHandler, mediaPath string, episodeID uint, sourceName string, groupID uint, assignedBy string) (*models.MediaGroup, error) {
+	var group models.MediaGroup
+	if err := sh.DB.Preload("MediaItems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Preload("MediaItems.EpisodeMedia").First(&group, groupID).Error; err != nil {
+		return nil, fmt.Errorf("grupa mediów %d nie istnieje", groupID)
+	}
+
+	playlist := buildPlaylist(mediaPath, group.MediaItems)
+	if len(playlist) == 0 {
+		return nil, fmt.Errorf("grupa %s nie zawiera odtwarzalnych plików", group.Name)
+	}
+
+	err := sh.OBSClient.SetInputSettings(sourceName, map[string]interface{}{
+		"playlist": playlist,
+		"loop":     false,
+		"shuffle":  false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("błąd wczytywania grupy %s do %s: %v", group.Name, sourceName, err)
+	}
+
+	models.SetEpisodeSourceGroup(sh.DB, episodeID, sourceName, group.ID, assignedBy)
+	sh.SaveVLCAssignment(episodeID, sourceName, group.Name, group.ID)
+	sh.BroadcastToEpisode(episodeID, "source_group_assigned", map[string]interface{}{
+		"episode_id":  episodeID,
+		"source_name": sourceName,
+		"group_id":    group.ID,
+		"name":        group.Name,
+	})
+	return &group, nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"obs-controller/models"
+	"obs-controller/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allowLocalURLs pozwala sprawdzać adresy lokalnego serwera testowego (httptest słucha na 127.0.0.1)
+func allowLocalURLs(t *testing.T) {
+	t.Helper()
+	utils.AllowPrivateURLs = true
+	t.Cleanup(func() { utils.AllowPrivateURLs = false })
+}
+
+func TestBuildPlaylistChecksURLsConcurrently(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	allowLocalURLs(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	str := func(s string) *string { return &s }
+	items := []models.EpisodeMediaGroup{
+		{EpisodeMedia: models.EpisodeMedia{Title: "a", URL: str(srv.URL + "/a")}},
+		{EpisodeMedia: models.EpisodeMedia{Title: "local", FilePath: str("clip.mp4")}},
+		{EpisodeMedia: models.EpisodeMedia{Title: "missing", URL: str(srv.URL + "/missing")}},
+		{EpisodeMedia: models.EpisodeMedia{Title: "b", URL: str(srv.URL + "/b")}},
+		{EpisodeMedia: models.EpisodeMedia{Title: "empty"}},
+		{EpisodeMedia: models.EpisodeMedia{Title: "c", URL: str(srv.URL + "/c")}},
+	}
+
+	start := time.Now()
+	playlist := buildPlaylist(t.TempDir(), items)
+	if elapsed := time.Since(start); elapsed > 3*delay {
+		t.Errorf("buildPlaylist took %v, want checks in parallel (< %v)", elapsed, 3*delay)
+	}
+
+	want := []string{srv.URL + "/a", "clip.mp4", srv.URL + "/b", srv.URL + "/c"}
+	if len(playlist) != len(want) {
+		t.Fatalf("playlist = %v, want %d entries", playlist, len(want))
+	}
+	for i, entry := range playlist {
+		value, _ := entry["value"].(string)
+		if i == 1 {
+			if !strings.HasSuffix(value, want[1]) {
+				t.Errorf("entry %d = %q, want local path ending in %q", i, value, want[1])
+			}
+			continue
+		}
+		if value != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, value, want[i])
+		}
+	}
+}
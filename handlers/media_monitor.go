@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"log"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MediaMonitor pilnuje punktów wejścia/wyjścia (in/out) plików w źródłach Media Source
+// Po starcie odtwarzania przewija do punktu wejścia, a po osiągnięciu punktu wyjścia
+// zatrzymuje źródło i wysyła media_ended (tak jak przy naturalnym końcu pliku)
+type MediaMonitor struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+
+	// Mapa: input_name → media aktualnie wczytane do źródła
+	loaded   map[string]models.EpisodeMedia
+	loadedMu sync.RWMutex
+
+	// Mapa: input_name → kanał zatrzymujący obserwację punktu wyjścia
+	watchers   map[string]chan struct{}
+	watchersMu sync.Mutex
+}
+
+// Co ile sprawdzać pozycję odtwarzania przy obserwacji punktu wyjścia
+const mediaOutPointPollInterval = 200 * time.Millisecond
+
+func NewMediaMonitor(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler) *MediaMonitor {
+	return &MediaMonitor{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		loaded:        make(map[string]models.EpisodeMedia),
+		watchers:      make(map[string]chan struct{}),
+	}
+}
+
+// Start rozpoczyna nasłuchiwanie na eventy odtwarzania mediów z OBS
+func (mm *MediaMonitor) Start() {
+	log.Println("Starting Media Monitor...")
+
+	mm.OBSClient.OnEvent("MediaInputPlaybackStarted", func(event map[string]interface{}) {
+		inputName, ok := event["inputName"].(string)
+		if !ok {
+			return
+		}
+		mm.handlePlaybackStarted(inputName)
+	})
+
+	mm.OBSClient.OnEvent("MediaInputPlaybackEnded", func(event map[string]interface{}) {
+		inputName, ok := event["inputName"].(string)
+		if !ok {
+			return
+		}
+		mm.stopWatching(inputName)
+		mm.broadcastEnded(inputName, "end")
+	})
+
+	log.Println("Media Monitor started successfully")
+}
+
+// Track zapisuje które media zostało wczytane do źródła (wywoływane po SetInputSettings)
+func (mm *MediaMonitor) Track(inputName string, media models.EpisodeMedia) {
+	mm.loadedMu.Lock()
+	mm.loaded[inputName] = media
+	mm.loadedMu.Unlock()
+
+	// Nowy plik - poprzednia obserwacja punktu wyjścia nie ma już sensu
+	mm.stopWatching(inputName)
+}
+
+// GetLoaded zwraca media wczytane do źródła (jeśli znane)
+func (mm *MediaMonitor) GetLoaded(inputName string) (models.EpisodeMedia, bool) {
+	mm.loadedMu.RLock()
+	defer mm.loadedMu.RUnlock()
+
+	media, exists := mm.loaded[inputName]
+	return media, exists
+}
+
+// resolveMedia zwraca media dla źródła - z pamięci lub z przypisania w bazie
+func (mm *MediaMonitor) resolveMedia(inputName string) (models.EpisodeMedia, bool) {
+	if media, ok := mm.GetLoaded(inputName); ok {
+		// Odśwież z bazy - punkty in/out mogły zostać zmienione po wczytaniu
+		var fresh models.EpisodeMedia
+		if err := mm.DB.First(&fresh, media.ID).Error; err == nil {
+			return fresh, true
+		}
+		return media, true
+	}
+
+	// Po restarcie serwera nie mamy mapy w pamięci - użyj przypisania z episode_sources
+	episode, err := models.GetCurrentEpisode(mm.DB)
+	if err != nil {
+		return models.EpisodeMedia{}, false
+	}
+
+	assignment, err := models.GetEpisodeSourceAssignment(mm.DB, episode.ID, inputName)
+	if err != nil || assignment == nil || assignment.MediaID == nil {
+		return models.EpisodeMedia{}, false
+	}
+
+	var media models.EpisodeMedia
+	if err := mm.DB.First(&media, *assignment.MediaID).Error; err != nil {
+		return models.EpisodeMedia{}, false
+	}
+	return media, true
+}
+
+// handlePlaybackStarted przewija do punktu wejścia i uruchamia obserwację punktu wyjścia
+func (mm *MediaMonitor) handlePlaybackStarted(inputName string) {
+	media, ok := mm.resolveMedia(inputName)
+	if !ok {
+		return
+	}
+
+	if media.InPoint > 0 {
+		if err := mm.OBSClient.SetMediaInputCursor(inputName, media.InPoint); err != nil {
+			log.Printf("Błąd przewijania %s do punktu wejścia %d ms: %v", inputName, media.InPoint, err)
+		} else {
+			log.Printf("Przewinięto %s do punktu wejścia %d ms (%s)", inputName, media.InPoint, media.Title)
+		}
+	}
+
+	if media.OutPoint > 0 {
+		mm.watchOutPoint(inputName, media)
+	}
+}
+
+// watchOutPoint obserwuje pozycję odtwarzania i zatrzymuje źródło w punkcie wyjścia
+func (mm *MediaMonitor) watchOutPoint(inputName string, media models.EpisodeMedia) {
+	stop := make(chan struct{})
+
+	mm.watchersMu.Lock()
+	if previous, exists := mm.watchers[inputName]; exists {
+		close(previous)
+	}
+	mm.watchers[inputName] = stop
+	mm.watchersMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(mediaOutPointPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				state, cursor, _, err := mm.OBSClient.GetMediaInputStatus(inputName)
+				if err != nil {
+					log.Printf("Błąd pobierania stanu mediów %s: %v", inputName, err)
+					mm.finishWatching(inputName, stop)
+					return
+				}
+
+				if state == "OBS_MEDIA_STATE_ENDED" || state == "OBS_MEDIA_STATE_STOPPED" {
+					mm.finishWatching(inputName, stop)
+					return
+				}
+
+				if cursor >= media.OutPoint {
+					mm.finishWatching(inputName, stop)
+					if err := mm.OBSClient.TriggerMediaInputAction(inputName, "OBS_WEBSOCKET_MEDIA_INPUT_ACTION_STOP"); err != nil {
+						log.Printf("Błąd zatrzymywania %s w punkcie wyjścia: %v", inputName, err)
+					}
+					log.Printf("Osiągnięto punkt wyjścia %d ms w %s (%s)", media.OutPoint, inputName, media.Title)
+					mm.broadcastEnded(inputName, "out_point")
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stopWatching kończy obserwację punktu wyjścia dla źródła
+func (mm *MediaMonitor) stopWatching(inputName string) {
+	mm.watchersMu.Lock()
+	defer mm.watchersMu.Unlock()
+
+	if stop, exists := mm.watchers[inputName]; exists {
+		close(stop)
+		delete(mm.watchers, inputName)
+	}
+}
+
+// finishWatching usuwa obserwację zakończoną przez samą goroutine
+// (tylko jeśli w międzyczasie nie została zastąpiona nową)
+func (mm *MediaMonitor) finishWatching(inputName string, stop chan struct{}) {
+	mm.watchersMu.Lock()
+	defer mm.watchersMu.Unlock()
+
+	if current, exists := mm.watchers[inputName]; exists && current == stop {
+		delete(mm.watchers, inputName)
+	}
+}
+
+// broadcastEnded informuje klientów o zakończeniu odtwarzania w źródle
+func (mm *MediaMonitor) broadcastEnded(inputName string, reason string) {
+	data := map[string]interface{}{
+		"source_name": inputName,
+		"reason":      reason, // "end" lub "out_point"
+	}
+
+	if media, ok := mm.GetLoaded(inputName); ok {
+		data["media_id"] = media.ID
+		data["title"] = media.Title
+	}
+
+	if mm.SocketHandler != nil {
+		mm.SocketHandler.Broadcast("media_ended", data)
+
+		if mm.SocketHandler.AsRun != nil {
+			mm.SocketHandler.AsRun.MediaEnded(inputName, reason)
+		}
+		if mm.SocketHandler.Rules != nil {
+			mm.SocketHandler.Rules.Fire(models.RuleTriggerMediaEnded, data)
+		}
+	}
+}
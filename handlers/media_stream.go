@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"obs-controller/models"
+	"obs-controller/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Folder z podglądami niskiej rozdzielczości wewnątrz katalogu mediów
+const mediaProxyFolder = ".proxies"
+
+func init() {
+	// Typy MIME, których brakuje w części systemów (np. Windows bez kodeków)
+	mime.AddExtensionType(".mp4", "video/mp4")
+	mime.AddExtensionType(".mov", "video/quicktime")
+	mime.AddExtensionType(".mkv", "video/x-matroska")
+	mime.AddExtensionType(".webm", "video/webm")
+	mime.AddExtensionType(".avi", "video/x-msvideo")
+	mime.AddExtensionType(".mp3", "audio/mpeg")
+	mime.AddExtensionType(".wav", "audio/wav")
+	mime.AddExtensionType(".flac", "audio/flac")
+	mime.AddExtensionType(".aac", "audio/aac")
+}
+
+type MediaStreamHandler struct {
+	DB        *gorm.DB
+	MediaPath string
+	Token     string // Token dostępu (pusty = podgląd wyłączony)
+
+	// Zbiór podglądów w trakcie generowania: ścieżka proxy → true
+	generating   map[string]bool
+	generatingMu sync.Mutex
+}
+
+func NewMediaStreamHandler(db *gorm.DB, mediaPath string, token string) *MediaStreamHandler {
+	if token == "" {
+		log.Println("UWAGA: podgląd mediów wyłączony (brak MEDIA_PREVIEW_TOKEN)")
+	}
+
+	return &MediaStreamHandler{
+		DB:         db,
+		MediaPath:  mediaPath,
+		Token:      token,
+		generating: make(map[string]bool),
+	}
+}
+
+// RequireToken sprawdza token dostępu (nagłówek Authorization: Bearer lub parametr ?token=)
+// Parametr w URL jest potrzebny, bo <video src> nie wysyła własnych nagłówków
+// Bez skonfigurowanego tokenu podgląd jest niedostępny
+func (h *MediaStreamHandler) RequireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.Token == "" {
+			http.Error(w, "Media preview disabled (MEDIA_PREVIEW_TOKEN not set)", http.StatusServiceUnavailable)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// StreamMedia - GET /api/episodes/{episode_id}/media/{id}/stream[?proxy=1]
+// Strumieniuje plik media z obsługą Range (przewijanie w przeglądarce)
+// proxy=1 → podgląd 360p; jeśli jeszcze nie istnieje, zwraca 202 i generuje go w tle
+func (h *MediaStreamHandler) StreamMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	episodeID, err := strconv.ParseUint(vars["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var media models.EpisodeMedia
+	if err := h.DB.Where("id = ? AND episode_id = ?", id, episodeID).First(&media).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Media not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Media z URL - przekieruj do źródła (tylko http/https, przeglądarka nie odtworzy rtmp/srt)
+	if isURLMedia(media) {
+		target, err := url.Parse(*media.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			http.Error(w, "Media URL cannot be previewed", http.StatusUnprocessableEntity)
+			return
+		}
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return
+	}
+
+	if media.FilePath == nil || *media.FilePath == "" {
+		http.Error(w, "Media has no file path", http.StatusNotFound)
+		return
+	}
+
+	fullPath, ok := mediaFilePath(h.MediaPath, *media.FilePath)
+	if !ok {
+		http.Error(w, "Invalid media file path", http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Query().Get("proxy") == "1" {
+		proxyPath, ok := h.proxyPath(*media.FilePath)
+		if !ok {
+			http.Error(w, "Invalid media file path", http.StatusForbidden)
+			return
+		}
+		if _, err := os.Stat(proxyPath); err != nil {
+			h.startProxyGeneration(fullPath, proxyPath)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":   "generating",
+				"media_id": media.ID,
+			})
+			return
+		}
+		fullPath = proxyPath
+	}
+
+	h.serveFile(w, r, fullPath)
+}
+
+// serveFile wysyła plik z obsługą Range i poprawnym typem MIME
+func (h *MediaStreamHandler) serveFile(w http.ResponseWriter, r *http.Request, fullPath string) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(fullPath)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+
+	// ServeContent obsługuje nagłówki Range, If-Range i If-Modified-Since
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// mediaFilePath zwraca ścieżkę pliku wewnątrz katalogu bazowego
+// Ścieżki bezwzględne i wychodzące poza katalog (../) są odrzucane
+func mediaFilePath(basePath, relativePath string) (string, bool) {
+	local := filepath.Clean(filepath.FromSlash(relativePath))
+	if local == "." || !filepath.IsLocal(local) {
+		return "", false
+	}
+	return filepath.Join(basePath, local), true
+}
+
+// proxyPath zwraca ścieżkę podglądu dla pliku (media/.proxies/season_N/plik.mp4)
+func (h *MediaStreamHandler) proxyPath(relativePath string) (string, bool) {
+	base := strings.TrimSuffix(relativePath, filepath.Ext(relativePath)) + ".mp4"
+	return mediaFilePath(filepath.Join(h.MediaPath, mediaProxyFolder), base)
+}
+
+// startProxyGeneration uruchamia generowanie podglądu w tle (jeśli jeszcze nie trwa)
+func (h *MediaStreamHandler) startProxyGeneration(srcPath, proxyPath string) {
+	h.generatingMu.Lock()
+	if h.generating[proxyPath] {
+		h.generatingMu.Unlock()
+		return
+	}
+	h.generating[proxyPath] = true
+	h.generatingMu.Unlock()
+
+	go func() {
+		defer func() {
+			h.generatingMu.Lock()
+			delete(h.generating, proxyPath)
+			h.generatingMu.Unlock()
+		}()
+
+		log.Printf("Generowanie podglądu: %s", srcPath)
+		if err := utils.GenerateProxy(srcPath, proxyPath); err != nil {
+			log.Printf("Błąd generowania podglądu %s: %v", srcPath, err)
+			return
+		}
+		log.Printf("Podgląd gotowy: %s", proxyPath)
+	}()
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"obs-controller/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRequireTokenRefusesWithoutConfiguredToken(t *testing.T) {
+	h := &MediaStreamHandler{}
+	called := false
+	handler := h.RequireToken(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/stream?token=", nil))
+
+	if called {
+		t.Fatal("handler called without configured token")
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRequireTokenChecksToken(t *testing.T) {
+	h := &MediaStreamHandler{Token: "secret"}
+	handler := h.RequireToken(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		want   int
+	}{
+		{"missing", "/stream", "", http.StatusUnauthorized},
+		{"wrong query", "/stream?token=nope", "", http.StatusUnauthorized},
+		{"query", "/stream?token=secret", "", http.StatusOK},
+		{"bearer", "/stream", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestMediaFilePath(t *testing.T) {
+	base := filepath.Join("srv", "media")
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"season_1/film.mp4", true},
+		{"season_1/../season_2/film.mp4", true},
+		{"../secret.txt", false},
+		{"season_1/../../secret.txt", false},
+		{"/etc/passwd", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := mediaFilePath(base, tt.path)
+		if ok != tt.ok {
+			t.Errorf("%q: ok = %v, want %v", tt.path, ok, tt.ok)
+			continue
+		}
+		if ok {
+			if rel, err := filepath.Rel(base, got); err != nil || !filepath.IsLocal(rel) {
+				t.Errorf("%q resolved outside base: %s", tt.path, got)
+			}
+		}
+	}
+}
+
+func TestStreamMediaRejectsTraversal(t *testing.T) {
+	db := openTestDB(t)
+	episode := createCurrentEpisode(t, db, nil)
+
+	root := t.TempDir()
+	mediaPath := filepath.Join(root, "media")
+	os.MkdirAll(mediaPath, 0755)
+	os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0644)
+
+	filePath := "../secret.txt"
+	media := models.EpisodeMedia{EpisodeID: episode.ID, Title: "Zły", FilePath: &filePath}
+	db.Create(&media)
+
+	h := NewMediaStreamHandler(db, mediaPath, "secret")
+	for _, query := range []string{"", "?proxy=1"} {
+		req := httptest.NewRequest("GET", "/stream"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{
+			"episode_id": fmt.Sprint(episode.ID),
+			"id":         fmt.Sprint(media.ID),
+		})
+		rec := httptest.NewRecorder()
+		h.StreamMedia(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%q: status = %d, want %d", query, rec.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestStreamMediaRedirectsOnlyHTTPURLsWithToken(t *testing.T) {
+	db := openTestDB(t)
+	episode := createCurrentEpisode(t, db, nil)
+	h := NewMediaStreamHandler(db, t.TempDir(), "secret")
+	handler := h.RequireToken(h.StreamMedia)
+
+	stream := func(rawURL, query string) *httptest.ResponseRecorder {
+		media := models.EpisodeMedia{EpisodeID: episode.ID, Title: rawURL, URL: &rawURL}
+		db.Create(&media)
+		req := httptest.NewRequest("GET", "/stream"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{
+			"episode_id": fmt.Sprint(episode.ID),
+			"id":         fmt.Sprint(media.ID),
+		})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := stream("https://cdn.example.com/clip.mp4", ""); rec.Code != http.StatusUnauthorized || rec.Header().Get("Location") != "" {
+		t.Errorf("without token: status = %d, location = %q; want 401 and no redirect", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := stream("https://cdn.example.com/clip.mp4", "?token=secret"); rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://cdn.example.com/clip.mp4" {
+		t.Errorf("http URL: status = %d, location = %q; want redirect", rec.Code, rec.Header().Get("Location"))
+	}
+	for _, rawURL := range []string{"javascript:alert(1)", "file:///etc/passwd", "rtmp://live.example.com/app/key", "//evil.example.com/x"} {
+		if rec := stream(rawURL, "?token=secret"); rec.Code != http.StatusUnprocessableEntity || rec.Header().Get("Location") != "" {
+			t.Errorf("%s: status = %d, location = %q; want 422 and no redirect", rawURL, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Parametr szablonu w formacie źródła natywnego: {name}
+var nativeOverlayPlaceholder = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+// Limit czasu pojedynczego żądania OBS backendu natywnego
+const nativeOverlayRequestTimeout = 2 * time.Second
+
+// Pojemność kolejki poleceń backendu natywnego (przy pełnej polecenie jest pomijane)
+const nativeOverlayQueueSize = 128
+
+// NativeOverlay - alternatywny backend szablonów overlayu dla OBS bez źródeł przeglądarki:
+// grafiki z OverlayManager trafiają do źródeł tekstowych i obrazów (SetInputSettings,
+// widoczność, transformacja). Strona overlayu dostaje te same polecenia co dotąd
+// Polecenia wykonuje w kolejności osobna gorutyna - wolny OBS nie blokuje overlayu
+type NativeOverlay struct {
+	DB        *gorm.DB
+	OBSClient *obsws.Client
+
+	queue chan func()
+}
+
+func NewNativeOverlay(db *gorm.DB, obsClient *obsws.Client) *NativeOverlay {
+	no := &NativeOverlay{
+		DB:        db,
+		OBSClient: obsClient,
+		queue:     make(chan func(), nativeOverlayQueueSize),
+	}
+	go no.run()
+	return no
+}
+
+// run wykonuje polecenia z kolejki (zachowuje kolejność pokaż → zdejmij)
+func (no *NativeOverlay) run() {
+	for command := range no.queue {
+		command()
+	}
+}
+
+// enqueue dodaje polecenie do kolejki bez czekania na OBS
+func (no *NativeOverlay) enqueue(name string, command func()) {
+	if no == nil || no.OBSClient == nil {
+		return
+	}
+
+	select {
+	case no.queue <- command:
+	default:
+		log.Printf("Overlay natywny: kolejka pełna, pominięto %s", name)
+	}
+}
+
+// targets zwraca włączone źródła natywne kanału (pusty template = wszystkie szablony)
+func (no *NativeOverlay) targets(channel, template string) []models.NativeOverlayTarget {
+	query := no.DB.Where("channel = ? AND enabled = ?", channel, true)
+	if template != "" {
+		query = query.Where("template = ?", template)
+	}
+
+	var targets []models.NativeOverlayTarget
+	if err := query.Order("id ASC").Find(&targets).Error; err != nil {
+		log.Printf("Overlay natywny: błąd odczytu źródeł: %v", err)
+	}
+	return targets
+}
+
+// Show - grafika pokazana: ustawienia, pozycja i widoczność źródeł szablonu
+func (no *NativeOverlay) Show(item OverlayItem) {
+	no.enqueue("show "+item.Template, func() {
+		no.show(item)
+	})
+}
+
+// Update - zmienione parametry wyświetlanej grafiki (bez zmiany widoczności)
+func (no *NativeOverlay) Update(item OverlayItem) {
+	no.enqueue("update "+item.Template, func() {
+		for _, target := range no.targets(item.Channel, item.Template) {
+			no.apply(target, item.Params)
+		}
+	})
+}
+
+// Hide - grafika zdjęta: ukrycie źródeł szablonu
+func (no *NativeOverlay) Hide(channel, template string) {
+	no.enqueue("hide "+template, func() {
+		for _, target := range no.targets(channel, template) {
+			no.setVisible(target, false)
+		}
+	})
+}
+
+// HideTarget ukrywa pojedyncze źródło (usunięte lub przeniesione w konfiguracji)
+func (no *NativeOverlay) HideTarget(target models.NativeOverlayTarget) {
+	no.enqueue("hide "+target.SourceName, func() {
+		no.setVisible(target, false)
+	})
+}
+
+// Sync doprowadza źródła natywne kanału do stanu overlayu (po zmianie konfiguracji)
+func (no *NativeOverlay) Sync(channel string, items []OverlayItem) {
+	no.enqueue("sync "+channel, func() {
+		shown := make(map[string]bool, len(items))
+		for _, item := range items {
+			shown[item.Template] = true
+			no.show(item)
+		}
+
+		for _, target := range no.targets(channel, "") {
+			if !shown[target.Template] {
+				no.setVisible(target, false)
+			}
+		}
+	})
+}
+
+// show ustawia źródła szablonu i je pokazuje (wywoływać z kolejki)
+func (no *NativeOverlay) show(item OverlayItem) {
+	for _, target := range no.targets(item.Channel, item.Template) {
+		no.apply(target, item.Params)
+		if target.PositionX != nil || target.PositionY != nil {
+			transform := map[string]interface{}{}
+			if target.PositionX != nil {
+				transform["positionX"] = *target.PositionX
+			}
+			if target.PositionY != nil {
+				transform["positionY"] = *target.PositionY
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), nativeOverlayRequestTimeout)
+			err := no.OBSClient.SetSceneItemTransformContext(ctx, target.SceneName, target.SourceName, transform)
+			cancel()
+			if err != nil {
+				log.Printf("Overlay natywny: błąd pozycji %s: %v", target.SourceName, err)
+			}
+		}
+		no.setVisible(target, true)
+	}
+}
+
+// apply ustawia tekst lub plik obrazu źródła z parametrów grafiki (wywoływać z kolejki)
+func (no *NativeOverlay) apply(target models.NativeOverlayTarget, params map[string]interface{}) {
+	value := nativeOverlayValue(target.Format, params)
+
+	setting := "text"
+	if target.Kind == models.NativeOverlayImage {
+		// Bez pliku obraz zostaje przy ustawieniu z OBS (np. logo bez src)
+		if value == "" {
+			return
+		}
+		setting = "file"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nativeOverlayRequestTimeout)
+	defer cancel()
+	if err := no.OBSClient.UpdateInputSettingsContext(ctx, target.SourceName, map[string]interface{}{setting: value}); err != nil {
+		log.Printf("Overlay natywny: błąd ustawień %s: %v", target.SourceName, err)
+	}
+}
+
+// setVisible przełącza widoczność źródła w scenie (wywoływać z kolejki)
+func (no *NativeOverlay) setVisible(target models.NativeOverlayTarget, visible bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), nativeOverlayRequestTimeout)
+	defer cancel()
+	if err := no.OBSClient.SetSourceVisibilityContext(ctx, target.SceneName, target.SourceName, visible); err != nil {
+		log.Printf("Overlay natywny: błąd widoczności %s: %v", target.SourceName, err)
+	}
+}
+
+// nativeOverlayValue podstawia parametry grafiki do formatu, np. "{role} • {topic}"
+// Separatory na brzegach (pusty parametr) są obcinane
+func nativeOverlayValue(format string, params map[string]interface{}) string {
+	value := nativeOverlayPlaceholder.ReplaceAllStringFunc(format, func(placeholder string) string {
+		return nativeOverlayParam(params[placeholder[1:len(placeholder)-1]])
+	})
+	return strings.Trim(value, " •|")
+}
+
+// nativeOverlayParam zamienia parametr na tekst; listy (np. wiadomości paska) łączy " • "
+func nativeOverlayParam(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int, uint, bool:
+		return fmt.Sprint(v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, element := range v {
+			if text := nativeOverlayParam(element); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, " • ")
+	case map[string]interface{}:
+		for _, key := range []string{"text", "name", "title"} {
+			if text, ok := v[key].(string); ok && text != "" {
+				return text
+			}
+		}
+		return ""
+	}
+
+	// Typy Go (np. []map[string]interface{}, []CreditsGroup) - przez JSON
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return ""
+	}
+	return nativeOverlayParam(generic)
+}
+
+// nativeOverlayTargetRequest - dane źródła natywnego (tworzenie i edycja)
+type nativeOverlayTargetRequest struct {
+	Channel    string   `json:"channel"` // Pusty = kanał domyślny
+	Template   string   `json:"template"`
+	SceneName  string   `json:"scene_name"`
+	SourceName string   `json:"source_name"`
+	Kind       string   `json:"kind"`
+	Format     string   `json:"format"`
+	PositionX  *float64 `json:"position_x"`
+	PositionY  *float64 `json:"position_y"`
+	Enabled    *bool    `json:"enabled"` // Pominięte = włączone
+}
+
+// validate sprawdza dane źródła i ustawia kanał domyślny
+func (req *nativeOverlayTargetRequest) validate() error {
+	channel, err := overlayChannelName(req.Channel)
+	if err != nil {
+		return err
+	}
+	req.Channel = channel
+
+	if _, ok := findOverlayTemplate(req.Template); !ok {
+		return fmt.Errorf("unknown template: %s", req.Template)
+	}
+	if req.SceneName == "" || req.SourceName == "" {
+		return fmt.Errorf("scene_name and source_name are required")
+	}
+
+	validKind := false
+	for _, kind := range models.NativeOverlayKinds {
+		if req.Kind == kind {
+			validKind = true
+			break
+		}
+	}
+	if !validKind {
+		return fmt.Errorf("invalid kind: %s", req.Kind)
+	}
+	if req.Kind == models.NativeOverlayText && req.Format == "" {
+		return fmt.Errorf("format is required for text sources")
+	}
+	return nil
+}
+
+type NativeOverlayHandler struct {
+	DB      *gorm.DB
+	Overlay *OverlayManager
+}
+
+func NewNativeOverlayHandler(db *gorm.DB, overlay *OverlayManager) *NativeOverlayHandler {
+	return &NativeOverlayHandler{DB: db, Overlay: overlay}
+}
+
+// sync odświeża źródła natywne kanału po zmianie konfiguracji
+func (h *NativeOverlayHandler) sync(channel string) {
+	h.Overlay.Native.Sync(channel, h.Overlay.State(channel))
+}
+
+// GetTargets - GET /api/overlay/native?channel=main
+func (h *NativeOverlayHandler) GetTargets(w http.ResponseWriter, r *http.Request) {
+	query := h.DB.Order("channel ASC").Order("template ASC").Order("id ASC")
+	if r.URL.Query().Get("channel") != "" {
+		channel, ok := overlayChannelFromRequest(w, r)
+		if !ok {
+			return
+		}
+		query = query.Where("channel = ?", channel)
+	}
+
+	var targets []models.NativeOverlayTarget
+	if err := query.Find(&targets).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// CreateTarget - POST /api/overlay/native
+func (h *NativeOverlayHandler) CreateTarget(w http.ResponseWriter, r *http.Request) {
+	var req nativeOverlayTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target := models.NativeOverlayTarget{
+		Channel:    req.Channel,
+		Template:   req.Template,
+		SceneName:  req.SceneName,
+		SourceName: req.SourceName,
+		Kind:       req.Kind,
+		Format:     req.Format,
+		PositionX:  req.PositionX,
+		PositionY:  req.PositionY,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if err := h.DB.Create(&target).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.sync(target.Channel)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(target)
+}
+
+// UpdateTarget - PUT /api/overlay/native/{id}
+func (h *NativeOverlayHandler) UpdateTarget(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req nativeOverlayTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Źródło przeniesione do innego szablonu/kanału - ukryj je w starym miejscu
+	h.Overlay.Native.HideTarget(target)
+	previousChannel := target.Channel
+
+	target.Channel = req.Channel
+	target.Template = req.Template
+	target.SceneName = req.SceneName
+	target.SourceName = req.SourceName
+	target.Kind = req.Kind
+	target.Format = req.Format
+	target.PositionX = req.PositionX
+	target.PositionY = req.PositionY
+	target.Enabled = req.Enabled == nil || *req.Enabled
+
+	if err := h.DB.Save(&target).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.sync(target.Channel)
+	if previousChannel != target.Channel {
+		h.sync(previousChannel)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(target)
+}
+
+// DeleteTarget - DELETE /api/overlay/native/{id}
+func (h *NativeOverlayHandler) DeleteTarget(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&target).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Usunięte źródło nie może zostać na antenie
+	h.Overlay.Native.HideTarget(target)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SyncTargets - POST /api/overlay/native/sync?channel=main
+// Np. po restarcie OBS - ustawia źródła zgodnie z aktualnym stanem overlayu
+func (h *NativeOverlayHandler) SyncTargets(w http.ResponseWriter, r *http.Request) {
+	channel, ok := overlayChannelFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	h.sync(channel)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadTarget pobiera źródło natywne z {id}
+func (h *NativeOverlayHandler) loadTarget(w http.ResponseWriter, r *http.Request) (models.NativeOverlayTarget, bool) {
+	var target models.NativeOverlayTarget
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return target, false
+	}
+
+	if err := h.DB.First(&target, id).Error; err != nil {
+		http.Error(w, "Native overlay target not found", http.StatusNotFound)
+		return target, false
+	}
+	return target, true
+}
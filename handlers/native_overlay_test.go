@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateNativeTargetEnabledFlag(t *testing.T) {
+	db := openTestDB(t)
+	overlay := NewOverlayManager(newTestSocketHandler(t, db))
+	h := NewNativeOverlayHandler(db, overlay)
+
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"channel":"main","template":"lower_third","scene_name":"KAMERY","source_name":"Nazwisko","kind":"text","format":"{name}"}`, true},
+		{`{"channel":"main","template":"lower_third","scene_name":"KAMERY","source_name":"Funkcja","kind":"text","format":"{title}","enabled":false}`, false},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.CreateTarget(rec, httptest.NewRequest("POST", "/api/overlay/native", strings.NewReader(tt.body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var created models.NativeOverlayTarget
+		json.NewDecoder(rec.Body).Decode(&created)
+
+		var loaded models.NativeOverlayTarget
+		db.First(&loaded, created.ID)
+		if created.Enabled != tt.want || loaded.Enabled != tt.want {
+			t.Errorf("%s: enabled = %v (stored %v), want %v", created.SourceName, created.Enabled, loaded.Enabled, tt.want)
+		}
+	}
+}
+
+func TestNativeOverlayDoesNotBlockCaller(t *testing.T) {
+	db := openTestDB(t)
+	db.Create(&models.NativeOverlayTarget{Channel: "main", Template: OverlayTemplateLowerThird,
+		SceneName: "KAMERY", SourceName: "Nazwisko", Kind: models.NativeOverlayText, Format: "{name}", Enabled: true})
+
+	// Kolejka bez gorutyny wykonującej - polecenia ponad pojemność są pomijane, a nie blokują
+	stalled := &NativeOverlay{DB: db, OBSClient: &obsws.Client{}, queue: make(chan func(), 1)}
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			stalled.Show(OverlayItem{Channel: "main", Template: OverlayTemplateLowerThird})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Show blocked on a full queue")
+	}
+
+	// Z niepołączonym OBS kolejka jest opróżniana (błędy tylko w logu)
+	no := NewNativeOverlay(db, &obsws.Client{})
+	item := OverlayItem{Channel: "main", Template: OverlayTemplateLowerThird, Params: map[string]interface{}{"name": "Jan"}}
+	no.Show(item)
+	no.Update(item)
+	no.Hide("main", OverlayTemplateLowerThird)
+	no.Sync("main", nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(no.queue) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("native overlay queue not drained")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Nazwy szablonów grafik overlayu
+const (
+	OverlayTemplateLowerThird = "lower_third"
+	OverlayTemplateTitleCard  = "title_card"
+	OverlayTemplateTicker     = "ticker"
+	OverlayTemplateClock      = "clock"
+	OverlayTemplateLogoBug    = "logo_bug"
+	OverlayTemplateCredits    = "credits"
+	OverlayTemplateSponsor    = "sponsor"
+)
+
+// OverlayTemplate opisuje szablon grafiki i jego parametry
+type OverlayTemplate struct {
+	Name       string   `json:"name"`
+	Label      string   `json:"label"`
+	Required   []string `json:"required"`    // Parametry wymagane przy pokazaniu
+	Optional   []string `json:"optional"`    // Pozostałe rozpoznawane parametry
+	DurationMs int      `json:"duration_ms"` // Domyślny czas wyświetlania (0 = do zdjęcia)
+}
+
+// overlayTemplates - szablony obsługiwane przez overlay.js (kolejność = kolejność warstw)
+var overlayTemplates = []OverlayTemplate{
+	{Name: OverlayTemplateLowerThird, Label: "Pasek z nazwiskiem", Required: []string{"name"}, Optional: []string{"role", "topic", "source_name", "person_type", "person_id"}, DurationMs: lowerThirdDefaultDuration},
+	{Name: OverlayTemplateTitleCard, Label: "Plansza tytułowa", Required: []string{"title"}, Optional: []string{"subtitle"}, DurationMs: 6000},
+	{Name: OverlayTemplateTicker, Label: "Pasek informacyjny", Required: []string{"messages"}, Optional: []string{"speed", "episode_id"}},
+	{Name: OverlayTemplateClock, Label: "Zegar", Optional: []string{"mode"}}, // mode: time / elapsed / remaining
+	{Name: OverlayTemplateLogoBug, Label: "Logo", Optional: []string{"src"}},
+	{Name: OverlayTemplateCredits, Label: "Napisy końcowe", Required: []string{"groups"}, Optional: []string{"title", "speed", "episode_id"}}, // Zdejmuje się sam po przewinięciu
+	{Name: OverlayTemplateSponsor, Label: "Logo sponsora", Required: []string{"src"}, Optional: []string{"name", "sponsor_id", "file"}},
+}
+
+// Kanały overlayu - każda strona /overlay?channel=<nazwa> ma własny zestaw grafik
+const OverlayDefaultChannel = "main"
+
+var overlayChannelPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// overlayChannelName zwraca nazwę kanału (pusta = domyślny) lub błąd dla niedozwolonej nazwy
+func overlayChannelName(channel string) (string, error) {
+	if channel == "" {
+		return OverlayDefaultChannel, nil
+	}
+	if !overlayChannelPattern.MatchString(channel) {
+		return "", fmt.Errorf("Niedozwolona nazwa kanału: %s", channel)
+	}
+	return channel, nil
+}
+
+// OverlayItem - grafika aktualnie wyświetlana w overlayu
+type OverlayItem struct {
+	Channel    string                 `json:"channel"`
+	Template   string                 `json:"template"`
+	Params     map[string]interface{} `json:"params"`
+	DurationMs int                    `json:"duration_ms"` // 0 = do zdjęcia
+	ShownAt    time.Time              `json:"shown_at"`
+	ExpiresAt  *time.Time             `json:"expires_at"`
+}
+
+// clone kopiuje grafikę razem z parametrami (kopia może wyjść poza blokadę)
+func (item *OverlayItem) clone() OverlayItem {
+	snapshot := *item
+	snapshot.Params = make(map[string]interface{}, len(item.Params))
+	for key, value := range item.Params {
+		snapshot.Params[key] = value
+	}
+	return snapshot
+}
+
+// overlayChannel - grafiki i timery jednego kanału
+type overlayChannel struct {
+	items  map[string]*OverlayItem
+	timers map[string]*time.Timer
+}
+
+// OverlayManager trzyma stan overlayu po stronie serwera (co jest wyświetlane na każdym kanale),
+// zdejmuje grafiki po czasie i odtwarza stan po połączeniu overlayu
+type OverlayManager struct {
+	SocketHandler *SocketHandler
+	Native        *NativeOverlay // Źródła tekstowe/obrazy OBS zamiast strony overlayu (nil = tylko strona)
+
+	channels map[string]*overlayChannel
+	mu       sync.Mutex
+}
+
+func NewOverlayManager(socketHandler *SocketHandler) *OverlayManager {
+	return &OverlayManager{
+		SocketHandler: socketHandler,
+		channels:      make(map[string]*overlayChannel),
+	}
+}
+
+// channel zwraca stan kanału, tworząc go przy pierwszym użyciu (wywoływać pod om.mu)
+func (om *OverlayManager) channel(name string) *overlayChannel {
+	ch, ok := om.channels[name]
+	if ok {
+		return ch
+	}
+
+	ch = &overlayChannel{
+		items:  make(map[string]*OverlayItem),
+		timers: make(map[string]*time.Timer),
+	}
+	// Logo było dotąd zawsze widoczne - nowy kanał zaczyna z pokazanym logo
+	ch.items[OverlayTemplateLogoBug] = &OverlayItem{
+		Channel:  name,
+		Template: OverlayTemplateLogoBug,
+		Params:   map[string]interface{}{},
+		ShownAt:  time.Now(),
+	}
+	om.channels[name] = ch
+	return ch
+}
+
+// findOverlayTemplate zwraca szablon o podanej nazwie
+func findOverlayTemplate(name string) (*OverlayTemplate, bool) {
+	for i := range overlayTemplates {
+		if overlayTemplates[i].Name == name {
+			return &overlayTemplates[i], true
+		}
+	}
+	return nil, false
+}
+
+// Show pokazuje grafikę na kanale (zastępuje poprzednią z tego szablonu)
+// durationMs: 0 = domyślny czas szablonu, <0 = do ręcznego zdjęcia
+func (om *OverlayManager) Show(channel, templateName string, params map[string]interface{}, durationMs int) (*OverlayItem, error) {
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return nil, err
+	}
+	template, ok := findOverlayTemplate(templateName)
+	if !ok {
+		return nil, fmt.Errorf("Nieznany szablon: %s", templateName)
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	for _, name := range template.Required {
+		if value, ok := params[name]; !ok || value == nil || value == "" {
+			return nil, fmt.Errorf("Brak parametru %s dla szablonu %s", name, templateName)
+		}
+	}
+
+	if durationMs == 0 {
+		durationMs = template.DurationMs
+	}
+	if durationMs < 0 {
+		durationMs = 0
+	}
+
+	item := &OverlayItem{
+		Channel:    channel,
+		Template:   templateName,
+		Params:     params,
+		DurationMs: durationMs,
+		ShownAt:    time.Now(),
+	}
+
+	om.mu.Lock()
+	om.channel(channel).items[templateName] = item
+	om.schedule(item)
+	snapshot := item.clone()
+	om.mu.Unlock()
+
+	om.SocketHandler.BroadcastToOverlay(channel, "overlay_show", snapshot)
+	om.Native.Show(snapshot)
+	om.logAsRun(template, snapshot)
+	log.Printf("Overlay [%s]: pokazano %s", channel, templateName)
+
+	return &snapshot, nil
+}
+
+// Update zmienia parametry wyświetlanej grafiki (durationMs != 0 ustawia nowy czas od teraz)
+func (om *OverlayManager) Update(channel, templateName string, params map[string]interface{}, durationMs int) (*OverlayItem, error) {
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	om.mu.Lock()
+	item, ok := om.channel(channel).items[templateName]
+	if !ok {
+		om.mu.Unlock()
+		return nil, fmt.Errorf("Szablon %s nie jest wyświetlany", templateName)
+	}
+
+	for key, value := range params {
+		item.Params[key] = value
+	}
+	if durationMs != 0 {
+		item.DurationMs = durationMs
+		if durationMs < 0 {
+			item.DurationMs = 0
+		}
+		item.ShownAt = time.Now()
+		om.schedule(item)
+	}
+	snapshot := item.clone()
+	om.mu.Unlock()
+
+	om.SocketHandler.BroadcastToOverlay(channel, "overlay_update", snapshot)
+	om.Native.Update(snapshot)
+	return &snapshot, nil
+}
+
+// Hide zdejmuje grafikę z kanału; zwraca false, jeśli nie była wyświetlana
+func (om *OverlayManager) Hide(channel, templateName string) bool {
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return false
+	}
+	return om.hide(channel, templateName, nil)
+}
+
+// HideAll zdejmuje wszystkie grafiki z kanału
+func (om *OverlayManager) HideAll(channel string) {
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return
+	}
+
+	om.mu.Lock()
+	items := om.channel(channel).items
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	om.mu.Unlock()
+
+	for _, name := range names {
+		om.hide(channel, name, nil)
+	}
+}
+
+// hide zdejmuje grafikę; only != nil - tylko jeśli nadal wyświetlana jest ta sama (wygasły timer)
+func (om *OverlayManager) hide(channel, templateName string, only *OverlayItem) bool {
+	om.mu.Lock()
+	ch := om.channel(channel)
+	item, ok := ch.items[templateName]
+	if !ok || (only != nil && item != only) {
+		om.mu.Unlock()
+		return false
+	}
+	delete(ch.items, templateName)
+	if timer, ok := ch.timers[templateName]; ok {
+		timer.Stop()
+		delete(ch.timers, templateName)
+	}
+	om.mu.Unlock()
+
+	om.SocketHandler.BroadcastToOverlay(channel, "overlay_hide", map[string]interface{}{
+		"channel":  channel,
+		"template": templateName,
+	})
+	om.Native.Hide(channel, templateName)
+	return true
+}
+
+// schedule ustawia automatyczne zdjęcie grafiki (wywoływać pod om.mu)
+func (om *OverlayManager) schedule(item *OverlayItem) {
+	ch := om.channel(item.Channel)
+	if timer, ok := ch.timers[item.Template]; ok {
+		timer.Stop()
+		delete(ch.timers, item.Template)
+	}
+
+	item.ExpiresAt = nil
+	if item.DurationMs <= 0 {
+		return
+	}
+
+	expiresAt := item.ShownAt.Add(time.Duration(item.DurationMs) * time.Millisecond)
+	item.ExpiresAt = &expiresAt
+	ch.timers[item.Template] = time.AfterFunc(time.Until(expiresAt), func() {
+		om.hide(item.Channel, item.Template, item)
+	})
+}
+
+// Get zwraca wyświetlaną grafikę danego szablonu na kanale (nil = nie jest wyświetlana)
+func (om *OverlayManager) Get(channel, templateName string) *OverlayItem {
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return nil
+	}
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	item, ok := om.channel(channel).items[templateName]
+	if !ok {
+		return nil
+	}
+	snapshot := item.clone()
+	return &snapshot
+}
+
+// State zwraca wszystkie grafiki wyświetlane na kanale w kolejności szablonów
+func (om *OverlayManager) State(channel string) []OverlayItem {
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return []OverlayItem{}
+	}
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	items := om.channel(channel).items
+	state := make([]OverlayItem, 0, len(items))
+	for _, template := range overlayTemplates {
+		if item, ok := items[template.Name]; ok {
+			state = append(state, item.clone())
+		}
+	}
+	return state
+}
+
+// Channels zwraca nazwy znanych kanałów (domyślny zawsze pierwszy)
+func (om *OverlayManager) Channels() []string {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	om.channel(OverlayDefaultChannel)
+	channels := make([]string, 0, len(om.channels))
+	for name := range om.channels {
+		if name != OverlayDefaultChannel {
+			channels = append(channels, name)
+		}
+	}
+	sort.Strings(channels)
+	return append([]string{OverlayDefaultChannel}, channels...)
+}
+
+// logAsRun zapisuje pokazanie grafiki w dzienniku emisji (z osobą, jeśli jest w parametrach)
+func (om *OverlayManager) logAsRun(template *OverlayTemplate, item OverlayItem) {
+	if om.SocketHandler.AsRun == nil {
+		return
+	}
+
+	title := template.Label
+	for _, key := range []string{"name", "title"} {
+		if value, ok := item.Params[key].(string); ok && value != "" {
+			title += ": " + value
+			break
+		}
+	}
+
+	details, _ := json.Marshal(item)
+	entry := &models.AsRunEntry{
+		EventType: models.AsRunOverlay,
+		Title:     title,
+		Details:   string(details),
+	}
+	entry.SourceName, _ = item.Params["source_name"].(string)
+
+	if personID, ok := overlayParamUint(item.Params, "person_id"); ok {
+		switch item.Params["person_type"] {
+		case "staff":
+			entry.StaffID = &personID
+		case "guest":
+			entry.GuestID = &personID
+		}
+	}
+
+	om.SocketHandler.AsRun.Log(entry)
+}
+
+// overlayParamUint odczytuje parametr liczbowy (JSON → float64, Go → uint)
+func overlayParamUint(params map[string]interface{}, key string) (uint, bool) {
+	switch value := params[key].(type) {
+	case float64:
+		return uint(value), value > 0
+	case uint:
+		return value, value > 0
+	case int:
+		return uint(value), value > 0
+	}
+	return 0, false
+}
+
+// OverlayRequest - polecenie dla overlayu (socket, REST, makra)
+type OverlayRequest struct {
+	Channel    string                 `json:"channel"` // Pusty = kanał domyślny
+	Template   string                 `json:"template"`
+	Params     map[string]interface{} `json:"params"`
+	DurationMs int                    `json:"duration_ms"`
+}
+
+type OverlayHandler struct {
+	Overlay *OverlayManager
+}
+
+func NewOverlayHandler(overlay *OverlayManager) *OverlayHandler {
+	return &OverlayHandler{Overlay: overlay}
+}
+
+// overlayChannelFromRequest odczytuje kanał z ?channel= (pusty = domyślny)
+func overlayChannelFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	channel, err := overlayChannelName(r.URL.Query().Get("channel"))
+	if err != nil {
+		http.Error(w, "Invalid channel", http.StatusBadRequest)
+		return "", false
+	}
+	return channel, true
+}
+
+// GetOverlay - GET /api/overlay?channel=main
+// Szablony, znane kanały i aktualny stan kanału
+func (h *OverlayHandler) GetOverlay(w http.ResponseWriter, r *http.Request) {
+	channel, ok := overlayChannelFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": overlayTemplates,
+		"channels":  h.Overlay.Channels(),
+		"channel":   channel,
+		"items":     h.Overlay.State(channel),
+	})
+}
+
+// ShowTemplate - POST /api/overlay/{template}/show?channel=main
+func (h *OverlayHandler) ShowTemplate(w http.ResponseWriter, r *http.Request) {
+	channel, ok := overlayChannelFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req OverlayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.Overlay.Show(channel, mux.Vars(r)["template"], req.Params, req.DurationMs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// UpdateTemplate - POST /api/overlay/{template}/update?channel=main
+func (h *OverlayHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	channel, ok := overlayChannelFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req OverlayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.Overlay.Update(channel, mux.Vars(r)["template"], req.Params, req.DurationMs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// HideTemplate - POST /api/overlay/{template}/hide?channel=main
+func (h *OverlayHandler) HideTemplate(w http.ResponseWriter, r *http.Request) {
+	channel, ok := overlayChannelFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.Overlay.Hide(channel, mux.Vars(r)["template"]) {
+		http.Error(w, "Template not shown", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HideAll - POST /api/overlay/hide-all?channel=main
+func (h *OverlayHandler) HideAll(w http.ResponseWriter, r *http.Request) {
+	channel, ok := overlayChannelFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	h.Overlay.HideAll(channel)
+	w.WriteHeader(http.StatusNoContent)
+}
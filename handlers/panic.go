@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scena z mikrofonami (jak w muteAllMicrophones)
+const panicMicrophonesScene = "MIKROFONY"
+
+// Jak długo czekać na odczyt stanu anteny przed przełączeniem na tryb awaryjny
+const panicSnapshotTimeout = time.Second
+
+// PanicSnapshot - stan anteny sprzed przycisku awaryjnego (przywracany przez Resume)
+type PanicSnapshot struct {
+	ProgramScene string          `json:"program_scene"`           // Pusta = nie udało się odczytać (bez powrotu sceny)
+	Microphones  map[string]bool `json:"microphones"`             // source_name → widoczność w scenie MIKROFONY
+	MusicVisible *bool           `json:"music_visible,omitempty"` // Widoczność źródła muzyki (nil = nie dotyczy)
+	TitleCard    *OverlayItem    `json:"title_card,omitempty"`    // Plansza wyświetlana przed awarią (zasłonięta naszą)
+}
+
+// PanicState - stan przycisku awaryjnego
+type PanicState struct {
+	Active      bool                  `json:"active"`
+	TriggeredBy string                `json:"triggered_by"`
+	ActivatedAt *time.Time            `json:"activated_at"`
+	Settings    *models.PanicSettings `json:"settings"` // Konfiguracja użyta przy aktywacji (lub bieżąca)
+	Snapshot    *PanicSnapshot        `json:"snapshot"`
+}
+
+// PanicButton - "usterki techniczne": scena zastępcza lub plansza, wyciszenie mikrofonów,
+// opcjonalnie muzyka w tle. Resume przywraca dokładnie stan sprzed aktywacji
+type PanicButton struct {
+	DB            *gorm.DB
+	SocketHandler *SocketHandler
+
+	active       bool
+	activationID uint // Zapis aktywacji w bazie (PanicActivation)
+	triggeredBy  string
+	activatedAt  time.Time
+	settings     models.PanicSettings
+	snapshot     PanicSnapshot
+	mu           sync.Mutex
+}
+
+func NewPanicButton(db *gorm.DB, socketHandler *SocketHandler) *PanicButton {
+	pb := &PanicButton{
+		DB:            db,
+		SocketHandler: socketHandler,
+	}
+	pb.restoreActivation()
+	return pb
+}
+
+// restoreActivation wczytuje aktywację sprzed restartu kontrolera (tryb awaryjny nadal na antenie)
+func (pb *PanicButton) restoreActivation() {
+	var activation models.PanicActivation
+	if err := pb.DB.Where("resumed_at IS NULL").Order("id DESC").First(&activation).Error; err != nil {
+		return
+	}
+
+	var settings models.PanicSettings
+	var snapshot PanicSnapshot
+	if err := json.Unmarshal(activation.Settings, &settings); err != nil {
+		log.Printf("Tryb awaryjny: nieczytelna aktywacja %d: %v", activation.ID, err)
+		return
+	}
+	if err := json.Unmarshal(activation.Snapshot, &snapshot); err != nil {
+		log.Printf("Tryb awaryjny: nieczytelna aktywacja %d: %v", activation.ID, err)
+		return
+	}
+
+	pb.active = true
+	pb.activationID = activation.ID
+	pb.triggeredBy = activation.TriggeredBy
+	pb.activatedAt = activation.ActivatedAt
+	pb.settings = settings
+	pb.snapshot = snapshot
+
+	// Stan overlayu nie przetrwał restartu - plansza awaryjna wraca na antenę
+	if settings.FallbackScene == "" && pb.SocketHandler.Overlay != nil {
+		if _, err := pb.SocketHandler.Overlay.Show(settings.Channel, OverlayTemplateTitleCard, map[string]interface{}{
+			"title":    settings.CardTitle,
+			"subtitle": settings.CardSubtitle,
+		}, -1); err != nil {
+			log.Printf("Tryb awaryjny: błąd przywracania planszy: %v", err)
+		}
+	}
+
+	log.Printf("Tryb awaryjny: wczytano aktywny tryb sprzed restartu (%s, scena przed awarią: %s)",
+		activation.TriggeredBy, snapshot.ProgramScene)
+}
+
+// Activate zapamiętuje stan anteny i przełącza na tryb awaryjny
+func (pb *PanicButton) Activate(triggeredBy string) (*PanicState, error) {
+	sh := pb.SocketHandler
+	if sh.OBSClient == nil {
+		return nil, fmt.Errorf("OBS nie jest połączony")
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	if pb.active {
+		return nil, fmt.Errorf("Tryb awaryjny jest już aktywny")
+	}
+
+	settings, err := models.GetPanicSettings(pb.DB)
+	if err != nil {
+		return nil, err
+	}
+	if settings.FallbackScene == "" && sh.Overlay == nil {
+		return nil, fmt.Errorf("Overlay niedostępny")
+	}
+
+	snapshot := pb.takeSnapshot(settings)
+
+	// Najpierw obraz - jeśli się nie uda, niczego nie zmieniamy
+	if settings.FallbackScene != "" {
+		if err := sh.OBSClient.SetCurrentProgramScene(settings.FallbackScene); err != nil {
+			return nil, fmt.Errorf("Błąd przełączania na scenę %s: %v", settings.FallbackScene, err)
+		}
+	} else {
+		if _, err := sh.Overlay.Show(settings.Channel, OverlayTemplateTitleCard, map[string]interface{}{
+			"title":    settings.CardTitle,
+			"subtitle": settings.CardSubtitle,
+		}, -1); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := sh.muteAllMicrophones(); err != nil {
+		log.Printf("Tryb awaryjny: %v", err)
+	}
+
+	if settings.MusicSource != "" {
+		if settings.MusicScene != "" {
+			if err := sh.OBSClient.SetSourceVisibility(settings.MusicScene, settings.MusicSource, true); err != nil {
+				log.Printf("Tryb awaryjny: błąd pokazania muzyki %s: %v", settings.MusicSource, err)
+			}
+		}
+		if err := sh.OBSClient.TriggerMediaInputAction(settings.MusicSource, "OBS_WEBSOCKET_MEDIA_INPUT_ACTION_RESTART"); err != nil {
+			log.Printf("Tryb awaryjny: błąd startu muzyki %s: %v", settings.MusicSource, err)
+		}
+	}
+
+	pb.active = true
+	pb.triggeredBy = triggeredBy
+	pb.activatedAt = time.Now()
+	pb.settings = *settings
+	pb.snapshot = *snapshot
+	pb.activationID = pb.saveActivation()
+
+	log.Printf("Tryb awaryjny: START (%s, scena przed awarią: %s)", triggeredBy, snapshot.ProgramScene)
+	state := pb.stateLocked()
+	sh.Broadcast("panic_state", state)
+	return state, nil
+}
+
+// takeSnapshot odczytuje z OBS scenę programu, mikrofony i muzykę - równolegle i najdłużej
+// panicSnapshotTimeout, żeby przycisk działał od razu; nieodczytane elementy nie są przywracane
+func (pb *PanicButton) takeSnapshot(settings *models.PanicSettings) *PanicSnapshot {
+	sh := pb.SocketHandler
+	snapshot := &PanicSnapshot{Microphones: make(map[string]bool)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), panicSnapshotTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		programScene, err := sh.OBSClient.GetCurrentProgramSceneContext(ctx)
+		if err != nil {
+			log.Printf("Tryb awaryjny: błąd odczytu sceny programu: %v", err)
+			return
+		}
+		mu.Lock()
+		snapshot.ProgramScene = programScene
+		mu.Unlock()
+	}()
+
+	var scene models.Scene
+	if err := pb.DB.Where("name = ?", panicMicrophonesScene).First(&scene).Error; err == nil {
+		var sources []models.Source
+		pb.DB.Where("scene_id = ?", scene.ID).Find(&sources)
+		for _, source := range sources {
+			wg.Add(1)
+			go func(sourceName string) {
+				defer wg.Done()
+				visible, err := sh.OBSClient.GetSourceVisibilityContext(ctx, panicMicrophonesScene, sourceName)
+				if err != nil {
+					// Nieznany stan - mikrofon nie będzie przywracany
+					log.Printf("Tryb awaryjny: błąd odczytu mikrofonu %s: %v", sourceName, err)
+					return
+				}
+				mu.Lock()
+				snapshot.Microphones[sourceName] = visible
+				mu.Unlock()
+			}(source.Name)
+		}
+	}
+
+	if settings.MusicSource != "" && settings.MusicScene != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			visible, err := sh.OBSClient.GetSourceVisibilityContext(ctx, settings.MusicScene, settings.MusicSource)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			snapshot.MusicVisible = &visible
+			mu.Unlock()
+		}()
+	}
+
+	if settings.FallbackScene == "" {
+		snapshot.TitleCard = sh.Overlay.Get(settings.Channel, OverlayTemplateTitleCard)
+	}
+
+	wg.Wait()
+	return snapshot
+}
+
+// saveActivation zapisuje aktywację w bazie (wywoływać pod pb.mu); zwraca ID zapisu (0 = błąd)
+func (pb *PanicButton) saveActivation() uint {
+	settings, _ := json.Marshal(pb.settings)
+	snapshot, _ := json.Marshal(pb.snapshot)
+
+	activation := models.PanicActivation{
+		TriggeredBy: pb.triggeredBy,
+		ActivatedAt: pb.activatedAt,
+		Settings:    settings,
+		Snapshot:    snapshot,
+	}
+	if err := pb.DB.Create(&activation).Error; err != nil {
+		log.Printf("Tryb awaryjny: błąd zapisu aktywacji: %v", err)
+		return 0
+	}
+	return activation.ID
+}
+
+// Resume przywraca stan anteny zapamiętany przy aktywacji
+func (pb *PanicButton) Resume(triggeredBy string) (*PanicState, error) {
+	sh := pb.SocketHandler
+	if sh.OBSClient == nil {
+		return nil, fmt.Errorf("OBS nie jest połączony")
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	if !pb.active {
+		return nil, fmt.Errorf("Tryb awaryjny nie jest aktywny")
+	}
+	settings := pb.settings
+	snapshot := pb.snapshot
+
+	if settings.MusicSource != "" {
+		if err := sh.OBSClient.TriggerMediaInputAction(settings.MusicSource, "OBS_WEBSOCKET_MEDIA_INPUT_ACTION_STOP"); err != nil {
+			log.Printf("Tryb awaryjny: błąd zatrzymania muzyki %s: %v", settings.MusicSource, err)
+		}
+		if snapshot.MusicVisible != nil {
+			if err := sh.OBSClient.SetSourceVisibility(settings.MusicScene, settings.MusicSource, *snapshot.MusicVisible); err != nil {
+				log.Printf("Tryb awaryjny: błąd przywracania muzyki %s: %v", settings.MusicSource, err)
+			}
+		}
+	}
+
+	// Mikrofony dokładnie jak przed awarią (bez zmiany is_visible, jak muteAllMicrophones)
+	for sourceName, visible := range snapshot.Microphones {
+		if err := sh.OBSClient.SetSourceVisibility(panicMicrophonesScene, sourceName, visible); err != nil {
+			log.Printf("Tryb awaryjny: błąd przywracania mikrofonu %s: %v", sourceName, err)
+			continue
+		}
+		sh.Broadcast("source_changed", map[string]interface{}{
+			"scene_name":  panicMicrophonesScene,
+			"source_name": sourceName,
+			"visible":     visible,
+		})
+	}
+
+	if settings.FallbackScene == "" && sh.Overlay != nil {
+		pb.restoreTitleCard(settings.Channel, snapshot.TitleCard)
+	}
+
+	if snapshot.ProgramScene != "" {
+		if err := sh.OBSClient.SetCurrentProgramScene(snapshot.ProgramScene); err != nil {
+			return nil, fmt.Errorf("Błąd powrotu do sceny %s: %v", snapshot.ProgramScene, err)
+		}
+	} else {
+		log.Printf("Tryb awaryjny: scena sprzed awarii nieznana - scena programu bez zmian")
+	}
+
+	if pb.activationID != 0 {
+		pb.DB.Model(&models.PanicActivation{}).Where("id = ?", pb.activationID).Update("resumed_at", time.Now())
+	}
+	pb.active = false
+	pb.activationID = 0
+	log.Printf("Tryb awaryjny: KONIEC (%s, powrót do sceny %s)", triggeredBy, snapshot.ProgramScene)
+
+	state := pb.stateLocked()
+	sh.Broadcast("panic_state", state)
+	return state, nil
+}
+
+// restoreTitleCard zdejmuje planszę awaryjną lub przywraca planszę sprzed awarii
+func (pb *PanicButton) restoreTitleCard(channel string, previous *OverlayItem) {
+	overlay := pb.SocketHandler.Overlay
+	if previous != nil {
+		durationMs := -1 // Plansza bez limitu czasu
+		if previous.ExpiresAt != nil {
+			durationMs = int(time.Until(*previous.ExpiresAt).Milliseconds())
+		}
+		if previous.ExpiresAt == nil || durationMs > 0 {
+			if _, err := overlay.Show(channel, OverlayTemplateTitleCard, previous.Params, durationMs); err == nil {
+				return
+			}
+		}
+	}
+	overlay.Hide(channel, OverlayTemplateTitleCard)
+}
+
+// State zwraca stan przycisku awaryjnego
+func (pb *PanicButton) State() *PanicState {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.stateLocked()
+}
+
+func (pb *PanicButton) stateLocked() *PanicState {
+	if !pb.active {
+		state := &PanicState{}
+		if settings, err := models.GetPanicSettings(pb.DB); err == nil {
+			state.Settings = settings
+		}
+		return state
+	}
+
+	activatedAt := pb.activatedAt
+	settings := pb.settings
+	snapshot := pb.snapshot
+	return &PanicState{
+		Active:      true,
+		TriggeredBy: pb.triggeredBy,
+		ActivatedAt: &activatedAt,
+		Settings:    &settings,
+		Snapshot:    &snapshot,
+	}
+}
+
+// validatePanicSettings sprawdza konfigurację przycisku awaryjnego
+func validatePanicSettings(settings *models.PanicSettings) error {
+	if settings.FallbackScene == "" && settings.CardTitle == "" {
+		return fmt.Errorf("fallback_scene or card_title is required")
+	}
+	if settings.MusicScene != "" && settings.MusicSource == "" {
+		return fmt.Errorf("music_source is required with music_scene")
+	}
+	if _, err := overlayChannelName(settings.Channel); err != nil {
+		return err
+	}
+	return nil
+}
+
+type PanicHandler struct {
+	DB    *gorm.DB
+	Panic *PanicButton
+}
+
+func NewPanicHandler(db *gorm.DB, button *PanicButton) *PanicHandler {
+	return &PanicHandler{DB: db, Panic: button}
+}
+
+// GetState - GET /api/panic
+func (h *PanicHandler) GetState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Panic.State())
+}
+
+// Activate - POST /api/panic/activate
+// Dla wyzwalaczy zewnętrznych (Stream Deck, Companion) - bez body
+func (h *PanicHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	state, err := h.Panic.Activate("api")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// Resume - POST /api/panic/resume
+func (h *PanicHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	state, err := h.Panic.Resume("api")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// GetSettings - GET /api/panic/settings
+func (h *PanicHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := models.GetPanicSettings(h.DB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateSettings - PUT /api/panic/settings
+// Zmiana działa od następnej aktywacji (trwająca awaria wraca wg ustawień z chwili startu)
+func (h *PanicHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := models.GetPanicSettings(h.DB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req models.PanicSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validatePanicSettings(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings.FallbackScene = req.FallbackScene
+	settings.CardTitle = req.CardTitle
+	settings.CardSubtitle = req.CardSubtitle
+	settings.Channel = req.Channel
+	settings.MusicScene = req.MusicScene
+	settings.MusicSource = req.MusicSource
+
+	if err := h.DB.Save(settings).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"testing"
+	"time"
+)
+
+func TestPanicActivationSurvivesRestart(t *testing.T) {
+	db := openTestDB(t)
+	sh := newTestSocketHandler(t, db)
+	sh.OBSClient = &obsws.Client{} // Niepołączony OBS - odczyty stanu kończą się od razu błędem
+	sh.Overlay = NewOverlayManager(sh)
+
+	// Plansza sprzed awarii - ma wrócić po Resume
+	sh.Overlay.Show("", OverlayTemplateTitleCard, map[string]interface{}{"title": "Za chwilę"}, -1)
+
+	start := time.Now()
+	state, err := NewPanicButton(db, sh).Activate("test")
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > panicSnapshotTimeout+time.Second {
+		t.Errorf("activation took %v", elapsed)
+	}
+	if state.Snapshot.TitleCard == nil || state.Snapshot.TitleCard.Params["title"] != "Za chwilę" {
+		t.Fatalf("title card not captured: %+v", state.Snapshot.TitleCard)
+	}
+
+	// Restart kontrolera: nowy przycisk i pusty overlay
+	sh.Overlay = NewOverlayManager(sh)
+	restored := NewPanicButton(db, sh)
+	if !restored.State().Active {
+		t.Fatal("panic state lost after restart")
+	}
+	if card := sh.Overlay.Get("", OverlayTemplateTitleCard); card == nil || card.Params["title"] != "Przerwa techniczna" {
+		t.Errorf("panic card not shown again after restart: %+v", card)
+	}
+
+	if _, err := restored.Resume("test"); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if card := sh.Overlay.Get("", OverlayTemplateTitleCard); card == nil || card.Params["title"] != "Za chwilę" {
+		t.Errorf("previous title card not restored: %+v", card)
+	}
+
+	var activation models.PanicActivation
+	db.First(&activation)
+	if activation.ResumedAt == nil {
+		t.Error("activation not marked as resumed")
+	}
+	if NewPanicButton(db, sh).State().Active {
+		t.Error("resumed activation restored as active")
+	}
+}
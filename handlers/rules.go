@@ -0,0 +1,592 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Klucze warunków wyzwalacza timer (nie są porównywane z danymi zdarzenia)
+const (
+	ruleTimerEvery       = "every_s"
+	ruleTimerShowElapsed = "show_elapsed_s"
+)
+
+// RuleEngine ocenia reguły automatyzacji po stronie serwera
+// Zdarzenia pochodzą z OBS (wszystkie eventy), z monitora mediów, z rundownu i z zegara
+type RuleEngine struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+
+	itemNames *sceneItemNames
+
+	// Źródła scen głównych na antenie (do wyzwalacza program_changed)
+	enabledSources map[string]string // scena główna → ostatnio włączone źródło
+	onAirScene     string            // Ostatnio odpalone program_changed (scena i źródło)
+	onAirSource    string
+	onAirMu        sync.Mutex
+
+	lastRun   map[uint]time.Time // rule_id → ostatnie wykonanie (cooldown, every_s)
+	showFired map[uint]time.Time // rule_id → start programu, dla którego odpalono show_elapsed_s
+	running   map[uint]bool      // Reguły w trakcie wykonywania (ochrona przed zapętleniem)
+	mu        sync.Mutex
+}
+
+func NewRuleEngine(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler) *RuleEngine {
+	return &RuleEngine{
+		DB:             db,
+		OBSClient:      obsClient,
+		SocketHandler:  socketHandler,
+		itemNames:      newSceneItemNames(obsClient),
+		enabledSources: make(map[string]string),
+		lastRun:        make(map[uint]time.Time),
+		showFired:      make(map[uint]time.Time),
+		running:        make(map[uint]bool),
+	}
+}
+
+// Start rozpoczyna nasłuchiwanie na eventy OBS i tykanie timerów
+func (re *RuleEngine) Start() {
+	log.Println("Starting Rule Engine...")
+
+	re.subscribe()
+	go re.tick()
+
+	log.Println("Rule Engine started successfully")
+}
+
+// subscribe rejestruje wyzwalacze oparte na eventach OBS
+func (re *RuleEngine) subscribe() {
+	re.OBSClient.OnAnyEvent(func(eventType string, event map[string]interface{}) {
+		// Mierniki audio przychodzą kilkanaście razy na sekundę - nie nadają się na wyzwalacz
+		if eventType == "InputVolumeMeters" {
+			return
+		}
+
+		fields := make(map[string]interface{}, len(event)+1)
+		for key, value := range event {
+			fields[key] = value
+		}
+		fields["event_type"] = eventType
+		re.Fire(models.RuleTriggerOBSEvent, fields)
+	})
+
+	// Źródło sceny głównej weszło na antenę: włączone w scenie lub scena podniesiona w SCREEN
+	// (scena programu to zawsze STREAM, więc CurrentProgramSceneChanged nic tu nie mówi)
+	re.OBSClient.OnEvent("SceneItemEnableStateChanged", re.handleItemEnabled)
+	re.OBSClient.OnEvent("SceneItemListReindexed", re.handleItemsReindexed)
+}
+
+// handleItemEnabled odpala program_changed, gdy w scenie głównej zostaje włączone źródło
+func (re *RuleEngine) handleItemEnabled(event map[string]interface{}) {
+	sceneName, _ := event["sceneName"].(string)
+	if !isMainScene(sceneName) {
+		return
+	}
+	itemID, _ := event["sceneItemId"].(float64)
+	enabled, _ := event["sceneItemEnabled"].(bool)
+	sourceName := re.itemNames.Get(sceneName, int(itemID))
+
+	re.onAirMu.Lock()
+	if !enabled {
+		// Zdjęte źródło może wrócić na antenę - wtedy reguły odpalają ponownie
+		if re.enabledSources[sceneName] == sourceName {
+			delete(re.enabledSources, sceneName)
+		}
+		if re.onAirScene == sceneName && re.onAirSource == sourceName {
+			re.onAirScene, re.onAirSource = "", ""
+		}
+		re.onAirMu.Unlock()
+		return
+	}
+	re.enabledSources[sceneName] = sourceName
+	re.onAirMu.Unlock()
+
+	re.programChanged(sceneName, sourceName)
+}
+
+// handleItemsReindexed odpala program_changed, gdy na wierzch SCREEN trafia inna scena główna
+// OBS: najwyższy sceneItemIndex = na wierzchu
+func (re *RuleEngine) handleItemsReindexed(event map[string]interface{}) {
+	sceneName, _ := event["sceneName"].(string)
+	items, _ := event["sceneItems"].([]interface{})
+	if sceneName != rundownScreenScene || len(items) == 0 {
+		return
+	}
+
+	topID, topIndex := -1, -1.0
+	for _, raw := range items {
+		item, _ := raw.(map[string]interface{})
+		id, _ := item["sceneItemId"].(float64)
+		index, _ := item["sceneItemIndex"].(float64)
+		if index > topIndex {
+			topID, topIndex = int(id), index
+		}
+	}
+
+	topScene := re.itemNames.Get(sceneName, topID)
+	if !isMainScene(topScene) {
+		return
+	}
+
+	re.onAirMu.Lock()
+	sourceName := re.enabledSources[topScene]
+	re.onAirMu.Unlock()
+
+	re.programChanged(topScene, sourceName)
+}
+
+// programChanged odpala reguły program_changed raz na wejście źródła na antenę
+// Włączenie źródła i podniesienie sceny w SCREEN przychodzą w dowolnej kolejności,
+// a podniesienie nie zawsze zna źródło - puste źródło pasuje do każdego w tej samej scenie
+func (re *RuleEngine) programChanged(sceneName, sourceName string) {
+	re.onAirMu.Lock()
+	if re.onAirScene == sceneName && (sourceName == "" || re.onAirSource == "" || re.onAirSource == sourceName) {
+		if sourceName != "" {
+			re.onAirSource = sourceName
+		}
+		re.onAirMu.Unlock()
+		return
+	}
+	re.onAirScene, re.onAirSource = sceneName, sourceName
+	re.onAirMu.Unlock()
+
+	re.Fire(models.RuleTriggerProgramChanged, map[string]interface{}{
+		"scene_name":  sceneName,
+		"source_name": sourceName,
+	})
+}
+
+// SegmentStarted odpala reguły startu segmentu rundownu
+func (re *RuleEngine) SegmentStarted(segment models.RundownSegment, sceneName string) {
+	re.Fire(models.RuleTriggerSegmentStarted, map[string]interface{}{
+		"segment_id":   segment.ID,
+		"segment_type": segment.Type,
+		"scene_name":   sceneName,
+		"title":        segment.Title,
+	})
+}
+
+// Fire wykonuje włączone reguły danego wyzwalacza, których warunki pasują do zdarzenia
+// Zdarzenie trafia też do skryptów podpiętych pod ten wyzwalacz
+func (re *RuleEngine) Fire(trigger string, fields map[string]interface{}) {
+	if re.SocketHandler.Scripts != nil {
+		re.SocketHandler.Scripts.Fire(trigger, fields)
+	}
+
+	var rules []models.Rule
+	err := re.DB.Preload("Actions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Where("\"trigger\" = ? AND enabled = ?", trigger, true).Order("id ASC").Find(&rules).Error
+	if err != nil {
+		log.Printf("Reguły: błąd pobierania reguł %s: %v", trigger, err)
+		return
+	}
+
+	for _, rule := range rules {
+		if !matchRuleConditions(rule.Conditions, fields) {
+			continue
+		}
+		re.run(rule, trigger, fields)
+	}
+}
+
+// tick co sekundę sprawdza reguły timer
+func (re *RuleEngine) tick() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		re.checkTimers(now)
+	}
+}
+
+// checkTimers odpala reguły every_s (cyklicznie) i show_elapsed_s (raz na program)
+func (re *RuleEngine) checkTimers(now time.Time) {
+	var rules []models.Rule
+	err := re.DB.Preload("Actions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Where("\"trigger\" = ? AND enabled = ?", models.RuleTriggerTimer, true).Find(&rules).Error
+	if err != nil || len(rules) == 0 {
+		return
+	}
+
+	var showStartedAt *time.Time
+	if episode, err := models.GetCurrentEpisode(re.DB); err == nil {
+		if timing, err := models.GetShowTiming(re.DB, episode.ID); err == nil && timing.StartedAt != nil && timing.EndedAt == nil {
+			showStartedAt = timing.StartedAt
+		}
+	}
+
+	fields := map[string]interface{}{
+		"show_running": showStartedAt != nil,
+	}
+	if showStartedAt != nil {
+		fields["show_elapsed"] = int(now.Sub(*showStartedAt).Seconds())
+	}
+
+	for _, rule := range rules {
+		var timer map[string]interface{}
+		if len(rule.Conditions) > 0 {
+			json.Unmarshal(rule.Conditions, &timer)
+		}
+		if !matchRuleConditions(rule.Conditions, fields) {
+			continue
+		}
+
+		if every, ok := timer[ruleTimerEvery].(float64); ok && every > 0 {
+			re.mu.Lock()
+			last, seen := re.lastRun[rule.ID]
+			if !seen {
+				// Pierwsze tyknięcie - odliczanie od startu silnika
+				re.lastRun[rule.ID] = now
+			}
+			re.mu.Unlock()
+
+			if seen && now.Sub(last) >= time.Duration(every*float64(time.Second)) {
+				re.run(rule, models.RuleTriggerTimer, fields)
+			}
+			continue
+		}
+
+		if elapsed, ok := timer[ruleTimerShowElapsed].(float64); ok && showStartedAt != nil {
+			if now.Sub(*showStartedAt) < time.Duration(elapsed*float64(time.Second)) {
+				continue
+			}
+
+			re.mu.Lock()
+			fired := re.showFired[rule.ID].Equal(*showStartedAt)
+			if !fired {
+				re.showFired[rule.ID] = *showStartedAt
+			}
+			re.mu.Unlock()
+
+			if !fired {
+				re.run(rule, models.RuleTriggerTimer, fields)
+			}
+		}
+	}
+}
+
+// run wykonuje akcje reguły w tle (z cooldownem i ochroną przed ponownym wejściem)
+func (re *RuleEngine) run(rule models.Rule, trigger string, fields map[string]interface{}) {
+	now := time.Now()
+
+	re.mu.Lock()
+	if re.running[rule.ID] {
+		re.mu.Unlock()
+		return
+	}
+	if last, ok := re.lastRun[rule.ID]; ok && rule.CooldownMs > 0 && now.Sub(last) < time.Duration(rule.CooldownMs)*time.Millisecond {
+		re.mu.Unlock()
+		return
+	}
+	re.running[rule.ID] = true
+	re.lastRun[rule.ID] = now
+	re.mu.Unlock()
+
+	go func() {
+		defer func() {
+			re.mu.Lock()
+			delete(re.running, rule.ID)
+			re.mu.Unlock()
+		}()
+
+		log.Printf("Reguła %s: wyzwolona (%s)", rule.Name, trigger)
+
+		errs := []string{}
+		for i, action := range rule.Actions {
+			if re.SocketHandler.Macros == nil {
+				errs = append(errs, "wykonywanie akcji nie jest dostępne")
+				break
+			}
+			if err := re.SocketHandler.Macros.executeStep(context.Background(), action.Action, action.Params, 0); err != nil {
+				log.Printf("Reguła %s: błąd akcji %d (%s): %v", rule.Name, i+1, action.Action, err)
+				errs = append(errs, fmt.Sprintf("akcja %d (%s): %v", i+1, action.Action, err))
+			}
+		}
+
+		re.SocketHandler.Broadcast("rule_fired", map[string]interface{}{
+			"rule_id":   rule.ID,
+			"rule_name": rule.Name,
+			"trigger":   trigger,
+			"event":     fields,
+			"errors":    errs,
+		})
+	}()
+}
+
+// isMainScene sprawdza, czy scena jest sceną główną (KAMERY, MEDIA, REPORTAZE)
+func isMainScene(sceneName string) bool {
+	for _, mainScene := range rundownMainScenes {
+		if mainScene == sceneName {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRuleConditions porównuje warunki reguły z danymi zdarzenia
+// Wartość warunku może być pojedyncza (równość) lub listą (dowolna z wartości)
+func matchRuleConditions(raw json.RawMessage, fields map[string]interface{}) bool {
+	if len(raw) == 0 || string(raw) == "null" {
+		return true
+	}
+
+	var conditions map[string]interface{}
+	if err := json.Unmarshal(raw, &conditions); err != nil {
+		return false
+	}
+
+	for key, expected := range conditions {
+		if key == ruleTimerEvery || key == ruleTimerShowElapsed {
+			continue
+		}
+
+		actual, ok := fields[key]
+		if !ok {
+			return false
+		}
+
+		if options, ok := expected.([]interface{}); ok {
+			matched := false
+			for _, option := range options {
+				if fmt.Sprint(option) == fmt.Sprint(actual) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+
+		if fmt.Sprint(expected) != fmt.Sprint(actual) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateRule sprawdza poprawność reguły
+func validateRule(rule *models.Rule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	known := false
+	for _, trigger := range models.RuleTriggers {
+		if rule.Trigger == trigger {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown trigger %q", rule.Trigger)
+	}
+
+	var conditions map[string]interface{}
+	if len(rule.Conditions) > 0 && string(rule.Conditions) != "null" {
+		if err := json.Unmarshal(rule.Conditions, &conditions); err != nil {
+			return fmt.Errorf("conditions must be a JSON object")
+		}
+	}
+	if rule.Trigger == models.RuleTriggerTimer {
+		_, every := conditions[ruleTimerEvery].(float64)
+		_, elapsed := conditions[ruleTimerShowElapsed].(float64)
+		if !every && !elapsed {
+			return fmt.Errorf("timer rules require %s or %s in conditions", ruleTimerEvery, ruleTimerShowElapsed)
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		return fmt.Errorf("at least one action is required")
+	}
+	for i, action := range rule.Actions {
+		if err := validateMacroAction(action.Action, action.Params); err != nil {
+			return fmt.Errorf("action %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+type RuleHandler struct {
+	DB *gorm.DB
+}
+
+func NewRuleHandler(db *gorm.DB) *RuleHandler {
+	return &RuleHandler{DB: db}
+}
+
+// GetRules - GET /api/rules
+func (h *RuleHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	var rules []models.Rule
+	err := h.DB.Preload("Actions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Order("id ASC").Find(&rules).Error
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GetRule - GET /api/rules/{id}
+func (h *RuleHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.loadRule(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreateRule - POST /api/rules
+func (h *RuleHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	rule := models.Rule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule.ID = 0
+	rule.IsSystem = false
+	if err := validateRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range rule.Actions {
+		rule.Actions[i].ID = 0
+		rule.Actions[i].Order = i + 1
+	}
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateRule - PUT /api/rules/{id}
+// Akcje są zastępowane w całości, w kolejności z żądania
+func (h *RuleHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.loadRule(w, r)
+	if !ok {
+		return
+	}
+
+	var updateData models.Rule
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule.Name = updateData.Name
+	rule.Description = updateData.Description
+	rule.Enabled = updateData.Enabled
+	rule.Trigger = updateData.Trigger
+	rule.Conditions = updateData.Conditions
+	rule.CooldownMs = updateData.CooldownMs
+	rule.Actions = updateData.Actions
+
+	if err := validateRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actions := rule.Actions
+	for i := range actions {
+		actions[i].ID = 0
+		actions[i].RuleID = rule.ID
+		actions[i].Order = i + 1
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.RuleAction{}).Error; err != nil {
+			return err
+		}
+		rule.Actions = nil
+		if err := tx.Save(&rule).Error; err != nil {
+			return err
+		}
+		return tx.Create(&actions).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rule.Actions = actions
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteRule - DELETE /api/rules/{id}
+func (h *RuleHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.loadRule(w, r)
+	if !ok {
+		return
+	}
+
+	if rule.IsSystem {
+		http.Error(w, "System rules cannot be deleted, disable them instead", http.StatusBadRequest)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.RuleAction{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&rule).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RuleHandler) loadRule(w http.ResponseWriter, r *http.Request) (models.Rule, bool) {
+	var rule models.Rule
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return rule, false
+	}
+
+	err = h.DB.Preload("Actions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).First(&rule, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Rule not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return rule, false
+	}
+
+	return rule, true
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+type fakeSceneItem struct {
+	id      int
+	source  string
+	enabled bool
+}
+
+// fakeOBS to serwer obs-websocket v5 trzymający sceny w pamięci
+// Obsługuje żądania używane przy przełączaniu źródeł i wysyła odpowiadające im eventy
+type fakeOBS struct {
+	mu     sync.Mutex
+	scenes map[string][]*fakeSceneItem // Kolejność w slice = sceneItemIndex (ostatni na wierzchu)
+	conn   *websocket.Conn
+	sendMu sync.Mutex
+}
+
+func newFakeOBS(t *testing.T, scenes map[string][]string) (*obsws.Client, *fakeOBS) {
+	t.Helper()
+	f := &fakeOBS{scenes: make(map[string][]*fakeSceneItem)}
+	for scene, sources := range scenes {
+		for i, source := range sources {
+			f.scenes[scene] = append(f.scenes[scene], &fakeSceneItem{id: i + 1, source: source})
+		}
+	}
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		f.conn = conn
+		for {
+			var msg obsws.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Op {
+			case 1:
+				f.send(2, map[string]interface{}{"negotiatedRpcVersion": 1})
+			case 6:
+				f.handleRequest(msg.D)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := obsws.NewClient("ws" + strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatalf("connect fake OBS: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, f
+}
+
+func (f *fakeOBS) send(op int, d map[string]interface{}) {
+	f.sendMu.Lock()
+	defer f.sendMu.Unlock()
+	f.conn.WriteJSON(obsws.Message{Op: op, D: d})
+}
+
+func (f *fakeOBS) event(eventType string, data map[string]interface{}) {
+	f.send(5, map[string]interface{}{"eventType": eventType, "eventData": data})
+}
+
+func (f *fakeOBS) find(scene string, match func(*fakeSceneItem) bool) (int, *fakeSceneItem) {
+	for i, item := range f.scenes[scene] {
+		if match(item) {
+			return i, item
+		}
+	}
+	return -1, nil
+}
+
+func (f *fakeOBS) itemList(scene string) []interface{} {
+	list := make([]interface{}, 0, len(f.scenes[scene]))
+	for i, item := range f.scenes[scene] {
+		list = append(list, map[string]interface{}{
+			"sceneItemId":      item.id,
+			"sourceName":       item.source,
+			"sceneItemIndex":   i,
+			"sceneItemEnabled": item.enabled,
+		})
+	}
+	return list
+}
+
+func (f *fakeOBS) handleRequest(d map[string]interface{}) {
+	requestType, _ := d["requestType"].(string)
+	data, _ := d["requestData"].(map[string]interface{})
+	scene, _ := data["sceneName"].(string)
+	itemID, _ := data["sceneItemId"].(float64)
+	byID := func(item *fakeSceneItem) bool { return item.id == int(itemID) }
+
+	var responseData map[string]interface{}
+	var events []func()
+	ok := true
+
+	f.mu.Lock()
+	switch requestType {
+	case "GetSceneItemId":
+		source, _ := data["sourceName"].(string)
+		_, item := f.find(scene, func(item *fakeSceneItem) bool { return item.source == source })
+		if item == nil {
+			ok = false
+			break
+		}
+		responseData = map[string]interface{}{"sceneItemId": item.id}
+
+	case "GetSceneItemList":
+		responseData = map[string]interface{}{"sceneItems": f.itemList(scene)}
+
+	case "SetSceneItemEnabled":
+		_, item := f.find(scene, byID)
+		if item == nil {
+			ok = false
+			break
+		}
+		item.enabled, _ = data["sceneItemEnabled"].(bool)
+		enabled := item.enabled
+		events = append(events, func() {
+			f.event("SceneItemEnableStateChanged", map[string]interface{}{
+				"sceneName": scene, "sceneItemId": itemID, "sceneItemEnabled": enabled,
+			})
+		})
+
+	case "SetSceneItemIndex":
+		from, item := f.find(scene, byID)
+		if item == nil {
+			ok = false
+			break
+		}
+		to, _ := data["sceneItemIndex"].(float64)
+		items := append(f.scenes[scene][:from:from], f.scenes[scene][from+1:]...)
+		items = append(items[:int(to)], append([]*fakeSceneItem{item}, items[int(to):]...)...)
+		f.scenes[scene] = items
+		list := f.itemList(scene)
+		events = append(events, func() {
+			f.event("SceneItemListReindexed", map[string]interface{}{"sceneName": scene, "sceneItems": list})
+		})
+	}
+	f.mu.Unlock()
+
+	f.send(7, map[string]interface{}{
+		"requestType":   requestType,
+		"requestId":     d["requestId"],
+		"requestStatus": map[string]interface{}{"result": ok, "code": 100},
+		"responseData":  responseData,
+	})
+	for _, event := range events {
+		event()
+	}
+}
+
+// enabled zwraca stan źródła w scenie fałszywego OBS
+func (f *fakeOBS) enabled(scene, source string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, item := f.find(scene, func(item *fakeSceneItem) bool { return item.source == source })
+	return item != nil && item.enabled
+}
+
+// seedScene zapisuje scenę ze źródłami w bazie (mikrofony z is_visible = true)
+func seedScene(t *testing.T, db *gorm.DB, name string, sources ...string) {
+	t.Helper()
+	scene := models.Scene{Name: name}
+	for i, source := range sources {
+		scene.Sources = append(scene.Sources, models.Source{Name: source, SourceOrder: i, IsVisible: true})
+	}
+	if err := db.Create(&scene).Error; err != nil {
+		t.Fatalf("create scene %s: %v", name, err)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTakeSourceFiresMicrophoneRules(t *testing.T) {
+	db := openTestDB(t)
+	scenes := map[string][]string{
+		"KAMERY":    {"Kamera1"},
+		"REPORTAZE": {"Reportaze1"},
+		"MIKROFONY": {"Mic1"},
+	}
+	for _, name := range []string{"KAMERY", "REPORTAZE", "MIKROFONY"} {
+		seedScene(t, db, name, scenes[name]...)
+	}
+	scenes["SCREEN"] = []string{"REPORTAZE", "MEDIA", "KAMERY"}
+	client, obs := newFakeOBS(t, scenes)
+
+	sh := newTestSocketHandler(t, db)
+	sh.OBSClient = client
+	sh.Macros = NewMacroRunner(db, sh, t.TempDir())
+	rules := NewRuleEngine(db, client, sh)
+	rules.subscribe()
+	executor := NewRundownExecutor(db, client, sh, t.TempDir())
+
+	if err := executor.takeSource("KAMERY", "Kamera1"); err != nil {
+		t.Fatalf("take KAMERY: %v", err)
+	}
+	waitFor(t, "microphones restored for KAMERY", func() bool { return obs.enabled("MIKROFONY", "Mic1") })
+
+	if err := executor.takeSource("REPORTAZE", "Reportaze1"); err != nil {
+		t.Fatalf("take REPORTAZE: %v", err)
+	}
+	waitFor(t, "microphones muted for REPORTAZE", func() bool { return !obs.enabled("MIKROFONY", "Mic1") })
+
+	var mic models.Source
+	db.Where("name = ?", "Mic1").First(&mic)
+	if !mic.IsVisible {
+		t.Error("muting for a report cleared is_visible")
+	}
+
+	if err := executor.takeSource("KAMERY", "Kamera1"); err != nil {
+		t.Fatalf("take KAMERY again: %v", err)
+	}
+	waitFor(t, "microphones restored after the report", func() bool { return obs.enabled("MIKROFONY", "Mic1") })
+}
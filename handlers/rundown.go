@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"obs-controller/models"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type RundownHandler struct {
+	DB       *gorm.DB
+	Executor *RundownExecutor // Realizacja na żywo (nil = tylko edycja)
+}
+
+func NewRundownHandler(db *gorm.DB) *RundownHandler {
+	return &RundownHandler{DB: db}
+}
+
+// rundownSegmentRequest reprezentuje dane segmentu przesyłane przy tworzeniu/edycji
+type rundownSegmentRequest struct {
+	Title           string `json:"title"`
+	Type            string `json:"type"`
+	PlannedDuration int    `json:"planned_duration"`
+	SceneName       string `json:"scene_name"`
+	CameraTypeID    *uint  `json:"camera_type_id"`
+	Notes           string `json:"notes"`
+	Order           int    `json:"order"`
+	GuestIDs        []uint `json:"guest_ids"` // ID gości (Guest) przypisanych do odcinka
+	Media           []struct {
+		EpisodeMediaID *uint `json:"episode_media_id"`
+		MediaGroupID   *uint `json:"media_group_id"`
+	} `json:"media"` // Kolejna lista plików lub grup
+}
+
+// RundownSegmentView reprezentuje segment z wyliczonymi czasami
+type RundownSegmentView struct {
+	models.RundownSegment
+	MediaDuration     int `json:"media_duration"`     // Łączny czas mediów segmentu
+	EffectiveDuration int `json:"effective_duration"` // Planowany czas, a gdy brak - czas mediów
+	StartOffset       int `json:"start_offset"`       // Planowany start względem początku odcinka
+}
+
+// RundownView reprezentuje cały scenariusz odcinka z podsumowaniem czasu
+type RundownView struct {
+	EpisodeID      uint                 `json:"episode_id"`
+	TargetDuration int                  `json:"target_duration"` // Docelowa długość odcinka
+	PlannedTotal   int                  `json:"planned_total"`   // Suma czasów segmentów
+	OverUnder      int                  `json:"over_under"`      // >0 = za długo, <0 = za krótko (0 gdy brak celu)
+	Segments       []RundownSegmentView `json:"segments"`
+}
+
+// BuildRundownView pobiera segmenty odcinka i wylicza czasy względem docelowej długości
+func BuildRundownView(db *gorm.DB, episodeID uint) (*RundownView, error) {
+	var episode models.Episode
+	if err := db.First(&episode, episodeID).Error; err != nil {
+		return nil, err
+	}
+
+	segments, err := models.GetRundownSegments(db, episodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &RundownView{
+		EpisodeID:      episodeID,
+		TargetDuration: episode.TargetDuration,
+		Segments:       make([]RundownSegmentView, 0, len(segments)),
+	}
+
+	offset := 0
+	for _, segment := range segments {
+		mediaDuration := models.SegmentMediaDuration(db, segment)
+		effective := segment.PlannedDuration
+		if effective == 0 {
+			effective = mediaDuration
+		}
+
+		view.Segments = append(view.Segments, RundownSegmentView{
+			RundownSegment:    segment,
+			MediaDuration:     mediaDuration,
+			EffectiveDuration: effective,
+			StartOffset:       offset,
+		})
+		offset += effective
+	}
+
+	view.PlannedTotal = offset
+	if episode.TargetDuration > 0 {
+		view.OverUnder = view.PlannedTotal - episode.TargetDuration
+	}
+
+	return view, nil
+}
+
+// validate sprawdza poprawność danych segmentu
+func (req *rundownSegmentRequest) validate(db *gorm.DB, episodeID uint) error {
+	if req.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+
+	validType := false
+	for _, t := range models.SegmentTypes {
+		if req.Type == t {
+			validType = true
+			break
+		}
+	}
+	if !validType {
+		return fmt.Errorf("invalid type, allowed: %v", models.SegmentTypes)
+	}
+
+	if req.PlannedDuration < 0 {
+		return fmt.Errorf("planned_duration must not be negative")
+	}
+
+	if req.CameraTypeID != nil {
+		var cameraType models.CameraType
+		if err := db.First(&cameraType, *req.CameraTypeID).Error; err != nil {
+			return fmt.Errorf("camera type not found")
+		}
+	}
+
+	for _, guestID := range req.GuestIDs {
+		var count int64
+		db.Model(&models.EpisodeGuest{}).Where("episode_id = ? AND guest_id = ?", episodeID, guestID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("guest %d is not assigned to this episode", guestID)
+		}
+	}
+
+	for _, item := range req.Media {
+		if (item.EpisodeMediaID == nil) == (item.MediaGroupID == nil) {
+			return fmt.Errorf("each media item needs exactly one of episode_media_id or media_group_id")
+		}
+		if item.EpisodeMediaID != nil {
+			var count int64
+			db.Model(&models.EpisodeMedia{}).Where("id = ? AND episode_id = ?", *item.EpisodeMediaID, episodeID).Count(&count)
+			if count == 0 {
+				return fmt.Errorf("media %d does not belong to this episode", *item.EpisodeMediaID)
+			}
+		}
+		if item.MediaGroupID != nil {
+			var count int64
+			db.Model(&models.MediaGroup{}).Where("id = ? AND episode_id = ?", *item.MediaGroupID, episodeID).Count(&count)
+			if count == 0 {
+				return fmt.Errorf("media group %d does not belong to this episode", *item.MediaGroupID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// saveLinks zastępuje powiązania segmentu z gośćmi i mediami
+func (req *rundownSegmentRequest) saveLinks(tx *gorm.DB, segmentID uint) error {
+	if err := tx.Where("segment_id = ?", segmentID).Delete(&models.RundownSegmentGuest{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("segment_id = ?", segmentID).Delete(&models.RundownSegmentMedia{}).Error; err != nil {
+		return err
+	}
+
+	for _, guestID := range req.GuestIDs {
+		if err := tx.Create(&models.RundownSegmentGuest{SegmentID: segmentID, GuestID: guestID}).Error; err != nil {
+			return err
+		}
+	}
+
+	for i, item := range req.Media {
+		link := models.RundownSegmentMedia{
+			SegmentID:      segmentID,
+			EpisodeMediaID: item.EpisodeMediaID,
+			MediaGroupID:   item.MediaGroupID,
+			Order:          i,
+		}
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetRundown - GET /api/episodes/{episode_id}/rundown
+func (h *RundownHandler) GetRundown(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	episodeID, err := strconv.ParseUint(vars["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	view, err := BuildRundownView(h.DB, uint(episodeID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Episode not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// GetSegment - GET /api/episodes/{episode_id}/rundown/{id}
+func (h *RundownHandler) GetSegment(w http.ResponseWriter, r *http.Request) {
+	segment, ok := h.loadSegment(w, r)
+	if !ok {
+		return
+	}
+
+	h.DB.Preload("CameraType").
+		Preload("Guests.Guest.GuestType").
+		Preload("Media.EpisodeMedia").
+		Preload("Media.MediaGroup").
+		First(&segment, segment.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segment)
+}
+
+// CreateSegment - POST /api/episodes/{episode_id}/rundown
+func (h *RundownHandler) CreateSegment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	episodeID, err := strconv.ParseUint(vars["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	var episode models.Episode
+	if err := h.DB.First(&episode, episodeID).Error; err != nil {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	var req rundownSegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.validate(h.DB, uint(episodeID)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	segment := models.RundownSegment{
+		EpisodeID:       uint(episodeID),
+		Title:           req.Title,
+		Type:            req.Type,
+		PlannedDuration: req.PlannedDuration,
+		SceneName:       req.SceneName,
+		CameraTypeID:    req.CameraTypeID,
+		Notes:           req.Notes,
+		Order:           models.GetNextRundownSegmentOrder(h.DB, uint(episodeID)),
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&segment).Error; err != nil {
+			return err
+		}
+		return req.saveLinks(tx, segment.ID)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.DB.Preload("CameraType").
+		Preload("Guests.Guest.GuestType").
+		Preload("Media.EpisodeMedia").
+		Preload("Media.MediaGroup").
+		First(&segment, segment.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(segment)
+}
+
+// UpdateSegment - PUT /api/episodes/{episode_id}/rundown/{id}
+func (h *RundownHandler) UpdateSegment(w http.ResponseWriter, r *http.Request) {
+	segment, ok := h.loadSegment(w, r)
+	if !ok {
+		return
+	}
+
+	var req rundownSegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.validate(h.DB, segment.EpisodeID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	segment.Title = req.Title
+	segment.Type = req.Type
+	segment.PlannedDuration = req.PlannedDuration
+	segment.SceneName = req.SceneName
+	segment.CameraTypeID = req.CameraTypeID
+	segment.CameraType = nil
+	segment.Notes = req.Notes
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Guests", "Media", "CameraType").Save(&segment).Error; err != nil {
+			return err
+		}
+		return req.saveLinks(tx, segment.ID)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.DB.Preload("CameraType").
+		Preload("Guests.Guest.GuestType").
+		Preload("Media.EpisodeMedia").
+		Preload("Media.MediaGroup").
+		First(&segment, segment.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segment)
+}
+
+// DeleteSegment - DELETE /api/episodes/{episode_id}/rundown/{id}
+func (h *RundownHandler) DeleteSegment(w http.ResponseWriter, r *http.Request) {
+	segment, ok := h.loadSegment(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		tx.Where("segment_id = ?", segment.ID).Delete(&models.RundownSegmentGuest{})
+		tx.Where("segment_id = ?", segment.ID).Delete(&models.RundownSegmentMedia{})
+
+		if err := tx.Delete(&segment).Error; err != nil {
+			return err
+		}
+
+		// Zamknij lukę w kolejności
+		return tx.Model(&models.RundownSegment{}).
+			Where("episode_id = ? AND \"order\" > ?", segment.EpisodeID, segment.Order).
+			UpdateColumn("order", gorm.Expr("\"order\" - 1")).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderSegment - PUT /api/episodes/{episode_id}/rundown/{id}/reorder
+func (h *RundownHandler) ReorderSegment(w http.ResponseWriter, r *http.Request) {
+	segment, ok := h.loadSegment(w, r)
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Order int `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		oldOrder := segment.Order
+		newOrder := data.Order
+
+		if newOrder > oldOrder {
+			tx.Model(&models.RundownSegment{}).
+				Where("episode_id = ? AND \"order\" > ? AND \"order\" <= ?", segment.EpisodeID, oldOrder, newOrder).
+				UpdateColumn("order", gorm.Expr("\"order\" - 1"))
+		} else if newOrder < oldOrder {
+			tx.Model(&models.RundownSegment{}).
+				Where("episode_id = ? AND \"order\" >= ? AND \"order\" < ?", segment.EpisodeID, newOrder, oldOrder).
+				UpdateColumn("order", gorm.Expr("\"order\" + 1"))
+		}
+
+		return tx.Model(&segment).Update("order", newOrder).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// loadSegment pobiera segment z parametrów URL i sprawdza przynależność do odcinka
+func (h *RundownHandler) loadSegment(w http.ResponseWriter, r *http.Request) (models.RundownSegment, bool) {
+	var segment models.RundownSegment
+
+	vars := mux.Vars(r)
+	episodeID, err := strconv.ParseUint(vars["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return segment, false
+	}
+
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return segment, false
+	}
+
+	if err := h.DB.Where("id = ? AND episode_id = ?", id, episodeID).First(&segment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Segment not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return segment, false
+	}
+
+	return segment, true
+}
+
+// GetLiveState - GET /api/rundown/live
+func (h *RundownHandler) GetLiveState(w http.ResponseWriter, r *http.Request) {
+	h.writeLiveState(w, h.Executor.State)
+}
+
+// NextSegment - POST /api/rundown/live/next
+func (h *RundownHandler) NextSegment(w http.ResponseWriter, r *http.Request) {
+	h.writeLiveState(w, h.Executor.Next)
+}
+
+// PreviousSegment - POST /api/rundown/live/previous
+func (h *RundownHandler) PreviousSegment(w http.ResponseWriter, r *http.Request) {
+	h.writeLiveState(w, h.Executor.Previous)
+}
+
+// GoToSegment - POST /api/rundown/live/goto/{id}
+func (h *RundownHandler) GoToSegment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	h.writeLiveState(w, func() (*RundownLiveState, error) {
+		return h.Executor.GoTo(uint(id))
+	})
+}
+
+// ResetLive - POST /api/rundown/live/reset
+func (h *RundownHandler) ResetLive(w http.ResponseWriter, r *http.Request) {
+	h.writeLiveState(w, h.Executor.Reset)
+}
+
+// writeLiveState wykonuje operację na rundownie i zwraca jego stan
+func (h *RundownHandler) writeLiveState(w http.ResponseWriter, action func() (*RundownLiveState, error)) {
+	if h.Executor == nil {
+		http.Error(w, "Rundown execution not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, err := action()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
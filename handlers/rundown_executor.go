@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sceny używane przy realizacji rundownu (zgodne z kontrolerem)
+const (
+	rundownStreamScene = "STREAM"
+	rundownScreenScene = "SCREEN"
+	rundownMicScene    = "MIKROFONY"
+)
+
+// Sceny główne - w danej chwili widoczne jest tylko jedno źródło z jednej z nich
+var rundownMainScenes = []string{"KAMERY", "MEDIA", "REPORTAZE"}
+
+// Domyślna scena dla typu segmentu (gdy segment nie ma ustawionej sceny)
+var rundownDefaultScenes = map[string]string{
+	models.SegmentTypeIntro:     "KAMERY",
+	models.SegmentTypeInterview: "KAMERY",
+	models.SegmentTypeReport:    "REPORTAZE",
+	models.SegmentTypeMusic:     "MEDIA",
+	models.SegmentTypeBreak:     "MEDIA",
+}
+
+// RundownExecutor realizuje rundown aktualnego odcinka na żywo
+// "Następny segment" przełącza scenę, wczytuje media, ustawia mikrofony gości
+// i zapisuje postęp w bazie (wznowienie po restarcie serwera)
+type RundownExecutor struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+	MediaPath     string
+
+	mu sync.Mutex // Jedna operacja na rundownie naraz
+}
+
+// RundownLiveState reprezentuje stan realizacji rundownu wysyłany do klientów
+type RundownLiveState struct {
+	EpisodeID        uint                   `json:"episode_id"`
+	CurrentIndex     int                    `json:"current_index"` // -1 = rundown nie rozpoczęty
+	SegmentCount     int                    `json:"segment_count"`
+	Current          *models.RundownSegment `json:"current"`
+	Next             *models.RundownSegment `json:"next"`
+	SegmentStartedAt *time.Time             `json:"segment_started_at"`
+}
+
+func NewRundownExecutor(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler, mediaPath string) *RundownExecutor {
+	return &RundownExecutor{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		MediaPath:     mediaPath,
+	}
+}
+
+// State zwraca bieżący stan rundownu aktualnego odcinka
+func (re *RundownExecutor) State() (*RundownLiveState, error) {
+	episode, err := models.GetCurrentEpisode(re.DB)
+	if err != nil {
+		return nil, fmt.Errorf("brak aktualnego odcinka")
+	}
+
+	segments, err := models.GetRundownSegments(re.DB, episode.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := models.GetRundownProgress(re.DB, episode.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildRundownLiveState(episode.ID, segments, progress), nil
+}
+
+// Next przechodzi do następnego segmentu (pierwszy, jeśli rundown nie rozpoczęty)
+func (re *RundownExecutor) Next() (*RundownLiveState, error) {
+	return re.step(1)
+}
+
+// Previous wraca do poprzedniego segmentu
+func (re *RundownExecutor) Previous() (*RundownLiveState, error) {
+	return re.step(-1)
+}
+
+// GoTo przechodzi do wskazanego segmentu
+func (re *RundownExecutor) GoTo(segmentID uint) (*RundownLiveState, error) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	episode, segments, _, err := re.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range segments {
+		if segments[i].ID == segmentID {
+			return re.execute(episode.ID, segments, i)
+		}
+	}
+
+	return nil, fmt.Errorf("segment %d nie należy do aktualnego odcinka", segmentID)
+}
+
+// Reset czyści postęp rundownu (bez zmian w OBS)
+func (re *RundownExecutor) Reset() (*RundownLiveState, error) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	episode, segments, _, err := re.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.SetRundownProgress(re.DB, episode.ID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Zresetowano rundown odcinka %d", episode.ID)
+
+	state := buildRundownLiveState(episode.ID, segments, &models.RundownProgress{EpisodeID: episode.ID})
+	re.broadcastState(state)
+	return state, nil
+}
+
+// step przesuwa rundown o delta segmentów
+func (re *RundownExecutor) step(delta int) (*RundownLiveState, error) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	episode, segments, progress, err := re.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("rundown odcinka jest pusty")
+	}
+
+	index := currentSegmentIndex(segments, progress) + delta
+	if index < 0 {
+		return nil, fmt.Errorf("to jest pierwszy segment")
+	}
+	if index >= len(segments) {
+		return nil, fmt.Errorf("to był ostatni segment")
+	}
+
+	return re.execute(episode.ID, segments, index)
+}
+
+// load pobiera aktualny odcinek, jego segmenty i zapisany postęp
+func (re *RundownExecutor) load() (*models.Episode, []models.RundownSegment, *models.RundownProgress, error) {
+	episode, err := models.GetCurrentEpisode(re.DB)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("brak aktualnego odcinka")
+	}
+
+	segments, err := models.GetRundownSegments(re.DB, episode.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	progress, err := models.GetRundownProgress(re.DB, episode.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return episode, segments, progress, nil
+}
+
+// execute realizuje segment: media → mikrofony → scena → zapis postępu
+func (re *RundownExecutor) execute(episodeID uint, segments []models.RundownSegment, index int) (*RundownLiveState, error) {
+	if re.OBSClient == nil || !re.OBSClient.IsConnected() {
+		return nil, fmt.Errorf("OBS nie jest połączony")
+	}
+
+	segment := segments[index]
+	sceneName := segmentSceneName(segment)
+
+	// 1. Media segmentu (przed przełączeniem sceny, żeby nie pokazać starego pliku)
+	sourceName, err := re.loadSegmentMedia(episodeID, segment, sceneName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Źródło do pokazania w scenie
+	if sourceName == "" {
+		sourceName = re.segmentVisualSource(episodeID, segment, sceneName)
+	}
+	if sourceName == "" {
+		return nil, fmt.Errorf("brak źródła do pokazania w scenie %s", sceneName)
+	}
+
+	// 3. Mikrofony gości segmentu - przed wejściem na antenę, bo włączenie źródła
+	// KAMERY odpala regułę "Kamery przywracają mikrofony", która czyta zapisane is_visible
+	re.setupMicrophones(episodeID, segment, sceneName)
+
+	if err := re.takeSource(sceneName, sourceName); err != nil {
+		return nil, err
+	}
+
+	// 4. Zapis postępu i start timera segmentu
+	now := time.Now()
+	segmentID := segment.ID
+	if re.SocketHandler.ShowClock != nil {
+		re.SocketHandler.ShowClock.SegmentStarted(episodeID, segmentID, now)
+	}
+	if re.SocketHandler.AsRun != nil {
+		re.SocketHandler.AsRun.SegmentStarted(segment, now)
+	}
+	if re.SocketHandler.Rules != nil {
+		re.SocketHandler.Rules.SegmentStarted(segment, sceneName)
+	}
+	if re.SocketHandler.Speakers != nil {
+		re.SocketHandler.Speakers.SegmentStarted()
+	}
+	if err := models.SetRundownProgress(re.DB, episodeID, &segmentID, &now); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Rundown: segment %d/%d \"%s\" (%s → %s)", index+1, len(segments), segment.Title, sceneName, sourceName)
+
+	state := buildRundownLiveState(episodeID, segments, &models.RundownProgress{
+		EpisodeID:        episodeID,
+		CurrentSegmentID: &segmentID,
+		SegmentStartedAt: &now,
+	})
+	re.broadcastState(state)
+	return state, nil
+}
+
+// loadSegmentMedia wczytuje pierwsze media segmentu do źródła sceny
+// Plik → Media1/Reportaze1, grupa → playlista Media2/Reportaze2
+// Zwraca nazwę źródła, do którego wczytano media (pusta, gdy segment nie ma mediów)
+func (re *RundownExecutor) loadSegmentMedia(episodeID uint, segment models.RundownSegment, sceneName string) (string, error) {
+	if len(segment.Media) == 0 || (sceneName != "MEDIA" && sceneName != "REPORTAZE") {
+		return "", nil
+	}
+
+	prefix := "Media"
+	if sceneName == "REPORTAZE" {
+		prefix = "Reportaze"
+	}
+
+	item := segment.Media[0]
+
+	if item.EpisodeMedia != nil {
+		sourceName := prefix + "1"
+		if err := loadMediaIntoSource(re.SocketHandler, re.MediaPath, episodeID, sourceName, *item.EpisodeMedia, "rundown"); err != nil {
+			return "", err
+		}
+		return sourceName, nil
+	}
+
+	if item.MediaGroupID != nil {
+		sourceName := prefix + "2"
+		if _, err := loadGroupIntoSource(re.SocketHandler, re.MediaPath, episodeID, sourceName, *item.MediaGroupID, "rundown"); err != nil {
+			return "", err
+		}
+		return sourceName, nil
+	}
+
+	return "", nil
+}
+
+// segmentVisualSource wybiera źródło do pokazania, gdy segment nie wczytał mediów
+// KAMERY → kamera przypisana do typu kamery segmentu, w pozostałych → pierwsze źródło sceny
+func (re *RundownExecutor) segmentVisualSource(episodeID uint, segment models.RundownSegment, sceneName string) string {
+	if sceneName == "KAMERY" && segment.CameraTypeID != nil {
+		var assignment models.EpisodeSource
+		err := re.DB.Where("episode_id = ? AND camera_type_id = ?", episodeID, *segment.CameraTypeID).
+			Order("source_name ASC").
+			First(&assignment).Error
+		if err == nil {
+			return assignment.SourceName
+		}
+		log.Printf("Rundown: brak kamery z typem %d, używam domyślnej", *segment.CameraTypeID)
+	}
+
+	var scene models.Scene
+	if err := re.DB.Where("name = ?", sceneName).First(&scene).Error; err != nil {
+		return ""
+	}
+
+	var source models.Source
+	if err := re.DB.Where("scene_id = ?", scene.ID).Order("source_order ASC").First(&source).Error; err != nil {
+		return ""
+	}
+	return source.Name
+}
+
+// takeSource pokazuje źródło na wyjściu - tak samo jak przycisk w kontrolerze
+// (STREAM → źródło widoczne i na wierzchu → scena na wierzchu w SCREEN → reszta wyłączona)
+func (re *RundownExecutor) takeSource(sceneName, sourceName string) error {
+	if err := re.OBSClient.SetCurrentProgramScene(rundownStreamScene); err != nil {
+		return fmt.Errorf("błąd przełączania na %s: %v", rundownStreamScene, err)
+	}
+
+	if err := re.OBSClient.SetSourceVisibility(sceneName, sourceName, true); err != nil {
+		return fmt.Errorf("błąd włączania %s -> %s: %v", sceneName, sourceName, err)
+	}
+	re.broadcastSourceChanged(sceneName, sourceName, true)
+
+	if err := re.OBSClient.SetSceneItemIndex(sceneName, sourceName, true); err != nil {
+		log.Printf("Rundown: błąd zmiany kolejności %s -> %s: %v", sceneName, sourceName, err)
+	}
+	if err := re.OBSClient.SetSceneItemIndex(rundownScreenScene, sceneName, true); err != nil {
+		log.Printf("Rundown: błąd zmiany kolejności %s -> %s: %v", rundownScreenScene, sceneName, err)
+	}
+
+	// Wyłącz pozostałe źródła scen głównych
+	for _, mainScene := range rundownMainScenes {
+		var scene models.Scene
+		if err := re.DB.Where("name = ?", mainScene).First(&scene).Error; err != nil {
+			continue
+		}
+
+		var sources []models.Source
+		re.DB.Where("scene_id = ?", scene.ID).Find(&sources)
+
+		for _, source := range sources {
+			if mainScene == sceneName && source.Name == sourceName {
+				continue
+			}
+			if err := re.OBSClient.SetSourceVisibility(mainScene, source.Name, false); err != nil {
+				log.Printf("Rundown: błąd wyłączania %s -> %s: %v", mainScene, source.Name, err)
+				continue
+			}
+			re.broadcastSourceChanged(mainScene, source.Name, false)
+		}
+	}
+
+	return nil
+}
+
+// setupMicrophones otwiera mikrofony gości segmentu i zamyka mikrofony pozostałych gości
+// Mikrofony prowadzących zostają w stanie ustawionym przez realizatora (is_visible)
+// Przy reportażu wszystkie mikrofony są wyciszane (bez zmiany is_visible)
+func (re *RundownExecutor) setupMicrophones(episodeID uint, segment models.RundownSegment, sceneName string) {
+	var scene models.Scene
+	if err := re.DB.Where("name = ?", rundownMicScene).First(&scene).Error; err != nil {
+		log.Printf("Rundown: scena %s nie znaleziona", rundownMicScene)
+		return
+	}
+
+	var sources []models.Source
+	re.DB.Where("scene_id = ?", scene.ID).Find(&sources)
+
+	segmentGuests := make(map[uint]bool)
+	for _, guest := range segment.Guests {
+		segmentGuests[guest.GuestID] = true
+	}
+
+	for _, source := range sources {
+		visible := source.IsVisible
+
+		if sceneName == "REPORTAZE" {
+			visible = false
+		} else {
+			assignment, err := models.GetEpisodeSourceAssignment(re.DB, episodeID, source.Name)
+			if err == nil && assignment != nil && assignment.GuestID != nil {
+				visible = segmentGuests[*assignment.GuestID]
+
+				// Zapamiętaj stan jak przy ręcznym przełączeniu (restore_microphones)
+				if source.IsVisible != visible {
+					re.DB.Model(&source).Update("is_visible", visible)
+				}
+			}
+		}
+
+		if err := re.OBSClient.SetSourceVisibility(rundownMicScene, source.Name, visible); err != nil {
+			log.Printf("Rundown: błąd ustawiania mikrofonu %s: %v", source.Name, err)
+			continue
+		}
+		re.broadcastSourceChanged(rundownMicScene, source.Name, visible)
+	}
+}
+
+// broadcastSourceChanged informuje kontrolery o zmianie widoczności źródła
+func (re *RundownExecutor) broadcastSourceChanged(sceneName, sourceName string, visible bool) {
+	re.SocketHandler.Broadcast("source_changed", map[string]interface{}{
+		"scene_name":  sceneName,
+		"source_name": sourceName,
+		"visible":     visible,
+	})
+}
+
+// broadcastState wysyła stan rundownu do wszystkich klientów
+func (re *RundownExecutor) broadcastState(state *RundownLiveState) {
+	re.SocketHandler.Broadcast("rundown_state", state)
+}
+
+// segmentSceneName zwraca scenę segmentu (ustawioną ręcznie lub domyślną dla typu)
+func segmentSceneName(segment models.RundownSegment) string {
+	if segment.SceneName != "" {
+		return segment.SceneName
+	}
+	if scene, ok := rundownDefaultScenes[segment.Type]; ok {
+		return scene
+	}
+	return "KAMERY"
+}
+
+// currentSegmentIndex zwraca indeks bieżącego segmentu (-1 = rundown nie rozpoczęty)
+func currentSegmentIndex(segments []models.RundownSegment, progress *models.RundownProgress) int {
+	if progress == nil || progress.CurrentSegmentID == nil {
+		return -1
+	}
+	for i := range segments {
+		if segments[i].ID == *progress.CurrentSegmentID {
+			return i
+		}
+	}
+	return -1
+}
+
+// buildRundownLiveState składa stan rundownu z segmentów i postępu
+func buildRundownLiveState(episodeID uint, segments []models.RundownSegment, progress *models.RundownProgress) *RundownLiveState {
+	index := currentSegmentIndex(segments, progress)
+
+	state := &RundownLiveState{
+		EpisodeID:    episodeID,
+		CurrentIndex: index,
+		SegmentCount: len(segments),
+	}
+
+	if index >= 0 {
+		state.Current = &segments[index]
+		state.SegmentStartedAt = progress.SegmentStartedAt
+	}
+	if index+1 < len(segments) {
+		state.Next = &segments[index+1]
+	}
+
+	return state
+}
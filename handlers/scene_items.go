@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"obs-controller/obsws"
+	"sync"
+)
+
+// sceneItemNames to cache nazw źródeł: scena → sceneItemId → nazwa źródła
+// Eventy OBS (np. SceneItemEnableStateChanged) podają tylko sceneItemId
+type sceneItemNames struct {
+	obsClient *obsws.Client
+	names     map[string]map[int]string
+	mu        sync.Mutex
+}
+
+// newSceneItemNames tworzy cache i czyści go, gdy zmienia się struktura scen
+func newSceneItemNames(obsClient *obsws.Client) *sceneItemNames {
+	cache := &sceneItemNames{
+		obsClient: obsClient,
+		names:     make(map[string]map[int]string),
+	}
+
+	for _, eventType := range []string{"SceneItemCreated", "SceneItemRemoved", "InputNameChanged"} {
+		obsClient.OnEvent(eventType, func(event map[string]interface{}) {
+			cache.mu.Lock()
+			cache.names = make(map[string]map[int]string)
+			cache.mu.Unlock()
+		})
+	}
+
+	return cache
+}
+
+// Get zwraca nazwę źródła dla sceneItemId (z cache lub z OBS)
+func (c *sceneItemNames) Get(sceneName string, itemID int) string {
+	c.mu.Lock()
+	if names, ok := c.names[sceneName]; ok {
+		if name, ok := names[itemID]; ok {
+			c.mu.Unlock()
+			return name
+		}
+	}
+	c.mu.Unlock()
+
+	items, err := c.obsClient.GetSceneItemList(sceneName)
+	if err != nil {
+		return ""
+	}
+
+	names := make(map[int]string)
+	for _, item := range items {
+		id, _ := item["sceneItemId"].(float64)
+		name, _ := item["sourceName"].(string)
+		names[int(id)] = name
+	}
+
+	c.mu.Lock()
+	c.names[sceneName] = names
+	c.mu.Unlock()
+
+	return names[itemID]
+}
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Jak długo po terminie akcja może się jeszcze wykonać (np. po restarcie kontrolera)
+const scheduleMissedGrace = 2 * time.Minute
+
+// Status akcji planu, która jeszcze się nie wykonała
+const (
+	scheduleStatusPending  = "pending"
+	scheduleStatusRunning  = "running"
+	scheduleStatusDisabled = "disabled"
+)
+
+// ScheduledItem reprezentuje akcję planu z terminem dla aktualnego odcinka
+type ScheduledItem struct {
+	models.ScheduleEntry
+	DueAt        time.Time  `json:"due_at"`
+	SecondsUntil int        `json:"seconds_until"` // <0 = termin minął
+	Status       string     `json:"status"`
+	Error        string     `json:"error"`
+	ExecutedAt   *time.Time `json:"executed_at"`
+}
+
+// ScheduleView reprezentuje plan emisji aktualnego odcinka
+type ScheduleView struct {
+	EpisodeID uint            `json:"episode_id"`
+	AirTime   *time.Time      `json:"air_time"` // nil = odcinek bez godziny emisji
+	Items     []ScheduledItem `json:"items"`
+}
+
+// Scheduler wykonuje plan emisji względem godziny emisji aktualnego odcinka
+type Scheduler struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+	MediaPath     string
+
+	mu sync.Mutex // Jedno sprawdzenie planu naraz
+}
+
+func NewScheduler(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler, mediaPath string) *Scheduler {
+	return &Scheduler{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		MediaPath:     mediaPath,
+	}
+}
+
+// Start uruchamia sprawdzanie planu co sekundę
+func (s *Scheduler) Start() {
+	log.Println("Starting Scheduler...")
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			s.check(now)
+		}
+	}()
+
+	log.Println("Scheduler started successfully")
+}
+
+// episodeAirTime zwraca godzinę emisji odcinka
+func episodeAirTime(episode *models.Episode) (time.Time, bool) {
+	if episode.AirTime == nil || episode.AirTime.IsZero() {
+		return time.Time{}, false
+	}
+	return *episode.AirTime, true
+}
+
+// check wykonuje akcje planu, których termin właśnie nadszedł
+func (s *Scheduler) check(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	episode, err := models.GetCurrentEpisode(s.DB)
+	if err != nil {
+		return
+	}
+	airTime, ok := episodeAirTime(episode)
+	if !ok {
+		return
+	}
+
+	var entries []models.ScheduleEntry
+	if err := s.DB.Where("enabled = ?", true).Order("offset_seconds ASC").Find(&entries).Error; err != nil {
+		return
+	}
+
+	runs := s.runsForEpisode(episode.ID)
+
+	for _, entry := range entries {
+		if _, done := runs[entry.ID]; done {
+			continue
+		}
+
+		dueAt := airTime.Add(time.Duration(entry.OffsetSeconds) * time.Second)
+		if now.Before(dueAt) {
+			continue
+		}
+
+		run := models.ScheduleRun{
+			EpisodeID: episode.ID,
+			EntryID:   entry.ID,
+			Status:    scheduleStatusRunning,
+		}
+
+		if now.Sub(dueAt) > scheduleMissedGrace {
+			run.Status = models.ScheduleStatusMissed
+			s.DB.Create(&run)
+			continue
+		}
+
+		// Zapis przed wykonaniem - akcja nie wykona się drugi raz przy kolejnym tyknięciu
+		if err := s.DB.Create(&run).Error; err != nil {
+			log.Printf("Plan emisji: błąd zapisu wykonania %s: %v", entry.Name, err)
+			continue
+		}
+
+		go s.execute(run, entry)
+	}
+}
+
+// execute wykonuje akcję planu i zapisuje wynik
+func (s *Scheduler) execute(run models.ScheduleRun, entry models.ScheduleEntry) {
+	log.Printf("Plan emisji: %s (%s)", entry.Name, entry.Action)
+
+	var err error
+	if entry.Action == models.ScheduleActionCheckReady {
+		if problems := s.Preflight(); len(problems) > 0 {
+			s.SocketHandler.Broadcast("schedule_warning", map[string]interface{}{
+				"entry_id": entry.ID,
+				"name":     entry.Name,
+				"problems": problems,
+			})
+			err = fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+	} else if s.SocketHandler.Macros == nil {
+		err = fmt.Errorf("wykonywanie akcji nie jest dostępne")
+	} else {
+		err = s.SocketHandler.Macros.executeStep(context.Background(), entry.Action, entry.Params, 0)
+	}
+
+	now := time.Now()
+	run.ExecutedAt = &now
+	run.Status = models.ScheduleStatusDone
+	if err != nil {
+		run.Status = models.ScheduleStatusFailed
+		run.Error = err.Error()
+		log.Printf("Plan emisji: %s - błąd: %v", entry.Name, err)
+	}
+	s.DB.Save(&run)
+
+	s.SocketHandler.Broadcast("schedule_action", map[string]interface{}{
+		"entry_id": entry.ID,
+		"name":     entry.Name,
+		"action":   entry.Action,
+		"status":   run.Status,
+		"error":    run.Error,
+	})
+}
+
+// Preflight sprawdza gotowość do emisji i zwraca listę problemów (pusta = gotowe)
+func (s *Scheduler) Preflight() []string {
+	problems := []string{}
+
+	if s.OBSClient == nil || !s.OBSClient.IsConnected() {
+		problems = append(problems, "OBS nie jest połączony")
+	} else if scenes, err := s.OBSClient.GetSceneList(); err != nil {
+		problems = append(problems, fmt.Sprintf("Błąd pobierania scen z OBS: %v", err))
+	} else {
+		existing := make(map[string]bool, len(scenes))
+		for _, scene := range scenes {
+			existing[scene] = true
+		}
+		required := append([]string{rundownStreamScene, rundownScreenScene, rundownMicScene}, rundownMainScenes...)
+		for _, scene := range required {
+			if !existing[scene] {
+				problems = append(problems, fmt.Sprintf("Brak sceny %s w OBS", scene))
+			}
+		}
+	}
+
+	episode, err := models.GetCurrentEpisode(s.DB)
+	if err != nil {
+		return append(problems, "Brak aktualnego odcinka")
+	}
+
+	var media []models.EpisodeMedia
+	s.DB.Where("episode_id = ?", episode.ID).Find(&media)
+	for _, item := range media {
+		if !hasMediaSource(item) || isURLMedia(item) {
+			continue
+		}
+		if _, err := os.Stat(mediaLocation(s.MediaPath, item)); err != nil {
+			problems = append(problems, fmt.Sprintf("Brak pliku: %s", item.Title))
+		}
+	}
+
+	return problems
+}
+
+// View zwraca plan aktualnego odcinka z terminami i statusami
+func (s *Scheduler) View() (*ScheduleView, error) {
+	episode, err := models.GetCurrentEpisode(s.DB)
+	if err != nil {
+		return nil, fmt.Errorf("brak aktualnego odcinka")
+	}
+
+	var entries []models.ScheduleEntry
+	if err := s.DB.Order("offset_seconds ASC").Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	view := &ScheduleView{EpisodeID: episode.ID, Items: []ScheduledItem{}}
+	airTime, hasAirTime := episodeAirTime(episode)
+	if hasAirTime {
+		view.AirTime = &airTime
+	}
+
+	runs := s.runsForEpisode(episode.ID)
+	now := time.Now()
+
+	for _, entry := range entries {
+		item := ScheduledItem{ScheduleEntry: entry, Status: scheduleStatusPending}
+		if hasAirTime {
+			item.DueAt = airTime.Add(time.Duration(entry.OffsetSeconds) * time.Second)
+			item.SecondsUntil = int(item.DueAt.Sub(now).Seconds())
+		}
+		if !entry.Enabled {
+			item.Status = scheduleStatusDisabled
+		}
+		if run, ok := runs[entry.ID]; ok {
+			item.Status = run.Status
+			item.Error = run.Error
+			item.ExecutedAt = run.ExecutedAt
+		}
+		view.Items = append(view.Items, item)
+	}
+
+	return view, nil
+}
+
+// Cancel anuluje akcję planu dla aktualnego odcinka
+func (s *Scheduler) Cancel(entryID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	episode, err := models.GetCurrentEpisode(s.DB)
+	if err != nil {
+		return fmt.Errorf("brak aktualnego odcinka")
+	}
+
+	if _, ok := s.runsForEpisode(episode.ID)[entryID]; ok {
+		return fmt.Errorf("akcja została już wykonana lub anulowana")
+	}
+
+	return s.DB.Create(&models.ScheduleRun{
+		EpisodeID: episode.ID,
+		EntryID:   entryID,
+		Status:    models.ScheduleStatusCancelled,
+	}).Error
+}
+
+// Restore przywraca anulowaną akcję planu dla aktualnego odcinka
+func (s *Scheduler) Restore(entryID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	episode, err := models.GetCurrentEpisode(s.DB)
+	if err != nil {
+		return fmt.Errorf("brak aktualnego odcinka")
+	}
+
+	result := s.DB.Where("episode_id = ? AND entry_id = ? AND status = ?", episode.ID, entryID, models.ScheduleStatusCancelled).
+		Delete(&models.ScheduleRun{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("akcja nie jest anulowana")
+	}
+	return nil
+}
+
+func (s *Scheduler) runsForEpisode(episodeID uint) map[uint]models.ScheduleRun {
+	var runs []models.ScheduleRun
+	s.DB.Where("episode_id = ?", episodeID).Find(&runs)
+
+	result := make(map[uint]models.ScheduleRun, len(runs))
+	for _, run := range runs {
+		result[run.EntryID] = run
+	}
+	return result
+}
+
+// validateScheduleEntry sprawdza poprawność akcji planu
+func validateScheduleEntry(entry *models.ScheduleEntry) error {
+	if strings.TrimSpace(entry.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if entry.Action == models.ScheduleActionCheckReady {
+		return nil
+	}
+	return validateMacroAction(entry.Action, entry.Params)
+}
+
+type SchedulerHandler struct {
+	DB        *gorm.DB
+	Scheduler *Scheduler
+}
+
+func NewSchedulerHandler(db *gorm.DB, scheduler *Scheduler) *SchedulerHandler {
+	return &SchedulerHandler{DB: db, Scheduler: scheduler}
+}
+
+// GetSchedule - GET /api/schedule
+func (h *SchedulerHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	view, err := h.Scheduler.View()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// GetPreflight - GET /api/schedule/preflight
+func (h *SchedulerHandler) GetPreflight(w http.ResponseWriter, r *http.Request) {
+	problems := h.Scheduler.Preflight()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":    len(problems) == 0,
+		"problems": problems,
+	})
+}
+
+// CreateEntry - POST /api/schedule
+func (h *SchedulerHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	entry := models.ScheduleEntry{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry.ID = 0
+	if err := validateScheduleEntry(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Create(&entry).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// UpdateEntry - PUT /api/schedule/{id}
+func (h *SchedulerHandler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.loadEntry(w, r)
+	if !ok {
+		return
+	}
+
+	var updateData models.ScheduleEntry
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry.Name = updateData.Name
+	entry.OffsetSeconds = updateData.OffsetSeconds
+	entry.Action = updateData.Action
+	entry.Params = updateData.Params
+	entry.Enabled = updateData.Enabled
+
+	if err := validateScheduleEntry(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Save(&entry).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeleteEntry - DELETE /api/schedule/{id}
+func (h *SchedulerHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.loadEntry(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entry_id = ?", entry.ID).Delete(&models.ScheduleRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entry).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelEntry - POST /api/schedule/{id}/cancel
+func (h *SchedulerHandler) CancelEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.loadEntry(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Scheduler.Cancel(entry.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreEntry - POST /api/schedule/{id}/restore
+func (h *SchedulerHandler) RestoreEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.loadEntry(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Scheduler.Restore(entry.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SchedulerHandler) loadEntry(w http.ResponseWriter, r *http.Request) (models.ScheduleEntry, bool) {
+	var entry models.ScheduleEntry
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return entry, false
+	}
+
+	if err := h.DB.First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Schedule entry not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return entry, false
+	}
+
+	return entry, true
+}
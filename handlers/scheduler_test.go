@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"obs-controller/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// enableOnlyEntry włącza wyłącznie wskazaną akcję planu i zwraca jej ID
+func enableOnlyEntry(t *testing.T, db *gorm.DB, action string) uint {
+	t.Helper()
+	db.Model(&models.ScheduleEntry{}).Where("1 = 1").Update("enabled", false)
+
+	var entry models.ScheduleEntry
+	if err := db.Where("action = ?", action).First(&entry).Error; err != nil {
+		t.Fatalf("load entry %s: %v", action, err)
+	}
+	db.Model(&entry).Update("enabled", true)
+	return entry.ID
+}
+
+// waitForRun czeka, aż akcja planu przestanie być w trakcie wykonywania
+func waitForRun(t *testing.T, db *gorm.DB, episodeID, entryID uint) models.ScheduleRun {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var run models.ScheduleRun
+		err := db.Where("episode_id = ? AND entry_id = ?", episodeID, entryID).First(&run).Error
+		if err == nil && run.Status != scheduleStatusRunning {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run for entry %d not finished (err=%v, status=%q)", entryID, err, run.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestScheduler(t *testing.T, db *gorm.DB) *Scheduler {
+	t.Helper()
+	return NewScheduler(db, nil, newTestSocketHandler(t, db), t.TempDir())
+}
+
+func TestSchedulerRunsDueEntryOnce(t *testing.T) {
+	db := openTestDB(t)
+	airTime := time.Now().Add(10 * time.Minute)
+	episode := createCurrentEpisode(t, db, &airTime)
+	entryID := enableOnlyEntry(t, db, models.ScheduleActionCheckReady)
+	s := newTestScheduler(t, db)
+
+	// Przed terminem (-15 min) nic się nie dzieje
+	s.check(airTime.Add(-16 * time.Minute))
+	var count int64
+	db.Model(&models.ScheduleRun{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no runs before due time, got %d", count)
+	}
+
+	// Minutę po terminie akcja mieści się w tolerancji i wykonuje się
+	s.check(airTime.Add(-14 * time.Minute))
+	run := waitForRun(t, db, episode.ID, entryID)
+	// Bez OBS sprawdzenie gotowości zgłasza problemy
+	if run.Status != models.ScheduleStatusFailed {
+		t.Errorf("status = %q, want %q", run.Status, models.ScheduleStatusFailed)
+	}
+	if run.ExecutedAt == nil {
+		t.Error("executed_at not set")
+	}
+
+	// Kolejne tyknięcie nie wykonuje akcji drugi raz
+	s.check(airTime.Add(-14*time.Minute + time.Second))
+	db.Model(&models.ScheduleRun{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected exactly one run, got %d", count)
+	}
+}
+
+func TestSchedulerMarksLateEntryMissed(t *testing.T) {
+	db := openTestDB(t)
+	airTime := time.Now().Add(10 * time.Minute)
+	episode := createCurrentEpisode(t, db, &airTime)
+	entryID := enableOnlyEntry(t, db, models.ScheduleActionCheckReady)
+	s := newTestScheduler(t, db)
+
+	// Kontroler wstał 15 min po terminie - poza tolerancją
+	s.check(airTime.Add(-15*time.Minute + scheduleMissedGrace + time.Second))
+
+	var run models.ScheduleRun
+	if err := db.Where("episode_id = ? AND entry_id = ?", episode.ID, entryID).First(&run).Error; err != nil {
+		t.Fatalf("load run: %v", err)
+	}
+	if run.Status != models.ScheduleStatusMissed {
+		t.Errorf("status = %q, want %q", run.Status, models.ScheduleStatusMissed)
+	}
+	if run.ExecutedAt != nil {
+		t.Error("missed run must not have executed_at")
+	}
+}
+
+func TestSchedulerSkipsCancelledEntry(t *testing.T) {
+	db := openTestDB(t)
+	airTime := time.Now().Add(10 * time.Minute)
+	episode := createCurrentEpisode(t, db, &airTime)
+	entryID := enableOnlyEntry(t, db, models.ScheduleActionCheckReady)
+	s := newTestScheduler(t, db)
+
+	if err := s.Cancel(entryID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	s.check(airTime.Add(-15 * time.Minute))
+
+	var run models.ScheduleRun
+	db.Where("episode_id = ? AND entry_id = ?", episode.ID, entryID).First(&run)
+	if run.Status != models.ScheduleStatusCancelled {
+		t.Errorf("status = %q, want %q", run.Status, models.ScheduleStatusCancelled)
+	}
+}
+
+func TestSchedulerMidnightAirTime(t *testing.T) {
+	db := openTestDB(t)
+	airTime := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	createCurrentEpisode(t, db, &airTime)
+	s := newTestScheduler(t, db)
+
+	view, err := s.View()
+	if err != nil {
+		t.Fatalf("view: %v", err)
+	}
+	if view.AirTime == nil || !view.AirTime.Equal(airTime) {
+		t.Fatalf("air time = %v, want %v", view.AirTime, airTime)
+	}
+	for _, item := range view.Items {
+		want := airTime.Add(time.Duration(item.OffsetSeconds) * time.Second)
+		if !item.DueAt.Equal(want) {
+			t.Errorf("%s: due_at = %v, want %v", item.Name, item.DueAt, want)
+		}
+	}
+}
+
+func TestSchedulerWithoutAirTime(t *testing.T) {
+	db := openTestDB(t)
+	createCurrentEpisode(t, db, nil)
+	enableOnlyEntry(t, db, models.ScheduleActionCheckReady)
+	s := newTestScheduler(t, db)
+
+	s.check(time.Now())
+	var count int64
+	db.Model(&models.ScheduleRun{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no runs without air time, got %d", count)
+	}
+}
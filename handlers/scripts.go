@@ -0,0 +1,912 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	"github.com/yuin/gopher-lua/pm"
+	"gorm.io/gorm"
+)
+
+// Liczba linii logu pamiętanych dla każdego skryptu
+const scriptLogSize = 200
+
+// Maksymalny limit czasu wykonania skryptu
+const scriptMaxTimeout = 30 * time.Second
+
+// Maksymalna liczba instrukcji Lua w jednym wykonaniu skryptu (pętle zalewające pamięć)
+const scriptMaxInstructions = 10_000_000
+
+// Maksymalna długość napisu tworzonego przez skrypt (konkatenacja, string.rep, string.gsub, table.concat)
+const scriptMaxStringLen = 1 << 20
+
+// Maksymalny przyrost sterty w czasie wykonania skryptu (mierzony co scriptMemoryCheckEvery instrukcji)
+// Sterta jest wspólna dla całego procesu - limit ma zapas na alokacje reszty serwera
+const (
+	scriptMaxMemory        = 256 << 20
+	scriptMemoryCheckEvery = 256
+)
+
+// Limity konwersji tablic Lua na wartości Go (broadcast, action, obs.request)
+const (
+	scriptMaxTableDepth = 32
+	scriptMaxTableItems = 100_000
+)
+
+// errScriptInstructionLimit - skrypt przekroczył limit instrukcji
+var errScriptInstructionLimit = errors.New("przekroczono limit instrukcji")
+
+// errScriptMemoryLimit - skrypt utworzył za długi napis lub zajął za dużo pamięci
+var errScriptMemoryLimit = errors.New("przekroczono limit pamięci")
+
+// Funkcje biblioteki bazowej Lua niedostępne w piaskownicy (dostęp do plików, ładowanie kodu)
+var scriptBlockedGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage"}
+
+// ScriptLogEntry reprezentuje linię logu skryptu
+type ScriptLogEntry struct {
+	Time       time.Time `json:"time"`
+	ScriptID   uint      `json:"script_id"`
+	ScriptName string    `json:"script_name"`
+	Level      string    `json:"level"` // info, error
+	Message    string    `json:"message"`
+}
+
+// compiledScript to skompilowany skrypt (przeładowywany po zmianie UpdatedAt)
+type compiledScript struct {
+	updatedAt time.Time
+	proto     *lua.FunctionProto
+}
+
+// ScriptEngine wykonuje skrypty Lua w piaskownicy z limitem czasu
+// API dla skryptu: event, episode, obs.*, broadcast(), action(), log()/print()
+type ScriptEngine struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+
+	compiled map[uint]compiledScript
+	logs     map[uint][]ScriptLogEntry
+	mu       sync.Mutex
+}
+
+func NewScriptEngine(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler) *ScriptEngine {
+	return &ScriptEngine{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		compiled:      make(map[uint]compiledScript),
+		logs:          make(map[uint][]ScriptLogEntry),
+	}
+}
+
+// Fire uruchamia w tle włączone skrypty podpięte pod zdarzenie (w zakresie aktualnego sezonu)
+func (se *ScriptEngine) Fire(event string, fields map[string]interface{}) {
+	query := se.DB.Where("event = ? AND enabled = ?", event, true)
+	if season, err := models.GetCurrentSeason(se.DB); err == nil {
+		query = query.Where("season_id IS NULL OR season_id = ?", season.ID)
+	} else {
+		query = query.Where("season_id IS NULL")
+	}
+
+	var scripts []models.Script
+	if err := query.Order("id ASC").Find(&scripts).Error; err != nil {
+		log.Printf("Skrypty: błąd pobierania skryptów %s: %v", event, err)
+		return
+	}
+
+	for _, script := range scripts {
+		go se.Run(script, event, fields)
+	}
+}
+
+// Run wykonuje skrypt synchronicznie; błąd trafia też do logu skryptu
+func (se *ScriptEngine) Run(script models.Script, event string, fields map[string]interface{}) error {
+	err := se.run(script, event, fields)
+	if err != nil {
+		se.appendLog(script, "error", err.Error())
+	}
+	return err
+}
+
+func (se *ScriptEngine) run(script models.Script, event string, fields map[string]interface{}) error {
+	proto, err := se.compile(script)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(script.TimeoutMs) * time.Millisecond
+	if timeout <= 0 || timeout > scriptMaxTimeout {
+		timeout = scriptMaxTimeout
+	}
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), timeout)
+	defer cancelTimeout()
+	ctx := newScriptBudget(timeoutCtx, scriptMaxInstructions)
+	defer ctx.cancel(nil)
+
+	L := newSandbox()
+	defer L.Close()
+	ctx.watch(L)
+	L.SetContext(ctx)
+
+	se.registerAPI(L, script, event, fields)
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		if errors.Is(context.Cause(ctx), errScriptInstructionLimit) {
+			return fmt.Errorf("%w (%d)", errScriptInstructionLimit, scriptMaxInstructions)
+		}
+		if errors.Is(context.Cause(ctx), errScriptMemoryLimit) {
+			return fmt.Errorf("%w (napis do %d bajtów, %d MiB sterty)", errScriptMemoryLimit, scriptMaxStringLen, scriptMaxMemory>>20)
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("przekroczono limit czasu %v", timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// scriptBudget to kontekst wykonania skryptu z limitem instrukcji i pamięci
+// gopher-lua sprawdza Done() przed każdą instrukcją - po wyczerpaniu limitu kontekst jest anulowany
+type scriptBudget struct {
+	context.Context
+	cancel    context.CancelCauseFunc
+	remaining atomic.Int64
+
+	L        *lua.LState // Stan sprawdzany przed instrukcją (nil - tylko limit instrukcji)
+	heap     []metrics.Sample
+	heapBase uint64
+}
+
+func newScriptBudget(parent context.Context, instructions int64) *scriptBudget {
+	ctx, cancel := context.WithCancelCause(parent)
+	budget := &scriptBudget{Context: ctx, cancel: cancel}
+	budget.remaining.Store(instructions)
+	return budget
+}
+
+// watch włącza limit pamięci dla stanu Lua; Done() musi być wtedy wołane tylko z goroutine skryptu
+// (wywołania Go w innych goroutines dostają scriptContext)
+func (b *scriptBudget) watch(L *lua.LState) {
+	b.L = L
+	b.heap = []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(b.heap)
+	b.heapBase = b.heap[0].Value.Uint64()
+}
+
+func (b *scriptBudget) Done() <-chan struct{} {
+	left := b.remaining.Add(-1)
+	if left < 0 {
+		b.cancel(errScriptInstructionLimit)
+	} else if b.L != nil && !b.withinMemory(left) {
+		b.cancel(errScriptMemoryLimit)
+	}
+	return b.Context.Done()
+}
+
+// withinMemory sprawdza rejestry bieżącej funkcji i co jakiś czas przyrost sterty
+// Wynik konkatenacji trafia do rejestru, więc za długi napis jest wykrywany przed kolejną instrukcją
+// (s = s .. s nie zdąży podwoić się wiele razy)
+func (b *scriptBudget) withinMemory(left int64) bool {
+	for i := 1; i <= b.L.GetTop(); i++ {
+		if str, ok := b.L.Get(i).(lua.LString); ok && len(str) > scriptMaxStringLen {
+			return false
+		}
+	}
+
+	if left%scriptMemoryCheckEvery != 0 {
+		return true
+	}
+	metrics.Read(b.heap)
+	return b.heap[0].Value.Uint64() <= b.heapBase+scriptMaxMemory
+}
+
+// scriptContext zwraca kontekst skryptu dla wywołań Go wykonywanych w innych goroutines
+// (bez liczenia instrukcji i sprawdzania rejestrów stanu Lua)
+func scriptContext(L *lua.LState) context.Context {
+	if budget, ok := L.Context().(*scriptBudget); ok {
+		return budget.Context
+	}
+	return L.Context()
+}
+
+// callWithContext wykonuje blokujące wywołanie Go (OBS, akcja makra) najdłużej do końca kontekstu
+// skryptu; wywołanie, które nie zdążyło, kończy się w tle, a skrypt dostaje błąd
+func callWithContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("przerwano wywołanie: %v", context.Cause(ctx))
+	}
+}
+
+// compile zwraca skompilowany skrypt z cache lub kompiluje go po zmianie (hot reload)
+func (se *ScriptEngine) compile(script models.Script) (*lua.FunctionProto, error) {
+	se.mu.Lock()
+	cached, ok := se.compiled[script.ID]
+	se.mu.Unlock()
+	if ok && cached.updatedAt.Equal(script.UpdatedAt) {
+		return cached.proto, nil
+	}
+
+	proto, err := compileScript(script.Name, script.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	se.mu.Lock()
+	se.compiled[script.ID] = compiledScript{updatedAt: script.UpdatedAt, proto: proto}
+	se.mu.Unlock()
+
+	return proto, nil
+}
+
+// Forget usuwa skrypt z cache i jego logi (po usunięciu skryptu)
+func (se *ScriptEngine) Forget(scriptID uint) {
+	se.mu.Lock()
+	delete(se.compiled, scriptID)
+	delete(se.logs, scriptID)
+	se.mu.Unlock()
+}
+
+// Logs zwraca ostatnie linie logu skryptu
+func (se *ScriptEngine) Logs(scriptID uint) []ScriptLogEntry {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	return append([]ScriptLogEntry{}, se.logs[scriptID]...)
+}
+
+// ClearLogs czyści log skryptu
+func (se *ScriptEngine) ClearLogs(scriptID uint) {
+	se.mu.Lock()
+	delete(se.logs, scriptID)
+	se.mu.Unlock()
+}
+
+// appendLog dopisuje linię logu i wysyła ją do UI
+func (se *ScriptEngine) appendLog(script models.Script, level string, message string) {
+	entry := ScriptLogEntry{
+		Time:       time.Now(),
+		ScriptID:   script.ID,
+		ScriptName: script.Name,
+		Level:      level,
+		Message:    message,
+	}
+
+	se.mu.Lock()
+	lines := append(se.logs[script.ID], entry)
+	if len(lines) > scriptLogSize {
+		lines = lines[len(lines)-scriptLogSize:]
+	}
+	se.logs[script.ID] = lines
+	se.mu.Unlock()
+
+	if level == "error" {
+		log.Printf("Skrypt %s: %s", script.Name, message)
+	}
+	se.SocketHandler.Broadcast("script_log", entry)
+}
+
+// registerAPI udostępnia skryptowi dane zdarzenia, odcinka i funkcje sterujące
+func (se *ScriptEngine) registerAPI(L *lua.LState, script models.Script, event string, fields map[string]interface{}) {
+	eventTable := L.NewTable()
+	for key, value := range fields {
+		eventTable.RawSetString(key, goToLua(L, value))
+	}
+	eventTable.RawSetString("name", lua.LString(event))
+	L.SetGlobal("event", eventTable)
+	L.SetGlobal("episode", goToLua(L, se.episodeData()))
+
+	logFn := func(L *lua.LState) int {
+		parts := make([]string, 0, L.GetTop())
+		for i := 1; i <= L.GetTop(); i++ {
+			parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+		}
+		se.appendLog(script, "info", strings.Join(parts, " "))
+		return 0
+	}
+	L.SetGlobal("log", L.NewFunction(logFn))
+	L.SetGlobal("print", L.NewFunction(logFn))
+
+	// broadcast(event, data) - wiadomość Socket.IO do wszystkich klientów
+	L.SetGlobal("broadcast", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		data, err := luaToGo(L.Get(2))
+		if err != nil {
+			return pushLuaError(L, err)
+		}
+		se.SocketHandler.Broadcast(name, data)
+		return 0
+	}))
+
+	// action(name, params) - dowolna akcja kroku makra
+	L.SetGlobal("action", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		if err := validateMacroAction(name, nil); err != nil {
+			return pushLuaError(L, err)
+		}
+		if se.SocketHandler.Macros == nil {
+			return pushLuaError(L, fmt.Errorf("wykonywanie akcji nie jest dostępne"))
+		}
+		data, err := luaToGo(L.Get(2))
+		if err != nil {
+			return pushLuaError(L, err)
+		}
+		params, _ := json.Marshal(data)
+		ctx := scriptContext(L)
+		err = callWithContext(L.Context(), func() error {
+			return se.SocketHandler.Macros.executeStep(ctx, name, params, 0)
+		})
+		return pushLuaResult(L, err)
+	}))
+
+	// Wywołania OBS kończą się najpóźniej z limitem czasu skryptu (obsws.Request nie ma własnego)
+	obs := L.NewTable()
+	obs.RawSetString("request", L.NewFunction(func(L *lua.LState) int {
+		requestType := L.CheckString(1)
+		data, err := luaToGo(L.Get(2))
+		if err != nil {
+			return pushLuaError(L, err)
+		}
+		requestData, _ := data.(map[string]interface{})
+		response, err := se.OBSClient.RequestContext(L.Context(), requestType, requestData)
+		if err != nil {
+			return pushLuaError(L, err)
+		}
+		L.Push(goToLua(L, response))
+		return 1
+	}))
+	obs.RawSetString("set_scene", L.NewFunction(func(L *lua.LState) int {
+		_, err := se.OBSClient.RequestContext(L.Context(), "SetCurrentProgramScene", map[string]interface{}{
+			"sceneName": L.CheckString(1),
+		})
+		return pushLuaResult(L, err)
+	}))
+	obs.RawSetString("set_visible", L.NewFunction(func(L *lua.LState) int {
+		sceneName, sourceName, visible := L.CheckString(1), L.CheckString(2), L.ToBool(3)
+		return pushLuaResult(L, callWithContext(L.Context(), func() error {
+			return se.SocketHandler.setSourceVisible(sceneName, sourceName, visible)
+		}))
+	}))
+	obs.RawSetString("set_volume", L.NewFunction(func(L *lua.LState) int {
+		inputName, volumeDb := L.CheckString(1), float64(L.CheckNumber(2))
+		return pushLuaResult(L, callWithContext(L.Context(), func() error {
+			return se.SocketHandler.setInputVolume(inputName, volumeDb)
+		}))
+	}))
+	L.SetGlobal("obs", obs)
+}
+
+// episodeData zwraca dane aktualnego odcinka udostępniane skryptom (nil gdy brak)
+func (se *ScriptEngine) episodeData() interface{} {
+	episode, err := models.GetCurrentEpisode(se.DB)
+	if err != nil {
+		return nil
+	}
+
+	var guests []models.EpisodeGuest
+	se.DB.Preload("Guest").Where("episode_id = ?", episode.ID).Order("segment_order ASC").Find(&guests)
+
+	guestList := make([]interface{}, 0, len(guests))
+	for _, guest := range guests {
+		guestList = append(guestList, map[string]interface{}{
+			"id":    guest.GuestID,
+			"name":  guest.Guest.FirstName + " " + guest.Guest.LastName,
+			"topic": guest.Topic,
+		})
+	}
+
+	return map[string]interface{}{
+		"id":              episode.ID,
+		"season_id":       episode.SeasonID,
+		"episode_number":  episode.EpisodeNumber,
+		"season_episode":  episode.SeasonEpisode,
+		"title":           episode.Title,
+		"episode_date":    episode.EpisodeDate.Format(time.RFC3339),
+		"target_duration": episode.TargetDuration,
+		"guests":          guestList,
+	}
+}
+
+// newSandbox tworzy stan Lua z bezpiecznym podzbiorem bibliotek (bez io/os/package)
+func newSandbox() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	for _, name := range scriptBlockedGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	// string.rep z limitem długości - jedna instrukcja mogłaby zaalokować gigabajty
+	if stringLib, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		stringLib.RawSetString("rep", L.NewFunction(func(L *lua.LState) int {
+			str := L.CheckString(1)
+			count := L.CheckInt(2)
+			if count <= 0 || str == "" {
+				L.Push(lua.LString(""))
+				return 1
+			}
+			if count > scriptMaxStringLen/len(str) {
+				L.RaiseError("string.rep: wynik dłuższy niż %d bajtów", scriptMaxStringLen)
+			}
+			L.Push(lua.LString(strings.Repeat(str, count)))
+			return 1
+		}))
+
+		if gsub, ok := stringLib.RawGetString("gsub").(*lua.LFunction); ok {
+			stringLib.RawSetString("gsub", L.NewFunction(limitedGsub(gsub.GFunction)))
+		}
+	}
+
+	// table.concat z limitem długości wyniku
+	if tableLib, ok := L.GetGlobal(lua.TabLibName).(*lua.LTable); ok {
+		if concat, ok := tableLib.RawGetString("concat").(*lua.LFunction); ok {
+			tableLib.RawSetString("concat", L.NewFunction(limitedTableConcat(concat.GFunction)))
+		}
+	}
+
+	return L
+}
+
+// limitedGsub ogranicza długość wyniku string.gsub (np. każdy znak zastąpiony długim napisem)
+// Przy zamienniku-napisie wynik jest szacowany z liczby dopasowań przed zamianą,
+// przy tablicy lub funkcji liczona jest suma zwracanych zamienników
+func limitedGsub(gsub lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		str := L.CheckString(1)
+		pattern := L.CheckString(2)
+		limit := L.OptInt(4, -1)
+
+		switch repl := L.Get(3).(type) {
+		case lua.LString:
+			matches, err := pm.Find(pattern, []byte(str), 0, limit)
+			if err != nil {
+				L.RaiseError("string.gsub: %v", err)
+			}
+			// Każde %n może wstawić co najwyżej cały napis wejściowy
+			perMatch := len(repl) + strings.Count(string(repl), "%")*len(str)
+			if perMatch > 0 && len(matches) > (scriptMaxStringLen-len(str))/perMatch {
+				L.RaiseError("string.gsub: wynik dłuższy niż %d bajtów", scriptMaxStringLen)
+			}
+
+		case *lua.LTable, *lua.LFunction:
+			total := len(str)
+			L.Replace(3, L.NewFunction(func(L *lua.LState) int {
+				var value lua.LValue
+				if table, ok := repl.(*lua.LTable); ok {
+					value = L.GetTable(table, L.Get(1))
+				} else {
+					args := L.GetTop()
+					L.Push(repl)
+					for i := 1; i <= args; i++ {
+						L.Push(L.Get(i))
+					}
+					L.Call(args, 1)
+					value = L.Get(-1)
+				}
+				if str, ok := value.(lua.LString); ok {
+					total += len(str)
+					if total > scriptMaxStringLen {
+						L.RaiseError("string.gsub: wynik dłuższy niż %d bajtów", scriptMaxStringLen)
+					}
+				}
+				L.Push(value)
+				return 1
+			}))
+		}
+
+		return gsub(L)
+	}
+}
+
+// limitedTableConcat sprawdza długość wyniku table.concat przed złączeniem
+func limitedTableConcat(concat lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		table := L.CheckTable(1)
+		sep := L.OptString(2, "")
+		from := L.OptInt(3, 1)
+		to := L.OptInt(4, table.Len())
+		if to > table.Len() {
+			to = table.Len()
+		}
+
+		total := 0
+		for i := from; i <= to && total <= scriptMaxStringLen; i++ {
+			if value := table.RawGetInt(i); value.Type() == lua.LTString || value.Type() == lua.LTNumber {
+				total += len(value.String()) + len(sep)
+			}
+		}
+		if total > scriptMaxStringLen {
+			L.RaiseError("table.concat: wynik dłuższy niż %d bajtów", scriptMaxStringLen)
+		}
+
+		return concat(L)
+	}
+}
+
+// compileScript kompiluje kod Lua (błędy składni zwracane przy zapisie skryptu)
+func compileScript(name, code string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(code), name)
+	if err != nil {
+		return nil, err
+	}
+	return lua.Compile(chunk, name)
+}
+
+// pushLuaResult zwraca do Lua true lub (nil, błąd)
+func pushLuaResult(L *lua.LState, err error) int {
+	if err != nil {
+		return pushLuaError(L, err)
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func pushLuaError(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// goToLua konwertuje wartość Go (jak z JSON) na wartość Lua
+func goToLua(L *lua.LState, value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case uint:
+		return lua.LNumber(v)
+	case map[string]interface{}:
+		table := L.NewTable()
+		for key, item := range v {
+			table.RawSetString(key, goToLua(L, item))
+		}
+		return table
+	case []interface{}:
+		table := L.NewTable()
+		for _, item := range v {
+			table.Append(goToLua(L, item))
+		}
+		return table
+	}
+
+	// Pozostałe typy (struktury, wskaźniki) przez JSON
+	data, err := json.Marshal(value)
+	if err != nil {
+		return lua.LNil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return lua.LNil
+	}
+	return goToLua(L, decoded)
+}
+
+// luaToGo konwertuje wartość Lua na wartość Go (tablica z indeksami 1..n → lista)
+// Tablice cykliczne, zbyt głęboko zagnieżdżone lub zbyt duże zwracają błąd
+func luaToGo(value lua.LValue) (interface{}, error) {
+	conv := luaConverter{visiting: make(map[*lua.LTable]bool)}
+	return conv.convert(value, 0)
+}
+
+type luaConverter struct {
+	visiting map[*lua.LTable]bool // Tablice na bieżącej ścieżce (wykrywanie cykli)
+	items    int                  // Wszystkie skonwertowane wartości (tablice współdzielone liczone wielokrotnie)
+}
+
+func (c *luaConverter) convert(value lua.LValue, depth int) (interface{}, error) {
+	c.items++
+	if c.items > scriptMaxTableItems {
+		return nil, fmt.Errorf("tablica ma więcej niż %d elementów", scriptMaxTableItems)
+	}
+
+	switch v := value.(type) {
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case *lua.LTable:
+		if c.visiting[v] {
+			return nil, fmt.Errorf("tablica zawiera odwołanie do samej siebie")
+		}
+		if depth >= scriptMaxTableDepth {
+			return nil, fmt.Errorf("tablica zagnieżdżona głębiej niż %d poziomów", scriptMaxTableDepth)
+		}
+		c.visiting[v] = true
+		defer delete(c.visiting, v)
+
+		if v.MaxN() > 0 {
+			list := make([]interface{}, 0, v.MaxN())
+			for i := 1; i <= v.MaxN(); i++ {
+				item, err := c.convert(v.RawGetInt(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			return list, nil
+		}
+
+		result := make(map[string]interface{})
+		var err error
+		v.ForEach(func(key, item lua.LValue) {
+			if err != nil {
+				return
+			}
+			result[key.String()], err = c.convert(item, depth+1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+// ScriptEvents zwraca zdarzenia, pod które można podpiąć skrypt
+func ScriptEvents() []string {
+	events := []string{models.ScriptEventManual}
+	for _, trigger := range models.RuleTriggers {
+		if trigger != models.RuleTriggerTimer {
+			events = append(events, trigger)
+		}
+	}
+	return events
+}
+
+// validateScript sprawdza poprawność skryptu (w tym składnię Lua)
+func validateScript(script *models.Script) error {
+	if strings.TrimSpace(script.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	known := false
+	for _, event := range ScriptEvents() {
+		if script.Event == event {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown event %q", script.Event)
+	}
+
+	if script.TimeoutMs <= 0 {
+		script.TimeoutMs = 1000
+	}
+	if time.Duration(script.TimeoutMs)*time.Millisecond > scriptMaxTimeout {
+		return fmt.Errorf("timeout_ms must not exceed %d", scriptMaxTimeout.Milliseconds())
+	}
+
+	if _, err := compileScript(script.Name, script.Code); err != nil {
+		return fmt.Errorf("syntax error: %v", err)
+	}
+	return nil
+}
+
+type ScriptHandler struct {
+	DB     *gorm.DB
+	Engine *ScriptEngine
+}
+
+func NewScriptHandler(db *gorm.DB, engine *ScriptEngine) *ScriptHandler {
+	return &ScriptHandler{DB: db, Engine: engine}
+}
+
+// GetScripts - GET /api/scripts
+func (h *ScriptHandler) GetScripts(w http.ResponseWriter, r *http.Request) {
+	var scripts []models.Script
+	if err := h.DB.Order("name ASC").Find(&scripts).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scripts)
+}
+
+// GetScriptEvents - GET /api/scripts/events
+func (h *ScriptHandler) GetScriptEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ScriptEvents())
+}
+
+// GetScript - GET /api/scripts/{id}
+func (h *ScriptHandler) GetScript(w http.ResponseWriter, r *http.Request) {
+	script, ok := h.loadScript(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(script)
+}
+
+// CreateScript - POST /api/scripts
+func (h *ScriptHandler) CreateScript(w http.ResponseWriter, r *http.Request) {
+	script := models.Script{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&script); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	script.ID = 0
+	if err := validateScript(&script); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Create(&script).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(script)
+}
+
+// UpdateScript - PUT /api/scripts/{id}
+// Zmiana kodu działa od następnego wywołania (cache po UpdatedAt)
+func (h *ScriptHandler) UpdateScript(w http.ResponseWriter, r *http.Request) {
+	script, ok := h.loadScript(w, r)
+	if !ok {
+		return
+	}
+
+	var updateData models.Script
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	script.Name = updateData.Name
+	script.Description = updateData.Description
+	script.Enabled = updateData.Enabled
+	script.SeasonID = updateData.SeasonID
+	script.Event = updateData.Event
+	script.Code = updateData.Code
+	script.TimeoutMs = updateData.TimeoutMs
+
+	if err := validateScript(&script); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Save(&script).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(script)
+}
+
+// DeleteScript - DELETE /api/scripts/{id}
+func (h *ScriptHandler) DeleteScript(w http.ResponseWriter, r *http.Request) {
+	script, ok := h.loadScript(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&script).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Engine.Forget(script.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunScript - POST /api/scripts/{id}/run
+// Opcjonalne body JSON trafia do skryptu jako pola tabeli event
+func (h *ScriptHandler) RunScript(w http.ResponseWriter, r *http.Request) {
+	script, ok := h.loadScript(w, r)
+	if !ok {
+		return
+	}
+
+	fields := map[string]interface{}{}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.Engine.Run(script, models.ScriptEventManual, fields); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Engine.Logs(script.ID))
+}
+
+// GetScriptLogs - GET /api/scripts/{id}/logs
+func (h *ScriptHandler) GetScriptLogs(w http.ResponseWriter, r *http.Request) {
+	script, ok := h.loadScript(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Engine.Logs(script.ID))
+}
+
+// ClearScriptLogs - DELETE /api/scripts/{id}/logs
+func (h *ScriptHandler) ClearScriptLogs(w http.ResponseWriter, r *http.Request) {
+	script, ok := h.loadScript(w, r)
+	if !ok {
+		return
+	}
+
+	h.Engine.ClearLogs(script.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ScriptHandler) loadScript(w http.ResponseWriter, r *http.Request) (models.Script, bool) {
+	var script models.Script
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return script, false
+	}
+
+	if err := h.DB.First(&script, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Script not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return script, false
+	}
+
+	return script, true
+}
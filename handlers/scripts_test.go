@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"obs-controller/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestScriptEngine(t *testing.T) *ScriptEngine {
+	t.Helper()
+	db := openTestDB(t)
+	return NewScriptEngine(db, nil, newTestSocketHandler(t, db))
+}
+
+func TestScriptInstructionLimit(t *testing.T) {
+	se := newTestScriptEngine(t)
+	script := models.Script{ID: 1, Name: "Pętla", Code: "local t = {} while true do t[#t + 1] = 1 end", TimeoutMs: 30000}
+
+	start := time.Now()
+	err := se.run(script, models.ScriptEventManual, nil)
+	if !errors.Is(err, errScriptInstructionLimit) {
+		t.Fatalf("err = %v, want instruction limit", err)
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Errorf("instruction limit hit only after %v", elapsed)
+	}
+}
+
+func TestScriptTimeout(t *testing.T) {
+	se := newTestScriptEngine(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	budget := newScriptBudget(ctx, scriptMaxInstructions)
+
+	err := callWithContext(budget, func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+
+	script := models.Script{ID: 2, Name: "Czekanie", Code: "while true do end", TimeoutMs: 50}
+	if err := se.run(script, models.ScriptEventManual, nil); err == nil || !strings.Contains(err.Error(), "limit czasu") {
+		t.Errorf("err = %v, want timeout", err)
+	}
+}
+
+func TestScriptStringRepLimit(t *testing.T) {
+	se := newTestScriptEngine(t)
+
+	ok := models.Script{ID: 3, Name: "Krótki", Code: `assert(string.rep("ab", 3) == "ababab")`, TimeoutMs: 1000}
+	if err := se.run(ok, models.ScriptEventManual, nil); err != nil {
+		t.Fatalf("short rep: %v", err)
+	}
+
+	huge := models.Script{ID: 4, Name: "Długi", Code: `local s = string.rep("x", 1000000000)`, TimeoutMs: 1000}
+	if err := se.run(huge, models.ScriptEventManual, nil); err == nil || !strings.Contains(err.Error(), "string.rep") {
+		t.Errorf("err = %v, want string.rep limit", err)
+	}
+}
+
+func TestScriptMemoryLimit(t *testing.T) {
+	se := newTestScriptEngine(t)
+
+	for i, code := range []string{
+		// Podwajanie napisu omija limit string.rep
+		`local s = string.rep("x", 1048576) while true do s = s .. s end`,
+		// Wiele napisów poniżej limitu długości
+		`local t = {} local s = string.rep("x", 1000000) for i = 1, 100000 do t[i] = s .. i end`,
+	} {
+		script := models.Script{ID: uint(10 + i), Name: "Pamięć", Code: code, TimeoutMs: 30000}
+		if err := se.run(script, models.ScriptEventManual, nil); !errors.Is(err, errScriptMemoryLimit) {
+			t.Errorf("%s: err = %v, want memory limit", code, err)
+		}
+	}
+}
+
+func TestScriptBuiltinResultLimits(t *testing.T) {
+	se := newTestScriptEngine(t)
+
+	ok := models.Script{ID: 6, Name: "Zamiany", TimeoutMs: 1000, Code: `
+		assert(string.gsub("hello world", "o", "0") == "hell0 w0rld")
+		assert(string.gsub("$a $b", "%$(%w)", {a = "1", b = "2"}) == "1 2")
+		assert(string.gsub("abc", "%w", function(c) return c:upper() end) == "ABC")
+		assert(string.gsub("abc", "%w", function(c) if c == "b" then return "B" end end) == "aBc")
+		assert(table.concat({"a", "b", 3}, ",") == "a,b,3")
+		assert(table.concat({"a", "b", "c"}, "", 2, 10) == "bc")`}
+	if err := se.run(ok, models.ScriptEventManual, nil); err != nil {
+		t.Fatalf("small results: %v", err)
+	}
+
+	id := uint(20)
+	for code, want := range map[string]string{
+		`string.gsub(string.rep("x", 100000), ".", string.rep("y", 100))`:                         "string.gsub",
+		`string.gsub(string.rep("x", 100000), ".", function() return string.rep("y", 100) end)`:   "string.gsub",
+		`string.gsub(string.rep("x", 100000), "(.)", {x = string.rep("y", 100)})`:                 "string.gsub",
+		`local t = {} for i = 1, 10 do t[i] = string.rep("x", 200000) end return table.concat(t)`: "table.concat",
+	} {
+		id++
+		script := models.Script{ID: id, Name: "Duże", Code: code, TimeoutMs: 5000}
+		if err := se.run(script, models.ScriptEventManual, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %s limit", code, err, want)
+		}
+	}
+}
+
+func TestLuaToGoRejectsCyclicAndHugeTables(t *testing.T) {
+	L := newSandbox()
+	defer L.Close()
+	if err := L.DoString(`
+		cyclic = {} cyclic.self = cyclic
+		deep = {} local node = deep for i = 1, 100 do node.next = {} node = node.next end
+		shared = {1} for i = 1, 20 do shared = {shared, shared} end
+		plain = {name = "x", items = {1, 2, {3}}}`); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	for _, name := range []string{"cyclic", "deep", "shared"} {
+		if _, err := luaToGo(L.GetGlobal(name)); err == nil {
+			t.Errorf("%s: converted without error", name)
+		}
+	}
+	value, err := luaToGo(L.GetGlobal("plain"))
+	if err != nil {
+		t.Fatalf("plain: %v", err)
+	}
+	if value.(map[string]interface{})["name"] != "x" {
+		t.Errorf("plain = %v", value)
+	}
+
+	// Cykl przekazany do API skryptu kończy się błędem zamiast przepełnienia stosu
+	se := newTestScriptEngine(t)
+	script := models.Script{ID: 8, Name: "Cykl", TimeoutMs: 1000, Code: `
+		local t = {} t.x = t
+		local ok, err = broadcast("cycle", t)
+		assert(ok == nil and err ~= nil, "broadcast accepted a cyclic table")
+		ok, err = action("wait", t)
+		assert(ok == nil and err ~= nil, "action accepted a cyclic table")`}
+	if err := se.run(script, models.ScriptEventManual, nil); err != nil {
+		t.Errorf("cyclic table through the API: %v", err)
+	}
+}
@@ -11,7 +11,8 @@ import (
 )
 
 type SeasonHandler struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	SocketHandler *SocketHandler // Motyw overlayu po zmianie aktualnego sezonu (nil = bez powiadomień)
 }
 
 func NewSeasonHandler(db *gorm.DB) *SeasonHandler {
@@ -78,6 +79,7 @@ func (h *SeasonHandler) CreateSeason(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.currentSeasonChanged()
 	} else {
 		if err := h.DB.Create(&season).Error; err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -130,6 +132,7 @@ func (h *SeasonHandler) UpdateSeason(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		h.currentSeasonChanged()
 		// Odśwież dane
 		h.DB.First(&season, id)
 	} else {
@@ -198,6 +201,8 @@ func (h *SeasonHandler) SetCurrentSeason(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	h.currentSeasonChanged()
+
 	var season models.Season
 	h.DB.First(&season, id)
 
@@ -205,6 +210,13 @@ func (h *SeasonHandler) SetCurrentSeason(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(season)
 }
 
+// currentSeasonChanged - overlaye przechodzą na motyw nowego aktualnego sezonu
+func (h *SeasonHandler) currentSeasonChanged() {
+	if h.SocketHandler != nil {
+		h.SocketHandler.BroadcastTheme()
+	}
+}
+
 // GetNextSeasonNumber - GET /api/seasons/next-number
 func (h *SeasonHandler) GetNextSeasonNumber(w http.ResponseWriter, r *http.Request) {
 	var maxSeason models.Season
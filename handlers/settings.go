@@ -9,17 +9,20 @@ import (
 )
 
 type SettingsHandler struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	MediaPath string // Ścieżka bazowa do mediów (dla zajętości dysku)
 }
 
-func NewSettingsHandler(db *gorm.DB) *SettingsHandler {
-	return &SettingsHandler{DB: db}
+func NewSettingsHandler(db *gorm.DB, mediaPath string) *SettingsHandler {
+	return &SettingsHandler{DB: db, MediaPath: mediaPath}
 }
 
 type SettingsStatus struct {
-	HasCurrentSeason    bool `json:"has_current_season"`
-	HasCurrentEpisode   bool `json:"has_current_episode"`
-	CanAccessController bool `json:"can_access_controller"`
+	HasCurrentSeason    bool              `json:"has_current_season"`
+	HasCurrentEpisode   bool              `json:"has_current_episode"`
+	CanAccessController bool              `json:"can_access_controller"`
+	MediaUsage          []SeasonDiskUsage `json:"media_usage"`      // Zajętość dysku per sezon
+	MediaTotalSize      string            `json:"media_total_size"` // Łączna zajętość (czytelna)
 }
 
 // GetStatus zwraca status aplikacji - czy ma aktualne dane
@@ -39,6 +42,13 @@ func (h *SettingsHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	// Kontroler dostępny tylko gdy są oba
 	status.CanAccessController = status.HasCurrentSeason && status.HasCurrentEpisode
 
+	// Zajętość dysku przez media
+	status.MediaUsage = make([]SeasonDiskUsage, 0)
+	if report, err := buildMediaGCReport(h.DB, h.MediaPath); err == nil {
+		status.MediaUsage = report.Seasons
+		status.MediaTotalSize = formatBytes(report.TotalSize)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Co ile wysyłać tyknięcie zegara do klientów
+const showClockTickInterval = time.Second
+
+// ShowClock śledzi czas programu i segmentów rundownu aktualnego odcinka
+// Stan jest zapisywany w bazie (ShowTiming, RundownProgress), więc przeżywa
+// odświeżenie przeglądarki i restart serwera; co sekundę wysyła show_clock_tick
+type ShowClock struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+
+	// Stan wyjść OBS (program kończy się, gdy oba są nieaktywne)
+	streaming bool
+	recording bool
+	outputsMu sync.Mutex
+
+	mu   sync.Mutex // Jedna zmiana stanu zegara naraz
+	stop chan struct{}
+}
+
+// SegmentTimer reprezentuje timer bieżącego segmentu
+type SegmentTimer struct {
+	ID            uint       `json:"id"`
+	Title         string     `json:"title"`
+	Index         int        `json:"index"`
+	StartedAt     *time.Time `json:"started_at"`
+	Running       bool       `json:"running"`
+	Elapsed       int        `json:"elapsed"`        // Sekundy od startu segmentu
+	Planned       int        `json:"planned"`        // Planowany czas segmentu (lub czas mediów)
+	Remaining     int        `json:"remaining"`      // Planned - Elapsed (ujemny = przekroczenie)
+	PlannedOffset int        `json:"planned_offset"` // Planowany start względem początku programu
+}
+
+// ShowClockState reprezentuje stan zegara wysyłany do klientów
+type ShowClockState struct {
+	Now            time.Time     `json:"now"`
+	EpisodeID      uint          `json:"episode_id"`
+	StartedAt      *time.Time    `json:"started_at"`
+	EndedAt        *time.Time    `json:"ended_at"`
+	StartedBy      string        `json:"started_by"`
+	Running        bool          `json:"running"`
+	Elapsed        int           `json:"elapsed"`         // Sekundy od startu programu
+	TargetDuration int           `json:"target_duration"` // Docelowa długość odcinka (0 = brak)
+	Remaining      int           `json:"remaining"`       // TargetDuration - Elapsed (0 gdy brak celu)
+	PlannedTotal   int           `json:"planned_total"`   // Suma planowanych czasów segmentów
+	Drift          int           `json:"drift"`           // Opóźnienie względem planu (>0 = spóźnienie)
+	Streaming      bool          `json:"streaming"`       // Stan wyjść z eventów OBS (bez odpytywania)
+	Recording      bool          `json:"recording"`
+	Segment        *SegmentTimer `json:"segment"`
+}
+
+func NewShowClock(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler) *ShowClock {
+	return &ShowClock{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start rozpoczyna nasłuchiwanie stanu streamu/nagrywania i wysyłanie tyknięć
+func (sc *ShowClock) Start() {
+	log.Println("Starting Show Clock...")
+
+	if sc.OBSClient != nil {
+		sc.OBSClient.OnEvent("StreamStateChanged", func(event map[string]interface{}) {
+			sc.handleOutputState("stream", event)
+		})
+		sc.OBSClient.OnEvent("RecordStateChanged", func(event map[string]interface{}) {
+			sc.handleOutputState("record", event)
+		})
+		sc.OBSClient.OnIdentified(sc.seedOutputs)
+		if sc.OBSClient.IsConnected() {
+			go sc.seedOutputs()
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(showClockTickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-sc.stop:
+				return
+			case <-ticker.C:
+				state, err := sc.State()
+				if err != nil {
+					continue
+				}
+				sc.SocketHandler.BroadcastToAll("show_clock_tick", state)
+			}
+		}
+	}()
+
+	log.Println("Show Clock started successfully")
+}
+
+// Stop zatrzymuje wysyłanie tyknięć
+func (sc *ShowClock) Stop() {
+	close(sc.stop)
+}
+
+// seedOutputs odczytuje stan streamu i nagrywania po połączeniu z OBS
+// (wyjście uruchomione przed połączeniem nie wyśle eventu), dalej stan śledzą eventy
+func (sc *ShowClock) seedOutputs() {
+	streaming, err := sc.OBSClient.GetStreamActive()
+	if err != nil {
+		log.Printf("Zegar: błąd odczytu stanu streamu: %v", err)
+		return
+	}
+	recording, err := sc.OBSClient.GetRecordActive()
+	if err != nil {
+		log.Printf("Zegar: błąd odczytu stanu nagrywania: %v", err)
+		return
+	}
+
+	sc.outputsMu.Lock()
+	sc.streaming = streaming
+	sc.recording = recording
+	sc.outputsMu.Unlock()
+}
+
+// handleOutputState startuje program przy pierwszym starcie wyjścia i kończy go,
+// gdy zatrzymany zostanie ostatni aktywny stream/nagrywanie
+func (sc *ShowClock) handleOutputState(output string, event map[string]interface{}) {
+	state, _ := event["outputState"].(string)
+
+	sc.outputsMu.Lock()
+	switch state {
+	case "OBS_WEBSOCKET_OUTPUT_STARTED":
+		if output == "stream" {
+			sc.streaming = true
+		} else {
+			sc.recording = true
+		}
+	case "OBS_WEBSOCKET_OUTPUT_STOPPED":
+		if output == "stream" {
+			sc.streaming = false
+		} else {
+			sc.recording = false
+		}
+	default:
+		sc.outputsMu.Unlock()
+		return
+	}
+	anyActive := sc.streaming || sc.recording
+	sc.outputsMu.Unlock()
+
+	if state == "OBS_WEBSOCKET_OUTPUT_STARTED" {
+		if output == "record" {
+			sc.markRecordStarted()
+		}
+		if _, err := sc.StartShow(output, false); err != nil {
+			log.Printf("Zegar: błąd startu programu (%s): %v", output, err)
+		}
+		return
+	}
+
+	if !anyActive {
+		if _, err := sc.StopShow(); err != nil {
+			log.Printf("Zegar: błąd zakończenia programu (%s): %v", output, err)
+		}
+	}
+}
+
+// markRecordStarted zapisuje start nagrywania aktualnego odcinka (początek rozdziałów pliku)
+func (sc *ShowClock) markRecordStarted() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	_, timing, err := sc.loadTiming()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if err := sc.DB.Model(timing).Update("record_started_at", &now).Error; err != nil {
+		log.Printf("Zegar: błąd zapisu startu nagrywania: %v", err)
+	}
+}
+
+// StartShow rozpoczyna zegar programu aktualnego odcinka
+// force = true uruchamia zegar od nowa nawet, jeśli program już trwa
+func (sc *ShowClock) StartShow(startedBy string, force bool) (*ShowClockState, error) {
+	sc.mu.Lock()
+	episode, timing, err := sc.loadTiming()
+	if err != nil {
+		sc.mu.Unlock()
+		return nil, err
+	}
+
+	if timing.StartedAt == nil || timing.EndedAt != nil || force {
+		now := time.Now()
+		err = sc.DB.Model(timing).Updates(map[string]interface{}{
+			"started_at": &now,
+			"ended_at":   nil,
+			"started_by": startedBy,
+		}).Error
+		if err != nil {
+			sc.mu.Unlock()
+			return nil, err
+		}
+		log.Printf("Zegar: start programu odcinka %d (%s)", episode.ID, startedBy)
+	}
+	sc.mu.Unlock()
+
+	return sc.broadcast()
+}
+
+// StopShow kończy zegar programu i timer bieżącego segmentu
+func (sc *ShowClock) StopShow() (*ShowClockState, error) {
+	sc.mu.Lock()
+	episode, timing, err := sc.loadTiming()
+	if err != nil {
+		sc.mu.Unlock()
+		return nil, err
+	}
+
+	if timing.StartedAt != nil && timing.EndedAt == nil {
+		now := time.Now()
+		if err := sc.DB.Model(timing).Update("ended_at", &now).Error; err != nil {
+			sc.mu.Unlock()
+			return nil, err
+		}
+		sc.closeSegment(episode.ID, now)
+		log.Printf("Zegar: koniec programu odcinka %d", episode.ID)
+	}
+	sc.mu.Unlock()
+
+	return sc.broadcast()
+}
+
+// ResetShow zeruje zegar programu aktualnego odcinka
+func (sc *ShowClock) ResetShow() (*ShowClockState, error) {
+	sc.mu.Lock()
+	_, timing, err := sc.loadTiming()
+	if err != nil {
+		sc.mu.Unlock()
+		return nil, err
+	}
+
+	err = sc.DB.Model(timing).Updates(map[string]interface{}{
+		"started_at": nil,
+		"ended_at":   nil,
+		"started_by": "",
+	}).Error
+	sc.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return sc.broadcast()
+}
+
+// SegmentStarted zamyka timer poprzedniego segmentu i zapisuje faktyczny start nowego
+// Wywoływane przez RundownExecutor przed zapisaniem postępu
+// Jeśli program jeszcze nie trwa, zegar startuje razem z pierwszym segmentem
+func (sc *ShowClock) SegmentStarted(episodeID uint, segmentID uint, at time.Time) {
+	sc.mu.Lock()
+	sc.closeSegment(episodeID, at)
+	sc.DB.Model(&models.RundownSegment{}).Where("id = ?", segmentID).Updates(map[string]interface{}{
+		"actual_started_at": &at,
+		"actual_duration":   0,
+	})
+
+	timing, err := models.GetShowTiming(sc.DB, episodeID)
+	if err == nil && timing.StartedAt == nil {
+		sc.DB.Model(timing).Updates(map[string]interface{}{
+			"started_at": &at,
+			"ended_at":   nil,
+			"started_by": "rundown",
+		})
+		log.Printf("Zegar: start programu odcinka %d (pierwszy segment)", episodeID)
+	}
+	sc.mu.Unlock()
+}
+
+// StopSegment zatrzymuje timer bieżącego segmentu (segment pozostaje bieżący)
+func (sc *ShowClock) StopSegment() (*ShowClockState, error) {
+	sc.mu.Lock()
+	episode, err := models.GetCurrentEpisode(sc.DB)
+	if err != nil {
+		sc.mu.Unlock()
+		return nil, fmt.Errorf("brak aktualnego odcinka")
+	}
+	sc.closeSegment(episode.ID, time.Now())
+	sc.mu.Unlock()
+
+	return sc.broadcast()
+}
+
+// closeSegment zapisuje faktyczny czas bieżącego segmentu i zatrzymuje jego timer
+func (sc *ShowClock) closeSegment(episodeID uint, at time.Time) {
+	progress, err := models.GetRundownProgress(sc.DB, episodeID)
+	if err != nil || progress.CurrentSegmentID == nil || progress.SegmentStartedAt == nil {
+		return
+	}
+
+	duration := int(at.Sub(*progress.SegmentStartedAt).Seconds())
+	sc.DB.Model(&models.RundownSegment{}).Where("id = ?", *progress.CurrentSegmentID).Update("actual_duration", duration)
+	sc.DB.Model(progress).Update("segment_started_at", nil)
+}
+
+// loadTiming pobiera aktualny odcinek i jego zegar
+func (sc *ShowClock) loadTiming() (*models.Episode, *models.ShowTiming, error) {
+	episode, err := models.GetCurrentEpisode(sc.DB)
+	if err != nil {
+		return nil, nil, fmt.Errorf("brak aktualnego odcinka")
+	}
+
+	timing, err := models.GetShowTiming(sc.DB, episode.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return episode, timing, nil
+}
+
+// broadcast wysyła aktualny stan zegara do wszystkich klientów
+func (sc *ShowClock) broadcast() (*ShowClockState, error) {
+	state, err := sc.State()
+	if err != nil {
+		return nil, err
+	}
+	sc.SocketHandler.BroadcastToAll("show_clock_tick", state)
+	return state, nil
+}
+
+// State wylicza stan zegara programu i segmentu dla aktualnego odcinka
+func (sc *ShowClock) State() (*ShowClockState, error) {
+	episode, timing, err := sc.loadTiming()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &ShowClockState{
+		Now:            now,
+		EpisodeID:      episode.ID,
+		StartedAt:      timing.StartedAt,
+		EndedAt:        timing.EndedAt,
+		StartedBy:      timing.StartedBy,
+		TargetDuration: episode.TargetDuration,
+	}
+
+	sc.outputsMu.Lock()
+	state.Streaming = sc.streaming
+	state.Recording = sc.recording
+	sc.outputsMu.Unlock()
+
+	if timing.StartedAt != nil {
+		end := now
+		if timing.EndedAt != nil {
+			end = *timing.EndedAt
+		} else {
+			state.Running = true
+		}
+		state.Elapsed = int(end.Sub(*timing.StartedAt).Seconds())
+	}
+	if episode.TargetDuration > 0 {
+		state.Remaining = episode.TargetDuration - state.Elapsed
+	}
+
+	view, err := BuildRundownView(sc.DB, episode.ID)
+	if err != nil {
+		return state, nil
+	}
+	state.PlannedTotal = view.PlannedTotal
+
+	progress, err := models.GetRundownProgress(sc.DB, episode.ID)
+	if err != nil || progress.CurrentSegmentID == nil {
+		return state, nil
+	}
+
+	for i, segment := range view.Segments {
+		if segment.ID != *progress.CurrentSegmentID {
+			continue
+		}
+
+		timer := &SegmentTimer{
+			ID:            segment.ID,
+			Title:         segment.Title,
+			Index:         i,
+			StartedAt:     segment.ActualStartedAt,
+			Planned:       segment.EffectiveDuration,
+			PlannedOffset: segment.StartOffset,
+			Elapsed:       segment.ActualDuration,
+		}
+
+		if progress.SegmentStartedAt != nil {
+			timer.Running = true
+			timer.Elapsed = int(now.Sub(*progress.SegmentStartedAt).Seconds())
+		}
+		timer.Remaining = timer.Planned - timer.Elapsed
+
+		// Spóźnienie: faktyczny start segmentu względem planowanego offsetu
+		if timing.StartedAt != nil && segment.ActualStartedAt != nil {
+			actualOffset := int(segment.ActualStartedAt.Sub(*timing.StartedAt).Seconds())
+			state.Drift = actualOffset - segment.StartOffset
+		}
+
+		state.Segment = timer
+		break
+	}
+
+	return state, nil
+}
+
+// GetClock - GET /api/show-clock
+func (sc *ShowClock) GetClock(w http.ResponseWriter, r *http.Request) {
+	sc.writeState(w, sc.State)
+}
+
+// StartClock - POST /api/show-clock/start
+func (sc *ShowClock) StartClock(w http.ResponseWriter, r *http.Request) {
+	sc.writeState(w, func() (*ShowClockState, error) {
+		return sc.StartShow("manual", true)
+	})
+}
+
+// StopClock - POST /api/show-clock/stop
+func (sc *ShowClock) StopClock(w http.ResponseWriter, r *http.Request) {
+	sc.writeState(w, sc.StopShow)
+}
+
+// ResetClock - POST /api/show-clock/reset
+func (sc *ShowClock) ResetClock(w http.ResponseWriter, r *http.Request) {
+	sc.writeState(w, sc.ResetShow)
+}
+
+// StopSegmentTimer - POST /api/show-clock/segment/stop
+func (sc *ShowClock) StopSegmentTimer(w http.ResponseWriter, r *http.Request) {
+	sc.writeState(w, sc.StopSegment)
+}
+
+// writeState wykonuje operację na zegarze i zwraca jego stan
+func (sc *ShowClock) writeState(w http.ResponseWriter, action func() (*ShowClockState, error)) {
+	state, err := action()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
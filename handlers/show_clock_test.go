@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"obs-controller/obsws"
+	"testing"
+)
+
+func outputEvent(state string) map[string]interface{} {
+	return map[string]interface{}{"outputState": state}
+}
+
+func TestShowClockStateReportsOutputsFromEvents(t *testing.T) {
+	db := openTestDB(t)
+	createCurrentEpisode(t, db, nil)
+	sc := NewShowClock(db, &obsws.Client{}, newTestSocketHandler(t, db))
+
+	sc.handleOutputState("stream", outputEvent("OBS_WEBSOCKET_OUTPUT_STARTED"))
+	sc.handleOutputState("record", outputEvent("OBS_WEBSOCKET_OUTPUT_STARTED"))
+
+	state, err := sc.State()
+	if err != nil {
+		t.Fatalf("state: %v", err)
+	}
+	if !state.Streaming || !state.Recording || !state.Running {
+		t.Fatalf("state = streaming %v, recording %v, running %v; want all true", state.Streaming, state.Recording, state.Running)
+	}
+
+	// Zatrzymanie streamu nie kończy programu, gdy trwa nagrywanie
+	sc.handleOutputState("stream", outputEvent("OBS_WEBSOCKET_OUTPUT_STOPPED"))
+	state, _ = sc.State()
+	if state.Streaming || !state.Recording || !state.Running {
+		t.Fatalf("after stream stop: streaming %v, recording %v, running %v", state.Streaming, state.Recording, state.Running)
+	}
+
+	sc.handleOutputState("record", outputEvent("OBS_WEBSOCKET_OUTPUT_STOPPED"))
+	state, _ = sc.State()
+	if state.Recording || state.Running {
+		t.Fatalf("after record stop: recording %v, running %v; want false", state.Recording, state.Running)
+	}
+}
+
+func TestShowClockSeedKeepsCacheWhenOBSUnavailable(t *testing.T) {
+	db := openTestDB(t)
+	createCurrentEpisode(t, db, nil)
+	sc := NewShowClock(db, &obsws.Client{}, newTestSocketHandler(t, db))
+
+	sc.handleOutputState("record", outputEvent("OBS_WEBSOCKET_OUTPUT_STARTED"))
+	sc.seedOutputs()
+
+	state, _ := sc.State()
+	if !state.Recording {
+		t.Error("failed seed cleared cached recording state")
+	}
+}
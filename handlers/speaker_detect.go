@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/obsws"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	speakerRelease      = 600 * time.Millisecond // Przerwa w mowie krótsza niż to nie zeruje licznika aktywności
+	speakerMappingTTL   = 5 * time.Second        // Jak często odświeżać przypisania mikrofonów z bazy
+	speakerMinThreshold = -60.0
+)
+
+// SpeakerDetectSettings - ustawienia automatycznych pasków z nazwiskami
+type SpeakerDetectSettings struct {
+	Enabled     bool    `json:"enabled"`
+	ThresholdDb float64 `json:"threshold_db"` // Poziom, powyżej którego mikrofon jest aktywny
+	HoldMs      int     `json:"hold_ms"`      // Jak długo mikrofon musi być aktywny
+	CooldownMs  int     `json:"cooldown_ms"`  // Minimalny odstęp między automatycznymi paskami
+	DurationMs  int     `json:"duration_ms"`  // Czas wyświetlania paska
+}
+
+// speakerMic - mikrofon gościa w aktualnym odcinku
+type speakerMic struct {
+	GuestID uint
+	Visible bool // Mikrofon włączony w scenie MIKROFONY
+}
+
+// SpeakerDetector pokazuje pasek gościa, gdy jego mikrofon pierwszy raz w segmencie
+// jest aktywny (mierniki audio OBS powyżej progu przez HoldMs)
+type SpeakerDetector struct {
+	DB            *gorm.DB
+	OBSClient     *obsws.Client
+	SocketHandler *SocketHandler
+
+	settings    SpeakerDetectSettings
+	mics        map[string]speakerMic // source_name -> gość
+	micsLoaded  time.Time
+	episodeID   uint
+	activeSince map[string]time.Time // Początek aktywności mikrofonu
+	lastAbove   map[string]time.Time // Ostatni pomiar powyżej progu
+	shown       map[uint]bool        // Goście z paskiem w bieżącym segmencie
+	lastShown   time.Time
+	mu          sync.Mutex
+}
+
+func NewSpeakerDetector(db *gorm.DB, obsClient *obsws.Client, socketHandler *SocketHandler) *SpeakerDetector {
+	return &SpeakerDetector{
+		DB:            db,
+		OBSClient:     obsClient,
+		SocketHandler: socketHandler,
+		settings: SpeakerDetectSettings{
+			Enabled:     false,
+			ThresholdDb: -30,
+			HoldMs:      1500,
+			CooldownMs:  20000,
+			DurationMs:  lowerThirdDefaultDuration,
+		},
+		activeSince: make(map[string]time.Time),
+		lastAbove:   make(map[string]time.Time),
+		shown:       make(map[uint]bool),
+	}
+}
+
+// Start podpina się pod mierniki audio OBS
+func (sd *SpeakerDetector) Start() {
+	sd.OBSClient.OnEvent("InputVolumeMeters", sd.handleMeters)
+	log.Println("Speaker Detector gotowy (domyślnie wyłączony)")
+}
+
+// Settings zwraca aktualne ustawienia
+func (sd *SpeakerDetector) Settings() SpeakerDetectSettings {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.settings
+}
+
+// UpdateSettings zmienia ustawienia i (wy)łącza subskrypcję mierników w OBS
+func (sd *SpeakerDetector) UpdateSettings(settings SpeakerDetectSettings) (SpeakerDetectSettings, error) {
+	if settings.ThresholdDb > 0 || settings.ThresholdDb < speakerMinThreshold {
+		return settings, fmt.Errorf("threshold_db musi być w zakresie %.0f..0", speakerMinThreshold)
+	}
+	if settings.HoldMs < 0 || settings.CooldownMs < 0 {
+		return settings, fmt.Errorf("hold_ms i cooldown_ms nie mogą być ujemne")
+	}
+	if settings.DurationMs <= 0 {
+		settings.DurationMs = lowerThirdDefaultDuration
+	}
+
+	sd.mu.Lock()
+	wasEnabled := sd.settings.Enabled
+	sd.settings = settings
+	sd.activeSince = make(map[string]time.Time)
+	sd.lastAbove = make(map[string]time.Time)
+	sd.micsLoaded = time.Time{}
+	sd.mu.Unlock()
+
+	if settings.Enabled != wasEnabled {
+		if err := sd.OBSClient.SetVolumeMetersEnabled(settings.Enabled); err != nil {
+			return settings, err
+		}
+		log.Printf("Automatyczne paski: %v", settings.Enabled)
+	}
+
+	sd.SocketHandler.Broadcast("speaker_detect_settings", settings)
+	return settings, nil
+}
+
+// SegmentStarted - nowy segment, każdy gość może znów dostać pasek
+func (sd *SpeakerDetector) SegmentStarted() {
+	sd.mu.Lock()
+	sd.shown = make(map[uint]bool)
+	sd.mu.Unlock()
+}
+
+// handleMeters - event InputVolumeMeters: {inputs: [{inputName, inputLevelsMul: [[magnitude, peak, inputPeak], ...]}]}
+func (sd *SpeakerDetector) handleMeters(event map[string]interface{}) {
+	inputs, ok := event["inputs"].([]interface{})
+	if !ok {
+		return
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if !sd.settings.Enabled {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(sd.micsLoaded) > speakerMappingTTL {
+		sd.loadMicrophones()
+		sd.micsLoaded = now
+	}
+
+	for _, raw := range inputs {
+		input, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		inputName, _ := input["inputName"].(string)
+		mic, ok := sd.mics[inputName]
+		if !ok {
+			continue
+		}
+
+		if meterLevelDb(input["inputLevelsMul"]) >= sd.settings.ThresholdDb && mic.Visible {
+			sd.lastAbove[inputName] = now
+			if _, active := sd.activeSince[inputName]; !active {
+				sd.activeSince[inputName] = now
+			}
+		} else if now.Sub(sd.lastAbove[inputName]) > speakerRelease {
+			delete(sd.activeSince, inputName)
+			continue
+		}
+
+		since, active := sd.activeSince[inputName]
+		if !active || now.Sub(since) < time.Duration(sd.settings.HoldMs)*time.Millisecond {
+			continue
+		}
+		if sd.shown[mic.GuestID] || now.Sub(sd.lastShown) < time.Duration(sd.settings.CooldownMs)*time.Millisecond {
+			continue
+		}
+		if sd.SocketHandler.LowerThirds == nil || sd.SocketHandler.LowerThirds.Current(OverlayDefaultChannel) != nil {
+			continue // Inny pasek jest na antenie
+		}
+
+		sd.shown[mic.GuestID] = true
+		sd.lastShown = now
+		go sd.show(inputName, sd.settings.DurationMs)
+	}
+}
+
+// show pokazuje pasek poza blokadą (Show odpytuje bazę i broadcastuje)
+func (sd *SpeakerDetector) show(sourceName string, durationMs int) {
+	if _, err := sd.SocketHandler.LowerThirds.Show(LowerThirdRequest{
+		SourceName: sourceName,
+		DurationMs: durationMs,
+	}); err != nil {
+		log.Printf("Automatyczny pasek %s: %v", sourceName, err)
+		return
+	}
+	log.Printf("Automatyczny pasek dla %s", sourceName)
+}
+
+// loadMicrophones odświeża przypisania gości do mikrofonów aktualnego odcinka
+// Zmiana odcinka zeruje listę gości, którzy już mieli pasek
+func (sd *SpeakerDetector) loadMicrophones() {
+	sd.mics = make(map[string]speakerMic)
+
+	episode, err := models.GetCurrentEpisode(sd.DB)
+	if err != nil {
+		return
+	}
+	if episode.ID != sd.episodeID {
+		sd.episodeID = episode.ID
+		sd.shown = make(map[uint]bool)
+	}
+
+	var sources []models.EpisodeSource
+	if err := sd.DB.Where("episode_id = ? AND guest_id IS NOT NULL", episode.ID).Find(&sources).Error; err != nil {
+		return
+	}
+
+	visible := make(map[string]bool)
+	var scene models.Scene
+	if err := sd.DB.Where("name = ?", rundownMicScene).First(&scene).Error; err == nil {
+		var micSources []models.Source
+		sd.DB.Where("scene_id = ?", scene.ID).Find(&micSources)
+		for _, source := range micSources {
+			visible[source.Name] = source.IsVisible
+		}
+	}
+
+	for _, source := range sources {
+		sd.mics[source.SourceName] = speakerMic{
+			GuestID: *source.GuestID,
+			Visible: visible[source.SourceName],
+		}
+	}
+}
+
+// meterLevelDb zwraca najwyższy poziom (magnitude) ze wszystkich kanałów w dB
+func meterLevelDb(levels interface{}) float64 {
+	channels, ok := levels.([]interface{})
+	if !ok {
+		return math.Inf(-1)
+	}
+
+	maxMul := 0.0
+	for _, raw := range channels {
+		channel, ok := raw.([]interface{})
+		if !ok || len(channel) == 0 {
+			continue
+		}
+		if magnitude, ok := channel[0].(float64); ok && magnitude > maxMul {
+			maxMul = magnitude
+		}
+	}
+
+	if maxMul <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(maxMul)
+}
+
+// SpeakerDetectHandler - ustawienia automatycznych pasków przez REST
+type SpeakerDetectHandler struct {
+	Detector *SpeakerDetector
+}
+
+func NewSpeakerDetectHandler(detector *SpeakerDetector) *SpeakerDetectHandler {
+	return &SpeakerDetectHandler{Detector: detector}
+}
+
+// GetSettings - GET /api/lower-thirds/auto
+func (h *SpeakerDetectHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Detector.Settings())
+}
+
+// UpdateSettings - PUT /api/lower-thirds/auto
+func (h *SpeakerDetectHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	settings := h.Detector.Settings()
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.Detector.UpdateSettings(settings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
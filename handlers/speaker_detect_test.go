@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"obs-controller/obsws"
+	"testing"
+	"time"
+)
+
+// meterEvent buduje InputVolumeMeters z jednym kanałem o podanej amplitudzie
+func meterEvent(levels map[string]float64) map[string]interface{} {
+	inputs := make([]interface{}, 0, len(levels))
+	for name, level := range levels {
+		inputs = append(inputs, map[string]interface{}{
+			"inputName":      name,
+			"inputLevelsMul": []interface{}{[]interface{}{level, level, level}},
+		})
+	}
+	return map[string]interface{}{"inputs": inputs}
+}
+
+func newTestSpeakerDetector(t *testing.T, hold, cooldown time.Duration) *SpeakerDetector {
+	t.Helper()
+	db := openTestDB(t)
+	sh := newTestSocketHandler(t, db)
+	sh.LowerThirds = NewLowerThirds(db, sh)
+	sd := NewSpeakerDetector(db, &obsws.Client{}, sh)
+	sd.settings.Enabled = true
+	sd.settings.HoldMs = int(hold.Milliseconds())
+	sd.settings.CooldownMs = int(cooldown.Milliseconds())
+	sd.settings.DurationMs = 1000
+	sd.mics = map[string]speakerMic{
+		"Mic1": {GuestID: 1, Visible: true},
+		"Mic2": {GuestID: 2, Visible: true},
+		"Mic3": {GuestID: 3, Visible: false},
+	}
+	sd.micsLoaded = time.Now().Add(time.Hour) // Bez odświeżania z bazy
+	return sd
+}
+
+func (sd *SpeakerDetector) shownFor(guestID uint) bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.shown[guestID]
+}
+
+func TestSpeakerDetectWaitsForHold(t *testing.T) {
+	sd := newTestSpeakerDetector(t, 100*time.Millisecond, 0)
+
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5, "Mic3": 0.5}))
+	if sd.shownFor(1) {
+		t.Fatal("strap shown before hold elapsed")
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5, "Mic3": 0.5}))
+	if !sd.shownFor(1) {
+		t.Error("strap not shown after continuous activity longer than hold")
+	}
+	if sd.shownFor(3) {
+		t.Error("strap shown for a microphone that is off in the mic scene")
+	}
+}
+
+func TestSpeakerDetectSilenceResetsHold(t *testing.T) {
+	sd := newTestSpeakerDetector(t, 100*time.Millisecond, 0)
+
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5}))
+	time.Sleep(speakerRelease + 50*time.Millisecond)
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0}))
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5}))
+	if sd.shownFor(1) {
+		t.Error("activity before a long pause counted towards hold")
+	}
+}
+
+func TestSpeakerDetectCooldownBetweenStraps(t *testing.T) {
+	const cooldown = 300 * time.Millisecond
+	sd := newTestSpeakerDetector(t, 0, cooldown)
+
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5}))
+	if !sd.shownFor(1) {
+		t.Fatal("first strap not shown")
+	}
+
+	sd.handleMeters(meterEvent(map[string]float64{"Mic2": 0.5}))
+	if sd.shownFor(2) {
+		t.Fatal("second strap shown within cooldown")
+	}
+
+	time.Sleep(cooldown + 50*time.Millisecond)
+	sd.handleMeters(meterEvent(map[string]float64{"Mic2": 0.5}))
+	if !sd.shownFor(2) {
+		t.Error("second strap not shown after cooldown")
+	}
+
+	// Ten sam gość nie dostaje drugiego paska w segmencie, dopiero po SegmentStarted
+	time.Sleep(cooldown + 50*time.Millisecond)
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5}))
+	sd.mu.Lock()
+	repeated := sd.lastShown.After(time.Now().Add(-cooldown))
+	sd.mu.Unlock()
+	if repeated {
+		t.Fatal("same guest got a second strap in one segment")
+	}
+	sd.SegmentStarted()
+	sd.handleMeters(meterEvent(map[string]float64{"Mic1": 0.5}))
+	if !sd.shownFor(1) {
+		t.Error("strap not shown again in a new segment")
+	}
+}
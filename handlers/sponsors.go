@@ -0,0 +1,738 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"obs-controller/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Folder logotypów sponsorów (media/.sponsors - pomijany przez porządkowanie mediów)
+const sponsorsFolder = ".sponsors"
+
+// Dawny folder logotypów, traktowany przez porządkowanie mediów jak folder sezonu
+const legacySponsorsFolder = "sponsors"
+
+// Najkrótsze dozwolone wyświetlenie logo sponsora
+const sponsorMinDisplayFloor = 1000
+
+// Co ile ponawiać rotację, gdy nie ma kogo pokazać (brak sponsorów z logo)
+const sponsorRetryInterval = 5 * time.Second
+
+// Dozwolone pliki logotypów sponsorów
+var sponsorLogoExtensions = []string{".png", ".svg", ".webp", ".jpg", ".jpeg"}
+
+// SponsorRotationRequest - parametry rotacji (socket, REST, makra)
+type SponsorRotationRequest struct {
+	Output     string `json:"output"`      // overlay (domyślnie) lub obs
+	Channel    string `json:"channel"`     // overlay: kanał (pusty = main)
+	SceneName  string `json:"scene_name"`  // obs: scena ze źródłem obrazu
+	SourceName string `json:"source_name"` // obs: źródło obrazu (image_source)
+}
+
+// SponsorOnAir - logo sponsora aktualnie na antenie
+type SponsorOnAir struct {
+	SponsorID uint      `json:"sponsor_id"`
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+	NextAt    time.Time `json:"next_at"` // Najbliższa zmiana logo
+}
+
+// SponsorRotationState - stan rotacji sponsorów
+type SponsorRotationState struct {
+	Running   bool          `json:"running"`
+	Output    string        `json:"output"`
+	Target    string        `json:"target"` // Kanał overlayu lub źródło OBS
+	EpisodeID uint          `json:"episode_id"`
+	Current   *SponsorOnAir `json:"current"`
+}
+
+// SponsorRotation pokazuje logotypy sponsorów na zmianę, tak by czas na antenie w odcinku
+// dążył do udziałów z wag (najpierw sponsor z najmniejszym czasem/wagą), i zapisuje
+// faktyczny czas każdego wyświetlenia w SponsorAirtime
+type SponsorRotation struct {
+	DB            *gorm.DB
+	SocketHandler *SocketHandler
+	MediaPath     string
+
+	running   bool
+	request   SponsorRotationRequest
+	episodeID uint
+	current   *SponsorOnAir
+	stop      chan struct{}
+	mu        sync.Mutex
+}
+
+func NewSponsorRotation(db *gorm.DB, socketHandler *SocketHandler, mediaPath string) *SponsorRotation {
+	return &SponsorRotation{
+		DB:            db,
+		SocketHandler: socketHandler,
+		MediaPath:     mediaPath,
+	}
+}
+
+// validate sprawdza parametry rotacji i uzupełnia wartości domyślne
+func (req *SponsorRotationRequest) validate(sh *SocketHandler) error {
+	switch req.Output {
+	case "", models.SponsorOutputOverlay:
+		req.Output = models.SponsorOutputOverlay
+		if sh.Overlay == nil {
+			return fmt.Errorf("Overlay niedostępny")
+		}
+		channel, err := overlayChannelName(req.Channel)
+		if err != nil {
+			return err
+		}
+		req.Channel = channel
+	case models.SponsorOutputOBS:
+		if sh.OBSClient == nil {
+			return fmt.Errorf("OBS nie jest połączony")
+		}
+		if req.SceneName == "" || req.SourceName == "" {
+			return fmt.Errorf("scene_name i source_name są wymagane dla wyjścia obs")
+		}
+	default:
+		return fmt.Errorf("Nieznane wyjście: %s", req.Output)
+	}
+	return nil
+}
+
+// target zwraca nazwę celu do dziennika czasu antenowego
+func (req *SponsorRotationRequest) target() string {
+	if req.Output == models.SponsorOutputOBS {
+		return req.SourceName
+	}
+	return req.Channel
+}
+
+// Start uruchamia rotację dla aktualnego odcinka (trwająca rotacja zostaje zastąpiona)
+func (sr *SponsorRotation) Start(req SponsorRotationRequest) (*SponsorRotationState, error) {
+	if err := req.validate(sr.SocketHandler); err != nil {
+		return nil, err
+	}
+
+	episode, err := models.GetCurrentEpisode(sr.DB)
+	if err != nil {
+		return nil, fmt.Errorf("Brak aktualnego odcinka")
+	}
+
+	sr.mu.Lock()
+	if sr.running {
+		sr.stopLocked()
+	}
+	sr.running = true
+	sr.request = req
+	sr.episodeID = episode.ID
+	sr.stop = make(chan struct{})
+	go sr.rotate(sr.stop)
+	sr.mu.Unlock()
+
+	log.Printf("Sponsorzy: start rotacji (odcinek %d, %s: %s)", episode.ID, req.Output, req.target())
+	return sr.State(), nil
+}
+
+// Stop kończy rotację i zdejmuje logo z anteny
+func (sr *SponsorRotation) Stop() *SponsorRotationState {
+	sr.mu.Lock()
+	if sr.running {
+		sr.stopLocked()
+		log.Println("Sponsorzy: stop rotacji")
+	}
+	sr.mu.Unlock()
+
+	state := sr.State()
+	sr.SocketHandler.Broadcast("sponsor_rotation_state", state)
+	return state
+}
+
+// stopLocked zamyka bieżące wyświetlenie, zdejmuje logo i zatrzymuje pętlę (wywoływać pod sr.mu)
+func (sr *SponsorRotation) stopLocked() {
+	close(sr.stop)
+	sr.closeCurrent(time.Now())
+	sr.hideLocked()
+	sr.running = false
+}
+
+// hideLocked zdejmuje logo z wyjścia rotacji (wywoływać pod sr.mu)
+func (sr *SponsorRotation) hideLocked() {
+	sh := sr.SocketHandler
+	if sr.request.Output == models.SponsorOutputOBS {
+		if sh.OBSClient != nil {
+			if err := sh.OBSClient.SetSourceVisibility(sr.request.SceneName, sr.request.SourceName, false); err != nil {
+				log.Printf("Sponsorzy: błąd ukrycia %s: %v", sr.request.SourceName, err)
+			}
+		}
+	} else if sh.Overlay != nil {
+		sh.Overlay.Hide(sr.request.Channel, OverlayTemplateSponsor)
+	}
+}
+
+// State zwraca stan rotacji
+func (sr *SponsorRotation) State() *SponsorRotationState {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.stateLocked()
+}
+
+func (sr *SponsorRotation) stateLocked() *SponsorRotationState {
+	state := &SponsorRotationState{Running: sr.running}
+	if sr.running {
+		state.Output = sr.request.Output
+		state.Target = sr.request.target()
+		state.EpisodeID = sr.episodeID
+		if sr.current != nil {
+			current := *sr.current
+			state.Current = &current
+		}
+	}
+	return state
+}
+
+// rotate zmienia logo co MinDisplayMs bieżącego sponsora aż do zatrzymania
+func (sr *SponsorRotation) rotate(stop chan struct{}) {
+	for {
+		delay := sr.next(stop)
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// next zamyka bieżące wyświetlenie i pokazuje kolejnego sponsora; zwraca czas do następnej zmiany
+func (sr *SponsorRotation) next(stop chan struct{}) time.Duration {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	select {
+	case <-stop:
+		return 0 // Zatrzymana w międzyczasie (Stop lub nowy Start)
+	default:
+	}
+
+	now := time.Now()
+	sh := sr.SocketHandler
+
+	// Logo zdjęte z overlayu ręcznie - koniec rotacji (czas liczony do teraz)
+	if sr.current != nil && sr.request.Output == models.SponsorOutputOverlay &&
+		sh.Overlay.Get(sr.request.Channel, OverlayTemplateSponsor) == nil {
+		log.Println("Sponsorzy: logo zdjęte z overlayu - koniec rotacji")
+		sr.closeCurrent(now)
+		close(sr.stop)
+		sr.running = false
+		sh.Broadcast("sponsor_rotation_state", sr.stateLocked())
+		return 0
+	}
+
+	previous := sr.closeCurrent(now)
+
+	sponsor, err := sr.pick(previous)
+	if err == nil && sponsor != nil {
+		err = sr.show(sponsor, previous)
+	}
+	if err != nil || sponsor == nil {
+		if err != nil {
+			log.Printf("Sponsorzy: %v", err)
+		}
+		// Nie ma kogo pokazać - logo nie może zostać na antenie bez liczenia czasu
+		if previous != 0 {
+			sr.hideLocked()
+			sh.Broadcast("sponsor_rotation_state", sr.stateLocked())
+		}
+		return sponsorRetryInterval
+	}
+
+	display := time.Duration(sponsor.MinDisplayMs) * time.Millisecond
+	if sponsor.MinDisplayMs < sponsorMinDisplayFloor {
+		display = sponsorMinDisplayFloor * time.Millisecond
+	}
+	sr.current = &SponsorOnAir{
+		SponsorID: sponsor.ID,
+		Name:      sponsor.Name,
+		StartedAt: now,
+		NextAt:    now.Add(display),
+	}
+
+	sh.Broadcast("sponsor_rotation_state", sr.stateLocked())
+	return display
+}
+
+// pick wybiera sponsora z najmniejszym czasem na antenie w odcinku względem wagi
+// Przy remisie pierwszeństwo ma inny niż poprzedni, potem niższe ID
+func (sr *SponsorRotation) pick(previous uint) (*models.Sponsor, error) {
+	var sponsors []models.Sponsor
+	if err := sr.DB.Where("enabled = ? AND weight > 0 AND logo_file <> ''", true).
+		Order("id ASC").Find(&sponsors).Error; err != nil {
+		return nil, err
+	}
+	if len(sponsors) == 0 {
+		return nil, nil
+	}
+
+	totals, err := models.GetSponsorAirtimeTotals(sr.DB, sr.episodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(sponsors, func(i, j int) bool {
+		a, b := sponsors[i], sponsors[j]
+		// Porównanie totals[a]/weight[a] < totals[b]/weight[b] bez dzielenia
+		left := totals[a.ID] * int64(b.Weight)
+		right := totals[b.ID] * int64(a.Weight)
+		if left != right {
+			return left < right
+		}
+		if (a.ID == previous) != (b.ID == previous) {
+			return b.ID == previous
+		}
+		return a.ID < b.ID
+	})
+	return &sponsors[0], nil
+}
+
+// show wysyła logo sponsora na wyjście rotacji (wywoływać pod sr.mu)
+func (sr *SponsorRotation) show(sponsor *models.Sponsor, previous uint) error {
+	sh := sr.SocketHandler
+
+	if sr.request.Output == models.SponsorOutputOBS {
+		file, err := filepath.Abs(sr.logoPath(sponsor.LogoFile))
+		if err != nil {
+			return err
+		}
+		if err := sh.OBSClient.UpdateInputSettings(sr.request.SourceName, map[string]interface{}{"file": file}); err != nil {
+			return err
+		}
+		if previous == 0 {
+			return sh.OBSClient.SetSourceVisibility(sr.request.SceneName, sr.request.SourceName, true)
+		}
+		return nil
+	}
+
+	// file - ścieżka dla natywnego overlayu (źródło obrazu z formatem "{file}")
+	file, _ := filepath.Abs(sr.logoPath(sponsor.LogoFile))
+	_, err := sh.Overlay.Show(sr.request.Channel, OverlayTemplateSponsor, map[string]interface{}{
+		"src":        fmt.Sprintf("/api/sponsors/%d/logo?file=%s", sponsor.ID, url.QueryEscape(sponsor.LogoFile)),
+		"file":       file,
+		"name":       sponsor.Name,
+		"sponsor_id": sponsor.ID,
+	}, -1)
+	return err
+}
+
+// closeCurrent zapisuje czas bieżącego wyświetlenia; zwraca ID sponsora (0 = nic nie było)
+// Wywoływać pod sr.mu
+func (sr *SponsorRotation) closeCurrent(now time.Time) uint {
+	if sr.current == nil {
+		return 0
+	}
+	current := sr.current
+	sr.current = nil
+
+	entry := models.SponsorAirtime{
+		SponsorID:  current.SponsorID,
+		EpisodeID:  sr.episodeID,
+		Output:     sr.request.Output,
+		Target:     sr.request.target(),
+		StartedAt:  current.StartedAt,
+		EndedAt:    now,
+		DurationMs: now.Sub(current.StartedAt).Milliseconds(),
+	}
+	if err := sr.DB.Create(&entry).Error; err != nil {
+		log.Printf("Sponsorzy: błąd zapisu czasu antenowego %s: %v", current.Name, err)
+	}
+	return current.SponsorID
+}
+
+// logoPath zwraca ścieżkę pliku logo sponsora
+func (sr *SponsorRotation) logoPath(fileName string) string {
+	return filepath.Join(sr.MediaPath, sponsorsFolder, fileName)
+}
+
+// sponsorRequest - dane sponsora (tworzenie i edycja)
+type sponsorRequest struct {
+	Name         string `json:"name"`
+	Weight       *int   `json:"weight"`         // Pominięte = 1
+	MinDisplayMs *int   `json:"min_display_ms"` // Pominięte = 10000
+	Enabled      *bool  `json:"enabled"`        // Pominięte = włączony
+}
+
+// validate sprawdza dane sponsora
+func (req *sponsorRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if req.Weight != nil && *req.Weight < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	if req.MinDisplayMs != nil && *req.MinDisplayMs < sponsorMinDisplayFloor {
+		return fmt.Errorf("min_display_ms must be at least %d", sponsorMinDisplayFloor)
+	}
+	return nil
+}
+
+// SponsorAirtimeReport - czas antenowy sponsora w odcinku
+type SponsorAirtimeReport struct {
+	SponsorID   uint    `json:"sponsor_id"`
+	Name        string  `json:"name"`
+	Weight      int     `json:"weight"`
+	Displays    int     `json:"displays"`
+	DurationMs  int64   `json:"duration_ms"`
+	Share       float64 `json:"share"`        // Faktyczny udział w czasie logotypów (%)
+	TargetShare float64 `json:"target_share"` // Udział wynikający z wag (%)
+}
+
+type SponsorHandler struct {
+	DB        *gorm.DB
+	MediaPath string
+	Rotation  *SponsorRotation
+}
+
+func NewSponsorHandler(db *gorm.DB, mediaPath string, rotation *SponsorRotation) *SponsorHandler {
+	migrateSponsorsFolder(mediaPath)
+	return &SponsorHandler{DB: db, MediaPath: mediaPath, Rotation: rotation}
+}
+
+// migrateSponsorsFolder przenosi logotypy z media/sponsors do media/.sponsors (migracja)
+func migrateSponsorsFolder(mediaPath string) {
+	legacyDir := filepath.Join(mediaPath, legacySponsorsFolder)
+	targetDir := filepath.Join(mediaPath, sponsorsFolder)
+
+	if _, err := os.Stat(legacyDir); err != nil {
+		return
+	}
+	if _, err := os.Stat(targetDir); err == nil {
+		log.Printf("Sponsorzy: istnieją oba foldery %s i %s - pozostawiono bez zmian", legacyDir, targetDir)
+		return
+	}
+
+	if err := os.Rename(legacyDir, targetDir); err != nil {
+		log.Printf("Sponsorzy: błąd przenoszenia logotypów do %s: %v", targetDir, err)
+		return
+	}
+	log.Printf("Sponsorzy: przeniesiono logotypy do %s", targetDir)
+}
+
+// GetSponsors - GET /api/sponsors
+func (h *SponsorHandler) GetSponsors(w http.ResponseWriter, r *http.Request) {
+	var sponsors []models.Sponsor
+	if err := h.DB.Order("name ASC").Find(&sponsors).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sponsors)
+}
+
+// CreateSponsor - POST /api/sponsors
+func (h *SponsorHandler) CreateSponsor(w http.ResponseWriter, r *http.Request) {
+	var req sponsorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sponsor := models.Sponsor{
+		Name:         req.Name,
+		Weight:       1,
+		MinDisplayMs: 10000,
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	if req.Weight != nil {
+		sponsor.Weight = *req.Weight
+	}
+	if req.MinDisplayMs != nil {
+		sponsor.MinDisplayMs = *req.MinDisplayMs
+	}
+	if err := h.DB.Create(&sponsor).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sponsor)
+}
+
+// UpdateSponsor - PUT /api/sponsors/{id}
+// Zmiany wag i czasów działają od następnej zmiany logo w rotacji
+func (h *SponsorHandler) UpdateSponsor(w http.ResponseWriter, r *http.Request) {
+	sponsor, ok := h.loadSponsor(w, r)
+	if !ok {
+		return
+	}
+
+	var req sponsorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sponsor.Name = req.Name
+	if req.Weight != nil {
+		sponsor.Weight = *req.Weight
+	}
+	if req.MinDisplayMs != nil {
+		sponsor.MinDisplayMs = *req.MinDisplayMs
+	}
+	sponsor.Enabled = req.Enabled == nil || *req.Enabled
+
+	if err := h.DB.Save(&sponsor).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sponsor)
+}
+
+// DeleteSponsor - DELETE /api/sponsors/{id}
+// Zapisany czas antenowy zostaje (raporty wcześniejszych odcinków)
+func (h *SponsorHandler) DeleteSponsor(w http.ResponseWriter, r *http.Request) {
+	sponsor, ok := h.loadSponsor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&sponsor).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UploadLogo - POST /api/sponsors/{id}/logo (multipart: file)
+func (h *SponsorHandler) UploadLogo(w http.ResponseWriter, r *http.Request) {
+	sponsor, ok := h.loadSponsor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(20 << 20); err != nil { // 20 MB max
+		http.Error(w, "File too large", http.StatusBadRequest)
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	fileName := filepath.Base(handler.Filename)
+	if !hasExtension(fileName, sponsorLogoExtensions) {
+		http.Error(w, fmt.Sprintf("Unsupported file type, allowed: %v", sponsorLogoExtensions), http.StatusBadRequest)
+		return
+	}
+
+	targetDir := filepath.Join(h.MediaPath, sponsorsFolder)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		http.Error(w, "Error creating directory", http.StatusInternalServerError)
+		return
+	}
+
+	fileName = uniqueFileName(targetDir, fileName)
+	dst, err := os.Create(filepath.Join(targetDir, fileName))
+	if err != nil {
+		http.Error(w, "Error saving file", http.StatusInternalServerError)
+		return
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		http.Error(w, "Error copying file", http.StatusInternalServerError)
+		return
+	}
+
+	sponsor.LogoFile = fileName
+	if err := h.DB.Save(&sponsor).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sponsor)
+}
+
+// GetLogo - GET /api/sponsors/{id}/logo
+func (h *SponsorHandler) GetLogo(w http.ResponseWriter, r *http.Request) {
+	sponsor, ok := h.loadSponsor(w, r)
+	if !ok {
+		return
+	}
+
+	if sponsor.LogoFile == "" {
+		http.Error(w, "Logo not found", http.StatusNotFound)
+		return
+	}
+
+	path := filepath.Join(h.MediaPath, sponsorsFolder, sponsor.LogoFile)
+	if _, err := os.Stat(path); err != nil {
+		http.Error(w, "Logo not found", http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// GetRotation - GET /api/sponsors/rotation
+func (h *SponsorHandler) GetRotation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Rotation.State())
+}
+
+// StartRotation - POST /api/sponsors/rotation/start
+// Body (opcjonalne): {"output": "overlay", "channel": "main"} lub {"output": "obs", "scene_name": "...", "source_name": "..."}
+func (h *SponsorHandler) StartRotation(w http.ResponseWriter, r *http.Request) {
+	var req SponsorRotationRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	state, err := h.Rotation.Start(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// StopRotation - POST /api/sponsors/rotation/stop
+func (h *SponsorHandler) StopRotation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Rotation.Stop())
+}
+
+// GetAirtime - GET /api/episodes/{episode_id}/sponsors/airtime
+// Faktyczny czas logotypów na antenie per sponsor (z trwającym wyświetleniem) i udział docelowy z wag
+func (h *SponsorHandler) GetAirtime(w http.ResponseWriter, r *http.Request) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	var rows []struct {
+		SponsorID uint
+		Displays  int
+		Total     int64
+	}
+	if err := h.DB.Model(&models.SponsorAirtime{}).
+		Select("sponsor_id, COUNT(*) AS displays, SUM(duration_ms) AS total").
+		Where("episode_id = ?", episodeID).
+		Group("sponsor_id").
+		Scan(&rows).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reports := make(map[uint]*SponsorAirtimeReport)
+	for _, row := range rows {
+		reports[row.SponsorID] = &SponsorAirtimeReport{SponsorID: row.SponsorID, Displays: row.Displays, DurationMs: row.Total}
+	}
+
+	// Trwające wyświetlenie (zapisywane dopiero przy zmianie logo)
+	if state := h.Rotation.State(); state.Running && state.EpisodeID == uint(episodeID) && state.Current != nil {
+		report, ok := reports[state.Current.SponsorID]
+		if !ok {
+			report = &SponsorAirtimeReport{SponsorID: state.Current.SponsorID}
+			reports[state.Current.SponsorID] = report
+		}
+		report.Displays++
+		report.DurationMs += time.Since(state.Current.StartedAt).Milliseconds()
+	}
+
+	// Udział docelowy tylko wśród sponsorów biorących udział w rotacji
+	var sponsors []models.Sponsor
+	h.DB.Find(&sponsors)
+	totalWeight := 0
+	inRotation := make(map[uint]bool, len(sponsors))
+	for _, sponsor := range sponsors {
+		inRotation[sponsor.ID] = sponsor.Enabled && sponsor.LogoFile != "" && sponsor.Weight > 0
+		report, ok := reports[sponsor.ID]
+		if !ok && !inRotation[sponsor.ID] {
+			continue
+		}
+		if !ok {
+			report = &SponsorAirtimeReport{SponsorID: sponsor.ID}
+			reports[sponsor.ID] = report
+		}
+		report.Name = sponsor.Name
+		report.Weight = sponsor.Weight
+		if inRotation[sponsor.ID] {
+			totalWeight += sponsor.Weight
+		}
+	}
+
+	var totalMs int64
+	for _, report := range reports {
+		totalMs += report.DurationMs
+	}
+
+	result := make([]SponsorAirtimeReport, 0, len(reports))
+	for _, report := range reports {
+		if totalMs > 0 {
+			report.Share = float64(report.DurationMs) * 100 / float64(totalMs)
+		}
+		if totalWeight > 0 && inRotation[report.SponsorID] {
+			report.TargetShare = float64(report.Weight) * 100 / float64(totalWeight)
+		}
+		result = append(result, *report)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].DurationMs != result[j].DurationMs {
+			return result[i].DurationMs > result[j].DurationMs
+		}
+		return result[i].SponsorID < result[j].SponsorID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// loadSponsor pobiera sponsora z {id}
+func (h *SponsorHandler) loadSponsor(w http.ResponseWriter, r *http.Request) (models.Sponsor, bool) {
+	var sponsor models.Sponsor
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return sponsor, false
+	}
+
+	if err := h.DB.First(&sponsor, id).Error; err != nil {
+		http.Error(w, "Sponsor not found", http.StatusNotFound)
+		return sponsor, false
+	}
+	return sponsor, true
+}
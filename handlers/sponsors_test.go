@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"obs-controller/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSponsorLogosAreNotMediaOrphans(t *testing.T) {
+	db := openTestDB(t)
+	mediaPath := t.TempDir()
+
+	// Logo w dawnym folderze zostaje przeniesione do folderu pomijanego przez porządkowanie
+	os.MkdirAll(filepath.Join(mediaPath, legacySponsorsFolder), 0755)
+	os.WriteFile(filepath.Join(mediaPath, legacySponsorsFolder, "logo.png"), []byte("png"), 0644)
+
+	NewSponsorHandler(db, mediaPath, nil)
+
+	if _, err := os.Stat(filepath.Join(mediaPath, sponsorsFolder, "logo.png")); err != nil {
+		t.Fatalf("logo not migrated: %v", err)
+	}
+
+	report, err := buildMediaGCReport(db, mediaPath)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(report.Orphans) != 0 {
+		t.Errorf("sponsor logos reported as orphans: %v", report.Orphans)
+	}
+}
+
+func TestCreateSponsorKeepsZeroValues(t *testing.T) {
+	db := openTestDB(t)
+	h := NewSponsorHandler(db, t.TempDir(), nil)
+
+	tests := []struct {
+		body        string
+		wantWeight  int
+		wantEnabled bool
+	}{
+		{`{"name":"Domyślny"}`, 1, true},
+		{`{"name":"Waga zero","weight":0}`, 0, true},
+		{`{"name":"Wyłączony","enabled":false}`, 1, false},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.CreateSponsor(rec, httptest.NewRequest("POST", "/api/sponsors", strings.NewReader(tt.body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: status = %d: %s", tt.body, rec.Code, rec.Body.String())
+		}
+
+		var created models.Sponsor
+		json.NewDecoder(rec.Body).Decode(&created)
+
+		var loaded models.Sponsor
+		db.First(&loaded, created.ID)
+		if loaded.Weight != tt.wantWeight || loaded.Enabled != tt.wantEnabled {
+			t.Errorf("%s: stored weight=%d enabled=%v, want weight=%d enabled=%v",
+				tt.body, loaded.Weight, loaded.Enabled, tt.wantWeight, tt.wantEnabled)
+		}
+	}
+}
+
+func TestSponsorPickBalancesAirtimeByWeight(t *testing.T) {
+	db := openTestDB(t)
+	sr := NewSponsorRotation(db, nil, t.TempDir())
+	sr.episodeID = 1
+
+	newSponsor := func(name string, weight int, enabled bool, logo string) models.Sponsor {
+		sponsor := models.Sponsor{Name: name, Weight: weight, Enabled: enabled, LogoFile: logo}
+		if err := db.Create(&sponsor).Error; err != nil {
+			t.Fatalf("create sponsor: %v", err)
+		}
+		return sponsor
+	}
+	light := newSponsor("Lekki", 1, true, "a.png")
+	heavy := newSponsor("Ciężki", 3, true, "b.png")
+	newSponsor("Waga zero", 0, true, "c.png")
+	newSponsor("Wyłączony", 5, false, "d.png")
+	newSponsor("Bez logo", 5, true, "")
+
+	airtime := func(sponsorID uint, ms int64) {
+		db.Create(&models.SponsorAirtime{SponsorID: sponsorID, EpisodeID: 1, Output: models.SponsorOutputOverlay, DurationMs: ms})
+	}
+	pick := func(previous uint) uint {
+		t.Helper()
+		sponsor, err := sr.pick(previous)
+		if err != nil || sponsor == nil {
+			t.Fatalf("pick: %v, %v", sponsor, err)
+		}
+		return sponsor.ID
+	}
+
+	// Remis bez czasu antenowego: niższe ID, a po nim inny niż poprzedni
+	if got := pick(0); got != light.ID {
+		t.Errorf("empty log: picked %d, want %d", got, light.ID)
+	}
+	if got := pick(light.ID); got != heavy.ID {
+		t.Errorf("tie after %d: picked %d, want %d", light.ID, got, heavy.ID)
+	}
+
+	// 10 s / waga 1 = 10 s, 20 s / waga 3 ≈ 6.7 s → ciężki ma zaległość
+	airtime(light.ID, 10_000)
+	airtime(heavy.ID, 20_000)
+	if got := pick(0); got != heavy.ID {
+		t.Errorf("picked %d, want weighted-behind sponsor %d", got, heavy.ID)
+	}
+
+	// 40 s / 3 ≈ 13.3 s > 10 s → teraz lekki
+	airtime(heavy.ID, 20_000)
+	if got := pick(0); got != light.ID {
+		t.Errorf("picked %d, want %d", got, light.ID)
+	}
+}
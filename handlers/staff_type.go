@@ -117,6 +117,7 @@ func (h *StaffTypeHandler) UpdateStaffType(w http.ResponseWriter, r *http.Reques
 	}
 
 	staffType.Name = updateData.Name
+	staffType.CreditsOrder = updateData.CreditsOrder
 
 	if err := h.DB.Save(&staffType).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Folder motywu wewnątrz folderu sezonu (media/season_{number}/theme)
+const themeFolder = "theme"
+
+// Dozwolone pliki motywu
+var (
+	themeLogoExtensions = []string{".png", ".svg", ".webp", ".jpg", ".jpeg"}
+	themeFontExtensions = []string{".woff2", ".woff", ".ttf", ".otf"}
+	themeColorPattern   = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// defaultSeasonTheme - oprawa sprzed motywów (kolory i logo z overlay.css / overlay.html)
+var defaultSeasonTheme = models.SeasonTheme{
+	PrimaryColor:   "#75460f",
+	SecondaryColor: "#492b09",
+	TextColor:      "#ffffff",
+	AccentColor:    "#ffd24a",
+	FontFamily:     "Arial, sans-serif",
+	StrapLayout:    models.StrapLayoutLeft,
+}
+
+// OverlayTheme - motyw aktualnego sezonu w postaci gotowej dla overlayu (adresy plików)
+type OverlayTheme struct {
+	SeasonID       uint   `json:"season_id"`
+	SeasonNumber   int    `json:"season_number"`
+	PrimaryColor   string `json:"primary_color"`
+	SecondaryColor string `json:"secondary_color"`
+	TextColor      string `json:"text_color"`
+	AccentColor    string `json:"accent_color"`
+	FontFamily     string `json:"font_family"`
+	FontURL        string `json:"font_url"` // Pusty = font systemowy
+	LogoURL        string `json:"logo_url"` // Pusty = logo domyślne overlayu
+	StrapLayout    string `json:"strap_layout"`
+}
+
+// seasonThemeOrDefault zwraca motyw sezonu lub motyw domyślny (bez zapisu w bazie)
+func seasonThemeOrDefault(db *gorm.DB, seasonID uint) models.SeasonTheme {
+	var theme models.SeasonTheme
+	if err := db.Where("season_id = ?", seasonID).First(&theme).Error; err != nil {
+		theme = defaultSeasonTheme
+		theme.SeasonID = seasonID
+	}
+	return theme
+}
+
+// CurrentOverlayTheme zwraca motyw aktualnego sezonu (domyślny, gdy brak sezonu lub motywu)
+func CurrentOverlayTheme(db *gorm.DB) OverlayTheme {
+	season, err := models.GetCurrentSeason(db)
+	if err != nil {
+		return overlayTheme(nil, defaultSeasonTheme)
+	}
+	return overlayTheme(season, seasonThemeOrDefault(db, season.ID))
+}
+
+func overlayTheme(season *models.Season, theme models.SeasonTheme) OverlayTheme {
+	view := OverlayTheme{
+		PrimaryColor:   theme.PrimaryColor,
+		SecondaryColor: theme.SecondaryColor,
+		TextColor:      theme.TextColor,
+		AccentColor:    theme.AccentColor,
+		FontFamily:     theme.FontFamily,
+		StrapLayout:    theme.StrapLayout,
+	}
+	if season == nil {
+		return view
+	}
+
+	view.SeasonID = season.ID
+	view.SeasonNumber = season.Number
+	if theme.FontFile != "" {
+		view.FontURL = fmt.Sprintf("/api/seasons/%d/theme/files/%s", season.ID, theme.FontFile)
+	}
+	if theme.LogoFile != "" {
+		view.LogoURL = fmt.Sprintf("/api/seasons/%d/theme/files/%s", season.ID, theme.LogoFile)
+	}
+	return view
+}
+
+// BroadcastTheme wysyła motyw aktualnego sezonu do overlayów wszystkich kanałów
+func (h *SocketHandler) BroadcastTheme() {
+	theme := CurrentOverlayTheme(h.DB)
+	h.BroadcastToAll("overlay_theme", theme)
+	log.Printf("Motyw overlayu: sezon %d", theme.SeasonNumber)
+}
+
+// validateSeasonTheme sprawdza kolory i układ paska (puste pola dostają wartości domyślne)
+func validateSeasonTheme(theme *models.SeasonTheme) error {
+	colors := []struct {
+		name     string
+		value    *string
+		fallback string
+	}{
+		{"primary_color", &theme.PrimaryColor, defaultSeasonTheme.PrimaryColor},
+		{"secondary_color", &theme.SecondaryColor, defaultSeasonTheme.SecondaryColor},
+		{"text_color", &theme.TextColor, defaultSeasonTheme.TextColor},
+		{"accent_color", &theme.AccentColor, defaultSeasonTheme.AccentColor},
+	}
+	for _, color := range colors {
+		if *color.value == "" {
+			*color.value = color.fallback
+		}
+		if !themeColorPattern.MatchString(*color.value) {
+			return fmt.Errorf("%s must be a #rrggbb color", color.name)
+		}
+	}
+
+	if theme.FontFamily == "" {
+		theme.FontFamily = defaultSeasonTheme.FontFamily
+	}
+	if strings.ContainsAny(theme.FontFamily, ";{}<>") {
+		return fmt.Errorf("invalid font_family")
+	}
+
+	if theme.StrapLayout == "" {
+		theme.StrapLayout = defaultSeasonTheme.StrapLayout
+	}
+	for _, layout := range models.StrapLayouts {
+		if theme.StrapLayout == layout {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid strap_layout, allowed: %v", models.StrapLayouts)
+}
+
+type ThemeHandler struct {
+	DB            *gorm.DB
+	MediaPath     string
+	SocketHandler *SocketHandler
+}
+
+func NewThemeHandler(db *gorm.DB, mediaPath string, socketHandler *SocketHandler) *ThemeHandler {
+	return &ThemeHandler{
+		DB:            db,
+		MediaPath:     mediaPath,
+		SocketHandler: socketHandler,
+	}
+}
+
+// GetCurrentTheme - GET /api/overlay/theme
+// Motyw aktualnego sezonu dla overlayu
+func (h *ThemeHandler) GetCurrentTheme(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CurrentOverlayTheme(h.DB))
+}
+
+// GetTheme - GET /api/seasons/{id}/theme
+func (h *ThemeHandler) GetTheme(w http.ResponseWriter, r *http.Request) {
+	season, ok := h.loadSeason(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seasonThemeOrDefault(h.DB, season.ID))
+}
+
+// UpdateTheme - PUT /api/seasons/{id}/theme
+// Pliki (font_file, logo_file) ustawia upload; tu można je tylko wyczyścić lub wskazać już wgrany plik
+func (h *ThemeHandler) UpdateTheme(w http.ResponseWriter, r *http.Request) {
+	season, ok := h.loadSeason(w, r)
+	if !ok {
+		return
+	}
+
+	theme := seasonThemeOrDefault(h.DB, season.ID)
+	var updateData models.SeasonTheme
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	theme.PrimaryColor = updateData.PrimaryColor
+	theme.SecondaryColor = updateData.SecondaryColor
+	theme.TextColor = updateData.TextColor
+	theme.AccentColor = updateData.AccentColor
+	theme.FontFamily = updateData.FontFamily
+	theme.StrapLayout = updateData.StrapLayout
+	theme.FontFile = updateData.FontFile
+	theme.LogoFile = updateData.LogoFile
+
+	if err := validateSeasonTheme(&theme); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, name := range []string{theme.FontFile, theme.LogoFile} {
+		if name == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(h.themeDir(season), filepath.Base(name))); err != nil || filepath.Base(name) != name {
+			http.Error(w, fmt.Sprintf("Theme file %s not found", name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.DB.Save(&theme).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if season.IsCurrent {
+		h.SocketHandler.BroadcastTheme()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(theme)
+}
+
+// UploadThemeFile - POST /api/seasons/{id}/theme/files
+// Multipart: file + kind ("logo" lub "font"); plik od razu staje się logo/fontem motywu
+func (h *ThemeHandler) UploadThemeFile(w http.ResponseWriter, r *http.Request) {
+	season, ok := h.loadSeason(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(20 << 20); err != nil { // 20 MB max
+		http.Error(w, "File too large", http.StatusBadRequest)
+		return
+	}
+
+	kind := r.FormValue("kind")
+	var allowed []string
+	switch kind {
+	case "logo":
+		allowed = themeLogoExtensions
+	case "font":
+		allowed = themeFontExtensions
+	default:
+		http.Error(w, "kind must be logo or font", http.StatusBadRequest)
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	fileName := filepath.Base(handler.Filename)
+	if !hasExtension(fileName, allowed) {
+		http.Error(w, fmt.Sprintf("Unsupported file type, allowed: %v", allowed), http.StatusBadRequest)
+		return
+	}
+
+	targetDir := h.themeDir(season)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		http.Error(w, "Error creating directory", http.StatusInternalServerError)
+		return
+	}
+
+	fileName = uniqueFileName(targetDir, fileName)
+	dst, err := os.Create(filepath.Join(targetDir, fileName))
+	if err != nil {
+		http.Error(w, "Error saving file", http.StatusInternalServerError)
+		return
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		http.Error(w, "Error copying file", http.StatusInternalServerError)
+		return
+	}
+
+	theme := seasonThemeOrDefault(h.DB, season.ID)
+	if kind == "logo" {
+		theme.LogoFile = fileName
+	} else {
+		theme.FontFile = fileName
+	}
+	if err := h.DB.Save(&theme).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if season.IsCurrent {
+		h.SocketHandler.BroadcastTheme()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(theme)
+}
+
+// GetThemeFile - GET /api/seasons/{id}/theme/files/{name}
+func (h *ThemeHandler) GetThemeFile(w http.ResponseWriter, r *http.Request) {
+	season, ok := h.loadSeason(w, r)
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+
+	path := filepath.Join(h.themeDir(season), name)
+	if _, err := os.Stat(path); err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// themeDir zwraca folder plików motywu sezonu
+func (h *ThemeHandler) themeDir(season models.Season) string {
+	return filepath.Join(h.MediaPath, fmt.Sprintf("season_%d", season.Number), themeFolder)
+}
+
+// loadSeason pobiera sezon z parametru URL
+func (h *ThemeHandler) loadSeason(w http.ResponseWriter, r *http.Request) (models.Season, bool) {
+	var season models.Season
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return season, false
+	}
+
+	if err := h.DB.First(&season, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Season not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return season, false
+	}
+
+	return season, true
+}
+
+// hasExtension sprawdza rozszerzenie pliku (bez rozróżniania wielkości liter)
+func hasExtension(name string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range extensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
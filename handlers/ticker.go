@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Co ile sprawdzać okna czasowe wiadomości paska (start/koniec emisji)
+const tickerCheckInterval = 5 * time.Second
+
+// TickerState - stan paska informacyjnego na żywo
+type TickerState struct {
+	Running   bool                   `json:"running"`
+	Channel   string                 `json:"channel"`
+	EpisodeID uint                   `json:"episode_id"`
+	Speed     int                    `json:"speed"`    // px/s (0 = domyślna overlayu)
+	Messages  []models.TickerMessage `json:"messages"` // Aktualnie emitowane (aktywne) wiadomości
+}
+
+// TickerFeed zasila szablon ticker overlayu aktywnymi wiadomościami odcinka
+// Zmiany wiadomości i okien czasowych trafiają na antenę bez przeładowania overlayu
+type TickerFeed struct {
+	DB            *gorm.DB
+	SocketHandler *SocketHandler
+
+	running   bool
+	channel   string
+	episodeID uint
+	speed     int
+	shown     bool   // Szablon ticker jest wyświetlany przez pasek
+	activeKey string // Identyfikator ostatnio wysłanego zestawu wiadomości
+	stop      chan struct{}
+	mu        sync.Mutex
+}
+
+func NewTickerFeed(db *gorm.DB, socketHandler *SocketHandler) *TickerFeed {
+	return &TickerFeed{
+		DB:            db,
+		SocketHandler: socketHandler,
+	}
+}
+
+// Start uruchamia pasek dla aktualnego odcinka na kanale overlayu
+func (tf *TickerFeed) Start(channel string, speed int) (*TickerState, error) {
+	if tf.SocketHandler.Overlay == nil {
+		return nil, fmt.Errorf("Overlay niedostępny")
+	}
+	channel, err := overlayChannelName(channel)
+	if err != nil {
+		return nil, err
+	}
+	if speed < 0 {
+		return nil, fmt.Errorf("speed nie może być ujemne")
+	}
+
+	episode, err := models.GetCurrentEpisode(tf.DB)
+	if err != nil {
+		return nil, fmt.Errorf("Brak aktualnego odcinka")
+	}
+
+	tf.mu.Lock()
+	if tf.running && tf.channel != channel && tf.shown {
+		tf.SocketHandler.Overlay.Hide(tf.channel, OverlayTemplateTicker)
+	}
+	if !tf.running {
+		tf.stop = make(chan struct{})
+		go tf.watch(tf.stop)
+	}
+	tf.running = true
+	tf.channel = channel
+	tf.episodeID = episode.ID
+	tf.speed = speed
+	tf.shown = false
+	tf.activeKey = ""
+	tf.mu.Unlock()
+
+	log.Printf("Pasek informacyjny: start (odcinek %d, kanał %s)", episode.ID, channel)
+	return tf.refresh(true)
+}
+
+// Stop zdejmuje pasek z anteny
+func (tf *TickerFeed) Stop() *TickerState {
+	tf.mu.Lock()
+	if tf.running {
+		close(tf.stop)
+		if tf.shown && tf.SocketHandler.Overlay != nil {
+			tf.SocketHandler.Overlay.Hide(tf.channel, OverlayTemplateTicker)
+		}
+		log.Println("Pasek informacyjny: stop")
+	}
+	tf.running = false
+	tf.shown = false
+	tf.activeKey = ""
+	tf.mu.Unlock()
+
+	state := tf.State()
+	tf.SocketHandler.Broadcast("ticker_state", state)
+	return state
+}
+
+// State zwraca stan paska z aktualnie emitowanymi wiadomościami
+func (tf *TickerFeed) State() *TickerState {
+	tf.mu.Lock()
+	state := &TickerState{
+		Running:   tf.running,
+		Channel:   tf.channel,
+		EpisodeID: tf.episodeID,
+		Speed:     tf.speed,
+		Messages:  []models.TickerMessage{},
+	}
+	tf.mu.Unlock()
+
+	if state.Running {
+		if messages, err := models.GetActiveTickerMessages(tf.DB, state.EpisodeID, time.Now()); err == nil {
+			state.Messages = messages
+		}
+	}
+	return state
+}
+
+// MessagesChanged - wiadomości odcinka zostały zmienione przez API (edycja na żywo)
+func (tf *TickerFeed) MessagesChanged(episodeID uint) {
+	if tf == nil {
+		return
+	}
+
+	tf.mu.Lock()
+	affected := tf.running && tf.episodeID == episodeID
+	tf.mu.Unlock()
+
+	if affected {
+		if _, err := tf.refresh(true); err != nil {
+			log.Printf("Pasek informacyjny: %v", err)
+		}
+	}
+}
+
+// watch pilnuje okien czasowych wiadomości (start/koniec emisji bez zmian w bazie)
+func (tf *TickerFeed) watch(stop chan struct{}) {
+	ticker := time.NewTicker(tickerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := tf.refresh(false); err != nil {
+				log.Printf("Pasek informacyjny: %v", err)
+			}
+		}
+	}
+}
+
+// refresh wysyła aktywne wiadomości do overlayu; force = wyślij nawet bez zmiany zestawu
+// (edycja treści lub priorytetu nie zmienia identyfikatorów)
+func (tf *TickerFeed) refresh(force bool) (*TickerState, error) {
+	tf.mu.Lock()
+	if !tf.running {
+		tf.mu.Unlock()
+		return tf.State(), nil
+	}
+
+	// Pasek zdjęty z overlayu z zewnątrz (hide, hide-all) - zasilanie się kończy
+	if tf.shown && tf.SocketHandler.Overlay.Get(tf.channel, OverlayTemplateTicker) == nil {
+		tf.mu.Unlock()
+		log.Println("Pasek informacyjny zdjęty z overlayu")
+		return tf.Stop(), nil
+	}
+
+	messages, err := models.GetActiveTickerMessages(tf.DB, tf.episodeID, time.Now())
+	if err != nil {
+		tf.mu.Unlock()
+		return nil, err
+	}
+
+	key := tickerMessagesKey(messages)
+	if !force && key == tf.activeKey {
+		tf.mu.Unlock()
+		return tf.State(), nil
+	}
+	tf.activeKey = key
+
+	if len(messages) == 0 {
+		// Brak aktywnych wiadomości - pasek znika i wraca, gdy któraś się zacznie
+		if tf.shown {
+			tf.SocketHandler.Overlay.Hide(tf.channel, OverlayTemplateTicker)
+			tf.shown = false
+		}
+	} else {
+		params := map[string]interface{}{
+			"messages":   tickerOverlayMessages(messages),
+			"episode_id": tf.episodeID,
+		}
+		if tf.speed > 0 {
+			params["speed"] = tf.speed
+		}
+
+		if tf.shown {
+			_, err = tf.SocketHandler.Overlay.Update(tf.channel, OverlayTemplateTicker, params, 0)
+		} else {
+			_, err = tf.SocketHandler.Overlay.Show(tf.channel, OverlayTemplateTicker, params, -1)
+		}
+		if err != nil {
+			tf.mu.Unlock()
+			return nil, err
+		}
+		tf.shown = true
+	}
+	tf.mu.Unlock()
+
+	state := tf.State()
+	tf.SocketHandler.Broadcast("ticker_state", state)
+	return state, nil
+}
+
+// tickerMessagesKey identyfikuje zestaw aktywnych wiadomości (kolejność się liczy)
+func tickerMessagesKey(messages []models.TickerMessage) string {
+	key := ""
+	for _, message := range messages {
+		key += fmt.Sprintf("%d,", message.ID)
+	}
+	return key
+}
+
+// tickerOverlayMessages zamienia wiadomości na parametry szablonu ticker
+func tickerOverlayMessages(messages []models.TickerMessage) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(messages))
+	for _, message := range messages {
+		result = append(result, map[string]interface{}{
+			"id":       message.ID,
+			"text":     message.Text,
+			"priority": message.Priority,
+		})
+	}
+	return result
+}
+
+// tickerMessageRequest reprezentuje dane wiadomości przesyłane przy tworzeniu/edycji
+type tickerMessageRequest struct {
+	Text     string     `json:"text"`
+	Priority int        `json:"priority"`
+	Enabled  *bool      `json:"enabled"` // Pominięte = włączona
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// validate sprawdza poprawność danych wiadomości
+func (req *tickerMessageRequest) validate() error {
+	if req.Text == "" {
+		return fmt.Errorf("text is required")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+type TickerHandler struct {
+	DB   *gorm.DB
+	Feed *TickerFeed // Pasek na żywo (nil = tylko edycja)
+}
+
+func NewTickerHandler(db *gorm.DB, feed *TickerFeed) *TickerHandler {
+	return &TickerHandler{DB: db, Feed: feed}
+}
+
+// GetMessages - GET /api/episodes/{episode_id}/ticker
+func (h *TickerHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	var messages []models.TickerMessage
+	if err := h.DB.Where("episode_id = ?", episodeID).Order("\"order\" ASC").Find(&messages).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// CreateMessage - POST /api/episodes/{episode_id}/ticker
+func (h *TickerHandler) CreateMessage(w http.ResponseWriter, r *http.Request) {
+	episodeID, err := strconv.ParseUint(mux.Vars(r)["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return
+	}
+
+	var episode models.Episode
+	if err := h.DB.First(&episode, episodeID).Error; err != nil {
+		http.Error(w, "Episode not found", http.StatusNotFound)
+		return
+	}
+
+	var req tickerMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message := models.TickerMessage{
+		EpisodeID: uint(episodeID),
+		Order:     models.GetNextTickerMessageOrder(h.DB, uint(episodeID)),
+		Text:      req.Text,
+		Priority:  req.Priority,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+	}
+	if err := h.DB.Create(&message).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.Feed.MessagesChanged(message.EpisodeID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// UpdateMessage - PUT /api/episodes/{episode_id}/ticker/{id}
+func (h *TickerHandler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+	message, ok := h.loadMessage(w, r)
+	if !ok {
+		return
+	}
+
+	var req tickerMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message.Text = req.Text
+	message.Priority = req.Priority
+	message.Enabled = req.Enabled == nil || *req.Enabled
+	message.StartsAt = req.StartsAt
+	message.EndsAt = req.EndsAt
+
+	if err := h.DB.Save(&message).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.Feed.MessagesChanged(message.EpisodeID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
+
+// DeleteMessage - DELETE /api/episodes/{episode_id}/ticker/{id}
+func (h *TickerHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	message, ok := h.loadMessage(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&message).Error; err != nil {
+			return err
+		}
+
+		// Zamknij lukę w kolejności
+		return tx.Model(&models.TickerMessage{}).
+			Where("episode_id = ? AND \"order\" > ?", message.EpisodeID, message.Order).
+			UpdateColumn("order", gorm.Expr("\"order\" - 1")).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.Feed.MessagesChanged(message.EpisodeID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderMessage - PUT /api/episodes/{episode_id}/ticker/{id}/reorder
+func (h *TickerHandler) ReorderMessage(w http.ResponseWriter, r *http.Request) {
+	message, ok := h.loadMessage(w, r)
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Order int `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		oldOrder := message.Order
+		newOrder := data.Order
+
+		if newOrder > oldOrder {
+			tx.Model(&models.TickerMessage{}).
+				Where("episode_id = ? AND \"order\" > ? AND \"order\" <= ?", message.EpisodeID, oldOrder, newOrder).
+				UpdateColumn("order", gorm.Expr("\"order\" - 1"))
+		} else if newOrder < oldOrder {
+			tx.Model(&models.TickerMessage{}).
+				Where("episode_id = ? AND \"order\" >= ? AND \"order\" < ?", message.EpisodeID, newOrder, oldOrder).
+				UpdateColumn("order", gorm.Expr("\"order\" + 1"))
+		}
+
+		return tx.Model(&message).Update("order", newOrder).Error
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.Feed.MessagesChanged(message.EpisodeID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// loadMessage pobiera wiadomość z parametrów URL i sprawdza przynależność do odcinka
+func (h *TickerHandler) loadMessage(w http.ResponseWriter, r *http.Request) (models.TickerMessage, bool) {
+	var message models.TickerMessage
+
+	vars := mux.Vars(r)
+	episodeID, err := strconv.ParseUint(vars["episode_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+		return message, false
+	}
+
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return message, false
+	}
+
+	if err := h.DB.Where("id = ? AND episode_id = ?", id, episodeID).First(&message).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Ticker message not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return message, false
+	}
+
+	return message, true
+}
+
+// GetLiveState - GET /api/ticker/live
+func (h *TickerHandler) GetLiveState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Feed.State())
+}
+
+// StartLive - POST /api/ticker/live/start
+// Body (opcjonalne): {"channel": "main", "speed": 120}
+func (h *TickerHandler) StartLive(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Channel string `json:"channel"`
+		Speed   int    `json:"speed"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	state, err := h.Feed.Start(req.Channel, req.Speed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// StopLive - POST /api/ticker/live/stop
+func (h *TickerHandler) StopLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Feed.Stop())
+}
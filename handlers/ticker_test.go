@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"obs-controller/models"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCreateTickerMessageEnabledFlag(t *testing.T) {
+	db := openTestDB(t)
+	episode := createCurrentEpisode(t, db, nil)
+	h := NewTickerHandler(db, nil)
+
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"text":"Domyślnie"}`, true},
+		{`{"text":"Włączona","enabled":true}`, true},
+		{`{"text":"Wyłączona","enabled":false}`, false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/ticker", strings.NewReader(tt.body))
+		req = mux.SetURLVars(req, map[string]string{"episode_id": fmt.Sprint(episode.ID)})
+		rec := httptest.NewRecorder()
+		h.CreateMessage(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: status = %d: %s", tt.body, rec.Code, rec.Body.String())
+		}
+	}
+
+	var messages []models.TickerMessage
+	db.Where("episode_id = ?", episode.ID).Order("id").Find(&messages)
+	if len(messages) != len(tests) {
+		t.Fatalf("expected %d messages, got %d", len(tests), len(messages))
+	}
+	for i, message := range messages {
+		if message.Enabled != tests[i].want {
+			t.Errorf("%q: enabled = %v, want %v", message.Text, message.Enabled, tests[i].want)
+		}
+	}
+}
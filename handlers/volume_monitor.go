@@ -60,7 +60,7 @@ func (vm *VolumeMonitor) Start() {
 		vm.UpdateCache(inputName, volumeDb)
 
 		if vm.SocketHandler != nil {
-			vm.SocketHandler.Server.BroadcastToNamespace("/", "volume_changed", map[string]interface{}{
+			vm.SocketHandler.Broadcast("volume_changed", map[string]interface{}{
 				"source_name": inputName,
 				"volume_db":   volumeDb,
 			})
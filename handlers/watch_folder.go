@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"obs-controller/models"
+	"obs-controller/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type WatchFolderHandler struct {
+	DB *gorm.DB
+}
+
+func NewWatchFolderHandler(db *gorm.DB) *WatchFolderHandler {
+	return &WatchFolderHandler{DB: db}
+}
+
+// validateWatchFolder sprawdza poprawność konfiguracji folderu
+func validateWatchFolder(folder *models.WatchFolder) error {
+	if folder.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if folder.GroupName == "" {
+		return fmt.Errorf("group_name is required")
+	}
+	if (folder.EpisodeID == nil) == (folder.SeasonID == nil) {
+		return fmt.Errorf("exactly one of episode_id or season_id is required")
+	}
+	if folder.Pattern != "" {
+		if _, err := filepath.Match(folder.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	info, err := os.Stat(folder.Path)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("path is not an accessible directory")
+	}
+	return nil
+}
+
+// GetWatchFolders - GET /api/watch-folders
+func (h *WatchFolderHandler) GetWatchFolders(w http.ResponseWriter, r *http.Request) {
+	var folders []models.WatchFolder
+	if err := h.DB.Order("id ASC").Find(&folders).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folders)
+}
+
+// GetWatchFolder - GET /api/watch-folders/{id}
+func (h *WatchFolderHandler) GetWatchFolder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var folder models.WatchFolder
+	if err := h.DB.First(&folder, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Watch folder not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folder)
+}
+
+// CreateWatchFolder - POST /api/watch-folders
+func (h *WatchFolderHandler) CreateWatchFolder(w http.ResponseWriter, r *http.Request) {
+	folder := models.WatchFolder{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWatchFolder(&folder); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Create(&folder).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(folder)
+}
+
+// UpdateWatchFolder - PUT /api/watch-folders/{id}
+func (h *WatchFolderHandler) UpdateWatchFolder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var folder models.WatchFolder
+	if err := h.DB.First(&folder, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Watch folder not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var updateData models.WatchFolder
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	folder.Path = updateData.Path
+	folder.EpisodeID = updateData.EpisodeID
+	folder.SeasonID = updateData.SeasonID
+	folder.GroupName = updateData.GroupName
+	folder.Move = updateData.Move
+	folder.Enabled = updateData.Enabled
+	folder.Pattern = updateData.Pattern
+
+	if err := validateWatchFolder(&folder); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Save(&folder).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folder)
+}
+
+// DeleteWatchFolder - DELETE /api/watch-folders/{id}
+func (h *WatchFolderHandler) DeleteWatchFolder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var folder models.WatchFolder
+	if err := h.DB.First(&folder, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Watch folder not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Usuń historię importów tego folderu
+	h.DB.Where("watch_folder_id = ?", id).Delete(&models.WatchFolderImport{})
+
+	if err := h.DB.Delete(&folder).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== USŁUGA OBSERWACJI FOLDERÓW =====
+
+// Co ile skanować obserwowane foldery
+const watchFolderPollInterval = 5 * time.Second
+
+// Ile kolejnych skanów plik musi mieć niezmieniony rozmiar, by uznać go za w pełni zapisany
+const watchFolderStableScans = 2
+
+// watchedFile przechowuje stan pliku pomiędzy skanami
+type watchedFile struct {
+	size        int64
+	modTime     time.Time
+	stableScans int
+}
+
+// WatchFolderService okresowo skanuje obserwowane foldery i importuje nowe pliki
+type WatchFolderService struct {
+	DB            *gorm.DB
+	MediaPath     string
+	SocketHandler *SocketHandler
+
+	// Mapa: ścieżka pliku → stan z poprzednich skanów
+	pending   map[string]*watchedFile
+	pendingMu sync.Mutex
+
+	stop chan struct{}
+}
+
+func NewWatchFolderService(db *gorm.DB, mediaPath string, socketHandler *SocketHandler) *WatchFolderService {
+	return &WatchFolderService{
+		DB:            db,
+		MediaPath:     mediaPath,
+		SocketHandler: socketHandler,
+		pending:       make(map[string]*watchedFile),
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start uruchamia okresowe skanowanie folderów
+func (s *WatchFolderService) Start() {
+	log.Println("Starting Watch Folder Service...")
+
+	go func() {
+		ticker := time.NewTicker(watchFolderPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.scanAll()
+			}
+		}
+	}()
+
+	log.Println("Watch Folder Service started successfully")
+}
+
+// Stop zatrzymuje skanowanie
+func (s *WatchFolderService) Stop() {
+	close(s.stop)
+}
+
+// scanAll skanuje wszystkie aktywne foldery
+func (s *WatchFolderService) scanAll() {
+	var folders []models.WatchFolder
+	if err := s.DB.Where("enabled = ?", true).Find(&folders).Error; err != nil {
+		log.Printf("Błąd pobierania obserwowanych folderów: %v", err)
+		return
+	}
+
+	for _, folder := range folders {
+		s.scanFolder(folder)
+	}
+}
+
+// scanFolder sprawdza pliki w folderze i importuje te, które są w pełni zapisane
+func (s *WatchFolderService) scanFolder(folder models.WatchFolder) {
+	entries, err := os.ReadDir(folder.Path)
+	if err != nil {
+		log.Printf("Błąd odczytu obserwowanego folderu %s: %v", folder.Path, err)
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// Pomiń foldery, pliki ukryte i tymczasowe
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".part") {
+			continue
+		}
+
+		if folder.Pattern != "" {
+			if matched, _ := filepath.Match(folder.Pattern, name); !matched {
+				continue
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		sourcePath := filepath.Join(folder.Path, name)
+
+		if s.alreadyImported(folder.ID, sourcePath, info) {
+			continue
+		}
+
+		if !s.isStable(sourcePath, info) {
+			continue
+		}
+
+		if err := s.importFile(folder, sourcePath, info); err != nil {
+			log.Printf("Błąd importu %s: %v", sourcePath, err)
+		}
+	}
+}
+
+// alreadyImported sprawdza czy plik był już zaimportowany (ta sama ścieżka, rozmiar i data modyfikacji)
+// Nadpisany plik o tym samym rozmiarze ma nową datę modyfikacji, więc jest importowany ponownie
+func (s *WatchFolderService) alreadyImported(folderID uint, sourcePath string, info os.FileInfo) bool {
+	var imports []models.WatchFolderImport
+	s.DB.Where("watch_folder_id = ? AND source_path = ? AND size = ?", folderID, sourcePath, info.Size()).
+		Find(&imports)
+	for _, imported := range imports {
+		if imported.ModTime.Equal(info.ModTime()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStable sprawdza czy plik nie zmienia się od kilku skanów (czyli jest w pełni zapisany)
+func (s *WatchFolderService) isStable(path string, info os.FileInfo) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	state, exists := s.pending[path]
+	if !exists || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+		s.pending[path] = &watchedFile{size: info.Size(), modTime: info.ModTime()}
+		return false
+	}
+
+	state.stableScans++
+	if state.stableScans < watchFolderStableScans || info.Size() == 0 {
+		return false
+	}
+
+	delete(s.pending, path)
+	return true
+}
+
+// resolveEpisode zwraca odcinek docelowy dla folderu
+func (s *WatchFolderService) resolveEpisode(folder models.WatchFolder) (*models.Episode, error) {
+	var episode models.Episode
+
+	if folder.EpisodeID != nil {
+		if err := s.DB.Preload("Season").First(&episode, *folder.EpisodeID).Error; err != nil {
+			return nil, err
+		}
+		return &episode, nil
+	}
+
+	// Folder sezonu - import do aktualnego odcinka, jeśli należy do tego sezonu
+	if err := s.DB.Preload("Season").
+		Where("season_id = ? AND is_current = ?", *folder.SeasonID, true).
+		First(&episode).Error; err != nil {
+		return nil, fmt.Errorf("brak aktualnego odcinka w sezonie %d", *folder.SeasonID)
+	}
+	return &episode, nil
+}
+
+// importFile kopiuje/przenosi plik do magazynu mediów, tworzy EpisodeMedia i dodaje do grupy
+func (s *WatchFolderService) importFile(folder models.WatchFolder, sourcePath string, info os.FileInfo) error {
+	episode, err := s.resolveEpisode(folder)
+	if err != nil {
+		return err
+	}
+
+	// Folder docelowy: media/season_{number}/
+	seasonFolder := fmt.Sprintf("season_%d", episode.Season.Number)
+	targetDir := filepath.Join(s.MediaPath, seasonFolder)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+
+	fileName := uniqueFileName(targetDir, filepath.Base(sourcePath))
+	targetPath := filepath.Join(targetDir, fileName)
+
+	// Przy przenoszeniu źródło jest usuwane dopiero po zapisie w bazie,
+	// więc nieudany import nie gubi pliku
+	if folder.Move {
+		err = linkOrCopyFile(sourcePath, targetPath)
+	} else {
+		err = copyFile(sourcePath, targetPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Względna ścieżka od folderu media - używamy / dla bazy danych
+	relativePath := seasonFolder + "/" + fileName
+	duration, _ := utils.GetMediaDuration(targetPath)
+
+	media := models.EpisodeMedia{
+		EpisodeID: episode.ID,
+		Title:     strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		FilePath:  &relativePath,
+		Duration:  duration,
+	}
+
+	var group models.MediaGroup
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&media).Error; err != nil {
+			return err
+		}
+
+		// Grupa docelowa - jeśli nie istnieje, utwórz grupę użytkownika
+		if err := tx.Where("episode_id = ? AND name = ?", episode.ID, folder.GroupName).First(&group).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			group = models.MediaGroup{
+				EpisodeID: episode.ID,
+				Name:      folder.GroupName,
+				Order:     models.GetNextMediaGroupOrder(tx, episode.ID),
+			}
+			if err := tx.Create(&group).Error; err != nil {
+				return err
+			}
+		}
+
+		assignment := models.EpisodeMediaGroup{
+			EpisodeMediaID: media.ID,
+			MediaGroupID:   group.ID,
+			Order:          models.GetNextMediaGroupItemOrder(tx, group.ID),
+		}
+		if err := tx.Create(&assignment).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.WatchFolderImport{
+			WatchFolderID:  folder.ID,
+			SourcePath:     sourcePath,
+			Size:           info.Size(),
+			ModTime:        info.ModTime(),
+			EpisodeMediaID: media.ID,
+		}).Error
+	})
+	if err != nil {
+		os.Remove(targetPath)
+		return err
+	}
+
+	if folder.Move {
+		if err := os.Remove(sourcePath); err != nil {
+			log.Printf("Nie udało się usunąć przeniesionego pliku %s: %v", sourcePath, err)
+		}
+	}
+
+	log.Printf("Zaimportowano %s → %s (odcinek %d, grupa %s)", sourcePath, relativePath, episode.ID, group.Name)
+
+	if s.SocketHandler != nil {
+		s.SocketHandler.Broadcast("media_imported", map[string]interface{}{
+			"episode_id": episode.ID,
+			"media_id":   media.ID,
+			"title":      media.Title,
+			"file_path":  relativePath,
+			"duration":   duration,
+			"group_id":   group.ID,
+			"group_name": group.Name,
+		})
+	}
+
+	return nil
+}
+
+// uniqueFileName zwraca nazwę pliku, która nie koliduje z istniejącymi w folderze
+func uniqueFileName(dir, name string) string {
+	if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if _, err := os.Stat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// copyFile kopiuje plik
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// linkOrCopyFile tworzy twarde dowiązanie (bez kopiowania danych), a gdy foldery są
+// na różnych dyskach - kopię; źródło zostaje na miejscu
+func linkOrCopyFile(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
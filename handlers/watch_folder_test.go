@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"obs-controller/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestWatchFolder tworzy folder przenoszący pliki do grupy MEDIA aktualnego odcinka
+func newTestWatchFolder(t *testing.T, db *gorm.DB) (*WatchFolderService, models.WatchFolder, string) {
+	t.Helper()
+	episode := createCurrentEpisode(t, db, nil)
+	folder := models.WatchFolder{Path: t.TempDir(), EpisodeID: &episode.ID, GroupName: "MEDIA", Move: true, Enabled: true}
+	if err := db.Create(&folder).Error; err != nil {
+		t.Fatalf("create watch folder: %v", err)
+	}
+	sourcePath := filepath.Join(folder.Path, "clip.mp4")
+	if err := os.WriteFile(sourcePath, []byte("video"), 0644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	return NewWatchFolderService(db, t.TempDir(), nil), folder, sourcePath
+}
+
+func TestImportFileMoveRemovesSourceAfterCommit(t *testing.T) {
+	db := openTestDB(t)
+	s, folder, sourcePath := newTestWatchFolder(t, db)
+	info, _ := os.Stat(sourcePath)
+
+	if err := s.importFile(folder, sourcePath, info); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	if _, err := os.Stat(sourcePath); !os.IsNotExist(err) {
+		t.Errorf("source still exists after move (err = %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.MediaPath, "season_1", "clip.mp4")); err != nil {
+		t.Errorf("target missing: %v", err)
+	}
+	if !s.alreadyImported(folder.ID, sourcePath, info) {
+		t.Error("import not recorded")
+	}
+}
+
+func TestImportFileMoveKeepsSourceWhenCommitFails(t *testing.T) {
+	db := openTestDB(t)
+	s, folder, sourcePath := newTestWatchFolder(t, db)
+	info, _ := os.Stat(sourcePath)
+
+	if err := db.Migrator().DropTable(&models.WatchFolderImport{}); err != nil {
+		t.Fatalf("drop table: %v", err)
+	}
+	if err := s.importFile(folder, sourcePath, info); err == nil {
+		t.Fatal("import succeeded without the imports table")
+	}
+
+	if _, err := os.Stat(sourcePath); err != nil {
+		t.Errorf("source lost after failed import: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.MediaPath, "season_1", "clip.mp4")); !os.IsNotExist(err) {
+		t.Errorf("target left behind after failed import (err = %v)", err)
+	}
+	var count int64
+	db.Model(&models.EpisodeMedia{}).Count(&count)
+	if count != 0 {
+		t.Errorf("media rows = %d, want rollback to 0", count)
+	}
+}
+
+func TestAlreadyImportedComparesModTime(t *testing.T) {
+	db := openTestDB(t)
+	s, folder, sourcePath := newTestWatchFolder(t, db)
+	folder.Move = false
+	info, _ := os.Stat(sourcePath)
+
+	if err := s.importFile(folder, sourcePath, info); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if !s.alreadyImported(folder.ID, sourcePath, info) {
+		t.Fatal("unchanged file not recognised as imported")
+	}
+
+	// Nadpisanie plikiem o tym samym rozmiarze
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(sourcePath, later, later); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	info, _ = os.Stat(sourcePath)
+	if s.alreadyImported(folder.ID, sourcePath, info) {
+		t.Error("overwritten file with the same size treated as already imported")
+	}
+}
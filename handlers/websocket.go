@@ -2,20 +2,43 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"obs-controller/models"
 	"obs-controller/obsws"
+	"strings"
 	"sync"
 
 	socketio "github.com/googollee/go-socket.io"
 	"gorm.io/gorm"
 )
 
+// Pokoje Socket.IO (namespace "/")
+const (
+	roomControllers   = "controllers" // Kontrolery - domyślny pokój każdego klienta
+	roomOverlayPrefix = "overlay:"    // overlay:<kanał> - strony overlayu danego kanału
+	roomEpisodePrefix = "episode:"    // episode:<id> - kontrolery pracujące na odcinku
+)
+
 type SocketHandler struct {
 	Server         *socketio.Server
 	DB             *gorm.DB
 	OBSClient      *obsws.Client
 	VolumeMonitor  *VolumeMonitor                    // Monitor zmian głośności
+	MediaMonitor   *MediaMonitor                     // Monitor punktów in/out mediów
+	Rundown        *RundownExecutor                  // Realizacja rundownu na żywo
+	ShowClock      *ShowClock                        // Zegar programu i segmentów
+	AsRun          *AsRunLogger                      // Dziennik emisji
+	Macros         *MacroRunner                      // Wykonywanie makr
+	Rules          *RuleEngine                       // Reguły automatyzacji
+	Scripts        *ScriptEngine                     // Skrypty Lua podpięte pod zdarzenia
+	Journal        *ActionJournal                    // Dziennik operacji (undo)
+	Overlay        *OverlayManager                   // Stan grafik overlayu (szablony)
+	LowerThirds    *LowerThirds                      // Paski z nazwiskami
+	Speakers       *SpeakerDetector                  // Automatyczne paski z aktywności mikrofonów
+	Ticker         *TickerFeed                       // Pasek informacyjny (crawl) z wiadomości odcinka
+	Panic          *PanicButton                      // Przycisk awaryjny ("usterki techniczne")
+	Sponsors       *SponsorRotation                  // Rotacja logotypów sponsorów
 	vlcAssignments map[uint]map[string]VLCAssignment // episode_id -> (source_name -> assignment)
 	mu             sync.RWMutex
 }
@@ -44,6 +67,16 @@ func NewSocketHandler(db *gorm.DB, obsClient *obsws.Client) (*SocketHandler, err
 
 	server.OnConnect("/", func(s socketio.Conn) error {
 		log.Printf("Połączono: %s", s.ID())
+
+		// Każdy klient jest kontrolerem, dopóki nie przedstawi się jako overlay (overlay_hello)
+		s.Join(roomControllers)
+
+		// Odświeżona strona od razu dostaje stan zegara (bez czekania na tyknięcie)
+		if handler.ShowClock != nil {
+			if state, err := handler.ShowClock.State(); err == nil {
+				s.Emit("show_clock_tick", state)
+			}
+		}
 		return nil
 	})
 
@@ -61,6 +94,42 @@ func NewSocketHandler(db *gorm.DB, obsClient *obsws.Client) (*SocketHandler, err
 	server.OnEvent("/", "mute_all_microphones", handler.handleMuteAllMicrophones)
 	server.OnEvent("/", "restore_microphones", handler.handleRestoreMicrophones)
 	server.OnEvent("/", "set_input_volume", handler.handleSetInputVolume)
+	server.OnEvent("/", "rundown_get_state", handler.handleRundownGetState)
+	server.OnEvent("/", "rundown_next", handler.handleRundownNext)
+	server.OnEvent("/", "rundown_previous", handler.handleRundownPrevious)
+	server.OnEvent("/", "rundown_goto", handler.handleRundownGoTo)
+	server.OnEvent("/", "rundown_reset", handler.handleRundownReset)
+	server.OnEvent("/", "show_clock_get_state", handler.handleShowClockGetState)
+	server.OnEvent("/", "show_clock_start", handler.handleShowClockStart)
+	server.OnEvent("/", "show_clock_stop", handler.handleShowClockStop)
+	server.OnEvent("/", "show_clock_reset", handler.handleShowClockReset)
+	server.OnEvent("/", "segment_timer_stop", handler.handleSegmentTimerStop)
+	server.OnEvent("/", "macro_list", handler.handleMacroList)
+	server.OnEvent("/", "macro_run", handler.handleMacroRun)
+	server.OnEvent("/", "macro_cancel", handler.handleMacroCancel)
+	server.OnEvent("/", "undo_last", handler.handleUndoLast)
+	server.OnEvent("/", "journal_history", handler.handleJournalHistory)
+	server.OnEvent("/", "overlay_hello", handler.handleOverlayHello)
+	server.OnEvent("/", "join_episode", handler.handleJoinEpisode)
+	server.OnEvent("/", "overlay_state", handler.handleOverlayState)
+	server.OnEvent("/", "overlay_show", handler.handleOverlayShow)
+	server.OnEvent("/", "overlay_update", handler.handleOverlayUpdate)
+	server.OnEvent("/", "overlay_hide", handler.handleOverlayHide)
+	server.OnEvent("/", "lower_third_show", handler.handleLowerThirdShow)
+	server.OnEvent("/", "lower_third_hide", handler.handleLowerThirdHide)
+	server.OnEvent("/", "lower_third_list", handler.handleLowerThirdList)
+	server.OnEvent("/", "speaker_detect_get", handler.handleSpeakerDetectGet)
+	server.OnEvent("/", "speaker_detect_set", handler.handleSpeakerDetectSet)
+	server.OnEvent("/", "ticker_state", handler.handleTickerState)
+	server.OnEvent("/", "ticker_start", handler.handleTickerStart)
+	server.OnEvent("/", "ticker_stop", handler.handleTickerStop)
+	server.OnEvent("/", "credits_roll", handler.handleCreditsRoll)
+	server.OnEvent("/", "panic_state", handler.handlePanicState)
+	server.OnEvent("/", "panic_activate", handler.handlePanicActivate)
+	server.OnEvent("/", "panic_resume", handler.handlePanicResume)
+	server.OnEvent("/", "sponsor_rotation_state", handler.handleSponsorRotationState)
+	server.OnEvent("/", "sponsor_rotation_start", handler.handleSponsorRotationStart)
+	server.OnEvent("/", "sponsor_rotation_stop", handler.handleSponsorRotationStop)
 	// server.OnEvent("/", "get_input_volume", handler.handleGetInputVolume)
 
 	go server.Serve()
@@ -68,6 +137,40 @@ func NewSocketHandler(db *gorm.DB, obsClient *obsws.Client) (*SocketHandler, err
 	return handler, nil
 }
 
+// Broadcast wysyła zdarzenie do wszystkich kontrolerów (bez overlayów)
+func (h *SocketHandler) Broadcast(event string, data interface{}) {
+	h.Server.BroadcastToRoom("/", roomControllers, event, data)
+}
+
+// BroadcastToEpisode wysyła zdarzenie do kontrolerów pracujących na danym odcinku
+func (h *SocketHandler) BroadcastToEpisode(episodeID uint, event string, data interface{}) {
+	h.Server.BroadcastToRoom("/", episodeRoom(episodeID), event, data)
+}
+
+// BroadcastToOverlay wysyła zdarzenie do overlayów kanału i do kontrolerów (podgląd stanu)
+func (h *SocketHandler) BroadcastToOverlay(channel string, event string, data interface{}) {
+	h.Server.BroadcastToRoom("/", roomOverlayPrefix+channel, event, data)
+	h.Server.BroadcastToRoom("/", roomControllers, event, data)
+}
+
+// BroadcastToAll wysyła zdarzenie do wszystkich klientów (kontrolery i overlaye wszystkich kanałów)
+func (h *SocketHandler) BroadcastToAll(event string, data interface{}) {
+	h.Server.BroadcastToNamespace("/", event, data)
+}
+
+func episodeRoom(episodeID uint) string {
+	return fmt.Sprintf("%s%d", roomEpisodePrefix, episodeID)
+}
+
+// leaveRooms wypisuje klienta ze wszystkich pokojów o danym prefiksie
+func leaveRooms(s socketio.Conn, prefix string) {
+	for _, room := range s.Rooms() {
+		if strings.HasPrefix(room, prefix) {
+			s.Leave(room)
+		}
+	}
+}
+
 func (h *SocketHandler) handleGetSources(s socketio.Conn, sceneName string) string {
 	if h.OBSClient == nil {
 		return h.errorResponse("OBS nie jest połączony")
@@ -148,34 +251,50 @@ func (h *SocketHandler) handleToggleSource(s socketio.Conn, msg string) string {
 		return h.errorResponse("Błąd")
 	}
 
-	if err := h.OBSClient.SetSourceVisibility(req.SceneName, req.SourceName, req.Visible); err != nil {
+	// Stan sprzed zmiany do dziennika operacji (undo)
+	previous, previousErr := h.OBSClient.GetSourceVisibility(req.SceneName, req.SourceName)
+
+	if err := h.setSourceVisible(req.SceneName, req.SourceName, req.Visible); err != nil {
 		return h.errorResponse(err.Error())
 	}
 
-	// Dla MIKROFONY zapisuj IsVisible do bazy (stan użytkownika)
-	if req.SceneName == "MIKROFONY" {
+	if previousErr == nil && previous != req.Visible {
+		h.Journal.RecordVisibility(operatorFromConn(s), req.SceneName, req.SourceName, previous)
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"scene_name":  req.SceneName,
+		"source_name": req.SourceName,
+		"visible":     req.Visible,
+	})
+}
+
+// setSourceVisible zmienia widoczność źródła w OBS i informuje klientów
+// Dla MIKROFONY zapisuje też IsVisible do bazy (stan użytkownika)
+func (h *SocketHandler) setSourceVisible(sceneName, sourceName string, visible bool) error {
+	if err := h.OBSClient.SetSourceVisibility(sceneName, sourceName, visible); err != nil {
+		return err
+	}
+
+	if sceneName == "MIKROFONY" {
 		var scene models.Scene
-		if err := h.DB.Where("name = ?", req.SceneName).First(&scene).Error; err == nil {
+		if err := h.DB.Where("name = ?", sceneName).First(&scene).Error; err == nil {
 			var source models.Source
-			if err := h.DB.Where("scene_id = ? AND name = ?", scene.ID, req.SourceName).First(&source).Error; err == nil {
-				source.IsVisible = req.Visible
+			if err := h.DB.Where("scene_id = ? AND name = ?", scene.ID, sourceName).First(&source).Error; err == nil {
+				source.IsVisible = visible
 				h.DB.Save(&source)
-				log.Printf("Zapisano IsVisible dla %s -> %s: %v", req.SceneName, req.SourceName, req.Visible)
+				log.Printf("Zapisano IsVisible dla %s -> %s: %v", sceneName, sourceName, visible)
 			}
 		}
 	}
 
-	h.Server.BroadcastToNamespace("/", "source_changed", map[string]interface{}{
-		"scene_name":  req.SceneName,
-		"source_name": req.SourceName,
-		"visible":     req.Visible,
+	h.Broadcast("source_changed", map[string]interface{}{
+		"scene_name":  sceneName,
+		"source_name": sourceName,
+		"visible":     visible,
 	})
 
-	return h.successResponse(map[string]interface{}{
-		"scene_name":  req.SceneName,
-		"source_name": req.SourceName,
-		"visible":     req.Visible,
-	})
+	return nil
 }
 
 func (h *SocketHandler) handleSendToOverlay(s socketio.Conn, msg string) string {
@@ -184,10 +303,25 @@ func (h *SocketHandler) handleSendToOverlay(s socketio.Conn, msg string) string
 		return h.errorResponse("Błąd")
 	}
 
-	h.Server.BroadcastToNamespace("/", "overlay_message", data)
+	h.sendToOverlay(data)
 	return h.successResponse(data)
 }
 
+// sendToOverlay wysyła wiadomość do overlayu (data.channel, domyślnie main) i zapisuje ją w dzienniku emisji
+func (h *SocketHandler) sendToOverlay(data map[string]interface{}) {
+	requested, _ := data["channel"].(string)
+	channel, err := overlayChannelName(requested)
+	if err != nil {
+		log.Printf("Wiadomość do overlayu: %v", err)
+		return
+	}
+	h.BroadcastToOverlay(channel, "overlay_message", data)
+
+	if h.AsRun != nil {
+		h.AsRun.Overlay(data)
+	}
+}
+
 func (h *SocketHandler) handleSetSourceIndex(s socketio.Conn, msg string) string {
 	if h.OBSClient == nil {
 		return h.errorResponse("OBS nie jest połączony")
@@ -354,10 +488,37 @@ func (h *SocketHandler) handleMuteAllMicrophones(s socketio.Conn, msg string) st
 		return h.errorResponse("OBS nie jest połączony")
 	}
 
+	mutedCount, err := h.muteAllMicrophones()
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"muted": mutedCount,
+	})
+}
+
+func (h *SocketHandler) handleRestoreMicrophones(s socketio.Conn, msg string) string {
+	if h.OBSClient == nil {
+		return h.errorResponse("OBS nie jest połączony")
+	}
+
+	restoredCount, err := h.restoreMicrophones()
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"restored": restoredCount,
+	})
+}
+
+// muteAllMicrophones wyłącza wszystkie mikrofony w OBS (BEZ zmiany is_visible)
+func (h *SocketHandler) muteAllMicrophones() (int, error) {
 	// Znajdź scenę MIKROFONY
 	var scene models.Scene
 	if err := h.DB.Where("name = ?", "MIKROFONY").First(&scene).Error; err != nil {
-		return h.errorResponse("Scena MIKROFONY nie znaleziona")
+		return 0, fmt.Errorf("Scena MIKROFONY nie znaleziona")
 	}
 
 	// Pobierz wszystkie mikrofony
@@ -372,7 +533,7 @@ func (h *SocketHandler) handleMuteAllMicrophones(s socketio.Conn, msg string) st
 		} else {
 			mutedCount++
 			// Broadcast zmiany do klientów
-			h.Server.BroadcastToNamespace("/", "source_changed", map[string]interface{}{
+			h.Broadcast("source_changed", map[string]interface{}{
 				"scene_name":  "MIKROFONY",
 				"source_name": source.Name,
 				"visible":     false,
@@ -381,21 +542,15 @@ func (h *SocketHandler) handleMuteAllMicrophones(s socketio.Conn, msg string) st
 	}
 
 	log.Printf("Wyciszono %d mikrofonów (reportaż)", mutedCount)
-
-	return h.successResponse(map[string]interface{}{
-		"muted": mutedCount,
-	})
+	return mutedCount, nil
 }
 
-func (h *SocketHandler) handleRestoreMicrophones(s socketio.Conn, msg string) string {
-	if h.OBSClient == nil {
-		return h.errorResponse("OBS nie jest połączony")
-	}
-
+// restoreMicrophones włącza mikrofony z is_visible = true
+func (h *SocketHandler) restoreMicrophones() (int, error) {
 	// Znajdź scenę MIKROFONY
 	var scene models.Scene
 	if err := h.DB.Where("name = ?", "MIKROFONY").First(&scene).Error; err != nil {
-		return h.errorResponse("Scena MIKROFONY nie znaleziona")
+		return 0, fmt.Errorf("Scena MIKROFONY nie znaleziona")
 	}
 
 	// Pobierz mikrofony z is_visible = true
@@ -410,7 +565,7 @@ func (h *SocketHandler) handleRestoreMicrophones(s socketio.Conn, msg string) st
 		} else {
 			restoredCount++
 			// Broadcast zmiany do klientów
-			h.Server.BroadcastToNamespace("/", "source_changed", map[string]interface{}{
+			h.Broadcast("source_changed", map[string]interface{}{
 				"scene_name":  "MIKROFONY",
 				"source_name": source.Name,
 				"visible":     true,
@@ -419,10 +574,7 @@ func (h *SocketHandler) handleRestoreMicrophones(s socketio.Conn, msg string) st
 	}
 
 	log.Printf("Przywrócono %d mikrofonów (kamery)", restoredCount)
-
-	return h.successResponse(map[string]interface{}{
-		"restored": restoredCount,
-	})
+	return restoredCount, nil
 }
 
 func (h *SocketHandler) successResponse(data interface{}) string {
@@ -494,18 +646,18 @@ func (h *SocketHandler) handleSetInputVolume(s socketio.Conn, msg string) string
 		return h.errorResponse("OBS not connected")
 	}
 
-	// Zarejestruj że TO NASZA ZMIANA (dla VolumeMonitor)
-	if h.VolumeMonitor != nil {
-		h.VolumeMonitor.RegisterOurChange(data.InputName, data.InputVolumeDb)
-	}
+	previous, previousOK := h.currentInputVolume(data.InputName)
 
-	// Ustaw głośność w OBS
-	err := h.OBSClient.SetInputVolume(data.InputName, data.InputVolumeDb)
+	err := h.setInputVolume(data.InputName, data.InputVolumeDb)
 	if err != nil {
 		log.Printf("Error setting volume for %s: %v", data.InputName, err)
 		return h.errorResponse(err.Error())
 	}
 
+	if previousOK && previous != data.InputVolumeDb {
+		h.Journal.RecordVolume(operatorFromConn(s), data.InputName, previous, data.InputVolumeDb)
+	}
+
 	log.Printf("Volume set: %s = %.2f dB", data.InputName, data.InputVolumeDb)
 
 	return h.successResponse(map[string]interface{}{
@@ -514,6 +666,567 @@ func (h *SocketHandler) handleSetInputVolume(s socketio.Conn, msg string) string
 	})
 }
 
+// setInputVolume ustawia głośność źródła audio w OBS
+func (h *SocketHandler) setInputVolume(inputName string, volumeDb float64) error {
+	// Zarejestruj że TO NASZA ZMIANA (dla VolumeMonitor)
+	if h.VolumeMonitor != nil {
+		h.VolumeMonitor.RegisterOurChange(inputName, volumeDb)
+	}
+
+	// Ustaw głośność w OBS
+	return h.OBSClient.SetInputVolume(inputName, volumeDb)
+}
+
+// currentInputVolume pobiera aktualną głośność wejścia (cache VolumeMonitor lub OBS)
+func (h *SocketHandler) currentInputVolume(inputName string) (float64, bool) {
+	if h.VolumeMonitor != nil {
+		if volume, ok := h.VolumeMonitor.GetCachedVolume(inputName); ok {
+			return volume, true
+		}
+	}
+
+	volume, err := h.OBSClient.GetInputVolume(inputName)
+	if err != nil {
+		return 0, false
+	}
+	return volume, true
+}
+
+// handleRundownGetState - pobierz stan realizacji rundownu
+func (h *SocketHandler) handleRundownGetState(s socketio.Conn, msg string) string {
+	if h.Rundown == nil {
+		return h.errorResponse("Rundown niedostępny")
+	}
+	return h.rundownResponse(h.Rundown.State())
+}
+
+// handleRundownNext - przejdź do następnego segmentu rundownu
+func (h *SocketHandler) handleRundownNext(s socketio.Conn, msg string) string {
+	if h.Rundown == nil {
+		return h.errorResponse("Rundown niedostępny")
+	}
+	return h.rundownResponse(h.Rundown.Next())
+}
+
+// handleRundownPrevious - wróć do poprzedniego segmentu rundownu
+func (h *SocketHandler) handleRundownPrevious(s socketio.Conn, msg string) string {
+	if h.Rundown == nil {
+		return h.errorResponse("Rundown niedostępny")
+	}
+	return h.rundownResponse(h.Rundown.Previous())
+}
+
+// handleRundownGoTo - przejdź do wskazanego segmentu rundownu
+func (h *SocketHandler) handleRundownGoTo(s socketio.Conn, msg string) string {
+	if h.Rundown == nil {
+		return h.errorResponse("Rundown niedostępny")
+	}
+
+	var req struct {
+		SegmentID uint `json:"segment_id"`
+	}
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return h.errorResponse("Błąd")
+	}
+
+	return h.rundownResponse(h.Rundown.GoTo(req.SegmentID))
+}
+
+// handleRundownReset - wyczyść postęp rundownu
+func (h *SocketHandler) handleRundownReset(s socketio.Conn, msg string) string {
+	if h.Rundown == nil {
+		return h.errorResponse("Rundown niedostępny")
+	}
+	return h.rundownResponse(h.Rundown.Reset())
+}
+
+func (h *SocketHandler) rundownResponse(state *RundownLiveState, err error) string {
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(state)
+}
+
+// handleShowClockGetState - pobierz stan zegara programu
+func (h *SocketHandler) handleShowClockGetState(s socketio.Conn, msg string) string {
+	if h.ShowClock == nil {
+		return h.errorResponse("Zegar niedostępny")
+	}
+	return h.showClockResponse(h.ShowClock.State())
+}
+
+// handleShowClockStart - ręczny start zegara programu (od nowa, jeśli już trwa)
+func (h *SocketHandler) handleShowClockStart(s socketio.Conn, msg string) string {
+	if h.ShowClock == nil {
+		return h.errorResponse("Zegar niedostępny")
+	}
+	return h.showClockResponse(h.ShowClock.StartShow("manual", true))
+}
+
+// handleShowClockStop - ręczne zakończenie programu
+func (h *SocketHandler) handleShowClockStop(s socketio.Conn, msg string) string {
+	if h.ShowClock == nil {
+		return h.errorResponse("Zegar niedostępny")
+	}
+	return h.showClockResponse(h.ShowClock.StopShow())
+}
+
+// handleShowClockReset - wyzerowanie zegara programu
+func (h *SocketHandler) handleShowClockReset(s socketio.Conn, msg string) string {
+	if h.ShowClock == nil {
+		return h.errorResponse("Zegar niedostępny")
+	}
+	return h.showClockResponse(h.ShowClock.ResetShow())
+}
+
+// handleSegmentTimerStop - zatrzymanie timera bieżącego segmentu
+func (h *SocketHandler) handleSegmentTimerStop(s socketio.Conn, msg string) string {
+	if h.ShowClock == nil {
+		return h.errorResponse("Zegar niedostępny")
+	}
+	return h.showClockResponse(h.ShowClock.StopSegment())
+}
+
+func (h *SocketHandler) showClockResponse(state *ShowClockState, err error) string {
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(state)
+}
+
+func (h *SocketHandler) handleMacroList(s socketio.Conn, msg string) string {
+	var macros []models.Macro
+	if err := h.DB.Order("name ASC").Find(&macros).Error; err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"macros": macros,
+		"runs":   h.Macros.Runs(),
+	})
+}
+
+func (h *SocketHandler) handleMacroRun(s socketio.Conn, msg string) string {
+	var req struct {
+		MacroID uint   `json:"macro_id"`
+		Name    string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return h.errorResponse("Błąd")
+	}
+
+	var run *MacroRun
+	var err error
+	if req.MacroID != 0 {
+		run, err = h.Macros.Run(req.MacroID, "controller")
+	} else {
+		run, err = h.Macros.RunByName(req.Name, "controller")
+	}
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(run)
+}
+
+// handleMacroCancel anuluje uruchomienie (bez run_id - wszystkie trwające makra)
+func (h *SocketHandler) handleMacroCancel(s socketio.Conn, msg string) string {
+	var req struct {
+		RunID string `json:"run_id"`
+	}
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	cancelled, err := h.Macros.Cancel(req.RunID)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"cancelled": cancelled,
+	})
+}
+
+// handleUndoLast - cofnij ostatnią operację operatora
+func (h *SocketHandler) handleUndoLast(s socketio.Conn, msg string) string {
+	if h.Journal == nil {
+		return h.errorResponse("Dziennik operacji niedostępny")
+	}
+
+	operator := operatorFromConn(s)
+	entry, err := h.Journal.UndoLast(operator)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	history, _ := h.Journal.History(operator, 0)
+	return h.successResponse(map[string]interface{}{
+		"undone":   entry,
+		"operator": operator,
+		"history":  history,
+	})
+}
+
+// handleJournalHistory - ostatnie operacje operatora
+func (h *SocketHandler) handleJournalHistory(s socketio.Conn, msg string) string {
+	if h.Journal == nil {
+		return h.errorResponse("Dziennik operacji niedostępny")
+	}
+
+	operator := operatorFromConn(s)
+	history, err := h.Journal.History(operator, 0)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"operator": operator,
+		"history":  history,
+	})
+}
+
+// handleOverlayHello - overlay po (ponownym) połączeniu przechodzi do pokoju swojego kanału
+// ({channel}, domyślnie main) i dostaje to, co powinno być wyświetlane, razem z motywem sezonu
+func (h *SocketHandler) handleOverlayHello(s socketio.Conn, msg string) string {
+	if h.Overlay == nil {
+		return h.errorResponse("Overlay niedostępny")
+	}
+
+	var req OverlayRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+	channel, err := overlayChannelName(req.Channel)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	s.Leave(roomControllers)
+	leaveRooms(s, roomOverlayPrefix)
+	leaveRooms(s, roomEpisodePrefix)
+	s.Join(roomOverlayPrefix + channel)
+	log.Printf("Overlay %s: kanał %s", s.ID(), channel)
+
+	state := map[string]interface{}{
+		"channel": channel,
+		"items":   h.Overlay.State(channel),
+		"theme":   CurrentOverlayTheme(h.DB),
+	}
+	s.Emit("overlay_state", state)
+	return h.successResponse(state)
+}
+
+// handleOverlayState - szablony, kanały i aktualny stan kanału overlayu (dla kontrolerów)
+func (h *SocketHandler) handleOverlayState(s socketio.Conn, msg string) string {
+	if h.Overlay == nil {
+		return h.errorResponse("Overlay niedostępny")
+	}
+
+	var req OverlayRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+	channel, err := overlayChannelName(req.Channel)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"templates": overlayTemplates,
+		"channels":  h.Overlay.Channels(),
+		"channel":   channel,
+		"items":     h.Overlay.State(channel),
+	})
+}
+
+// handleJoinEpisode - kontroler dostaje zdarzenia przypisań źródeł tylko dla swojego odcinka
+func (h *SocketHandler) handleJoinEpisode(s socketio.Conn, msg string) string {
+	var req struct {
+		EpisodeID uint `json:"episode_id"`
+	}
+	if err := json.Unmarshal([]byte(msg), &req); err != nil || req.EpisodeID == 0 {
+		return h.errorResponse("Invalid episode ID")
+	}
+
+	leaveRooms(s, roomEpisodePrefix)
+	s.Join(episodeRoom(req.EpisodeID))
+	return h.successResponse(map[string]interface{}{"episode_id": req.EpisodeID})
+}
+
+// handleOverlayShow - pokaż grafikę z szablonu
+func (h *SocketHandler) handleOverlayShow(s socketio.Conn, msg string) string {
+	if h.Overlay == nil {
+		return h.errorResponse("Overlay niedostępny")
+	}
+
+	var req OverlayRequest
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return h.errorResponse("Błąd")
+	}
+
+	item, err := h.Overlay.Show(req.Channel, req.Template, req.Params, req.DurationMs)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(item)
+}
+
+// handleOverlayUpdate - zmień parametry wyświetlanej grafiki
+func (h *SocketHandler) handleOverlayUpdate(s socketio.Conn, msg string) string {
+	if h.Overlay == nil {
+		return h.errorResponse("Overlay niedostępny")
+	}
+
+	var req OverlayRequest
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return h.errorResponse("Błąd")
+	}
+
+	item, err := h.Overlay.Update(req.Channel, req.Template, req.Params, req.DurationMs)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(item)
+}
+
+// handleOverlayHide - zdejmij grafikę (bez template - wszystkie)
+func (h *SocketHandler) handleOverlayHide(s socketio.Conn, msg string) string {
+	if h.Overlay == nil {
+		return h.errorResponse("Overlay niedostępny")
+	}
+
+	var req OverlayRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	if req.Template == "" {
+		h.Overlay.HideAll(req.Channel)
+	} else if !h.Overlay.Hide(req.Channel, req.Template) {
+		return h.errorResponse("Szablon nie jest wyświetlany")
+	}
+	return h.successResponse(map[string]interface{}{"channel": req.Channel, "template": req.Template})
+}
+
+// handleLowerThirdShow - pokaż pasek z nazwiskiem
+func (h *SocketHandler) handleLowerThirdShow(s socketio.Conn, msg string) string {
+	if h.LowerThirds == nil {
+		return h.errorResponse("Paski niedostępne")
+	}
+
+	var req LowerThirdRequest
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return h.errorResponse("Błąd")
+	}
+
+	strap, err := h.LowerThirds.Show(req)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(strap)
+}
+
+// handleLowerThirdHide - zdejmij pasek ({channel}, domyślnie main)
+func (h *SocketHandler) handleLowerThirdHide(s socketio.Conn, msg string) string {
+	if h.LowerThirds == nil {
+		return h.errorResponse("Paski niedostępne")
+	}
+
+	var req LowerThirdRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	h.LowerThirds.Hide(req.Channel)
+	return h.successResponse(map[string]interface{}{})
+}
+
+// handleLowerThirdList - paski dla mikrofonów aktualnego odcinka i aktualnie wyświetlany ({channel})
+func (h *SocketHandler) handleLowerThirdList(s socketio.Conn, msg string) string {
+	if h.LowerThirds == nil {
+		return h.errorResponse("Paski niedostępne")
+	}
+
+	var req LowerThirdRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	straps, err := h.LowerThirds.Microphones()
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"microphones": straps,
+		"current":     h.LowerThirds.Current(req.Channel),
+	})
+}
+
+// handleSpeakerDetectGet - ustawienia automatycznych pasków
+func (h *SocketHandler) handleSpeakerDetectGet(s socketio.Conn, msg string) string {
+	if h.Speakers == nil {
+		return h.errorResponse("Automatyczne paski niedostępne")
+	}
+	return h.successResponse(h.Speakers.Settings())
+}
+
+// handleSpeakerDetectSet - zmień ustawienia automatycznych pasków (pola pominięte zostają bez zmian)
+func (h *SocketHandler) handleSpeakerDetectSet(s socketio.Conn, msg string) string {
+	if h.Speakers == nil {
+		return h.errorResponse("Automatyczne paski niedostępne")
+	}
+
+	settings := h.Speakers.Settings()
+	if err := json.Unmarshal([]byte(msg), &settings); err != nil {
+		return h.errorResponse("Błąd")
+	}
+
+	settings, err := h.Speakers.UpdateSettings(settings)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(settings)
+}
+
+// handleTickerState - stan paska informacyjnego i emitowane wiadomości
+func (h *SocketHandler) handleTickerState(s socketio.Conn, msg string) string {
+	if h.Ticker == nil {
+		return h.errorResponse("Pasek informacyjny niedostępny")
+	}
+	return h.successResponse(h.Ticker.State())
+}
+
+// handleTickerStart - uruchom pasek dla aktualnego odcinka ({channel, speed}, opcjonalne)
+func (h *SocketHandler) handleTickerStart(s socketio.Conn, msg string) string {
+	if h.Ticker == nil {
+		return h.errorResponse("Pasek informacyjny niedostępny")
+	}
+
+	var req struct {
+		Channel string `json:"channel"`
+		Speed   int    `json:"speed"`
+	}
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	state, err := h.Ticker.Start(req.Channel, req.Speed)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(state)
+}
+
+// handleTickerStop - zdejmij pasek informacyjny
+func (h *SocketHandler) handleTickerStop(s socketio.Conn, msg string) string {
+	if h.Ticker == nil {
+		return h.errorResponse("Pasek informacyjny niedostępny")
+	}
+	return h.successResponse(h.Ticker.Stop())
+}
+
+// handleCreditsRoll - napisy końcowe ({episode_id, speed, channel, type_order}, opcjonalne)
+func (h *SocketHandler) handleCreditsRoll(s socketio.Conn, msg string) string {
+	var req CreditsRollRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	item, err := rollCredits(h, req)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(item)
+}
+
+// handlePanicState - stan przycisku awaryjnego
+func (h *SocketHandler) handlePanicState(s socketio.Conn, msg string) string {
+	if h.Panic == nil {
+		return h.errorResponse("Przycisk awaryjny niedostępny")
+	}
+	return h.successResponse(h.Panic.State())
+}
+
+// handlePanicActivate - tryb awaryjny: scena zastępcza/plansza, wyciszenie mikrofonów, muzyka
+func (h *SocketHandler) handlePanicActivate(s socketio.Conn, msg string) string {
+	if h.Panic == nil {
+		return h.errorResponse("Przycisk awaryjny niedostępny")
+	}
+
+	state, err := h.Panic.Activate("controller")
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(state)
+}
+
+// handlePanicResume - powrót do stanu anteny sprzed trybu awaryjnego
+func (h *SocketHandler) handlePanicResume(s socketio.Conn, msg string) string {
+	if h.Panic == nil {
+		return h.errorResponse("Przycisk awaryjny niedostępny")
+	}
+
+	state, err := h.Panic.Resume("controller")
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(state)
+}
+
+// handleSponsorRotationState - stan rotacji sponsorów
+func (h *SocketHandler) handleSponsorRotationState(s socketio.Conn, msg string) string {
+	if h.Sponsors == nil {
+		return h.errorResponse("Rotacja sponsorów niedostępna")
+	}
+	return h.successResponse(h.Sponsors.State())
+}
+
+// handleSponsorRotationStart - uruchom rotację ({output, channel, scene_name, source_name}, opcjonalne)
+func (h *SocketHandler) handleSponsorRotationStart(s socketio.Conn, msg string) string {
+	if h.Sponsors == nil {
+		return h.errorResponse("Rotacja sponsorów niedostępna")
+	}
+
+	var req SponsorRotationRequest
+	if msg != "" {
+		if err := json.Unmarshal([]byte(msg), &req); err != nil {
+			return h.errorResponse("Błąd")
+		}
+	}
+
+	state, err := h.Sponsors.Start(req)
+	if err != nil {
+		return h.errorResponse(err.Error())
+	}
+	return h.successResponse(state)
+}
+
+// handleSponsorRotationStop - zatrzymaj rotację i zdejmij logo
+func (h *SocketHandler) handleSponsorRotationStop(s socketio.Conn, msg string) string {
+	if h.Sponsors == nil {
+		return h.errorResponse("Rotacja sponsorów niedostępna")
+	}
+	return h.successResponse(h.Sponsors.Stop())
+}
+
 // handleGetInputVolume - pobierz aktualną głośność źródła audio
 // func (h *SocketHandler) handleGetInputVolume(s socketio.Conn, inputName string) string {
 // 	if h.OBSClient == nil {
@@ -6,6 +6,7 @@ import (
 	"obs-controller/handlers"
 	"obs-controller/models"
 	"obs-controller/obsws"
+	"obs-controller/utils"
 	"os"
 	"strings"
 	"time"
@@ -58,6 +59,18 @@ func main() {
 	volumeMonitor.Start()
 	log.Println("Volume Monitor OK")
 
+	// Inicjalizacja Media Monitor (punkty in/out)
+	mediaMonitor := handlers.NewMediaMonitor(db, obsClient, socketHandler)
+	socketHandler.MediaMonitor = mediaMonitor
+	mediaMonitor.Start()
+	log.Println("Media Monitor OK")
+
+	// Dziennik emisji (as-run log)
+	asRunLogger := handlers.NewAsRunLogger(db, obsClient, socketHandler)
+	socketHandler.AsRun = asRunLogger
+	asRunLogger.Start()
+	log.Println("As-Run Logger OK")
+
 	// Inicjalizacja handlerów
 	seasonHandler := handlers.NewSeasonHandler(db)
 	episodeHandler := handlers.NewEpisodeHandler(db)
@@ -70,15 +83,117 @@ func main() {
 	cameraTypeHandler := handlers.NewCameraTypeHandler(db) // NOWE
 	sceneHandler := handlers.NewSceneHandler(db)
 	mediaGroupHandler := handlers.NewMediaGroupHandler(db)
-	settingsHandler := handlers.NewSettingsHandler(db)
-
-	// Middleware do sprawdzania wymagań i inicjalizacji
-	initMiddleware := handlers.NewInitMiddleware(db, obsClient)
 
 	// Ścieżka do mediów
 	mediaPath := "./media"
 	os.MkdirAll(mediaPath, 0755)
+
+	// Sprawdzanie adresów mediów w sieci lokalnej (domyślnie serwer pobiera tylko adresy publiczne)
+	if os.Getenv("MEDIA_URL_ALLOW_PRIVATE") == "1" {
+		utils.AllowPrivateURLs = true
+		log.Println("UWAGA: serwer sprawdza adresy mediów w sieci lokalnej (MEDIA_URL_ALLOW_PRIVATE)")
+	}
+
+	settingsHandler := handlers.NewSettingsHandler(db, mediaPath)
+	mediaGCHandler := handlers.NewMediaGCHandler(db, mediaPath)
+	mediaGCHandler.PurgeTrash()
+	watchFolderHandler := handlers.NewWatchFolderHandler(db)
+	mediaStreamHandler := handlers.NewMediaStreamHandler(db, mediaPath, os.Getenv("MEDIA_PREVIEW_TOKEN"))
+	rundownHandler := handlers.NewRundownHandler(db)
+	asRunHandler := handlers.NewAsRunHandler(db)
+	chaptersHandler := handlers.NewChaptersHandler(db)
+
+	// Realizacja rundownu na żywo ("następny segment")
+	rundownExecutor := handlers.NewRundownExecutor(db, obsClient, socketHandler, mediaPath)
+	socketHandler.Rundown = rundownExecutor
+	rundownHandler.Executor = rundownExecutor
+
+	// Zegar programu i timery segmentów
+	showClock := handlers.NewShowClock(db, obsClient, socketHandler)
+	socketHandler.ShowClock = showClock
+	showClock.Start()
+
+	// Makra (zapisane sekwencje akcji)
+	macroRunner := handlers.NewMacroRunner(db, socketHandler, mediaPath)
+	socketHandler.Macros = macroRunner
+	macroHandler := handlers.NewMacroHandler(db, macroRunner)
+
+	// Skrypty Lua (uruchamiane przez zdarzenia reguł lub ręcznie)
+	scriptEngine := handlers.NewScriptEngine(db, obsClient, socketHandler)
+	socketHandler.Scripts = scriptEngine
+	scriptHandler := handlers.NewScriptHandler(db, scriptEngine)
+
+	// Reguły automatyzacji (zdarzenie → warunki → akcje)
+	ruleEngine := handlers.NewRuleEngine(db, obsClient, socketHandler)
+	socketHandler.Rules = ruleEngine
+	ruleEngine.Start()
+	ruleHandler := handlers.NewRuleHandler(db)
+	log.Println("Rule Engine OK")
+
+	// Plan emisji względem godziny emisji odcinka
+	scheduler := handlers.NewScheduler(db, obsClient, socketHandler, mediaPath)
+	scheduler.Start()
+	schedulerHandler := handlers.NewSchedulerHandler(db, scheduler)
+
+	// Stan grafik overlayu (szablony, automatyczne zdejmowanie, odtworzenie po połączeniu)
+	overlayManager := handlers.NewOverlayManager(socketHandler)
+	socketHandler.Overlay = overlayManager
+	overlayHandler := handlers.NewOverlayHandler(overlayManager)
+
+	// Overlay bez przeglądarki: szablony w źródłach tekstowych i obrazach OBS
+	overlayManager.Native = handlers.NewNativeOverlay(db, obsClient)
+	nativeOverlayHandler := handlers.NewNativeOverlayHandler(db, overlayManager)
+
+	// Paski z nazwiskami osób przy mikrofonach
+	lowerThirds := handlers.NewLowerThirds(db, socketHandler)
+	socketHandler.LowerThirds = lowerThirds
+
+	// Automatyczne paski gości z aktywności mikrofonów (mierniki audio OBS)
+	speakerDetector := handlers.NewSpeakerDetector(db, obsClient, socketHandler)
+	socketHandler.Speakers = speakerDetector
+	speakerDetector.Start()
+	speakerDetectHandler := handlers.NewSpeakerDetectHandler(speakerDetector)
+
+	// Motywy sezonów (oprawa graficzna overlayu)
+	seasonHandler.SocketHandler = socketHandler
+
+	// Pokój aktualnego odcinka dla kontrolerów (przypisania źródeł, niedostępne URL mediów)
+	episodeHandler.SocketHandler = socketHandler
+	themeHandler := handlers.NewThemeHandler(db, mediaPath, socketHandler)
+
+	// Napisy końcowe z ekipy i gości odcinka
+	creditsHandler := handlers.NewCreditsHandler(db, socketHandler)
+
+	// Pasek informacyjny (crawl) z wiadomości odcinka
+	tickerFeed := handlers.NewTickerFeed(db, socketHandler)
+	socketHandler.Ticker = tickerFeed
+	tickerHandler := handlers.NewTickerHandler(db, tickerFeed)
+
+	// Przycisk awaryjny ("usterki techniczne") z powrotem do stanu sprzed awarii
+	panicButton := handlers.NewPanicButton(db, socketHandler)
+	socketHandler.Panic = panicButton
+	panicHandler := handlers.NewPanicHandler(db, panicButton)
+
+	// Rotacja logotypów sponsorów z dziennikiem czasu antenowego
+	sponsorRotation := handlers.NewSponsorRotation(db, socketHandler, mediaPath)
+	socketHandler.Sponsors = sponsorRotation
+	sponsorHandler := handlers.NewSponsorHandler(db, mediaPath, sponsorRotation)
+
+	// Dziennik operacji operatorów (undo)
+	actionJournal := handlers.NewActionJournal(db, obsClient, socketHandler, mediaPath)
+	socketHandler.Journal = actionJournal
+	mediaGroupHandler.Journal = actionJournal
+	journalHandler := handlers.NewJournalHandler(db, actionJournal)
+
+	// Auto-import z obserwowanych folderów
+	watchFolderService := handlers.NewWatchFolderService(db, mediaPath, socketHandler)
+	watchFolderService.Start()
+	log.Println("Watch Folder Service OK")
+
+	// Middleware do sprawdzania wymagań i inicjalizacji
+	initMiddleware := handlers.NewInitMiddleware(db, obsClient)
 	episodeMediaHandler := handlers.NewEpisodeMediaHandler(db, mediaPath, obsClient)
+	episodeMediaHandler.MediaMonitor = mediaMonitor
 	episodeSourceHandler := handlers.NewEpisodeSourceHandler(db, obsClient, mediaPath, socketHandler)
 
 	// Routing
@@ -131,6 +246,14 @@ func main() {
 		http.ServeFile(w, r, "./web/overlay.html")
 	})
 
+	router.HandleFunc("/clock", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./web/clock.html")
+	})
+
+	router.HandleFunc("/scripts", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./web/scripts.html")
+	})
+
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
 			http.Redirect(w, r, "/settings", http.StatusSeeOther)
@@ -148,6 +271,10 @@ func main() {
 	api.HandleFunc("/seasons/{id}", seasonHandler.UpdateSeason).Methods("PUT")
 	api.HandleFunc("/seasons/{id}", seasonHandler.DeleteSeason).Methods("DELETE")
 	api.HandleFunc("/seasons/{id}/set-current", seasonHandler.SetCurrentSeason).Methods("POST")
+	api.HandleFunc("/seasons/{id}/theme", themeHandler.GetTheme).Methods("GET")
+	api.HandleFunc("/seasons/{id}/theme", themeHandler.UpdateTheme).Methods("PUT")
+	api.HandleFunc("/seasons/{id}/theme/files", themeHandler.UploadThemeFile).Methods("POST")
+	api.HandleFunc("/seasons/{id}/theme/files/{name}", themeHandler.GetThemeFile).Methods("GET")
 
 	// API REST dla Episode
 	api.HandleFunc("/episodes", episodeHandler.GetEpisodes).Methods("GET")
@@ -206,12 +333,148 @@ func main() {
 	api.HandleFunc("/episodes/{episode_id}/media", episodeMediaHandler.CreateEpisodeMedia).Methods("POST")
 	api.HandleFunc("/episodes/{episode_id}/media/{id}", episodeMediaHandler.UpdateEpisodeMedia).Methods("PUT")
 	api.HandleFunc("/episodes/{episode_id}/media/{id}", episodeMediaHandler.DeleteEpisodeMedia).Methods("DELETE")
+	api.HandleFunc("/episodes/{episode_id}/media/{id}/url-status", episodeMediaHandler.CheckMediaURL).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/media/{id}/stream", mediaStreamHandler.RequireToken(mediaStreamHandler.StreamMedia)).Methods("GET", "HEAD")
 	// USUNIĘTE: SetCurrentMedia - teraz przez MediaGroup
 	// USUNIĘTE: ReorderEpisodeMedia - kolejność teraz w grupie
 	api.HandleFunc("/episodes/{episode_id}/media/upload", episodeMediaHandler.UploadMedia).Methods("POST")
 	api.HandleFunc("/episodes/{episode_id}/media/files", episodeMediaHandler.ListMediaFiles).Methods("GET")
 	api.HandleFunc("/episodes/current/media/scene/{scene_name}", episodeMediaHandler.GetCurrentMediaForScene).Methods("GET")
 
+	// API REST dla porządkowania mediów (sieroty, kosz, zajętość dysku)
+	api.HandleFunc("/media/gc-report", mediaGCHandler.GetReport).Methods("GET")
+	api.HandleFunc("/media/gc/trash", mediaGCHandler.TrashOrphans).Methods("POST")
+	api.HandleFunc("/media/gc/purge", mediaGCHandler.PurgeTrashHandler).Methods("POST")
+
+	// API REST dla obserwowanych folderów (auto-import)
+	api.HandleFunc("/watch-folders", watchFolderHandler.GetWatchFolders).Methods("GET")
+	api.HandleFunc("/watch-folders", watchFolderHandler.CreateWatchFolder).Methods("POST")
+	api.HandleFunc("/watch-folders/{id}", watchFolderHandler.GetWatchFolder).Methods("GET")
+	api.HandleFunc("/watch-folders/{id}", watchFolderHandler.UpdateWatchFolder).Methods("PUT")
+	api.HandleFunc("/watch-folders/{id}", watchFolderHandler.DeleteWatchFolder).Methods("DELETE")
+
+	// API REST dla Rundown (scenariusz odcinka)
+	api.HandleFunc("/episodes/{episode_id}/rundown", rundownHandler.GetRundown).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/rundown", rundownHandler.CreateSegment).Methods("POST")
+	api.HandleFunc("/episodes/{episode_id}/rundown/{id}", rundownHandler.GetSegment).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/rundown/{id}", rundownHandler.UpdateSegment).Methods("PUT")
+	api.HandleFunc("/episodes/{episode_id}/rundown/{id}", rundownHandler.DeleteSegment).Methods("DELETE")
+	api.HandleFunc("/episodes/{episode_id}/rundown/{id}/reorder", rundownHandler.ReorderSegment).Methods("PUT")
+	api.HandleFunc("/rundown/live", rundownHandler.GetLiveState).Methods("GET")
+	api.HandleFunc("/rundown/live/next", rundownHandler.NextSegment).Methods("POST")
+	api.HandleFunc("/rundown/live/previous", rundownHandler.PreviousSegment).Methods("POST")
+	api.HandleFunc("/rundown/live/goto/{id}", rundownHandler.GoToSegment).Methods("POST")
+	api.HandleFunc("/rundown/live/reset", rundownHandler.ResetLive).Methods("POST")
+
+	// API REST dla dziennika emisji (as-run)
+	api.HandleFunc("/episodes/{episode_id}/as-run", asRunHandler.GetAsRun).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/as-run", asRunHandler.ClearAsRun).Methods("DELETE")
+	api.HandleFunc("/episodes/{episode_id}/as-run/csv", asRunHandler.GetAsRunCSV).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/as-run/report", asRunHandler.GetAsRunReport).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/chapters", chaptersHandler.GetChapters).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/chapters/youtube", chaptersHandler.GetYouTubeChapters).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/chapters/ffmetadata", chaptersHandler.GetFFMetadataChapters).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/chapters/podcast", chaptersHandler.GetPodcastChapters).Methods("GET")
+
+	// API REST dla zegara programu
+	api.HandleFunc("/show-clock", showClock.GetClock).Methods("GET")
+	api.HandleFunc("/show-clock/start", showClock.StartClock).Methods("POST")
+	api.HandleFunc("/show-clock/stop", showClock.StopClock).Methods("POST")
+	api.HandleFunc("/show-clock/reset", showClock.ResetClock).Methods("POST")
+	api.HandleFunc("/show-clock/segment/stop", showClock.StopSegmentTimer).Methods("POST")
+
+	// API REST dla makr
+	api.HandleFunc("/macros", macroHandler.GetMacros).Methods("GET")
+	api.HandleFunc("/macros", macroHandler.CreateMacro).Methods("POST")
+	api.HandleFunc("/macros/runs", macroHandler.GetRuns).Methods("GET")
+	api.HandleFunc("/macros/runs/{run_id}/cancel", macroHandler.CancelRun).Methods("POST")
+	api.HandleFunc("/macros/by-name/{name}/run", macroHandler.RunMacroByName).Methods("POST")
+	api.HandleFunc("/macros/{id}", macroHandler.GetMacro).Methods("GET")
+	api.HandleFunc("/macros/{id}", macroHandler.UpdateMacro).Methods("PUT")
+	api.HandleFunc("/macros/{id}", macroHandler.DeleteMacro).Methods("DELETE")
+	api.HandleFunc("/macros/{id}/run", macroHandler.RunMacro).Methods("POST")
+
+	// API REST dla reguł automatyzacji
+	api.HandleFunc("/rules", ruleHandler.GetRules).Methods("GET")
+	api.HandleFunc("/rules", ruleHandler.CreateRule).Methods("POST")
+	api.HandleFunc("/rules/{id}", ruleHandler.GetRule).Methods("GET")
+	api.HandleFunc("/rules/{id}", ruleHandler.UpdateRule).Methods("PUT")
+	api.HandleFunc("/rules/{id}", ruleHandler.DeleteRule).Methods("DELETE")
+
+	// API REST dla skryptów
+	api.HandleFunc("/scripts", scriptHandler.GetScripts).Methods("GET")
+	api.HandleFunc("/scripts", scriptHandler.CreateScript).Methods("POST")
+	api.HandleFunc("/scripts/events", scriptHandler.GetScriptEvents).Methods("GET")
+	api.HandleFunc("/scripts/{id}", scriptHandler.GetScript).Methods("GET")
+	api.HandleFunc("/scripts/{id}", scriptHandler.UpdateScript).Methods("PUT")
+	api.HandleFunc("/scripts/{id}", scriptHandler.DeleteScript).Methods("DELETE")
+	api.HandleFunc("/scripts/{id}/run", scriptHandler.RunScript).Methods("POST")
+	api.HandleFunc("/scripts/{id}/logs", scriptHandler.GetScriptLogs).Methods("GET")
+	api.HandleFunc("/scripts/{id}/logs", scriptHandler.ClearScriptLogs).Methods("DELETE")
+
+	// API REST dla planu emisji
+	api.HandleFunc("/schedule", schedulerHandler.GetSchedule).Methods("GET")
+	api.HandleFunc("/schedule", schedulerHandler.CreateEntry).Methods("POST")
+	api.HandleFunc("/schedule/preflight", schedulerHandler.GetPreflight).Methods("GET")
+	api.HandleFunc("/schedule/{id}", schedulerHandler.UpdateEntry).Methods("PUT")
+	api.HandleFunc("/schedule/{id}", schedulerHandler.DeleteEntry).Methods("DELETE")
+	api.HandleFunc("/schedule/{id}/cancel", schedulerHandler.CancelEntry).Methods("POST")
+	api.HandleFunc("/schedule/{id}/restore", schedulerHandler.RestoreEntry).Methods("POST")
+
+	// API REST dla overlayu (szablony grafik)
+	api.HandleFunc("/overlay", overlayHandler.GetOverlay).Methods("GET")
+	api.HandleFunc("/overlay/theme", themeHandler.GetCurrentTheme).Methods("GET")
+	api.HandleFunc("/overlay/hide-all", overlayHandler.HideAll).Methods("POST")
+	api.HandleFunc("/overlay/native", nativeOverlayHandler.GetTargets).Methods("GET")
+	api.HandleFunc("/overlay/native", nativeOverlayHandler.CreateTarget).Methods("POST")
+	api.HandleFunc("/overlay/native/sync", nativeOverlayHandler.SyncTargets).Methods("POST")
+	api.HandleFunc("/overlay/native/{id}", nativeOverlayHandler.UpdateTarget).Methods("PUT")
+	api.HandleFunc("/overlay/native/{id}", nativeOverlayHandler.DeleteTarget).Methods("DELETE")
+	api.HandleFunc("/overlay/{template}/show", overlayHandler.ShowTemplate).Methods("POST")
+	api.HandleFunc("/overlay/{template}/update", overlayHandler.UpdateTemplate).Methods("POST")
+	api.HandleFunc("/overlay/{template}/hide", overlayHandler.HideTemplate).Methods("POST")
+
+	// API REST dla automatycznych pasków z nazwiskami
+	api.HandleFunc("/lower-thirds/auto", speakerDetectHandler.GetSettings).Methods("GET")
+	api.HandleFunc("/lower-thirds/auto", speakerDetectHandler.UpdateSettings).Methods("PUT")
+
+	// API REST dla paska informacyjnego (wiadomości odcinka i emisja na żywo)
+	api.HandleFunc("/episodes/{episode_id}/ticker", tickerHandler.GetMessages).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/ticker", tickerHandler.CreateMessage).Methods("POST")
+	api.HandleFunc("/episodes/{episode_id}/ticker/{id}", tickerHandler.UpdateMessage).Methods("PUT")
+	api.HandleFunc("/episodes/{episode_id}/ticker/{id}", tickerHandler.DeleteMessage).Methods("DELETE")
+	api.HandleFunc("/episodes/{episode_id}/ticker/{id}/reorder", tickerHandler.ReorderMessage).Methods("PUT")
+	api.HandleFunc("/ticker/live", tickerHandler.GetLiveState).Methods("GET")
+	api.HandleFunc("/ticker/live/start", tickerHandler.StartLive).Methods("POST")
+	api.HandleFunc("/ticker/live/stop", tickerHandler.StopLive).Methods("POST")
+
+	// API REST dla napisów końcowych
+	api.HandleFunc("/episodes/{episode_id}/credits", creditsHandler.GetCredits).Methods("GET")
+	api.HandleFunc("/credits/roll", creditsHandler.RollCredits).Methods("POST")
+
+	// API REST dla przycisku awaryjnego
+	api.HandleFunc("/panic", panicHandler.GetState).Methods("GET")
+	api.HandleFunc("/panic/activate", panicHandler.Activate).Methods("POST")
+	api.HandleFunc("/panic/resume", panicHandler.Resume).Methods("POST")
+	api.HandleFunc("/panic/settings", panicHandler.GetSettings).Methods("GET")
+	api.HandleFunc("/panic/settings", panicHandler.UpdateSettings).Methods("PUT")
+
+	// API REST dla sponsorów (rotacja logotypów)
+	api.HandleFunc("/sponsors", sponsorHandler.GetSponsors).Methods("GET")
+	api.HandleFunc("/sponsors", sponsorHandler.CreateSponsor).Methods("POST")
+	api.HandleFunc("/sponsors/rotation", sponsorHandler.GetRotation).Methods("GET")
+	api.HandleFunc("/sponsors/rotation/start", sponsorHandler.StartRotation).Methods("POST")
+	api.HandleFunc("/sponsors/rotation/stop", sponsorHandler.StopRotation).Methods("POST")
+	api.HandleFunc("/sponsors/{id}", sponsorHandler.UpdateSponsor).Methods("PUT")
+	api.HandleFunc("/sponsors/{id}", sponsorHandler.DeleteSponsor).Methods("DELETE")
+	api.HandleFunc("/sponsors/{id}/logo", sponsorHandler.UploadLogo).Methods("POST")
+	api.HandleFunc("/sponsors/{id}/logo", sponsorHandler.GetLogo).Methods("GET")
+	api.HandleFunc("/episodes/{episode_id}/sponsors/airtime", sponsorHandler.GetAirtime).Methods("GET")
+
+	// API REST dla dziennika operacji (undo)
+	api.HandleFunc("/journal", journalHandler.GetHistory).Methods("GET")
+	api.HandleFunc("/journal/undo", journalHandler.UndoLast).Methods("POST")
+
 	// API REST dla Scenes
 	api.HandleFunc("/scenes", sceneHandler.GetScenes).Methods("GET")
 	api.HandleFunc("/scenes/media", sceneHandler.GetMediaScenes).Methods("GET")
@@ -259,7 +522,8 @@ func main() {
 	log.Println("Odcinki: http://localhost:8080/episodes")
 	log.Println("Ekipa: http://localhost:8080/staff")
 	log.Println("Goście: http://localhost:8080/guests")
-	log.Println("Overlay: http://localhost:8080/overlay")
+	log.Println("Overlay: http://localhost:8080/overlay (inne kanały: ?channel=vertical)")
+	log.Println("Skrypty: http://localhost:8080/scripts")
 	log.Println("========================================")
 
 	if err := http.ListenAndServe(":8080", router); err != nil {
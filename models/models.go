@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -9,39 +10,70 @@ import (
 
 // Season reprezentuje sezon audycji
 type Season struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Number      int       `gorm:"uniqueIndex;not null" json:"number"` // Numer sezonu
-	Description string    `gorm:"type:text" json:"description"`
-	IsCurrent   bool      `gorm:"default:false;index" json:"is_current"` // Czy to aktualny sezon
-	Episodes    []Episode `gorm:"foreignKey:SeasonID" json:"episodes"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Number      int          `gorm:"uniqueIndex;not null" json:"number"` // Numer sezonu
+	Description string       `gorm:"type:text" json:"description"`
+	IsCurrent   bool         `gorm:"default:false;index" json:"is_current"` // Czy to aktualny sezon
+	Episodes    []Episode    `gorm:"foreignKey:SeasonID" json:"episodes"`
+	Theme       *SeasonTheme `gorm:"foreignKey:SeasonID;constraint:OnDelete:CASCADE" json:"theme,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Układy paska z nazwiskiem w motywie sezonu
+const (
+	StrapLayoutLeft   = "left"
+	StrapLayoutCenter = "center"
+	StrapLayoutRight  = "right"
+)
+
+// StrapLayouts zawiera dozwolone układy paska z nazwiskiem
+var StrapLayouts = []string{StrapLayoutLeft, StrapLayoutCenter, StrapLayoutRight}
+
+// SeasonTheme reprezentuje oprawę graficzną overlayu w sezonie (kolory, fonty, logo, układ paska)
+// Pliki (logo, font) leżą w media/season_{number}/theme/
+type SeasonTheme struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	SeasonID       uint      `gorm:"uniqueIndex;not null" json:"season_id"`
+	PrimaryColor   string    `gorm:"size:7" json:"primary_color"`   // #rrggbb - tła grafik
+	SecondaryColor string    `gorm:"size:7" json:"secondary_color"` // #rrggbb - drugi kolor gradientów
+	TextColor      string    `gorm:"size:7" json:"text_color"`
+	AccentColor    string    `gorm:"size:7" json:"accent_color"` // Wyróżnienia (pilne wiadomości paska)
+	FontFamily     string    `gorm:"size:100" json:"font_family"`
+	FontFile       string    `gorm:"size:300" json:"font_file"` // Plik fontu w folderze motywu (pusty = font systemowy)
+	LogoFile       string    `gorm:"size:300" json:"logo_file"` // Plik logo w folderze motywu (pusty = logo domyślne)
+	StrapLayout    string    `gorm:"size:20" json:"strap_layout"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // Episode reprezentuje odcinek audycji
 type Episode struct {
-	ID            uint           `gorm:"primaryKey" json:"id"`
-	SeasonID      uint           `gorm:"index;not null" json:"season_id"`
-	Season        Season         `gorm:"foreignKey:SeasonID" json:"season"`
-	EpisodeNumber int            `gorm:"not null" json:"episode_number"` // Ogólny numer odcinka (ciągły)
-	SeasonEpisode int            `gorm:"not null" json:"season_episode"` // Numer w sezonie
-	Title         string         `gorm:"size:300;not null" json:"title"` // Tytuł odcinka
-	EpisodeDate   time.Time      `json:"episode_date"`
-	IsCurrent     bool           `gorm:"default:false;index" json:"is_current"` // Czy to aktualny odcinek
-	Staff         []EpisodeStaff `gorm:"foreignKey:EpisodeID" json:"staff"`
-	Guests        []EpisodeGuest `gorm:"foreignKey:EpisodeID" json:"guests"`
-	MediaGroups   []MediaGroup   `gorm:"foreignKey:EpisodeID" json:"media_groups"`
-	Media         []EpisodeMedia `gorm:"foreignKey:EpisodeID;constraint:OnDelete:CASCADE" json:"media"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	SeasonID       uint           `gorm:"index;not null" json:"season_id"`
+	Season         Season         `gorm:"foreignKey:SeasonID" json:"season"`
+	EpisodeNumber  int            `gorm:"not null" json:"episode_number"` // Ogólny numer odcinka (ciągły)
+	SeasonEpisode  int            `gorm:"not null" json:"season_episode"` // Numer w sezonie
+	Title          string         `gorm:"size:300;not null" json:"title"` // Tytuł odcinka
+	EpisodeDate    time.Time      `json:"episode_date"`
+	AirTime        *time.Time     `json:"air_time"`                              // Godzina emisji (nil = brak, plan emisji nieaktywny)
+	TargetDuration int            `gorm:"default:0" json:"target_duration"`      // Docelowa długość odcinka w sekundach (0 = brak)
+	IsCurrent      bool           `gorm:"default:false;index" json:"is_current"` // Czy to aktualny odcinek
+	Staff          []EpisodeStaff `gorm:"foreignKey:EpisodeID" json:"staff"`
+	Guests         []EpisodeGuest `gorm:"foreignKey:EpisodeID" json:"guests"`
+	MediaGroups    []MediaGroup   `gorm:"foreignKey:EpisodeID" json:"media_groups"`
+	Media          []EpisodeMedia `gorm:"foreignKey:EpisodeID;constraint:OnDelete:CASCADE" json:"media"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
 // StaffType reprezentuje typ członka ekipy
 type StaffType struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:100;uniqueIndex;not null" json:"name"` // np. "Redaktor prowadzący", "Realizator dźwięku"
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"size:100;uniqueIndex;not null" json:"name"` // np. "Redaktor prowadzący", "Realizator dźwięku"
+	CreditsOrder int       `gorm:"default:0" json:"credits_order"`            // Kolejność w napisach końcowych (rosnąco, potem nazwa)
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // Staff reprezentuje członka ekipy
@@ -130,6 +162,7 @@ type MediaGroup struct {
 	CurrentInScene *uint               `gorm:"index" json:"current_in_scene"`  // NULL = nieużywana, 0 = w obu scenach, scene_id = w konkretnej scenie
 	CurrentScene   *Scene              `gorm:"foreignKey:CurrentInScene" json:"current_scene,omitempty"`
 	MediaItems     []EpisodeMediaGroup `gorm:"foreignKey:MediaGroupID" json:"media_items"`
+	TotalDuration  int                 `gorm:"-" json:"total_duration"` // Suma pełnych długości (playlista VLC bez przycięcia, w sekundach), wyliczana
 	CreatedAt      time.Time           `json:"created_at"`
 	UpdatedAt      time.Time           `json:"updated_at"`
 }
@@ -216,11 +249,449 @@ type EpisodeMedia struct {
 	FilePath       *string             `gorm:"size:1000" json:"file_path"`                    // Ścieżka do pliku (nullable)
 	URL            *string             `gorm:"size:1000" json:"url"`                          // URL jeśli zewnętrzne (nullable)
 	Duration       int                 `json:"duration"`                                      // Czas trwania w sekundach
+	InPoint        int                 `gorm:"default:0" json:"in_point"`                     // Punkt wejścia w milisekundach (0 = od początku)
+	OutPoint       int                 `gorm:"default:0" json:"out_point"`                    // Punkt wyjścia w milisekundach (0 = do końca)
 	MediaGroups    []EpisodeMediaGroup `gorm:"foreignKey:EpisodeMediaID" json:"media_groups"` // Przynależność do grup
 	CreatedAt      time.Time           `json:"created_at"`
 	UpdatedAt      time.Time           `json:"updated_at"`
 }
 
+// Typy segmentów rundownu
+const (
+	SegmentTypeIntro     = "intro"
+	SegmentTypeInterview = "interview"
+	SegmentTypeReport    = "report"
+	SegmentTypeMusic     = "music"
+	SegmentTypeBreak     = "break"
+)
+
+// SegmentTypes zawiera dozwolone typy segmentów
+var SegmentTypes = []string{SegmentTypeIntro, SegmentTypeInterview, SegmentTypeReport, SegmentTypeMusic, SegmentTypeBreak}
+
+// RundownSegment reprezentuje segment w scenariuszu (rundownie) odcinka
+type RundownSegment struct {
+	ID              uint                  `gorm:"primaryKey" json:"id"`
+	EpisodeID       uint                  `gorm:"index;not null" json:"episode_id"`
+	Order           int                   `gorm:"not null" json:"order"` // Kolejność w odcinku
+	Title           string                `gorm:"size:300;not null" json:"title"`
+	Type            string                `gorm:"size:50;not null" json:"type"`      // intro, interview, report, music, break
+	PlannedDuration int                   `gorm:"default:0" json:"planned_duration"` // Planowany czas w sekundach
+	SceneName       string                `gorm:"size:100" json:"scene_name"`        // Docelowa scena (np. KAMERY, MEDIA, REPORTAZE)
+	CameraTypeID    *uint                 `gorm:"index" json:"camera_type_id"`       // Docelowy typ kamery (nullable)
+	CameraType      *CameraType           `gorm:"foreignKey:CameraTypeID" json:"camera_type,omitempty"`
+	Notes           string                `gorm:"type:text" json:"notes"`
+	ActualStartedAt *time.Time            `json:"actual_started_at"`                // Faktyczny start na antenie (nullable)
+	ActualDuration  int                   `gorm:"default:0" json:"actual_duration"` // Faktyczny czas w sekundach (po zakończeniu)
+	Guests          []RundownSegmentGuest `gorm:"foreignKey:SegmentID;constraint:OnDelete:CASCADE" json:"guests"`
+	Media           []RundownSegmentMedia `gorm:"foreignKey:SegmentID;constraint:OnDelete:CASCADE" json:"media"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+// RundownSegmentGuest reprezentuje gościa występującego w segmencie
+type RundownSegmentGuest struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SegmentID uint      `gorm:"index;not null" json:"segment_id"`
+	GuestID   uint      `gorm:"index;not null" json:"guest_id"`
+	Guest     Guest     `gorm:"foreignKey:GuestID" json:"guest"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RundownSegmentMedia reprezentuje media lub grupę mediów użytą w segmencie
+// Wypełnione jest EpisodeMediaID (konkretny plik) albo MediaGroupID (cała grupa)
+type RundownSegmentMedia struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	SegmentID      uint          `gorm:"index;not null" json:"segment_id"`
+	EpisodeMediaID *uint         `gorm:"index" json:"episode_media_id"`
+	EpisodeMedia   *EpisodeMedia `gorm:"foreignKey:EpisodeMediaID" json:"episode_media,omitempty"`
+	MediaGroupID   *uint         `gorm:"index" json:"media_group_id"`
+	MediaGroup     *MediaGroup   `gorm:"foreignKey:MediaGroupID" json:"media_group,omitempty"`
+	Order          int           `gorm:"not null" json:"order"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// RundownProgress reprezentuje postęp realizacji rundownu odcinka na żywo
+// Zapisywany w bazie, aby po restarcie serwera wznowić od właściwego segmentu
+type RundownProgress struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	EpisodeID        uint       `gorm:"uniqueIndex;not null" json:"episode_id"`
+	CurrentSegmentID *uint      `gorm:"index" json:"current_segment_id"` // NULL = rundown nie rozpoczęty
+	SegmentStartedAt *time.Time `json:"segment_started_at"`              // Start bieżącego segmentu (timer)
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// ShowTiming reprezentuje czas emisji odcinka (zegar programu)
+// Start z rozpoczęcia streamu/nagrywania w OBS lub ręcznie z kontrolera
+type ShowTiming struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	EpisodeID uint       `gorm:"uniqueIndex;not null" json:"episode_id"`
+	StartedAt *time.Time `json:"started_at"`                // NULL = program nie rozpoczęty
+	EndedAt   *time.Time `json:"ended_at"`                  // NULL = program trwa (lub nie rozpoczęty)
+	StartedBy string     `gorm:"size:20" json:"started_by"` // "stream", "record", "manual", "rundown"
+	// Start ostatniego nagrywania - od niego liczone są rozdziały pliku (NULL = nie nagrywano)
+	RecordStartedAt *time.Time `json:"record_started_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Typy wpisów as-run
+const (
+	AsRunProgramScene = "program_scene" // Zmiana sceny programu
+	AsRunSourceOn     = "source_on"     // Źródło pokazane na antenie
+	AsRunSourceOff    = "source_off"    // Źródło zdjęte z anteny
+	AsRunMicOpen      = "mic_open"      // Mikrofon otwarty
+	AsRunMicClose     = "mic_close"     // Mikrofon zamknięty
+	AsRunMediaStart   = "media_start"   // Start odtwarzania pliku
+	AsRunMediaEnd     = "media_end"     // Koniec odtwarzania pliku
+	AsRunOverlay      = "overlay"       // Grafika/komunikat w overlay
+	AsRunSegment      = "segment"       // Start segmentu rundownu
+)
+
+// AsRunEntry reprezentuje wpis w dzienniku emisji (as-run log) odcinka
+type AsRunEntry struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	EpisodeID  uint      `gorm:"index;not null" json:"episode_id"`
+	Timestamp  time.Time `gorm:"index;not null" json:"timestamp"`
+	OffsetMs   *int64    `json:"offset_ms"`                                // Czas od startu programu (NULL = przed startem)
+	EventType  string    `gorm:"size:30;index;not null" json:"event_type"` // Typ wpisu (AsRun*)
+	SceneName  string    `gorm:"size:100" json:"scene_name"`
+	SourceName string    `gorm:"size:200" json:"source_name"`
+	Title      string    `gorm:"size:300" json:"title"`        // Tytuł media, osoba przy mikrofonie, akcja overlay
+	MediaID    *uint     `gorm:"index" json:"media_id"`        // Dla media_start/media_end
+	SegmentID  *uint     `gorm:"index" json:"segment_id"`      // Dla segment
+	StaffID    *uint     `gorm:"index" json:"staff_id"`        // Dla mikrofonów prowadzących
+	GuestID    *uint     `gorm:"index" json:"guest_id"`        // Dla mikrofonów gości
+	DurationMs int64     `gorm:"default:0" json:"duration_ms"` // Faktyczny czas (media_end)
+	Details    string    `gorm:"type:text" json:"details"`     // Dodatkowe dane (JSON)
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName - dziennik emisji w tabeli as_run
+func (AsRunEntry) TableName() string {
+	return "as_run"
+}
+
+// Akcje kroków makra
+const (
+	MacroActionSetScene           = "set_scene"           // {scene_name}
+	MacroActionToggleSource       = "toggle_source"       // {scene_name, source_name, visible}
+	MacroActionTakeSource         = "take_source"         // {scene_name, source_name} - jak przycisk w kontrolerze
+	MacroActionSetSourceIndex     = "set_source_index"    // {scene_name, source_name, to_top}
+	MacroActionSetVolume          = "set_volume"          // {input_name, volume_db}
+	MacroActionWait               = "wait"                // {ms}
+	MacroActionOverlayMessage     = "overlay_message"     // {data} (data.channel = kanał overlayu)
+	MacroActionLoadMedia          = "load_media"          // {source_name, media_id}
+	MacroActionLoadGroup          = "load_group"          // {source_name, group_id}
+	MacroActionMuteMicrophones    = "mute_microphones"    // {}
+	MacroActionRestoreMicrophones = "restore_microphones" // {}
+	MacroActionStartRecording     = "start_recording"     // {}
+	MacroActionStopRecording      = "stop_recording"      // {}
+	MacroActionStartStreaming     = "start_streaming"     // {}
+	MacroActionStopStreaming      = "stop_streaming"      // {}
+	MacroActionRundownNext        = "rundown_next"        // {}
+	MacroActionRunMacro           = "run_macro"           // {macro_id} lub {macro_name}
+	MacroActionOBSRequest         = "obs_request"         // {request_type, request_data}
+	MacroActionOverlayShow        = "overlay_show"        // {template, params, duration_ms, channel}
+	MacroActionOverlayHide        = "overlay_hide"        // {template, channel} (pusty template = wszystkie)
+	MacroActionRollCredits        = "roll_credits"        // {episode_id, speed, channel} (episode_id 0 = aktualny)
+	MacroActionPanic              = "panic"               // {} - przycisk awaryjny wg PanicSettings
+	MacroActionPanicResume        = "panic_resume"        // {} - powrót do stanu sprzed przycisku awaryjnego
+	MacroActionSponsorsStart      = "sponsors_start"      // {output, channel, scene_name, source_name}
+	MacroActionSponsorsStop       = "sponsors_stop"       // {}
+)
+
+// MacroActions zawiera dozwolone akcje kroków makra
+var MacroActions = []string{
+	MacroActionSetScene, MacroActionToggleSource, MacroActionTakeSource, MacroActionSetSourceIndex,
+	MacroActionSetVolume, MacroActionWait, MacroActionOverlayMessage, MacroActionLoadMedia,
+	MacroActionLoadGroup, MacroActionMuteMicrophones, MacroActionRestoreMicrophones,
+	MacroActionStartRecording, MacroActionStopRecording, MacroActionStartStreaming,
+	MacroActionStopStreaming, MacroActionRundownNext, MacroActionRunMacro, MacroActionOBSRequest,
+	MacroActionOverlayShow, MacroActionOverlayHide, MacroActionRollCredits, MacroActionPanic,
+	MacroActionPanicResume, MacroActionSponsorsStart, MacroActionSponsorsStop,
+}
+
+// Polityka błędów makra
+const (
+	MacroOnErrorStop     = "stop"     // Przerwij makro przy pierwszym błędzie
+	MacroOnErrorContinue = "continue" // Zaloguj błąd i wykonuj dalej
+)
+
+// Macro reprezentuje zapisaną, nazwaną sekwencję akcji (np. otwarcie programu, przerwa)
+type Macro struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	Name        string      `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string      `gorm:"type:text" json:"description"`
+	OnError     string      `gorm:"size:20;default:'stop'" json:"on_error"` // stop lub continue
+	Color       string      `gorm:"size:20" json:"color"`                   // Kolor przycisku w kontrolerze
+	Steps       []MacroStep `gorm:"foreignKey:MacroID;constraint:OnDelete:CASCADE" json:"steps"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// MacroStep reprezentuje krok makra (akcja + parametry JSON)
+type MacroStep struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	MacroID     uint            `gorm:"index;not null" json:"macro_id"`
+	Order       int             `gorm:"not null" json:"order"`
+	Action      string          `gorm:"size:50;not null" json:"action"`
+	Params      json.RawMessage `gorm:"type:text" json:"params"`
+	Description string          `gorm:"size:300" json:"description"`
+}
+
+// Wyzwalacze reguł automatyzacji
+const (
+	RuleTriggerOBSEvent       = "obs_event"       // Dowolny event OBS: event_type + pola eventData
+	RuleTriggerProgramChanged = "program_changed" // Źródło sceny głównej weszło na antenę: scene_name, source_name
+	RuleTriggerMediaEnded     = "media_ended"     // Koniec odtwarzania: source_name, media_id, reason
+	RuleTriggerTimer          = "timer"           // Co every_s sekund lub raz po show_elapsed_s od startu programu
+	RuleTriggerSegmentStarted = "segment_started" // Start segmentu rundownu: segment_id, segment_type, scene_name, title
+)
+
+// RuleTriggers zawiera dozwolone wyzwalacze reguł
+var RuleTriggers = []string{
+	RuleTriggerOBSEvent, RuleTriggerProgramChanged, RuleTriggerMediaEnded,
+	RuleTriggerTimer, RuleTriggerSegmentStarted,
+}
+
+// Rule reprezentuje regułę automatyzacji: wyzwalacz + warunki → akcje (jak kroki makra)
+// Warunki to obiekt JSON pole → wartość (lub lista wartości) porównywany z danymi zdarzenia
+type Rule struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Name        string          `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string          `gorm:"type:text" json:"description"`
+	Enabled     bool            `gorm:"default:false" json:"enabled"`
+	IsSystem    bool            `gorm:"default:false" json:"is_system"` // Reguła domyślna - można wyłączyć, nie można usunąć
+	Trigger     string          `gorm:"size:50;index;not null" json:"trigger"`
+	Conditions  json.RawMessage `gorm:"type:text" json:"conditions"`
+	CooldownMs  int             `gorm:"default:0" json:"cooldown_ms"` // Minimalny odstęp między wykonaniami
+	Actions     []RuleAction    `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE" json:"actions"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// RuleAction reprezentuje akcję reguły (te same akcje co kroki makra)
+type RuleAction struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	RuleID      uint            `gorm:"index;not null" json:"rule_id"`
+	Order       int             `gorm:"not null" json:"order"`
+	Action      string          `gorm:"size:50;not null" json:"action"`
+	Params      json.RawMessage `gorm:"type:text" json:"params"`
+	Description string          `gorm:"size:300" json:"description"`
+}
+
+// ScriptEventManual oznacza skrypt uruchamiany tylko ręcznie (z UI lub REST)
+const ScriptEventManual = "manual"
+
+// Script reprezentuje skrypt Lua podpięty pod zdarzenie kontrolera (dla przypadków,
+// których nie pokrywają reguły). Zdarzenia jak wyzwalacze reguł (bez timer) + manual
+type Script struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Enabled     bool      `gorm:"default:false" json:"enabled"`
+	SeasonID    *uint     `gorm:"index" json:"season_id"` // nil = wszystkie sezony programu
+	Event       string    `gorm:"size:50;index;not null" json:"event"`
+	Code        string    `gorm:"type:text" json:"code"`
+	TimeoutMs   int       `gorm:"default:1000" json:"timeout_ms"` // Limit czasu wykonania
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ScheduleActionCheckReady sprawdza gotowość (OBS, pre-flight) i ostrzega realizatora
+const ScheduleActionCheckReady = "check_ready"
+
+// Statusy wykonania zaplanowanej akcji
+const (
+	ScheduleStatusDone      = "done"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+	ScheduleStatusMissed    = "missed" // Termin minął, gdy kontroler nie działał
+)
+
+// ScheduleEntry reprezentuje akcję planu emisji względem godziny emisji (Episode.AirTime)
+// Akcja to check_ready lub dowolna akcja kroku makra (np. run_macro otwarcia programu)
+type ScheduleEntry struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	Name          string          `gorm:"size:100;not null" json:"name"`
+	OffsetSeconds int             `gorm:"not null" json:"offset_seconds"` // <0 = przed emisją, 0 = o godzinie emisji
+	Action        string          `gorm:"size:50;not null" json:"action"`
+	Params        json.RawMessage `gorm:"type:text" json:"params"`
+	Enabled       bool            `gorm:"default:false" json:"enabled"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// ScheduleRun zapisuje wykonanie (lub anulowanie) akcji planu dla odcinka
+type ScheduleRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	EpisodeID  uint       `gorm:"uniqueIndex:idx_schedule_run;not null" json:"episode_id"`
+	EntryID    uint       `gorm:"uniqueIndex:idx_schedule_run;not null" json:"entry_id"`
+	Status     string     `gorm:"size:20;not null" json:"status"`
+	Error      string     `gorm:"type:text" json:"error"`
+	ExecutedAt *time.Time `json:"executed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Rodzaje wpisów dziennika operacji (undo)
+const (
+	JournalKindSourceVisibility = "source_visibility" // Widoczność źródła w scenie
+	JournalKindInputVolume      = "input_volume"      // Głośność wejścia audio
+	JournalKindSourceAssignment = "source_assignment" // Przypisanie media/grupy/kamery/mikrofonu do źródła
+	JournalKindGroupCurrent     = "group_current"     // Aktywne media w grupie
+)
+
+// JournalEntry zapisuje odwracalną operację operatora wraz ze stanem sprzed zmiany
+type JournalEntry struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Operator    string          `gorm:"size:100;index;not null" json:"operator"` // Nagłówek X-Operator lub adres IP klienta
+	Kind        string          `gorm:"size:30;not null" json:"kind"`
+	Description string          `gorm:"size:300" json:"description"`
+	Previous    json.RawMessage `gorm:"type:text" json:"previous"` // Stan do przywrócenia (zależny od Kind)
+	UndoneAt    *time.Time      `json:"undone_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// TickerMessage reprezentuje wiadomość paska informacyjnego (crawl) odcinka
+// Aktywna, gdy włączona i bieżący czas mieści się w oknie StartsAt-EndsAt (puste = bez ograniczeń)
+type TickerMessage struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	EpisodeID uint       `gorm:"index;not null" json:"episode_id"`
+	Order     int        `gorm:"not null" json:"order"` // Kolejność w odcinku
+	Text      string     `gorm:"size:500;not null" json:"text"`
+	Priority  int        `gorm:"default:0" json:"priority"` // Wyższy = wcześniej w cyklu; >0 wyróżniony na antenie
+	Enabled   bool       `gorm:"default:false" json:"enabled"`
+	StartsAt  *time.Time `json:"starts_at"` // Początek emisji (nullable)
+	EndsAt    *time.Time `json:"ends_at"`   // Koniec emisji (nullable)
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// PanicSettings - konfiguracja przycisku awaryjnego ("usterki techniczne"), jeden wiersz (ID = 1)
+// Pusta FallbackScene = zamiast zmiany sceny plansza title_card na pełnym ekranie overlayu
+type PanicSettings struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	FallbackScene string    `gorm:"size:100" json:"fallback_scene"` // Scena zastępcza w programie
+	CardTitle     string    `gorm:"size:200" json:"card_title"`     // Plansza overlayu
+	CardSubtitle  string    `gorm:"size:300" json:"card_subtitle"`  // Plansza overlayu
+	Channel       string    `gorm:"size:32" json:"channel"`         // Kanał overlayu planszy (pusty = main)
+	MusicScene    string    `gorm:"size:100" json:"music_scene"`    // Scena ze źródłem muzyki (pusta = tylko start odtwarzania)
+	MusicSource   string    `gorm:"size:100" json:"music_source"`   // Źródło mediów z muzyką w tle (puste = bez muzyki)
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PanicActivation - aktywacja przycisku awaryjnego z zapamiętanym stanem anteny
+// Aktywacja bez ResumedAt jest wczytywana po restarcie, więc powrót działa także po awarii kontrolera
+type PanicActivation struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	TriggeredBy string          `gorm:"size:50" json:"triggered_by"`
+	ActivatedAt time.Time       `json:"activated_at"`
+	ResumedAt   *time.Time      `gorm:"index" json:"resumed_at"`   // nil = tryb awaryjny nadal aktywny
+	Settings    json.RawMessage `gorm:"type:text" json:"settings"` // Konfiguracja użyta przy aktywacji (PanicSettings)
+	Snapshot    json.RawMessage `gorm:"type:text" json:"snapshot"` // Stan anteny sprzed aktywacji
+}
+
+// Rodzaje źródeł OBS natywnego overlayu (bez źródła przeglądarki)
+const (
+	NativeOverlayText  = "text"  // Tekst freetype2 / GDI+ - ustawienie "text"
+	NativeOverlayImage = "image" // Obraz (image_source) - ustawienie "file"
+)
+
+// NativeOverlayKinds zawiera dozwolone rodzaje źródeł natywnego overlayu
+var NativeOverlayKinds = []string{NativeOverlayText, NativeOverlayImage}
+
+// NativeOverlayTarget wiąże szablon overlayu na kanale ze źródłem OBS (tekst lub obraz)
+// Format: tekst z parametrami szablonu, np. "{name}" lub "{role} • {topic}"; dla obrazu ścieżka pliku
+// lub "{src}". Pokazanie szablonu = ustawienia + pozycja + widoczność, zdjęcie = ukrycie źródła
+type NativeOverlayTarget struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Channel    string    `gorm:"size:32;index;not null" json:"channel"`
+	Template   string    `gorm:"size:50;not null" json:"template"`
+	SceneName  string    `gorm:"size:100;not null" json:"scene_name"`
+	SourceName string    `gorm:"size:100;not null" json:"source_name"`
+	Kind       string    `gorm:"size:10;not null" json:"kind"`
+	Format     string    `gorm:"size:500" json:"format"`
+	PositionX  *float64  `json:"position_x"` // Pozycja w scenie (nullable = bez zmiany transformacji)
+	PositionY  *float64  `json:"position_y"`
+	Enabled    bool      `gorm:"default:false" json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Sponsor reprezentuje sponsora w rotacji logotypów
+// Weight - udział w czasie rotacji względem pozostałych, MinDisplayMs - minimalny czas jednego wyświetlenia
+type Sponsor struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	LogoFile     string    `gorm:"size:255" json:"logo_file"` // Plik w media/.sponsors (pusty = pomijany w rotacji)
+	Weight       int       `gorm:"not null" json:"weight"`    // 0 = sponsor pomijany w rotacji
+	MinDisplayMs int       `gorm:"default:10000;not null" json:"min_display_ms"`
+	Enabled      bool      `gorm:"default:false" json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Wyjścia rotacji sponsorów
+const (
+	SponsorOutputOverlay = "overlay" // Szablon sponsor na kanale overlayu
+	SponsorOutputOBS     = "obs"     // Źródło obrazu (image_source) w OBS
+)
+
+// SponsorAirtime - faktyczny czas logo sponsora na antenie (jedno wyświetlenie) do raportów
+type SponsorAirtime struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SponsorID  uint      `gorm:"index;not null" json:"sponsor_id"`
+	EpisodeID  uint      `gorm:"index;not null" json:"episode_id"`
+	Output     string    `gorm:"size:20;not null" json:"output"`
+	Target     string    `gorm:"size:100" json:"target"` // Kanał overlayu lub nazwa źródła OBS
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WatchFolder reprezentuje folder obserwowany pod kątem nowych plików (auto-import)
+// Zakres: konkretny odcinek (EpisodeID) lub sezon (SeasonID → aktualny odcinek sezonu)
+type WatchFolder struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Path      string    `gorm:"size:1000;not null" json:"path"`      // Ścieżka do obserwowanego folderu
+	EpisodeID *uint     `gorm:"index" json:"episode_id"`             // Import do konkretnego odcinka (nullable)
+	SeasonID  *uint     `gorm:"index" json:"season_id"`              // Import do aktualnego odcinka sezonu (nullable)
+	GroupName string    `gorm:"size:200;not null" json:"group_name"` // Grupa mediów docelowa, np. "REPORTAZE"
+	Move      bool      `gorm:"default:false" json:"move"`           // true = przenieś, false = kopiuj
+	Enabled   bool      `gorm:"default:false" json:"enabled"`        // Czy folder jest obserwowany
+	Pattern   string    `gorm:"size:100" json:"pattern"`             // Opcjonalny filtr nazw, np. "*.mp4"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WatchFolderImport reprezentuje plik już zaimportowany z obserwowanego folderu
+// Zapobiega ponownemu importowi (np. w trybie kopiowania lub po restarcie)
+type WatchFolderImport struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	WatchFolderID  uint      `gorm:"index;not null" json:"watch_folder_id"`
+	SourcePath     string    `gorm:"size:1000;not null" json:"source_path"`
+	Size           int64     `json:"size"`
+	ModTime        time.Time `json:"mod_time"`
+	EpisodeMediaID uint      `gorm:"index" json:"episode_media_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PlayDuration zwraca długość media po uwzględnieniu punktów wejścia/wyjścia (w sekundach)
+func (m *EpisodeMedia) PlayDuration() int {
+	endMs := m.Duration * 1000
+	if m.OutPoint > 0 && (endMs == 0 || m.OutPoint < endMs) {
+		endMs = m.OutPoint
+	}
+
+	played := endMs - m.InPoint
+	if played < 0 {
+		return 0
+	}
+	return played / 1000
+}
+
 // InitDB inicjalizuje bazę danych
 func InitDB(db *gorm.DB) error {
 	err := db.AutoMigrate(
@@ -240,6 +711,29 @@ func InitDB(db *gorm.DB) error {
 		&EpisodeSource{}, // NOWE: tabela pomostowa episode-source
 		&EpisodeMedia{},
 		&EpisodeMediaGroup{},
+		&WatchFolder{},
+		&WatchFolderImport{},
+		&RundownSegment{},
+		&RundownSegmentGuest{},
+		&RundownSegmentMedia{},
+		&RundownProgress{},
+		&ShowTiming{},
+		&AsRunEntry{},
+		&Macro{},
+		&MacroStep{},
+		&Rule{},
+		&RuleAction{},
+		&Script{},
+		&ScheduleEntry{},
+		&ScheduleRun{},
+		&JournalEntry{},
+		&TickerMessage{},
+		&SeasonTheme{},
+		&PanicSettings{},
+		&PanicActivation{},
+		&NativeOverlayTarget{},
+		&Sponsor{},
+		&SponsorAirtime{},
 	)
 
 	if err != nil {
@@ -251,6 +745,21 @@ func InitDB(db *gorm.DB) error {
 		return err
 	}
 
+	// Seed domyślnych reguł automatyzacji (mikrofony przy reportażu)
+	if err := SeedRules(db); err != nil {
+		return err
+	}
+
+	// Seed domyślnego planu emisji (wyłączone akcje do skonfigurowania)
+	if err := SeedScheduleEntries(db); err != nil {
+		return err
+	}
+
+	// Przenieś godzinę emisji zapisaną dotąd w dacie odcinka (migracja)
+	if err := migrateEpisodeAirTimes(db); err != nil {
+		return err
+	}
+
 	// Ustaw domyślny typ dla istniejących źródeł (migracja)
 	db.Exec("UPDATE sources SET source_type = 'UNKNOWN' WHERE source_type = '' OR source_type IS NULL")
 
@@ -260,6 +769,30 @@ func InitDB(db *gorm.DB) error {
 	return nil
 }
 
+// migrateEpisodeAirTimes ustawia AirTime odcinkom, których data zawiera godzinę emisji
+// Dawny format: data bez godziny to północ UTC, z godziną - czas lokalny ze strefą
+func migrateEpisodeAirTimes(db *gorm.DB) error {
+	var episodes []Episode
+	if err := db.Where("air_time IS NULL").Find(&episodes).Error; err != nil {
+		return err
+	}
+
+	for _, episode := range episodes {
+		date := episode.EpisodeDate
+		if date.IsZero() {
+			continue
+		}
+		if _, offset := date.Zone(); offset == 0 && date.Hour() == 0 && date.Minute() == 0 && date.Second() == 0 {
+			continue
+		}
+		if err := db.Model(&Episode{}).Where("id = ?", episode.ID).UpdateColumn("air_time", date).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetCurrentSeason pobiera aktualny sezon
 func GetCurrentSeason(db *gorm.DB) (*Season, error) {
 	var season Season
@@ -599,6 +1132,163 @@ func GetNextMediaGroupItemOrder(db *gorm.DB, groupID uint) int {
 	return maxItem.Order + 1
 }
 
+// GetMediaGroupTotalDuration zwraca łączny czas mediów w grupie (w sekundach)
+func GetMediaGroupTotalDuration(db *gorm.DB, groupID uint) int {
+	return GetMediaGroupTotalDurations(db, []uint{groupID})[groupID]
+}
+
+// GetMediaGroupTotalDurations zwraca łączny czas mediów grup jednym zapytaniem (group_id → sekundy)
+// Grupa gra jako playlista VLC, która nie zna punktów wejścia/wyjścia - liczone są pełne długości
+func GetMediaGroupTotalDurations(db *gorm.DB, groupIDs []uint) map[uint]int {
+	totals := make(map[uint]int, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return totals
+	}
+
+	var items []EpisodeMediaGroup
+	db.Joins("EpisodeMedia").Where("media_group_id IN ?", groupIDs).Find(&items)
+
+	for _, item := range items {
+		totals[item.MediaGroupID] += item.EpisodeMedia.Duration
+	}
+	return totals
+}
+
+// GetNextRundownSegmentOrder zwraca następny dostępny numer kolejności segmentu w odcinku
+func GetNextRundownSegmentOrder(db *gorm.DB, episodeID uint) int {
+	var maxSegment RundownSegment
+	result := db.Where("episode_id = ?", episodeID).Order("\"order\" DESC").First(&maxSegment)
+	if result.Error != nil {
+		return 0
+	}
+	return maxSegment.Order + 1
+}
+
+// GetNextTickerMessageOrder zwraca kolejny numer porządkowy wiadomości paska w odcinku
+func GetNextTickerMessageOrder(db *gorm.DB, episodeID uint) int {
+	var maxMessage TickerMessage
+	result := db.Where("episode_id = ?", episodeID).Order("\"order\" DESC").First(&maxMessage)
+	if result.Error != nil {
+		return 0
+	}
+	return maxMessage.Order + 1
+}
+
+// GetActiveTickerMessages pobiera wiadomości paska aktywne w chwili now (priorytet, potem kolejność)
+func GetActiveTickerMessages(db *gorm.DB, episodeID uint, now time.Time) ([]TickerMessage, error) {
+	var messages []TickerMessage
+	err := db.Where("episode_id = ? AND enabled = ?", episodeID, true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("priority DESC").Order("\"order\" ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// GetRundownSegments pobiera segmenty odcinka w kolejności, z gośćmi i mediami
+func GetRundownSegments(db *gorm.DB, episodeID uint) ([]RundownSegment, error) {
+	var segments []RundownSegment
+	err := db.Preload("CameraType").
+		Preload("Guests.Guest.GuestType").
+		Preload("Media", func(db *gorm.DB) *gorm.DB {
+			return db.Order("\"order\" ASC")
+		}).
+		Preload("Media.EpisodeMedia").
+		Preload("Media.MediaGroup").
+		Where("episode_id = ?", episodeID).
+		Order("\"order\" ASC").
+		Find(&segments).Error
+	return segments, err
+}
+
+// SegmentMediaDuration zwraca łączny czas mediów przypisanych do segmentu (w sekundach)
+func SegmentMediaDuration(db *gorm.DB, segment RundownSegment) int {
+	total := 0
+	groupIDs := make([]uint, 0)
+	for _, item := range segment.Media {
+		if item.EpisodeMedia != nil {
+			total += item.EpisodeMedia.PlayDuration()
+		} else if item.MediaGroupID != nil {
+			groupIDs = append(groupIDs, *item.MediaGroupID)
+		}
+	}
+
+	groupTotals := GetMediaGroupTotalDurations(db, groupIDs)
+	for _, groupID := range groupIDs {
+		total += groupTotals[groupID]
+	}
+	return total
+}
+
+// GetRundownProgress pobiera postęp rundownu odcinka (pusty, jeśli jeszcze nie rozpoczęty)
+func GetRundownProgress(db *gorm.DB, episodeID uint) (*RundownProgress, error) {
+	var progress RundownProgress
+	err := db.Where(RundownProgress{EpisodeID: episodeID}).FirstOrCreate(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// SetRundownProgress zapisuje bieżący segment rundownu i czas jego rozpoczęcia
+// segmentID = nil resetuje postęp
+func SetRundownProgress(db *gorm.DB, episodeID uint, segmentID *uint, startedAt *time.Time) error {
+	progress, err := GetRundownProgress(db, episodeID)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(progress).Updates(map[string]interface{}{
+		"current_segment_id": segmentID,
+		"segment_started_at": startedAt,
+	}).Error
+}
+
+// GetShowTiming pobiera czas emisji odcinka (pusty, jeśli program nie rozpoczęty)
+func GetShowTiming(db *gorm.DB, episodeID uint) (*ShowTiming, error) {
+	var timing ShowTiming
+	err := db.Where(ShowTiming{EpisodeID: episodeID}).FirstOrCreate(&timing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &timing, nil
+}
+
+// GetPanicSettings pobiera konfigurację przycisku awaryjnego (tworzy domyślną przy pierwszym odczycie)
+func GetPanicSettings(db *gorm.DB) (*PanicSettings, error) {
+	var settings PanicSettings
+	err := db.Where(PanicSettings{ID: 1}).Attrs(PanicSettings{
+		CardTitle:    "Przerwa techniczna",
+		CardSubtitle: "Przepraszamy za usterki, zaraz wracamy",
+	}).FirstOrCreate(&settings).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// GetSponsorAirtimeTotals sumuje czas antenowy sponsorów w odcinku (sponsor_id → ms)
+func GetSponsorAirtimeTotals(db *gorm.DB, episodeID uint) (map[uint]int64, error) {
+	var rows []struct {
+		SponsorID uint
+		Total     int64
+	}
+	err := db.Model(&SponsorAirtime{}).
+		Select("sponsor_id, SUM(duration_ms) AS total").
+		Where("episode_id = ?", episodeID).
+		Group("sponsor_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		totals[row.SponsorID] = row.Total
+	}
+	return totals, nil
+}
+
 // SetCurrentMediaGroup ustawia grupę jako aktywną w danej scenie lub w obu scenach
 // sceneID: konkretne ID sceny, lub 0 dla obu scen
 // Wyłącza inne grupy w tym odcinku dla tej sceny/scen
@@ -1014,3 +1704,67 @@ func SeedCameraTypes(db *gorm.DB) error {
 
 	return nil
 }
+
+// SeedRules tworzy domyślne reguły (dawne zachowanie manageMicrophones z kontrolera)
+func SeedRules(db *gorm.DB) error {
+	systemRules := []Rule{
+		{
+			Name:        "Reportaż wycisza mikrofony",
+			Description: "Gdy źródło REPORTAZE wchodzi na antenę, wszystkie mikrofony są wyłączane (bez zmiany is_visible)",
+			Enabled:     true,
+			IsSystem:    true,
+			Trigger:     RuleTriggerProgramChanged,
+			Conditions:  json.RawMessage(`{"scene_name":"REPORTAZE"}`),
+			Actions:     []RuleAction{{Order: 1, Action: MacroActionMuteMicrophones}},
+		},
+		{
+			Name:        "Kamery przywracają mikrofony",
+			Description: "Gdy źródło KAMERY wchodzi na antenę, włączane są mikrofony z is_visible = true",
+			Enabled:     true,
+			IsSystem:    true,
+			Trigger:     RuleTriggerProgramChanged,
+			Conditions:  json.RawMessage(`{"scene_name":"KAMERY"}`),
+			Actions:     []RuleAction{{Order: 1, Action: MacroActionRestoreMicrophones}},
+		},
+	}
+
+	for _, rule := range systemRules {
+		var existing Rule
+		result := db.Where("name = ?", rule.Name).First(&existing)
+
+		if result.Error == gorm.ErrRecordNotFound {
+			// Nie istnieje - utwórz
+			if err := db.Create(&rule).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SeedScheduleEntries tworzy domyślny plan emisji przy pierwszym uruchomieniu
+// Tylko ostrzeżenie o gotowości jest włączone - resztę realizator włącza po konfiguracji
+func SeedScheduleEntries(db *gorm.DB) error {
+	var count int64
+	db.Model(&ScheduleEntry{}).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	entries := []ScheduleEntry{
+		{Name: "Sprawdzenie gotowości", OffsetSeconds: -15 * 60, Action: ScheduleActionCheckReady, Enabled: true},
+		{Name: "Odliczanie przed programem", OffsetSeconds: -5 * 60, Action: MacroActionSetScene, Params: json.RawMessage(`{"scene_name":"ODLICZANIE"}`), Enabled: false},
+		{Name: "Start nagrywania", OffsetSeconds: -60, Action: MacroActionStartRecording, Enabled: false},
+		{Name: "Start streamu", OffsetSeconds: -60, Action: MacroActionStartStreaming, Enabled: false},
+		{Name: "Otwarcie programu", OffsetSeconds: 0, Action: MacroActionRunMacro, Params: json.RawMessage(`{"macro_name":"Otwarcie programu"}`), Enabled: false},
+	}
+
+	for _, entry := range entries {
+		if err := db.Create(&entry).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := InitDB(db); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	return db
+}
+
+func TestSeedScheduleEntriesKeepsDisabled(t *testing.T) {
+	db := openTestDB(t)
+
+	var entries []ScheduleEntry
+	if err := db.Order("id").Find(&entries).Error; err != nil {
+		t.Fatalf("load entries: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 seeded entries, got %d", len(entries))
+	}
+
+	for _, entry := range entries {
+		want := entry.Action == ScheduleActionCheckReady
+		if entry.Enabled != want {
+			t.Errorf("entry %q: enabled = %v, want %v", entry.Name, entry.Enabled, want)
+		}
+	}
+
+	// Ponowny seed nie może dublować wpisów
+	if err := SeedScheduleEntries(db); err != nil {
+		t.Fatalf("reseed: %v", err)
+	}
+	var count int64
+	db.Model(&ScheduleEntry{}).Count(&count)
+	if count != 5 {
+		t.Errorf("expected 5 entries after reseed, got %d", count)
+	}
+}
+
+func TestScheduleEntryCreatedDisabled(t *testing.T) {
+	db := openTestDB(t)
+
+	entry := ScheduleEntry{Name: "Test", Action: MacroActionStartRecording, Enabled: false}
+	if err := db.Create(&entry).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	var loaded ScheduleEntry
+	if err := db.First(&loaded, entry.ID).Error; err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Enabled {
+		t.Error("entry created with enabled=false was stored enabled")
+	}
+}
+
+func TestMigrateEpisodeAirTimes(t *testing.T) {
+	db := openTestDB(t)
+
+	season := Season{Number: 1}
+	db.Create(&season)
+
+	warsaw := time.FixedZone("CET", 3600)
+	withTime := Episode{SeasonID: season.ID, EpisodeNumber: 1, SeasonEpisode: 1, Title: "Z godziną",
+		EpisodeDate: time.Date(2026, 3, 1, 20, 0, 0, 0, warsaw)}
+	dateOnly := Episode{SeasonID: season.ID, EpisodeNumber: 2, SeasonEpisode: 2, Title: "Bez godziny",
+		EpisodeDate: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)}
+	db.Create(&withTime)
+	db.Create(&dateOnly)
+
+	if err := migrateEpisodeAirTimes(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var loaded Episode
+	db.First(&loaded, withTime.ID)
+	if loaded.AirTime == nil || !loaded.AirTime.Equal(withTime.EpisodeDate) {
+		t.Errorf("air time = %v, want %v", loaded.AirTime, withTime.EpisodeDate)
+	}
+
+	var loadedDateOnly Episode
+	db.First(&loadedDateOnly, dateOnly.ID)
+	if loadedDateOnly.AirTime != nil {
+		t.Errorf("date-only episode got air time %v", loadedDateOnly.AirTime)
+	}
+}
+
+func TestRuleCreatedDisabled(t *testing.T) {
+	db := openTestDB(t)
+
+	rule := Rule{Name: "Test", Trigger: RuleTriggerProgramChanged, Enabled: false}
+	if err := db.Create(&rule).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	var loaded Rule
+	db.First(&loaded, rule.ID)
+	if loaded.Enabled {
+		t.Error("rule created with enabled=false was stored enabled")
+	}
+
+	// Reguły systemowe z seeda są włączone
+	var systemRules []Rule
+	db.Where("is_system = ?", true).Find(&systemRules)
+	if len(systemRules) == 0 {
+		t.Fatal("no seeded system rules")
+	}
+	for _, r := range systemRules {
+		if !r.Enabled {
+			t.Errorf("system rule %q seeded disabled", r.Name)
+		}
+	}
+}
+
+func TestScriptCreatedDisabled(t *testing.T) {
+	db := openTestDB(t)
+
+	script := Script{Name: "Test", Event: ScriptEventManual, Enabled: false}
+	if err := db.Create(&script).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	var loaded Script
+	db.First(&loaded, script.ID)
+	if loaded.Enabled {
+		t.Error("script created with enabled=false was stored enabled")
+	}
+}
+
+func TestWatchFolderCreatedDisabled(t *testing.T) {
+	db := openTestDB(t)
+
+	folder := WatchFolder{Path: t.TempDir(), GroupName: "MEDIA", Enabled: false}
+	if err := db.Create(&folder).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	var loaded WatchFolder
+	db.First(&loaded, folder.ID)
+	if loaded.Enabled {
+		t.Error("watch folder created with enabled=false was stored enabled")
+	}
+}
+
+func TestMediaGroupTotalDurationsUntrimmed(t *testing.T) {
+	db := openTestDB(t)
+
+	season := Season{Number: 1}
+	db.Create(&season)
+	episode := Episode{SeasonID: season.ID, EpisodeNumber: 1, SeasonEpisode: 1, Title: "Grupy"}
+	db.Create(&episode)
+
+	groups := []MediaGroup{{EpisodeID: episode.ID, Name: "A", Order: 1}, {EpisodeID: episode.ID, Name: "B", Order: 2}, {EpisodeID: episode.ID, Name: "Pusta", Order: 3}}
+	db.Create(&groups)
+
+	// Przycięcie nie skraca pozycji playlisty VLC
+	trimmed := EpisodeMedia{EpisodeID: episode.ID, Title: "Przycięty", Duration: 120, InPoint: 10_000, OutPoint: 60_000}
+	full := EpisodeMedia{EpisodeID: episode.ID, Title: "Pełny", Duration: 30}
+	db.Create(&trimmed)
+	db.Create(&full)
+	db.Create(&[]EpisodeMediaGroup{
+		{EpisodeMediaID: trimmed.ID, MediaGroupID: groups[0].ID, Order: 1},
+		{EpisodeMediaID: full.ID, MediaGroupID: groups[0].ID, Order: 2},
+		{EpisodeMediaID: full.ID, MediaGroupID: groups[1].ID, Order: 1},
+	})
+
+	totals := GetMediaGroupTotalDurations(db, []uint{groups[0].ID, groups[1].ID, groups[2].ID})
+	if totals[groups[0].ID] != 150 || totals[groups[1].ID] != 30 || totals[groups[2].ID] != 0 {
+		t.Errorf("totals = %v, want A=150 B=30 empty=0", totals)
+	}
+	if got := GetMediaGroupTotalDuration(db, groups[0].ID); got != 150 {
+		t.Errorf("group A total = %d, want 150", got)
+	}
+}
@@ -1,6 +1,8 @@
 package obsws
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -9,17 +11,29 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// ErrUnsupportedRequest - OBS nie zna żądania (starsza wersja OBS lub obs-websocket)
+var ErrUnsupportedRequest = errors.New("żądanie nieobsługiwane przez OBS")
+
+// Subskrypcje eventów obs-websocket v5 (EventSubscription)
+const (
+	eventSubscriptionAll               = 0x7FF   // Wszystkie eventy o małej częstotliwości (domyślne)
+	eventSubscriptionInputVolumeMeters = 1 << 16 // Mierniki audio (~20 razy na sekundę)
+)
+
 // Client reprezentuje klienta OBS-WebSocket
 type Client struct {
 	conn          *websocket.Conn
 	mu            sync.Mutex
 	callbacks     map[string]chan map[string]interface{}
 	eventHandlers map[string][]func(map[string]interface{}) // Handlery eventów
+	anyHandlers   []func(string, map[string]interface{})    // Handlery wszystkich eventów
+	identified    []func()                                  // Handlery gotowości połączenia (także po reconnect)
 	eventMu       sync.RWMutex                              // Mutex dla eventów
 	requestID     int
 	address       string
 	reconnect     bool
 	connected     bool
+	volumeMeters  bool // Subskrypcja InputVolumeMeters (przywracana po ponownym połączeniu)
 }
 
 // Message reprezentuje wiadomość OBS-WebSocket
@@ -65,7 +79,8 @@ func (c *Client) connect() error {
 	identifyMsg := Message{
 		Op: 1,
 		D: map[string]interface{}{
-			"rpcVersion": 1,
+			"rpcVersion":         1,
+			"eventSubscriptions": c.eventSubscriptions(),
 		},
 	}
 
@@ -77,6 +92,35 @@ func (c *Client) connect() error {
 	return nil
 }
 
+// eventSubscriptions zwraca maskę subskrybowanych eventów
+func (c *Client) eventSubscriptions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.volumeMeters {
+		return eventSubscriptionAll | eventSubscriptionInputVolumeMeters
+	}
+	return eventSubscriptionAll
+}
+
+// SetVolumeMetersEnabled włącza/wyłącza event InputVolumeMeters (Reidentify, op code 3)
+func (c *Client) SetVolumeMetersEnabled(enabled bool) error {
+	c.mu.Lock()
+	c.volumeMeters = enabled
+	c.mu.Unlock()
+
+	if !c.IsConnected() {
+		return nil // Zostanie ustawione przy ponownym połączeniu
+	}
+
+	return c.send(Message{
+		Op: 3,
+		D: map[string]interface{}{
+			"eventSubscriptions": c.eventSubscriptions(),
+		},
+	})
+}
+
 // send wysyła wiadomość do OBS
 func (c *Client) send(msg Message) error {
 	c.mu.Lock()
@@ -112,6 +156,16 @@ func (c *Client) receiveMessages() {
 			return
 		}
 
+		// Połączenie zidentyfikowane - można wysyłać żądania (op code 2)
+		if msg.Op == 2 {
+			c.eventMu.RLock()
+			handlers := c.identified
+			c.eventMu.RUnlock()
+			for _, handler := range handlers {
+				go handler()
+			}
+		}
+
 		// Obsługa odpowiedzi na żądania (op code 7)
 		if msg.Op == 7 {
 			if requestID, ok := msg.D["requestId"].(string); ok {
@@ -137,6 +191,12 @@ func (c *Client) receiveMessages() {
 
 // Request wysyła żądanie do OBS i czeka na odpowiedź
 func (c *Client) Request(requestType string, requestData map[string]interface{}) (map[string]interface{}, error) {
+	return c.RequestContext(context.Background(), requestType, requestData)
+}
+
+// RequestContext wysyła żądanie do OBS i czeka na odpowiedź najdłużej do końca kontekstu
+// Odpowiedź, która nadejdzie później, jest pomijana
+func (c *Client) RequestContext(ctx context.Context, requestType string, requestData map[string]interface{}) (map[string]interface{}, error) {
 	c.mu.Lock()
 	if !c.connected {
 		c.mu.Unlock()
@@ -170,7 +230,15 @@ func (c *Client) Request(requestType string, requestData map[string]interface{})
 	}
 
 	// Czekaj na odpowiedź
-	response := <-responseChan
+	var response map[string]interface{}
+	select {
+	case response = <-responseChan:
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.callbacks, requestID)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("brak odpowiedzi OBS na %s: %w", requestType, ctx.Err())
+	}
 
 	// Sprawdź status
 	if status, ok := response["requestStatus"].(map[string]interface{}); ok {
@@ -187,14 +255,42 @@ func (c *Client) Request(requestType string, requestData map[string]interface{})
 
 // SetSourceVisibility ustawia widoczność źródła w scenie
 func (c *Client) SetSourceVisibility(sceneName, sourceName string, visible bool) error {
-	_, err := c.Request("SetSceneItemEnabled", map[string]interface{}{
+	return c.SetSourceVisibilityContext(context.Background(), sceneName, sourceName, visible)
+}
+
+// SetSourceVisibilityContext ustawia widoczność źródła w scenie z limitem czasu z kontekstu
+func (c *Client) SetSourceVisibilityContext(ctx context.Context, sceneName, sourceName string, visible bool) error {
+	_, err := c.RequestContext(ctx, "SetSceneItemEnabled", map[string]interface{}{
 		"sceneName":        sceneName,
-		"sceneItemId":      c.getSceneItemID(sceneName, sourceName),
+		"sceneItemId":      c.getSceneItemIDContext(ctx, sceneName, sourceName),
 		"sceneItemEnabled": visible,
 	})
 	return err
 }
 
+// GetSourceVisibility pobiera widoczność źródła w scenie
+func (c *Client) GetSourceVisibility(sceneName, sourceName string) (bool, error) {
+	return c.GetSourceVisibilityContext(context.Background(), sceneName, sourceName)
+}
+
+// GetSourceVisibilityContext pobiera widoczność źródła w scenie z limitem czasu z kontekstu
+func (c *Client) GetSourceVisibilityContext(ctx context.Context, sceneName, sourceName string) (bool, error) {
+	response, err := c.RequestContext(ctx, "GetSceneItemEnabled", map[string]interface{}{
+		"sceneName":   sceneName,
+		"sceneItemId": c.getSceneItemIDContext(ctx, sceneName, sourceName),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if responseData, ok := response["responseData"].(map[string]interface{}); ok {
+		if enabled, ok := responseData["sceneItemEnabled"].(bool); ok {
+			return enabled, nil
+		}
+	}
+	return false, fmt.Errorf("nie można pobrać widoczności źródła %s", sourceName)
+}
+
 // SetSceneItemIndex ustawia pozycję źródła w scenie (0 = najwyżej)
 func (c *Client) SetSceneItemIndex(sceneName, sourceName string, toTop bool) error {
 	sceneItemID := c.getSceneItemID(sceneName, sourceName)
@@ -236,9 +332,33 @@ func (c *Client) SetCurrentProgramScene(sceneName string) error {
 	return err
 }
 
+// GetCurrentProgramScene pobiera nazwę aktywnej sceny (program scene)
+func (c *Client) GetCurrentProgramScene() (string, error) {
+	return c.GetCurrentProgramSceneContext(context.Background())
+}
+
+// GetCurrentProgramSceneContext pobiera nazwę aktywnej sceny z limitem czasu z kontekstu
+func (c *Client) GetCurrentProgramSceneContext(ctx context.Context) (string, error) {
+	response, err := c.RequestContext(ctx, "GetCurrentProgramScene", nil)
+	if err != nil {
+		return "", err
+	}
+
+	if responseData, ok := response["responseData"].(map[string]interface{}); ok {
+		if sceneName, ok := responseData["currentProgramSceneName"].(string); ok {
+			return sceneName, nil
+		}
+	}
+	return "", fmt.Errorf("nie można pobrać sceny programu")
+}
+
 // getSceneItemID pobiera ID elementu sceny (uproszczona wersja - wymaga rozbudowy)
 func (c *Client) getSceneItemID(sceneName, sourceName string) int {
-	response, err := c.Request("GetSceneItemId", map[string]interface{}{
+	return c.getSceneItemIDContext(context.Background(), sceneName, sourceName)
+}
+
+func (c *Client) getSceneItemIDContext(ctx context.Context, sceneName, sourceName string) int {
+	response, err := c.RequestContext(ctx, "GetSceneItemId", map[string]interface{}{
 		"sceneName":  sceneName,
 		"sourceName": sourceName,
 	})
@@ -319,6 +439,26 @@ func (c *Client) SetSceneItemIndexByValue(sceneName, sourceName string, index in
 	return err
 }
 
+// SetSceneItemTransform ustawia transformację źródła w scenie (np. positionX, positionY, scaleX)
+func (c *Client) SetSceneItemTransform(sceneName, sourceName string, transform map[string]interface{}) error {
+	return c.SetSceneItemTransformContext(context.Background(), sceneName, sourceName, transform)
+}
+
+// SetSceneItemTransformContext ustawia transformację elementu sceny z limitem czasu z kontekstu
+func (c *Client) SetSceneItemTransformContext(ctx context.Context, sceneName, sourceName string, transform map[string]interface{}) error {
+	sceneItemID := c.getSceneItemIDContext(ctx, sceneName, sourceName)
+	if sceneItemID == 0 {
+		return fmt.Errorf("nie znaleziono źródła: %s w scenie: %s", sourceName, sceneName)
+	}
+
+	_, err := c.RequestContext(ctx, "SetSceneItemTransform", map[string]interface{}{
+		"sceneName":          sceneName,
+		"sceneItemId":        sceneItemID,
+		"sceneItemTransform": transform,
+	})
+	return err
+}
+
 // SetInputSettings ustawia ustawienia źródła wejściowego (np. plik dla Media Source)
 func (c *Client) SetInputSettings(inputName string, inputSettings map[string]interface{}) error {
 	_, err := c.Request("SetInputSettings", map[string]interface{}{
@@ -329,6 +469,21 @@ func (c *Client) SetInputSettings(inputName string, inputSettings map[string]int
 	return err
 }
 
+// UpdateInputSettings zmienia wybrane ustawienia źródła (pozostałe, np. czcionka tekstu, bez zmian)
+func (c *Client) UpdateInputSettings(inputName string, inputSettings map[string]interface{}) error {
+	return c.UpdateInputSettingsContext(context.Background(), inputName, inputSettings)
+}
+
+// UpdateInputSettingsContext zmienia wybrane ustawienia źródła z limitem czasu z kontekstu
+func (c *Client) UpdateInputSettingsContext(ctx context.Context, inputName string, inputSettings map[string]interface{}) error {
+	_, err := c.RequestContext(ctx, "SetInputSettings", map[string]interface{}{
+		"inputName":     inputName,
+		"inputSettings": inputSettings,
+		"overlay":       true,
+	})
+	return err
+}
+
 // SetInputVolume ustawia głośność źródła audio (w dB)
 func (c *Client) SetInputVolume(inputName string, inputVolumeDb float64) error {
 	_, err := c.Request("SetInputVolume", map[string]interface{}{
@@ -338,6 +493,128 @@ func (c *Client) SetInputVolume(inputName string, inputVolumeDb float64) error {
 	return err
 }
 
+// GetInputVolume pobiera głośność źródła audio (w dB)
+func (c *Client) GetInputVolume(inputName string) (float64, error) {
+	response, err := c.Request("GetInputVolume", map[string]interface{}{
+		"inputName": inputName,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if responseData, ok := response["responseData"].(map[string]interface{}); ok {
+		if volumeDb, ok := responseData["inputVolumeDb"].(float64); ok {
+			return volumeDb, nil
+		}
+	}
+	return 0, fmt.Errorf("nie można pobrać głośności %s", inputName)
+}
+
+// SetMediaInputCursor ustawia pozycję odtwarzania źródła mediów (w milisekundach)
+func (c *Client) SetMediaInputCursor(inputName string, cursorMs int) error {
+	_, err := c.Request("SetMediaInputCursor", map[string]interface{}{
+		"inputName":   inputName,
+		"mediaCursor": cursorMs,
+	})
+	return err
+}
+
+// GetMediaInputStatus pobiera stan odtwarzania źródła mediów
+// Zwraca stan (np. OBS_MEDIA_STATE_PLAYING), pozycję i długość w milisekundach
+func (c *Client) GetMediaInputStatus(inputName string) (string, int, int, error) {
+	response, err := c.Request("GetMediaInputStatus", map[string]interface{}{
+		"inputName": inputName,
+	})
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	if responseData, ok := response["responseData"].(map[string]interface{}); ok {
+		state, _ := responseData["mediaState"].(string)
+		cursor, _ := responseData["mediaCursor"].(float64)
+		duration, _ := responseData["mediaDuration"].(float64)
+		return state, int(cursor), int(duration), nil
+	}
+
+	return "", 0, 0, fmt.Errorf("nie można pobrać stanu mediów")
+}
+
+// TriggerMediaInputAction wywołuje akcję na źródle mediów
+// (np. OBS_WEBSOCKET_MEDIA_INPUT_ACTION_STOP)
+func (c *Client) TriggerMediaInputAction(inputName string, mediaAction string) error {
+	_, err := c.Request("TriggerMediaInputAction", map[string]interface{}{
+		"inputName":   inputName,
+		"mediaAction": mediaAction,
+	})
+	return err
+}
+
+// StartRecord rozpoczyna nagrywanie
+func (c *Client) StartRecord() error {
+	_, err := c.Request("StartRecord", nil)
+	return err
+}
+
+// StopRecord zatrzymuje nagrywanie
+func (c *Client) StopRecord() error {
+	_, err := c.Request("StopRecord", nil)
+	return err
+}
+
+// StartStream rozpoczyna streamowanie
+func (c *Client) StartStream() error {
+	_, err := c.Request("StartStream", nil)
+	return err
+}
+
+// StopStream zatrzymuje streamowanie
+func (c *Client) StopStream() error {
+	_, err := c.Request("StopStream", nil)
+	return err
+}
+
+// GetStreamActive sprawdza czy stream jest aktywny
+func (c *Client) GetStreamActive() (bool, error) {
+	return c.outputActive("GetStreamStatus")
+}
+
+// GetRecordActive sprawdza czy nagrywanie jest aktywne
+func (c *Client) GetRecordActive() (bool, error) {
+	return c.outputActive("GetRecordStatus")
+}
+
+// outputActive odczytuje pole outputActive z odpowiedzi Get*Status
+func (c *Client) outputActive(requestType string) (bool, error) {
+	resp, err := c.Request(requestType, nil)
+	if err != nil {
+		return false, err
+	}
+	if responseData, ok := resp["responseData"].(map[string]interface{}); ok {
+		if active, ok := responseData["outputActive"].(bool); ok {
+			return active, nil
+		}
+	}
+	return false, fmt.Errorf("invalid response format: missing outputActive")
+}
+
+// CreateRecordChapter dodaje znacznik rozdziału do trwającego nagrania
+// (OBS 30.2+, tylko formaty obsługujące rozdziały, np. Hybrid MP4)
+func (c *Client) CreateRecordChapter(chapterName string) error {
+	response, err := c.Request("CreateRecordChapter", map[string]interface{}{
+		"chapterName": chapterName,
+	})
+	if err != nil {
+		// Kod 204 = UnknownRequestType
+		if status, ok := response["requestStatus"].(map[string]interface{}); ok {
+			if code, ok := status["code"].(float64); ok && int(code) == 204 {
+				return ErrUnsupportedRequest
+			}
+		}
+		return err
+	}
+	return nil
+}
+
 // GetInputVolume pobiera aktualną głośność źródła audio (w dB)
 // func (c *Client) GetInputVolume(inputName string) (float64, error) {
 // 	resp, err := c.Request("GetInputVolume", map[string]interface{}{
@@ -368,12 +645,35 @@ func (c *Client) OnEvent(eventType string, handler func(map[string]interface{}))
 	log.Printf("Registered handler for event: %s", eventType)
 }
 
+// OnIdentified rejestruje handler wywoływany po każdym (ponownym) połączeniu z OBS,
+// gdy można już wysyłać żądania - np. do odczytu stanu, który potem śledzą eventy
+func (c *Client) OnIdentified(handler func()) {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+
+	c.identified = append(c.identified, handler)
+}
+
+// OnAnyEvent rejestruje handler wywoływany dla każdego eventu (z typem eventu)
+func (c *Client) OnAnyEvent(handler func(eventType string, eventData map[string]interface{})) {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+
+	c.anyHandlers = append(c.anyHandlers, handler)
+	log.Println("Registered handler for all events")
+}
+
 // triggerEvent wywołuje wszystkie handlery dla danego eventu
 func (c *Client) triggerEvent(eventType string, eventData map[string]interface{}) {
 	c.eventMu.RLock()
 	handlers := c.eventHandlers[eventType]
+	anyHandlers := c.anyHandlers
 	c.eventMu.RUnlock()
 
+	for _, handler := range anyHandlers {
+		go handler(eventType, eventData)
+	}
+
 	if len(handlers) > 0 {
 		// Wywołaj wszystkie handlery w osobnych goroutines
 		for _, handler := range handlers {
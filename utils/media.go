@@ -1,14 +1,35 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// Limit czasu pojedynczego odczytu ffprobe (plik lokalny lub URL)
+const mediaProbeTimeout = 30 * time.Second
+
 // GetMediaDuration zwraca długość pliku multimedialnego w sekundach używając ffprobe
 func GetMediaDuration(filePath string) (int, error) {
-	cmd := exec.Command("ffprobe",
+	ctx, cancel := context.WithTimeout(context.Background(), mediaProbeTimeout)
+	defer cancel()
+	return GetMediaDurationContext(ctx, filePath)
+}
+
+// GetMediaDurationContext działa jak GetMediaDuration, ale ffprobe jest zabijany
+// po przekroczeniu terminu z ctx (wolne lub zawieszone adresy sieciowe)
+func GetMediaDurationContext(ctx context.Context, filePath string) (int, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
 		"-v", "error",
 		"-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1",
@@ -16,6 +37,9 @@ func GetMediaDuration(filePath string) (int, error) {
 
 	output, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("ffprobe: %w", ctx.Err())
+		}
 		return 0, err
 	}
 
@@ -27,3 +51,149 @@ func GetMediaDuration(filePath string) (int, error) {
 
 	return int(duration), nil
 }
+
+// ErrURLNotAllowed - serwer nie pobiera adresu (protokół inny niż http/https lub host spoza sieci publicznej)
+var ErrURLNotAllowed = errors.New("adres URL niedozwolony")
+
+// AllowPrivateURLs pozwala serwerowi sprawdzać adresy w sieci lokalnej i na localhost
+// (MEDIA_URL_ALLOW_PRIVATE=1, np. media z NAS w studiu); adresy link-local są zawsze odrzucane
+var AllowPrivateURLs bool
+
+// CheckPublicURL sprawdza, czy serwer może pobrać adres: tylko http/https i host w sieci publicznej
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: protokół %q", ErrURLNotAllowed, parsed.Scheme)
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: brak hosta", ErrURLNotAllowed)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !allowedIP(addr.IP) {
+			return fmt.Errorf("%w: %s (%s)", ErrURLNotAllowed, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// allowedIP odrzuca adresy, pod którymi serwer mógłby sięgnąć do usług wewnętrznych
+func allowedIP(ip net.IP) bool {
+	if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() {
+		return AllowPrivateURLs
+	}
+	return true
+}
+
+// dialAllowedOnly sprawdza adres tuż przed połączeniem - także po przekierowaniu
+// i gdy DNS zwróci inny adres niż przy CheckPublicURL
+func dialAllowedOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !allowedIP(ip) {
+		return fmt.Errorf("%w: %s", ErrURLNotAllowed, host)
+	}
+	return nil
+}
+
+// CheckURLReachable sprawdza czy adres URL odpowiada (HEAD, a gdy serwer go nie obsługuje - GET)
+// Dla protokołów strumieniowych innych niż http/https (rtmp, srt, rtsp...) sprawdzenie jest pomijane -
+// serwer ich nie pobiera. Hosty spoza sieci publicznej zwracają ErrURLNotAllowed
+func CheckURLReachable(rawURL string, timeout time.Duration) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := CheckPublicURL(ctx, rawURL); err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: timeout, Control: dialAllowedOnly}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:       dialer.DialContext,
+			DisableKeepAlives: true,
+		},
+	}
+
+	resp, err := client.Head(rawURL)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			return nil
+		}
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			return fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+	} else if errors.Is(err, ErrURLNotAllowed) {
+		return err
+	}
+
+	// Część serwerów nie obsługuje HEAD - spróbuj GET z pobraniem tylko pierwszego bajtu
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GenerateProxy tworzy podgląd niskiej rozdzielczości (360p, H.264/AAC) używając ffmpeg
+// Plik jest najpierw zapisywany tymczasowo, a po zakończeniu przenoszony na docelową ścieżkę
+func GenerateProxy(srcPath, dstPath string) error {
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := dstPath + ".part.mp4"
+	cmd := exec.Command("ffmpeg",
+		"-y",
+		"-v", "error",
+		"-i", srcPath,
+		"-vf", "scale=-2:360",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "28",
+		"-c:a", "aac",
+		"-b:a", "96k",
+		"-movflags", "+faststart",
+		tmpPath)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return os.Rename(tmpPath, dstPath)
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGetMediaDurationContextKillsSlowProbe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffprobe is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nexec sleep 10\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(script), 0755); err != nil {
+		t.Fatalf("write fake ffprobe: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := GetMediaDurationContext(ctx, "http://example.invalid/stream.mp4")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("probe returned after %v, want it killed at the deadline", elapsed)
+	}
+}
+
+func TestCheckPublicURLRejectsInternalAddresses(t *testing.T) {
+	for _, rawURL := range []string{
+		"file:///etc/passwd",
+		"ftp://example.com/clip.mp4",
+		"http:///clip.mp4",
+		"http://127.0.0.1:8080/admin",
+		"http://[::1]/",
+		"http://10.1.2.3/clip.mp4",
+		"http://192.168.0.10/clip.mp4",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/",
+	} {
+		if err := CheckPublicURL(context.Background(), rawURL); !errors.Is(err, ErrURLNotAllowed) {
+			t.Errorf("%s: err = %v, want ErrURLNotAllowed", rawURL, err)
+		}
+	}
+	if err := CheckPublicURL(context.Background(), "https://93.184.216.34/clip.mp4"); err != nil {
+		t.Errorf("public address rejected: %v", err)
+	}
+}
+
+func TestCheckURLReachableDoesNotFetchInternalHosts(t *testing.T) {
+	fetched := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	if err := CheckURLReachable(srv.URL+"/clip.mp4", time.Second); !errors.Is(err, ErrURLNotAllowed) {
+		t.Errorf("loopback: err = %v, want ErrURLNotAllowed", err)
+	}
+	if fetched {
+		t.Error("server fetched a loopback address")
+	}
+
+	// Sieć lokalna dozwolona jawnie - przekierowanie do link-local i tak jest blokowane przy połączeniu
+	AllowPrivateURLs = true
+	defer func() { AllowPrivateURLs = false }()
+	if err := CheckURLReachable(srv.URL+"/clip.mp4", time.Second); err != nil {
+		t.Errorf("allowed private address: %v", err)
+	}
+	if err := CheckURLReachable(srv.URL+"/redirect", time.Second); !errors.Is(err, ErrURLNotAllowed) {
+		t.Errorf("redirect to link-local: err = %v, want ErrURLNotAllowed", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Monitor zegara dla prowadzących (confidence monitor)
+// Stan przychodzi z serwera (show_clock_tick), więc odświeżenie strony niczego nie gubi
+const socket = io();
+
+// Ile sekund przed końcem segmentu pokazać ostrzeżenie
+const WARNING_SECONDS = 30;
+
+function formatClock(seconds) {
+    const sign = seconds < 0 ? '-' : '';
+    seconds = Math.abs(seconds);
+    const h = Math.floor(seconds / 3600);
+    const m = Math.floor((seconds % 3600) / 60);
+    const s = seconds % 60;
+    const mmss = String(m).padStart(2, '0') + ':' + String(s).padStart(2, '0');
+    return sign + (h > 0 ? h + ':' + mmss : mmss);
+}
+
+function setText(id, text, className) {
+    const element = document.getElementById(id);
+    if (!element) return;
+    element.textContent = text;
+    element.classList.remove('clock-over', 'clock-warning');
+    if (className) {
+        element.classList.add(className);
+    }
+}
+
+socket.on('connect', () => {
+    setText('clockStatus', 'Połączono');
+});
+
+socket.on('disconnect', () => {
+    setText('clockStatus', 'Rozłączono - ponowne łączenie...', 'clock-over');
+});
+
+socket.on('show_clock_tick', (state) => {
+    const now = new Date(state.now);
+    setText('wallClock', now.toLocaleTimeString('pl-PL'));
+
+    if (state.started_at) {
+        setText('showElapsed', formatClock(state.elapsed));
+    } else {
+        setText('showElapsed', '--:--');
+    }
+
+    if (state.target_duration > 0 && state.started_at) {
+        setText('showRemaining', formatClock(state.remaining), state.remaining < 0 ? 'clock-over' : null);
+    } else {
+        setText('showRemaining', '--:--');
+    }
+
+    const segment = state.segment;
+    if (!segment) {
+        setText('segmentTitle', 'Brak segmentu');
+        setText('segmentRemaining', '--:--');
+        setText('segmentElapsed', '--:--');
+        setText('segmentPlanned', '--:--');
+        setText('showDrift', '--:--');
+        return;
+    }
+
+    setText('segmentTitle', (segment.index + 1) + '. ' + segment.title + (segment.running ? '' : ' (zatrzymany)'));
+    setText('segmentElapsed', formatClock(segment.elapsed));
+    setText('segmentPlanned', segment.planned > 0 ? formatClock(segment.planned) : '--:--');
+
+    if (segment.planned > 0) {
+        let className = null;
+        if (segment.remaining < 0) {
+            className = 'clock-over';
+        } else if (segment.remaining <= WARNING_SECONDS) {
+            className = 'clock-warning';
+        }
+        setText('segmentRemaining', formatClock(segment.remaining), className);
+    } else {
+        setText('segmentRemaining', formatClock(segment.elapsed));
+    }
+
+    setText('showDrift', formatClock(state.drift), state.drift > 0 ? 'clock-over' : null);
+});
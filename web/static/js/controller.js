@@ -67,12 +67,40 @@ function obsTransition() {
     });
 }
 
+// Zdarzenia odcinka (przypisania źródeł, niedostępne URL mediów) serwer wysyła do pokoju odcinka
+function joinEpisodeRoom(episodeId) {
+	socket.emit('join_episode', JSON.stringify({ episode_id: episodeId }), () => {});
+}
+
+async function joinCurrentEpisodeRoom() {
+	try {
+		const response = await fetch('/api/episodes?current=true');
+		const episodes = await response.json();
+		if (episodes && episodes.length > 0) {
+			joinEpisodeRoom(episodes[0].id);
+		}
+	} catch (error) {
+		console.error('Błąd dołączania do pokoju odcinka:', error);
+	}
+}
+
 socket.on('connect', () => {
 	console.log('Połączono z Socket.IO');
 	socketStatus.classList.add('connected');
+	joinCurrentEpisodeRoom();
 	loadAllScenes();
 });
 
+socket.on('current_episode_changed', (data) => {
+	console.log('Zmieniono aktualny odcinek:', data);
+	joinEpisodeRoom(data.episode_id);
+});
+
+socket.on('media_url_unreachable', (data) => {
+	console.warn('Niedostępny URL mediów:', data);
+	alert(`Media "${data.title}" (${data.source_name}) niedostępne: ${data.error}`);
+});
+
 socket.on('disconnect', () => {
 	console.log('Rozłączono z Socket.IO');
 	socketStatus.classList.remove('connected');
@@ -299,8 +327,8 @@ function switchMainSource(sceneName, sourceName, sceneItemId) {
 						source_name: sceneName,
 						to_top: true
 					}), () => {
+						// Mikrofony (reportaż/kamery) obsługują reguły automatyzacji na serwerze
 						turnOffAllMainScenes(sceneName, sourceName);
-						manageMicrophones(sceneName);
 						updateSourceButton(sceneName, sourceName, true);
 					});
 				});
@@ -309,23 +337,6 @@ function switchMainSource(sceneName, sourceName, sceneItemId) {
 	});
 }
 
-// Zarządzanie mikrofonami w zależności od sceny
-function manageMicrophones(sceneName) {
-	if (sceneName === 'REPORTAZE') {
-		// Wyłącz wszystkie mikrofony przy reportażu
-		console.log('Wyłączam wszystkie mikrofony (reportaż)');
-		socket.emit('mute_all_microphones', JSON.stringify({}), (response) => {
-			console.log('Mikrofony wyłączone:', response);
-		});
-	} else if (sceneName === 'KAMERY') {
-		// Przywróć mikrofony z is_visible = true przy kamerach
-		console.log('Przywracam aktywne mikrofony (kamery)');
-		socket.emit('restore_microphones', JSON.stringify({}), (response) => {
-			console.log('Mikrofony przywrócone:', response);
-		});
-	}
-}
-
 function turnOffAllMainScenes(exceptScene, exceptSource) {
 	MAIN_SCENES.forEach(sceneName => {
 		const containerId = `sources-${sceneName.toLowerCase()}`;
@@ -371,4 +382,341 @@ function updateSourceButton(sceneName, sourceName, visible) {
 			}
 		}
 	});
-}
\ No newline at end of file
+}
+// Rundown na żywo - klawisz "następny segment" (PageDown, np. pilot prezentera)
+function rundownStep(eventName) {
+	socket.emit(eventName, JSON.stringify({}), (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Rundown: ' + data.error);
+		}
+	});
+}
+
+document.addEventListener('keydown', (e) => {
+	const tag = e.target.tagName;
+	if (tag === 'INPUT' || tag === 'TEXTAREA' || tag === 'SELECT' || e.target.isContentEditable) return;
+
+	if (e.key === 'PageDown') {
+		e.preventDefault();
+		rundownStep('rundown_next');
+	} else if (e.key === 'PageUp') {
+		e.preventDefault();
+		rundownStep('rundown_previous');
+	} else if (e.key === 'z' && (e.ctrlKey || e.metaKey)) {
+		e.preventDefault();
+		undoLast();
+	}
+});
+
+// Makra - przyciski z zapisanymi sekwencjami akcji
+function loadMacros() {
+	socket.emit('macro_list', '', (response) => {
+		const data = JSON.parse(response);
+		const container = document.getElementById('macroButtons');
+		if (!container || !data.success) return;
+
+		container.innerHTML = '';
+		data.data.macros.forEach(macro => {
+			const button = document.createElement('button');
+			button.className = 'obs-btn';
+			button.textContent = macro.name;
+			button.title = macro.description || '';
+			if (macro.color) button.style.background = macro.color;
+			button.onclick = () => runMacro(macro.id);
+			container.appendChild(button);
+		});
+
+		const cancel = document.createElement('button');
+		cancel.className = 'obs-btn';
+		cancel.textContent = '✖ Anuluj makra';
+		cancel.onclick = cancelMacros;
+		container.appendChild(cancel);
+	});
+}
+
+function runMacro(macroId) {
+	socket.emit('macro_run', JSON.stringify({ macro_id: macroId }), (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Makro: ' + data.error);
+		}
+	});
+}
+
+function cancelMacros() {
+	socket.emit('macro_cancel', JSON.stringify({}), () => {});
+}
+
+socket.on('connect', loadMacros);
+
+socket.on('macro_progress', (progress) => {
+	const status = document.getElementById('macroStatus');
+	if (!status) return;
+	status.textContent = progress.macro_name + ' ' + (progress.step + 1) + '/' + progress.step_count;
+	status.style.color = progress.status === 'error' ? '#e74c3c' : '';
+});
+
+socket.on('macro_finished', (run) => {
+	const status = document.getElementById('macroStatus');
+	if (!status) return;
+	if (run.status === 'done') {
+		status.textContent = '';
+		return;
+	}
+	status.textContent = run.macro_name + ': ' + run.status;
+	status.style.color = '#e74c3c';
+	if (run.errors && run.errors.length > 0) {
+		console.error('Makro ' + run.macro_name + ':', run.errors);
+	}
+});
+
+// Paski z nazwiskami - przycisk 🏷 przy mikrofonie pokazuje/zdejmuje pasek osoby
+function toggleLowerThird(sourceName, isShown) {
+	if (isShown) {
+		socket.emit('lower_third_hide', '', () => {});
+		return;
+	}
+
+	socket.emit('lower_third_show', JSON.stringify({ source_name: sourceName }), (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Pasek: ' + data.error);
+		}
+	});
+}
+
+function markLowerThird(sourceName) {
+	document.querySelectorAll('.lower-third-btn').forEach(button => {
+		button.classList.toggle('active', sourceName !== null && button.dataset.sourceName === sourceName);
+	});
+}
+
+// Przyciski 🏷 pokazują stan kanału głównego (main)
+socket.on('overlay_show', (item) => {
+	if (item.channel === 'main' && item.template === 'lower_third') markLowerThird(item.params.source_name || null);
+});
+
+socket.on('overlay_hide', (data) => {
+	if (data.channel === 'main' && data.template === 'lower_third') markLowerThird(null);
+});
+
+// Automatyczne paski gości (aktywność mikrofonów)
+let speakerDetectEnabled = false;
+
+function renderSpeakerDetect(settings) {
+	speakerDetectEnabled = settings.enabled;
+	const button = document.getElementById('speakerDetectBtn');
+	if (!button) return;
+	button.textContent = '🎙 Auto-paski: ' + (settings.enabled ? 'wł.' : 'wył.');
+	button.classList.toggle('active', settings.enabled);
+}
+
+function toggleSpeakerDetect() {
+	socket.emit('speaker_detect_set', JSON.stringify({ enabled: !speakerDetectEnabled }), (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Auto-paski: ' + data.error);
+		}
+	});
+}
+
+socket.on('connect', () => {
+	socket.emit('speaker_detect_get', '', (response) => {
+		const data = JSON.parse(response);
+		if (data.success) renderSpeakerDetect(data.data);
+	});
+});
+
+socket.on('speaker_detect_settings', renderSpeakerDetect);
+
+// Pasek informacyjny (crawl) - wiadomości odcinka edytowane przez API trafiają na antenę na żywo
+let tickerRunning = false;
+
+function renderTickerState(state) {
+	tickerRunning = state.running;
+	const button = document.getElementById('tickerBtn');
+	if (!button) return;
+	button.textContent = '📰 Pasek: ' + (state.running ? 'wł. (' + state.messages.length + ')' : 'wył.');
+	button.classList.toggle('active', state.running);
+}
+
+function toggleTicker() {
+	socket.emit(tickerRunning ? 'ticker_stop' : 'ticker_start', '', (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Pasek informacyjny: ' + data.error);
+		}
+	});
+}
+
+socket.on('connect', () => {
+	socket.emit('ticker_state', '', (response) => {
+		const data = JSON.parse(response);
+		if (data.success) renderTickerState(data.data);
+	});
+});
+
+socket.on('ticker_state', renderTickerState);
+
+// Napisy końcowe - overlay zdejmuje je sam po przewinięciu
+function rollCredits() {
+	socket.emit('credits_roll', '', (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Napisy końcowe: ' + data.error);
+		}
+	});
+}
+
+// Rotacja logotypów sponsorów - czas na antenie zapisywany per sponsor i odcinek
+let sponsorsRunning = false;
+
+function renderSponsorRotationState(state) {
+	sponsorsRunning = state.running;
+	const button = document.getElementById('sponsorsBtn');
+	if (!button) return;
+	button.textContent = '🤝 Sponsorzy: ' + (state.running ? (state.current ? state.current.name : 'wł.') : 'wył.');
+	button.classList.toggle('active', state.running);
+}
+
+function toggleSponsors() {
+	socket.emit(sponsorsRunning ? 'sponsor_rotation_stop' : 'sponsor_rotation_start', '', (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Sponsorzy: ' + data.error);
+		}
+	});
+}
+
+socket.on('connect', () => {
+	socket.emit('sponsor_rotation_state', '', (response) => {
+		const data = JSON.parse(response);
+		if (data.success) renderSponsorRotationState(data.data);
+	});
+});
+
+socket.on('sponsor_rotation_state', renderSponsorRotationState);
+
+// Przycisk awaryjny - powrót przywraca scenę i mikrofony sprzed awarii
+let panicActive = false;
+
+function renderPanicState(state) {
+	panicActive = state.active;
+	const button = document.getElementById('panicBtn');
+	if (!button) return;
+	button.textContent = state.active ? '✅ Wracamy na antenę' : '🚨 Usterki';
+	button.classList.toggle('active', state.active);
+}
+
+function togglePanic() {
+	if (!panicActive && !confirm('Przełączyć na tryb awaryjny (usterki techniczne)?')) return;
+	socket.emit(panicActive ? 'panic_resume' : 'panic_activate', '', (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Tryb awaryjny: ' + data.error);
+		}
+	});
+}
+
+socket.on('connect', () => {
+	socket.emit('panic_state', '', (response) => {
+		const data = JSON.parse(response);
+		if (data.success) renderPanicState(data.data);
+	});
+});
+
+socket.on('panic_state', renderPanicState);
+
+// Dziennik operacji - cofnięcie ostatniej operacji tego stanowiska (Ctrl+Z)
+let journalOperator = null;
+
+function undoLast() {
+	socket.emit('undo_last', '', (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) {
+			alert('Cofnij: ' + data.error);
+			return;
+		}
+		renderJournalHistory(data.data.history);
+	});
+}
+
+function loadJournalHistory() {
+	socket.emit('journal_history', '', (response) => {
+		const data = JSON.parse(response);
+		if (!data.success) return;
+		journalOperator = data.data.operator;
+		renderJournalHistory(data.data.history);
+	});
+}
+
+function renderJournalHistory(entries) {
+	const list = document.getElementById('journalHistory');
+	if (!list) return;
+
+	list.innerHTML = '';
+	(entries || []).forEach(entry => {
+		const item = document.createElement('li');
+		item.textContent = new Date(entry.created_at).toLocaleTimeString() + ' ' + entry.description;
+		if (entry.undone_at) item.classList.add('undone');
+		list.appendChild(item);
+	});
+}
+
+socket.on('connect', loadJournalHistory);
+
+socket.on('journal_updated', (data) => {
+	if (journalOperator === null || data.operator === journalOperator) {
+		loadJournalHistory();
+	}
+});
+
+// Plan emisji - ostrzeżenie o braku gotowości przed emisją (kliknięcie ukrywa)
+socket.on('schedule_warning', (warning) => {
+	const element = document.getElementById('scheduleWarning');
+	if (!element) return;
+	element.textContent = '⚠️ ' + warning.problems.join(', ');
+	element.title = warning.name;
+	element.style.display = '';
+});
+
+socket.on('schedule_action', (action) => {
+	console.log('Plan emisji:', action);
+	if (action.status === 'failed' && action.action !== 'check_ready') {
+		alert('Plan emisji: ' + action.name + ' - ' + action.error);
+	}
+});
+
+socket.on('rundown_state', (state) => {
+	console.log('Rundown:', state);
+	if (state.current) {
+		currentActiveScene = state.current.scene_name || currentActiveScene;
+	}
+});
+
+// Zegar programu (show_clock_tick co sekundę z serwera)
+function formatClock(seconds) {
+	const sign = seconds < 0 ? '-' : '';
+	seconds = Math.abs(seconds);
+	const h = Math.floor(seconds / 3600);
+	const m = Math.floor((seconds % 3600) / 60);
+	const s = seconds % 60;
+	const mmss = String(m).padStart(2, '0') + ':' + String(s).padStart(2, '0');
+	return sign + (h > 0 ? h + ':' + mmss : mmss);
+}
+
+socket.on('show_clock_tick', (state) => {
+	const elapsed = document.getElementById('showClockElapsed');
+	const remaining = document.getElementById('segmentClockRemaining');
+	if (!elapsed || !remaining) return;
+
+	elapsed.textContent = state.started_at ? formatClock(state.elapsed) : '--:--';
+
+	if (state.segment && state.segment.running && state.segment.planned > 0) {
+		remaining.textContent = '(' + formatClock(state.segment.remaining) + ')';
+		remaining.style.color = state.segment.remaining < 0 ? '#e74c3c' : '';
+	} else {
+		remaining.textContent = '';
+	}
+});
@@ -264,6 +264,7 @@ async function openCreateModal() {
     const mm = String(today.getMonth() + 1).padStart(2, '0');
     const dd = String(today.getDate()).padStart(2, '0');
     document.getElementById('episodeDate').value = `${yyyy}-${mm}-${dd}`;
+    document.getElementById('episodeAirTime').value = '';
     
     // Switch to first tab
     switchTab('data');
@@ -283,9 +284,13 @@ function openEditModal(id) {
     document.getElementById('seasonEpisode').value = episode.season_episode;
     document.getElementById('episodeTitle').value = episode.title;
     
-    if (episode.episode_date) {
-        const date = new Date(episode.episode_date);
-        document.getElementById('episodeDate').value = date.toISOString().split('T')[0];
+    document.getElementById('episodeAirTime').value = '';
+    if (episode.air_time) {
+        const parts = splitAirTime(episode.air_time);
+        document.getElementById('episodeDate').value = parts.date;
+        document.getElementById('episodeAirTime').value = parts.time;
+    } else if (episode.episode_date) {
+        document.getElementById('episodeDate').value = new Date(episode.episode_date).toISOString().split('T')[0];
     }
     
     document.getElementById('episodeIsCurrent').checked = episode.is_current;
@@ -301,6 +306,31 @@ function closeModal() {
     currentEpisodeId = null;
 }
 
+// Godzina emisji zapisywana jest osobno jako czas lokalny ze strefą
+// (plan emisji liczy od tej chwili, brak godziny = null)
+function joinAirTime(date, time) {
+    if (!time) {
+        return null;
+    }
+
+    const local = new Date(`${date}T${time}:00`);
+    const offset = -local.getTimezoneOffset();
+    const sign = offset >= 0 ? '+' : '-';
+    const hh = String(Math.floor(Math.abs(offset) / 60)).padStart(2, '0');
+    const mm = String(Math.abs(offset) % 60).padStart(2, '0');
+    return `${date}T${time}:00${sign}${hh}:${mm}`;
+}
+
+function splitAirTime(value) {
+    const d = new Date(value);
+    const yyyy = d.getFullYear();
+    const mm = String(d.getMonth() + 1).padStart(2, '0');
+    const dd = String(d.getDate()).padStart(2, '0');
+    const hh = String(d.getHours()).padStart(2, '0');
+    const min = String(d.getMinutes()).padStart(2, '0');
+    return { date: `${yyyy}-${mm}-${dd}`, time: `${hh}:${min}` };
+}
+
 async function saveEpisode() {
     const id = document.getElementById('episodeId').value;
     const dateValue = document.getElementById('episodeDate').value;
@@ -327,7 +357,8 @@ async function saveEpisode() {
         episode_number: parseInt(document.getElementById('episodeNumber').value),
         season_episode: parseInt(document.getElementById('seasonEpisode').value),
         title: document.getElementById('episodeTitle').value,
-        episode_date: episodeDate + 'T00:00:00Z', // Dodaj czas dla kompatybilności z backendem
+        episode_date: episodeDate + 'T00:00:00Z',
+        air_time: joinAirTime(episodeDate, document.getElementById('episodeAirTime').value),
         is_current: document.getElementById('episodeIsCurrent').checked
     };
 
@@ -404,6 +404,13 @@ document.addEventListener('DOMContentLoaded', () => {
     document.getElementById('media-modal-close').addEventListener('click', closeMediaModal);
 });
 
+// Po zmianie aktualnego odcinka przypisania źródeł dotyczą nowego odcinka
+// (do pokoju odcinka dołącza controller.js)
+socket.on('current_episode_changed', (data) => {
+    currentEpisodeId = data.episode_id;
+    loadAllSourceAssignments();
+});
+
 // Załaduj aktualny odcinek przy połączeniu
 socket.on('connect', () => {
     loadCurrentEpisode().then(() => {
@@ -1,7 +1,31 @@
 const socket = io();
-        
+
+// Kanał overlayu z adresu strony: /overlay?channel=vertical (domyślnie main)
+const overlayChannel = new URLSearchParams(window.location.search).get('channel') || 'main';
+
+// Szablony aktualnie wyświetlane (template -> params), stan trzyma serwer
+const overlayItems = {};
+let lastClockState = null;
+let tickerTimer = null;
+let creditsFrame = null;
+
+// Pasek informacyjny - wiadomości przewijane po kolei
+let tickerMessages = [];
+let tickerIndex = 0;
+let tickerCurrent = null;
+let tickerPosition = 0;
+let tickerSpeed = 120;
+
 socket.on('connect', () => {
-    console.log('Overlay połączony');
+    console.log('Overlay połączony, kanał:', overlayChannel);
+
+    // Serwer przypisuje overlay do pokoju kanału i odsyła overlay_state z tym, co powinno być wyświetlane
+    socket.emit('overlay_hello', JSON.stringify({ channel: overlayChannel }), (response) => {
+        const data = JSON.parse(response);
+        if (!data.success) {
+            console.error('Overlay:', data.error);
+        }
+    });
 });
 
 socket.on('overlay_message', (data) => {
@@ -12,7 +36,7 @@ socket.on('overlay_message', (data) => {
         case 'show_transition':
             showTransition();
             break;
-            
+
         default:
             console.log('Nieznana akcja:', data.action);
     }
@@ -23,9 +47,313 @@ function showTransition() {
     const transitionBox = document.getElementById('transitionBox');
     if (transitionBox) {
         transitionBox.style.display = 'flex';
-        
+
         setTimeout(() => {
             transitionBox.style.display = 'none';
         }, 2000);
     }
-}
\ No newline at end of file
+}
+
+// ===== SZABLONY GRAFIK =====
+
+// Renderery szablonów: show(params) pokazuje/odświeża, hide() zdejmuje
+const overlayTemplates = {
+    lower_third: {
+        show(params) {
+            const role = [params.role, params.topic].filter(Boolean).join(' • ');
+            document.getElementById('lowerThirdName').textContent = params.name || '';
+            document.getElementById('lowerThirdRole').textContent = role;
+            const box = document.getElementById('lowerThird');
+            box.classList.toggle('no-role', role === '');
+            box.classList.add('visible');
+        },
+        hide() {
+            document.getElementById('lowerThird').classList.remove('visible');
+        }
+    },
+
+    title_card: {
+        show(params) {
+            document.getElementById('titleCardTitle').textContent = params.title || '';
+            document.getElementById('titleCardSubtitle').textContent = params.subtitle || '';
+            document.getElementById('titleCard').classList.add('visible');
+        },
+        hide() {
+            document.getElementById('titleCard').classList.remove('visible');
+        }
+    },
+
+    ticker: {
+        show(params) {
+            // Wiadomości: napisy lub {id, text, priority} (pasek informacyjny odcinka)
+            const messages = Array.isArray(params.messages) ? params.messages : [params.messages];
+            tickerMessages = messages
+                .map(message => typeof message === 'string' ? { text: message, priority: 0 } : message)
+                .filter(message => message && message.text);
+            tickerSpeed = params.speed || 120; // px/s
+
+            document.getElementById('ticker').classList.add('visible');
+            if (tickerTimer) {
+                // Edycja na żywo - bieżąca wiadomość dojeżdża do końca (z nową treścią), dalej nowa lista
+                refreshTickerMessage();
+                return;
+            }
+
+            // Przewijanie od prawej krawędzi do zniknięcia wiadomości, potem następna
+            const text = document.getElementById('tickerText');
+            tickerIndex = 0;
+            nextTickerMessage();
+            let last = performance.now();
+            tickerTimer = setInterval(() => {
+                const now = performance.now();
+                tickerPosition -= tickerSpeed * (now - last) / 1000;
+                last = now;
+                if (tickerPosition < -text.offsetWidth) {
+                    nextTickerMessage();
+                }
+                text.style.transform = `translateX(${tickerPosition}px)`;
+            }, 16);
+        },
+        hide() {
+            clearInterval(tickerTimer);
+            tickerTimer = null;
+            tickerCurrent = null;
+            document.getElementById('ticker').classList.remove('visible');
+        }
+    },
+
+    credits: {
+        show(params) {
+            const roll = document.getElementById('creditsRoll');
+            roll.innerHTML = '';
+
+            if (params.title) {
+                const title = document.createElement('div');
+                title.className = 'credits-title';
+                title.textContent = params.title;
+                roll.appendChild(title);
+            }
+            (params.groups || []).forEach(group => {
+                const block = document.createElement('div');
+                block.className = 'credits-group';
+                const title = document.createElement('div');
+                title.className = 'credits-group-title';
+                title.textContent = group.title;
+                block.appendChild(title);
+                (group.names || []).forEach(person => {
+                    const name = document.createElement('div');
+                    name.className = 'credits-name';
+                    name.textContent = person.name;
+                    if (person.detail) {
+                        const detail = document.createElement('span');
+                        detail.className = 'credits-detail';
+                        detail.textContent = person.detail;
+                        name.appendChild(detail);
+                    }
+                    block.appendChild(name);
+                });
+                roll.appendChild(block);
+            });
+
+            document.getElementById('credits').classList.add('visible');
+
+            // Od dolnej krawędzi do zniknięcia ostatniej grupy, potem overlay sam zdejmuje szablon
+            const speed = params.speed || 60; // px/s
+            let position = window.innerHeight;
+            let last = performance.now();
+            cancelAnimationFrame(creditsFrame);
+            const step = (now) => {
+                position -= speed * (now - last) / 1000;
+                last = now;
+                roll.style.transform = `translateY(${position}px)`;
+                if (position < -roll.offsetHeight) {
+                    creditsFrame = null;
+                    socket.emit('overlay_hide', JSON.stringify({ channel: overlayChannel, template: 'credits' }), () => {});
+                    return;
+                }
+                creditsFrame = requestAnimationFrame(step);
+            };
+            creditsFrame = requestAnimationFrame(step);
+        },
+        hide() {
+            cancelAnimationFrame(creditsFrame);
+            creditsFrame = null;
+            document.getElementById('credits').classList.remove('visible');
+        }
+    },
+
+    clock: {
+        show() {
+            document.getElementById('overlayClock').classList.add('visible');
+            renderClock();
+        },
+        hide() {
+            document.getElementById('overlayClock').classList.remove('visible');
+        }
+    },
+
+    sponsor: {
+        show(params) {
+            // Zmiana sponsora: wygaszenie, nowe logo po załadowaniu
+            const box = document.getElementById('sponsor');
+            const logo = document.getElementById('sponsorLogo');
+            if (logo.getAttribute('src') === params.src) {
+                box.classList.add('visible');
+                return;
+            }
+            box.classList.remove('visible');
+            setTimeout(() => {
+                logo.onload = () => box.classList.add('visible');
+                logo.alt = params.name || '';
+                logo.src = params.src;
+            }, 400);
+        },
+        hide() {
+            document.getElementById('sponsor').classList.remove('visible');
+        }
+    },
+
+    logo_bug: {
+        show(params) {
+            const logo = document.getElementById('logoOverlay');
+            logo.querySelector('img').src = params.src || themeLogoSrc || defaultLogoSrc;
+            logo.classList.remove('hidden');
+        },
+        hide() {
+            document.getElementById('logoOverlay').classList.add('hidden');
+        }
+    }
+};
+
+function renderTickerMessage(message) {
+    const text = document.getElementById('tickerText');
+    text.textContent = message ? message.text : '';
+    text.classList.toggle('urgent', !!message && message.priority > 0);
+}
+
+function nextTickerMessage() {
+    tickerPosition = window.innerWidth;
+    if (tickerMessages.length === 0) {
+        tickerCurrent = null;
+        renderTickerMessage(null);
+        return;
+    }
+    tickerIndex = tickerIndex % tickerMessages.length;
+    tickerCurrent = tickerMessages[tickerIndex++];
+    renderTickerMessage(tickerCurrent);
+}
+
+// Po zmianie listy: nowa treść bieżącej wiadomości, kolejność od pozycji bieżącej
+function refreshTickerMessage() {
+    if (!tickerCurrent || tickerCurrent.id === undefined) return;
+    const index = tickerMessages.findIndex(message => message.id === tickerCurrent.id);
+    if (index === -1) return;
+    tickerCurrent = tickerMessages[index];
+    tickerIndex = index + 1;
+    renderTickerMessage(tickerCurrent);
+}
+
+function showOverlayItem(item) {
+    const template = overlayTemplates[item.template];
+    if (!template) {
+        console.log('Nieznany szablon:', item.template);
+        return;
+    }
+    overlayItems[item.template] = item.params || {};
+    template.show(overlayItems[item.template]);
+}
+
+function hideOverlayItem(templateName) {
+    const template = overlayTemplates[templateName];
+    delete overlayItems[templateName];
+    if (template) template.hide();
+}
+
+// ===== MOTYW SEZONU =====
+
+// Logo z overlay.html, gdy sezon nie ma własnego
+const defaultLogoSrc = document.querySelector('#logoOverlay img').getAttribute('src');
+let themeLogoSrc = null;
+
+function hexToRgb(hex) {
+    const value = parseInt(hex.slice(1), 16);
+    return [(value >> 16) & 255, (value >> 8) & 255, value & 255].join(', ');
+}
+
+// Kolory, font, logo i układ paska aktualnego sezonu (CSS czyta zmienne --theme-*)
+function applyTheme(theme) {
+    if (!theme) return;
+
+    const root = document.documentElement.style;
+    root.setProperty('--theme-primary-rgb', hexToRgb(theme.primary_color));
+    root.setProperty('--theme-secondary-rgb', hexToRgb(theme.secondary_color));
+    root.setProperty('--theme-text', theme.text_color);
+    root.setProperty('--theme-accent', theme.accent_color);
+
+    let fontFamily = theme.font_family;
+    if (theme.font_url) {
+        const fontName = 'SeasonTheme' + theme.season_id;
+        const face = new FontFace(fontName, `url(${theme.font_url})`);
+        face.load()
+            .then(loaded => document.fonts.add(loaded))
+            .catch(error => console.error('Font motywu:', error));
+        fontFamily = `${fontName}, ${theme.font_family}`;
+    }
+    root.setProperty('--theme-font', fontFamily);
+
+    document.body.classList.remove('strap-left', 'strap-center', 'strap-right');
+    document.body.classList.add('strap-' + theme.strap_layout);
+
+    themeLogoSrc = theme.logo_url || null;
+    if (overlayItems.logo_bug) {
+        overlayTemplates.logo_bug.show(overlayItems.logo_bug);
+    }
+    console.log('Motyw sezonu:', theme.season_number);
+}
+
+socket.on('overlay_theme', applyTheme);
+
+// Pełny stan po (ponownym) połączeniu - zdejmij to, czego już nie ma, pokaż resztę
+socket.on('overlay_state', (state) => {
+    applyTheme(state.theme);
+    const items = state.items || [];
+    const shown = items.map(item => item.template);
+
+    Object.keys(overlayTemplates).forEach(name => {
+        if (!shown.includes(name)) hideOverlayItem(name);
+    });
+    items.forEach(showOverlayItem);
+});
+
+socket.on('overlay_show', showOverlayItem);
+socket.on('overlay_update', showOverlayItem);
+socket.on('overlay_hide', (data) => hideOverlayItem(data.template));
+
+// Zegar programu - szablon clock (mode: time / elapsed / remaining)
+function formatOverlayDuration(seconds) {
+    const sign = seconds < 0 ? '-' : '';
+    seconds = Math.abs(seconds);
+    const m = Math.floor(seconds / 60);
+    const s = seconds % 60;
+    return sign + String(m).padStart(2, '0') + ':' + String(s).padStart(2, '0');
+}
+
+function renderClock() {
+    const clock = document.getElementById('showClock');
+    if (!clock || !lastClockState) return;
+
+    const mode = (overlayItems.clock && overlayItems.clock.mode) || 'time';
+    if (mode === 'elapsed') {
+        clock.textContent = formatOverlayDuration(lastClockState.elapsed);
+    } else if (mode === 'remaining') {
+        clock.textContent = formatOverlayDuration(lastClockState.remaining);
+    } else {
+        const now = new Date(lastClockState.now);
+        clock.textContent = now.toLocaleTimeString('pl-PL', { hour: '2-digit', minute: '2-digit' });
+    }
+}
+
+socket.on('show_clock_tick', (state) => {
+    lastClockState = state;
+    renderClock();
+});
@@ -0,0 +1,203 @@
+// scripts.js - Edycja skryptów Lua i podgląd ich logów
+
+const socket = io();
+
+let scripts = [];
+let selectedId = null;
+
+socket.on('connect', () => {
+    document.getElementById('socketStatus').classList.add('connected');
+});
+
+socket.on('disconnect', () => {
+    document.getElementById('socketStatus').classList.remove('connected');
+});
+
+// Logi na żywo z serwera (tylko dla wybranego skryptu)
+socket.on('script_log', (entry) => {
+    if (entry.script_id === selectedId) {
+        appendLog(entry);
+    }
+});
+
+async function loadEvents() {
+    const response = await fetch('/api/scripts/events');
+    const events = await response.json();
+    document.getElementById('scriptEvent').innerHTML = events
+        .map(event => `<option value="${event}">${event}</option>`)
+        .join('');
+}
+
+async function loadSeasons() {
+    const response = await fetch('/api/seasons');
+    const seasons = await response.json();
+    const select = document.getElementById('scriptSeason');
+    seasons.forEach(season => {
+        const option = document.createElement('option');
+        option.value = season.id;
+        option.textContent = 'Sezon ' + season.number;
+        select.appendChild(option);
+    });
+}
+
+async function loadScripts() {
+    try {
+        const response = await fetch('/api/scripts');
+        scripts = await response.json();
+        renderScripts();
+    } catch (error) {
+        console.error('Błąd ładowania skryptów:', error);
+        document.getElementById('scriptsContainer').innerHTML =
+            '<div class="error">Błąd ładowania danych</div>';
+    }
+}
+
+function renderScripts() {
+    const container = document.getElementById('scriptsContainer');
+
+    if (scripts.length === 0) {
+        container.innerHTML = '<div class="empty">Brak skryptów</div>';
+        return;
+    }
+
+    container.innerHTML = '';
+    scripts.forEach(script => {
+        const item = document.createElement('div');
+        item.className = 'list-item' +
+            (script.id === selectedId ? ' selected' : '') +
+            (script.enabled ? '' : ' disabled');
+        item.onclick = () => selectScript(script.id);
+
+        const name = document.createElement('span');
+        name.textContent = script.name;
+        const badge = document.createElement('span');
+        badge.className = 'list-item-badge';
+        badge.textContent = script.event;
+
+        item.appendChild(name);
+        item.appendChild(badge);
+        container.appendChild(item);
+    });
+}
+
+function newScript() {
+    selectedId = null;
+    document.getElementById('scriptForm').reset();
+    document.getElementById('scriptId').value = '';
+    document.getElementById('scriptLogs').innerHTML = '';
+    renderScripts();
+}
+
+async function selectScript(id) {
+    const script = scripts.find(s => s.id === id);
+    if (!script) return;
+
+    selectedId = id;
+    document.getElementById('scriptId').value = script.id;
+    document.getElementById('scriptName').value = script.name;
+    document.getElementById('scriptEvent').value = script.event;
+    document.getElementById('scriptSeason').value = script.season_id || '';
+    document.getElementById('scriptTimeout').value = script.timeout_ms;
+    document.getElementById('scriptEnabled').checked = script.enabled;
+    document.getElementById('scriptDescription').value = script.description || '';
+    document.getElementById('scriptCode').value = script.code || '';
+    renderScripts();
+
+    const response = await fetch(`/api/scripts/${id}/logs`);
+    const logs = await response.json();
+    document.getElementById('scriptLogs').innerHTML = '';
+    logs.forEach(appendLog);
+}
+
+async function saveScript(event) {
+    event.preventDefault();
+
+    const id = document.getElementById('scriptId').value;
+    const seasonId = document.getElementById('scriptSeason').value;
+    const data = {
+        name: document.getElementById('scriptName').value,
+        event: document.getElementById('scriptEvent').value,
+        season_id: seasonId ? parseInt(seasonId) : null,
+        timeout_ms: parseInt(document.getElementById('scriptTimeout').value) || 1000,
+        enabled: document.getElementById('scriptEnabled').checked,
+        description: document.getElementById('scriptDescription').value,
+        code: document.getElementById('scriptCode').value
+    };
+
+    const response = await fetch(id ? `/api/scripts/${id}` : '/api/scripts', {
+        method: id ? 'PUT' : 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(data)
+    });
+
+    if (!response.ok) {
+        alert('Błąd zapisu: ' + await response.text());
+        return;
+    }
+
+    const saved = await response.json();
+    await loadScripts();
+    selectScript(saved.id);
+}
+
+async function runScript() {
+    if (!selectedId) return;
+
+    const response = await fetch(`/api/scripts/${selectedId}/run`, { method: 'POST' });
+    if (!response.ok) {
+        // Błąd trafia też do logu przez script_log
+        console.error('Skrypt:', await response.text());
+    }
+}
+
+async function clearLogs() {
+    if (!selectedId) return;
+
+    await fetch(`/api/scripts/${selectedId}/logs`, { method: 'DELETE' });
+    document.getElementById('scriptLogs').innerHTML = '';
+}
+
+async function deleteScript() {
+    if (!selectedId) return;
+
+    const script = scripts.find(s => s.id === selectedId);
+    if (!confirm(`Czy na pewno usunąć skrypt "${script.name}"?`)) return;
+
+    const response = await fetch(`/api/scripts/${selectedId}`, { method: 'DELETE' });
+    if (!response.ok) {
+        alert('Błąd usuwania: ' + await response.text());
+        return;
+    }
+
+    newScript();
+    loadScripts();
+}
+
+function appendLog(entry) {
+    const container = document.getElementById('scriptLogs');
+    const line = document.createElement('div');
+    line.className = 'log-line ' + entry.level;
+
+    const time = document.createElement('span');
+    time.className = 'log-time';
+    time.textContent = new Date(entry.time).toLocaleTimeString('pl-PL');
+
+    line.appendChild(time);
+    line.appendChild(document.createTextNode(entry.message));
+    container.appendChild(line);
+    container.scrollTop = container.scrollHeight;
+}
+
+// Tab w edytorze wstawia tabulator zamiast zmieniać fokus
+document.getElementById('scriptCode').addEventListener('keydown', (e) => {
+    if (e.key !== 'Tab') return;
+    e.preventDefault();
+    const area = e.target;
+    const start = area.selectionStart;
+    area.value = area.value.substring(0, start) + '\t' + area.value.substring(area.selectionEnd);
+    area.selectionStart = area.selectionEnd = start + 1;
+});
+
+loadEvents();
+loadSeasons();
+loadScripts();
@@ -136,6 +136,18 @@ function renderSourceWithVolume(source, sceneName, container) {
             openMicrophoneAssignModal(sourceName, sceneName);
         };
         buttonWrapper.appendChild(modalButton);
+
+        // Pasek z nazwiskiem osoby przy mikrofonie (ponowne kliknięcie zdejmuje)
+        const strapButton = document.createElement('button');
+        strapButton.className = 'open-modal-btn lower-third-btn';
+        strapButton.textContent = '🏷';
+        strapButton.title = 'Pokaż pasek z nazwiskiem';
+        strapButton.dataset.sourceName = sourceName;
+        strapButton.onclick = (e) => {
+            e.stopPropagation();
+            toggleLowerThird(sourceName, strapButton.classList.contains('active'));
+        };
+        buttonWrapper.appendChild(strapButton);
     }
     
     mainWrapper.appendChild(buttonWrapper);